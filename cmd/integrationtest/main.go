@@ -0,0 +1,154 @@
+// Command integrationtest manages the Docker resources that the packages
+// of this module create.
+//
+// Usage:
+//
+//	go run github.com/olivere/integrationtest/cmd/integrationtest prune [flags]
+//
+// The prune command force-removes the containers and volumes that are
+// labeled by this module, e.g. those left behind by aborted test runs.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+func main() {
+	if err := run(context.Background(), os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "integrationtest: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+const usage = `Usage: integrationtest <command> [flags]
+
+Commands:
+  prune   remove containers and volumes created by integration tests
+`
+
+func run(ctx context.Context, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return errors.New("missing command")
+	}
+	switch args[0] {
+	case "prune":
+		return prune(ctx, args[1:], w)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(w, usage)
+		return nil
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+func prune(ctx context.Context, args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("prune", flag.ContinueOnError)
+	var (
+		olderThan = fs.Duration("older-than", 0, "only remove resources started at least this long ago, e.g. 1h")
+		pkg       = fs.String("package", "", "only remove resources of this package, e.g. postgres")
+		dryRun    = fs.Bool("dry-run", false, "list the resources without removing them")
+	)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+
+	pool, err := dockerutil.NewPool()
+	if err != nil {
+		return fmt.Errorf("unable to connect to Docker: %w", err)
+	}
+	client := pool.Client
+
+	containers, err := client.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {core.LabelSession}},
+		Context: ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("could not list containers: %w", err)
+	}
+	now := time.Now()
+	for _, c := range containers {
+		if !matches(c.Labels, *pkg, *olderThan, now) {
+			continue
+		}
+		fmt.Fprintf(w, "container %s (%s)\n", shortID(c.ID), c.Labels[core.LabelPackage])
+		if *dryRun {
+			continue
+		}
+		err := client.RemoveContainer(docker.RemoveContainerOptions{
+			ID:            c.ID,
+			Force:         true,
+			RemoveVolumes: true,
+			Context:       ctx,
+		})
+		var notFound *docker.NoSuchContainer
+		if err != nil && !errors.As(err, &notFound) {
+			return fmt.Errorf("could not remove container %s: %w", shortID(c.ID), err)
+		}
+	}
+
+	volumes, err := client.ListVolumes(docker.ListVolumesOptions{
+		Filters: map[string][]string{"label": {core.LabelSession}},
+		Context: ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("could not list volumes: %w", err)
+	}
+	for _, v := range volumes {
+		if !matches(v.Labels, *pkg, *olderThan, now) {
+			continue
+		}
+		fmt.Fprintf(w, "volume %s (%s)\n", v.Name, v.Labels[core.LabelPackage])
+		if *dryRun {
+			continue
+		}
+		err := client.RemoveVolumeWithOptions(docker.RemoveVolumeOptions{
+			Name:    v.Name,
+			Force:   true,
+			Context: ctx,
+		})
+		if err != nil && !errors.Is(err, docker.ErrNoSuchVolume) {
+			return fmt.Errorf("could not remove volume %s: %w", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// matches returns true if a resource with the given labels belongs to pkg,
+// if set, and was started at least olderThan before now. Resources without
+// a valid start time only match if there is no age filter.
+func matches(labels map[string]string, pkg string, olderThan time.Duration, now time.Time) bool {
+	if pkg != "" && labels[core.LabelPackage] != pkg {
+		return false
+	}
+	if olderThan <= 0 {
+		return true
+	}
+	started, err := time.Parse(time.RFC3339, labels[core.LabelStarted])
+	if err != nil {
+		return false
+	}
+	return now.Sub(started) >= olderThan
+}
+
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
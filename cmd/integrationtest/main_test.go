@@ -0,0 +1,37 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+func TestMatches(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	labels := map[string]string{
+		core.LabelPackage: "postgres",
+		core.LabelStarted: now.Add(-2 * time.Hour).Format(time.RFC3339),
+	}
+
+	tests := []struct {
+		labels    map[string]string
+		pkg       string
+		olderThan time.Duration
+		want      bool
+	}{
+		{labels, "", 0, true},
+		{labels, "postgres", 0, true},
+		{labels, "elasticsearch", 0, false},
+		{labels, "", time.Hour, true},
+		{labels, "", 3 * time.Hour, false},
+		{labels, "postgres", time.Hour, true},
+		{map[string]string{core.LabelPackage: "postgres"}, "", 0, true},
+		{map[string]string{core.LabelPackage: "postgres"}, "", time.Hour, false},
+	}
+	for i, tt := range tests {
+		if have := matches(tt.labels, tt.pkg, tt.olderThan, now); tt.want != have {
+			t.Errorf("#%d: want %v, have %v", i, tt.want, have)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+// Package compose starts the services of a docker-compose.yml for
+// integration tests, for projects whose stack is already described in a
+// Compose file.
+//
+// It uses the "docker compose" command, i.e. Docker Compose V2 must be
+// installed.
+package compose
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
+)
+
+// Stack is a Compose project that was started from a Compose file.
+type Stack struct {
+	project  string
+	files    []string
+	env      []string
+	services []string
+	timeout  time.Duration
+
+	mu     sync.Mutex
+	closed bool
+}
+
+type startConfig struct {
+	project  string
+	files    []string
+	services []string
+	env      []string
+	timeout  time.Duration
+	skip     bool
+}
+
+type startConfigFunc func(*startConfig)
+
+// WithOptions applies the options that all container packages support,
+// see core.Config. The version is ignored, as the Compose file specifies
+// the images of the services.
+func WithOptions(options ...core.Option) startConfigFunc {
+	return func(cfg *startConfig) {
+		coreCfg := core.NewConfig(options...)
+		if coreCfg.Timeout != 0 {
+			cfg.timeout = coreCfg.Timeout
+		}
+		cfg.env = append(cfg.env, coreCfg.Env...)
+	}
+}
+
+// WithServices only starts the given services, and the services they
+// depend on. It defaults to all services of the Compose file.
+func WithServices(services ...string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.services = append(cfg.services, services...)
+	}
+}
+
+// WithEnv sets variables, in the form "KEY=value", for the substitution of
+// variables like ${KEY} in the Compose file. They take precedence over the
+// environment of the test process.
+func WithEnv(env ...string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.env = append(cfg.env, env...)
+	}
+}
+
+// WithFiles adds Compose files that override the first one, like passing
+// additional -f flags to docker compose.
+func WithFiles(files ...string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.files = append(cfg.files, files...)
+	}
+}
+
+// WithProjectName sets the name of the Compose project. It defaults to
+// a unique name, so that tests can start the same Compose file in
+// parallel.
+func WithProjectName(project string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.project = project
+	}
+}
+
+// WithTimeout sets the time to wait for the services to become healthy.
+// It defaults to 2 minutes.
+func WithTimeout(timeout time.Duration) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.timeout = timeout
+	}
+}
+
+// WithSkipIfNoDocker skips the test, instead of failing it, if the Docker
+// daemon is unavailable. See core.SkipIfUnavailable.
+func WithSkipIfNoDocker() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.skip = true
+	}
+}
+
+// Start the services of the Compose file and wait for them to become
+// healthy, or running if they have no health check. The services are
+// removed, including their volumes, when the test finishes.
+func Start(tb testing.TB, file string, options ...startConfigFunc) *Stack {
+	tb.Helper()
+
+	var cfg startConfig
+	for _, o := range options {
+		o(&cfg)
+	}
+	if cfg.skip {
+		core.SkipIfUnavailable(tb)
+	}
+
+	s, err := start(context.Background(), file, options...)
+	if s != nil {
+		tb.Cleanup(func() {
+			s.Close()
+		})
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return s
+}
+
+// StartE is like Start but returns an error instead of failing a test,
+// e.g. to start a stack in TestMain. The caller is responsible for closing
+// the stack.
+func StartE(file string, options ...startConfigFunc) (*Stack, error) {
+	s, err := start(context.Background(), file, options...)
+	if err != nil {
+		if s != nil {
+			s.Close()
+		}
+		return nil, err
+	}
+	return s, nil
+}
+
+// start the services of the Compose file. If it returns an error along
+// with a non-nil Stack, the caller is responsible for closing it.
+func start(ctx context.Context, file string, options ...startConfigFunc) (*Stack, error) {
+	startCfg := startConfig{
+		project: dockerutil.Name("integrationtest", ""),
+		timeout: 2 * time.Minute,
+	}
+	for _, o := range options {
+		o(&startCfg)
+	}
+
+	s := &Stack{
+		project:  startCfg.project,
+		files:    slices.Concat([]string{file}, startCfg.files),
+		env:      startCfg.env,
+		services: startCfg.services,
+		timeout:  startCfg.timeout,
+	}
+
+	pool, err := dockerutil.Pool()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to Docker: %w", err)
+	}
+	core.ReapOnce(pool.Client)
+	if os.Getenv("DOCKER_HOST") == "" {
+		// Use the same daemon as the other packages if it was discovered
+		s.env = append([]string{"DOCKER_HOST=" + pool.Client.Endpoint()}, s.env...)
+	}
+
+	// Label the containers like those of the other packages, so that the
+	// reaper and the prune command find them
+	names, err := s.compose(ctx, "config", "--services")
+	if err != nil {
+		return nil, fmt.Errorf("invalid Compose file %s: %w", file, err)
+	}
+	override, err := labelsOverride(strings.Fields(names), core.Labels("compose", ""))
+	if err != nil {
+		return nil, err
+	}
+	dir, err := os.MkdirTemp("", "integrationtest-compose-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	overrideFile := filepath.Join(dir, "labels.json")
+	if err := os.WriteFile(overrideFile, override, 0644); err != nil {
+		return nil, err
+	}
+
+	args := []string{"up", "--detach", "--wait",
+		"--wait-timeout", strconv.Itoa(int(max(s.timeout.Seconds(), 1)))}
+	args = append(args, s.services...)
+	if _, err := s.run(ctx, slices.Concat(s.files, []string{overrideFile}), args...); err != nil {
+		return s, fmt.Errorf("could not start Compose services: %w", err)
+	}
+	return s, nil
+}
+
+// labelsOverride returns a Compose file that adds labels to services.
+// Compose files can be JSON, as it is a subset of YAML.
+func labelsOverride(services []string, labels map[string]string) ([]byte, error) {
+	type service struct {
+		Labels map[string]string `json:"labels"`
+	}
+	override := struct {
+		Services map[string]service `json:"services"`
+	}{
+		Services: make(map[string]service, len(services)),
+	}
+	for _, name := range services {
+		override.Services[name] = service{Labels: labels}
+	}
+	return json.Marshal(override)
+}
+
+// compose runs docker compose for the stack with args, and returns its
+// output.
+func (s *Stack) compose(ctx context.Context, args ...string) (string, error) {
+	return s.run(ctx, s.files, args...)
+}
+
+// run is like compose, but with the given Compose files.
+func (s *Stack) run(ctx context.Context, files []string, args ...string) (string, error) {
+	cmdArgs := []string{"compose", "--project-name", s.project}
+	for _, file := range files {
+		cmdArgs = append(cmdArgs, "--file", file)
+	}
+	cmdArgs = append(cmdArgs, args...)
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "docker", cmdArgs...)
+	cmd.Env = append(os.Environ(), s.env...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, msg)
+		}
+		return "", err
+	}
+	return stdout.String(), nil
+}
+
+// Close stops and removes the containers, networks, and volumes of the
+// stack.
+func (s *Stack) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	if _, err := s.compose(ctx, "down", "--volumes", "--remove-orphans", "--timeout", "5"); err != nil {
+		return fmt.Errorf("could not remove Compose services: %w", err)
+	}
+	s.closed = true
+	return nil
+}
+
+// ProjectName returns the name of the Compose project.
+func (s *Stack) ProjectName() string {
+	return s.project
+}
+
+// HostPort returns the address on the host, e.g. "localhost:49153", that
+// the given port of service, e.g. 5432, is published on.
+func (s *Stack) HostPort(service string, port int) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	out, err := s.compose(ctx, "port", service, strconv.Itoa(port))
+	if err != nil {
+		return "", fmt.Errorf("could not get port %d of service %s: %w", port, service, err)
+	}
+	return hostPort(out)
+}
+
+// hostPort returns the first address of the output of docker compose port,
+// with unspecified addresses replaced by localhost, or the remote Docker
+// host.
+func hostPort(out string) (string, error) {
+	line, _, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	_, port, err := net.SplitHostPort(strings.TrimSpace(line))
+	if err != nil || port == "" || port == "0" {
+		return "", fmt.Errorf("port is not published: %q", out)
+	}
+	return dockerutil.ReplaceHost(strings.TrimSpace(line)), nil
+}
+
+// ContainerID returns the ID of the container of service.
+func (s *Stack) ContainerID(service string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	out, err := s.compose(ctx, "ps", "--quiet", service)
+	if err != nil {
+		return "", fmt.Errorf("could not get container of service %s: %w", service, err)
+	}
+	id, _, _ := strings.Cut(strings.TrimSpace(out), "\n")
+	if id == "" {
+		return "", errors.New("service " + service + " is not running")
+	}
+	return id, nil
+}
+
+// Logs returns the output of the given services so far, or of all
+// services if none are given. Each line is prefixed with its service.
+func (s *Stack) Logs(ctx context.Context, services ...string) (string, error) {
+	return s.compose(ctx, append([]string{"logs", "--no-color"}, services...)...)
+}
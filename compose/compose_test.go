@@ -0,0 +1,43 @@
+package compose_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/olivere/integrationtest/compose"
+)
+
+func TestStart(t *testing.T) {
+	stack := compose.Start(t, "testdata/docker-compose.yml",
+		compose.WithServices("web"),
+		compose.WithEnv("NGINX_TAG=alpine"),
+	)
+
+	hostPort, err := stack.HostPort("web", 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.Get("http://" + hostPort + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if want, have := http.StatusOK, res.StatusCode; want != have {
+		t.Fatalf("want status %d, have %d", want, have)
+	}
+
+	// Only the selected service is started
+	if _, err := stack.ContainerID("worker"); err == nil {
+		t.Fatal("want worker not to be started")
+	}
+
+	logs, err := stack.Logs(context.Background(), "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(logs, "web") {
+		t.Fatalf("want logs of web, have:\n%s", logs)
+	}
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// buildOutputTail is the number of lines of the build output that the
+// error of a failed build includes.
+const buildOutputTail = 20
+
+// Build is an image to build from a Dockerfile before the container
+// starts, see WithBuiltImage.
+type Build struct {
+	// Dir is the build context, e.g. ".".
+	Dir string
+
+	// Dockerfile is the path of the Dockerfile relative to Dir. Empty
+	// means "Dockerfile".
+	Dockerfile string
+}
+
+// Image returns the repository and tag that the package pkg builds the
+// image as, e.g. "integrationtest-build/postgres:3f9a1c2b7d4e". The tag
+// is the same for the same build context and Dockerfile, so Docker's
+// build cache makes later builds fast.
+func (b Build) Image(pkg string) (string, string) {
+	dir, err := filepath.Abs(b.Dir)
+	if err != nil {
+		dir = b.Dir
+	}
+	return "integrationtest-build/" + pkg, Checksum(dir, b.Dockerfile)[:12]
+}
+
+// WithBuiltImage builds the image of the container from the Dockerfile in
+// dir, e.g. a PostgreSQL image with custom extensions, instead of pulling
+// it. The image must behave like the image of the package. An empty
+// dockerfile means dir/Dockerfile. See BuildImage.
+func WithBuiltImage(dir, dockerfile string) Option {
+	return func(cfg *Config) {
+		cfg.Build = &Build{Dir: dir, Dockerfile: dockerfile}
+	}
+}
+
+// BuildImage builds the Dockerfile dockerfile, relative to the build
+// context dir, as the image tag, e.g. "app:test". Use it to build the
+// image of the application under test, and start it with StartImage.
+// An empty dockerfile means dir/Dockerfile.
+//
+// The proxy environment of the test process (see ProxyEnv) is passed as
+// build arguments, so that the build can download packages behind a proxy.
+// If the build fails, the error includes the last lines of its output.
+func BuildImage(ctx context.Context, dir, dockerfile, tag string) error {
+	pool, err := dockerutil.Pool()
+	if err != nil {
+		return fmt.Errorf("unable to connect to Docker: %w", err)
+	}
+	var args []docker.BuildArg
+	for _, kv := range ProxyEnv() {
+		name, value, _ := strings.Cut(kv, "=")
+		args = append(args, docker.BuildArg{Name: name, Value: value})
+	}
+	var out bytes.Buffer
+	err = pool.Client.BuildImage(docker.BuildImageOptions{
+		Name:           tag,
+		Dockerfile:     dockerfile,
+		ContextDir:     dir,
+		BuildArgs:      args,
+		RmTmpContainer: true,
+		OutputStream:   &out,
+		Context:        ctx,
+	})
+	if err != nil {
+		lines := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+		if len(lines) > buildOutputTail {
+			lines = lines[len(lines)-buildOutputTail:]
+		}
+		return fmt.Errorf("could not build image %s from %s: %w\n%s", tag, filepath.Join(dir, cmp.Or(dockerfile, "Dockerfile")), err, strings.Join(lines, "\n"))
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package core_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+func TestWithBuiltImage(t *testing.T) {
+	cfg := core.NewConfig(core.WithBuiltImage("testdata/app", "Dockerfile.test"))
+	if cfg.Build == nil {
+		t.Fatal("want build, have nil")
+	}
+	if want, have := (core.Build{Dir: "testdata/app", Dockerfile: "Dockerfile.test"}), *cfg.Build; want != have {
+		t.Fatalf("want build %+v, have %+v", want, have)
+	}
+}
+
+func TestBuild_Image(t *testing.T) {
+	repository, tag := core.Build{Dir: "testdata/app"}.Image("postgres")
+	if want, have := "integrationtest-build/postgres", repository; want != have {
+		t.Errorf("want repository %q, have %q", want, have)
+	}
+	if _, again := (core.Build{Dir: "testdata/app"}).Image("postgres"); again != tag {
+		t.Errorf("want the same tag for the same build, have %q and %q", tag, again)
+	}
+	if _, other := (core.Build{Dir: "testdata/app", Dockerfile: "Dockerfile.dev"}).Image("postgres"); other == tag {
+		t.Errorf("want a different tag for a different Dockerfile, have %q", other)
+	}
+	if strings.ContainsAny(tag, ":/") {
+		t.Errorf("want valid tag, have %q", tag)
+	}
+}
@@ -0,0 +1,257 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Cache is a thread-safe cache for containers of any package, e.g. to
+// share a container between the tests of a package.
+type Cache[T io.Closer] struct {
+	mu      sync.Mutex
+	cache   map[string]*cacheEntry[T]
+	idleTTL time.Duration
+}
+
+// CacheOption configures a Cache.
+type CacheOption func(*cacheConfig)
+
+type cacheConfig struct {
+	idleTTL time.Duration
+}
+
+// WithIdleTTL closes containers returned by Acquire that no user has held
+// for the given duration. This keeps long-running processes, e.g. a
+// developer running tests of single packages in watch mode, from
+// accumulating idle containers. With an idle TTL, Acquire no longer closes
+// a container when its last user releases it, but when it is idle for the
+// TTL. Containers returned by GetOrCreate are pinned and never expire.
+func WithIdleTTL(ttl time.Duration) CacheOption {
+	return func(cfg *cacheConfig) {
+		cfg.idleTTL = ttl
+	}
+}
+
+// cacheEntry is a container of a Cache. ready is closed when the
+// container has been created, or its creation failed.
+type cacheEntry[T io.Closer] struct {
+	ready chan struct{}
+	c     T
+	ok    bool
+
+	// refs is the number of users that acquired the container and did not
+	// release it yet. Containers returned by GetOrCreate are pinned and
+	// live until the cache is closed, even with an idle TTL. Both are
+	// protected by Cache.mu.
+	refs     int
+	pinned   bool
+	lastUsed time.Time
+}
+
+// NewCache returns a new Cache.
+func NewCache[T io.Closer](options ...CacheOption) *Cache[T] {
+	var cfg cacheConfig
+	for _, o := range options {
+		o(&cfg)
+	}
+	return &Cache[T]{
+		cache:   make(map[string]*cacheEntry[T]),
+		idleTTL: cfg.idleTTL,
+	}
+}
+
+// Close stops all containers in the cache. It waits for containers that
+// are being created.
+func (p *Cache[T]) Close() error {
+	p.mu.Lock()
+	entries := p.cache
+	p.cache = make(map[string]*cacheEntry[T])
+	p.mu.Unlock()
+
+	var firstErr error
+	for _, e := range entries {
+		<-e.ready
+		if !e.ok {
+			continue
+		}
+		if err := e.c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// GetOrCreate starts a new container if none is running, otherwise returns
+// the pooled container.
+//
+// Containers with different ids are created in parallel. Concurrent calls
+// with the same id wait for the first one to create the container and
+// share it. If createFunc panics or stops its goroutine, e.g. with
+// testing.TB.Fatal, the next waiting call creates the container instead.
+func (p *Cache[T]) GetOrCreate(id string, createFunc func() T) T {
+	c, _ := p.GetOrCreateE(id, func() (T, error) {
+		return createFunc(), nil
+	})
+	return c
+}
+
+// GetOrCreateE is like GetOrCreate, but createFunc can fail. A failed
+// creation is not cached: GetOrCreateE returns its error, and the next call
+// with the same id creates the container again. createFunc is responsible
+// for cleaning up a container that it could not start completely.
+func (p *Cache[T]) GetOrCreateE(id string, createFunc func() (T, error)) (T, error) {
+	e, err := p.getOrCreate(id, createFunc, func(e *cacheEntry[T]) {
+		e.pinned = true
+	})
+	return e.c, err
+}
+
+// Acquire is like GetOrCreateE, but counts the users of the container.
+// Every user calls the returned release func when done with the container,
+// instead of closing it, and the container is closed when the last user
+// releases it or the cache is closed. Calling release more than once has
+// no effect.
+func (p *Cache[T]) Acquire(id string, createFunc func() (T, error)) (T, func() error, error) {
+	e, err := p.getOrCreate(id, createFunc, func(e *cacheEntry[T]) {
+		e.refs++
+	})
+	if err != nil {
+		return e.c, func() error { return nil }, err
+	}
+	var once sync.Once
+	release := func() (err error) {
+		once.Do(func() {
+			err = p.release(id, e)
+		})
+		return err
+	}
+	return e.c, release, nil
+}
+
+// release decrements the users of e and closes its container when it was
+// the last user.
+func (p *Cache[T]) release(id string, e *cacheEntry[T]) error {
+	p.mu.Lock()
+	e.refs--
+	if e.refs > 0 || e.pinned || p.idleTTL > 0 {
+		p.touch(id, e)
+		p.mu.Unlock()
+		return nil
+	}
+	if p.cache[id] != e {
+		// Removed by Delete or Close in the meantime
+		p.mu.Unlock()
+		return nil
+	}
+	delete(p.cache, id)
+	p.mu.Unlock()
+	return e.c.Close()
+}
+
+// getOrCreate returns the entry for id, creating its container if needed.
+// use is called with the lock held once the container is available.
+func (p *Cache[T]) getOrCreate(id string, createFunc func() (T, error), use func(*cacheEntry[T])) (*cacheEntry[T], error) {
+	for {
+		p.mu.Lock()
+		e, ok := p.cache[id]
+		if !ok {
+			e = &cacheEntry[T]{ready: make(chan struct{})}
+			p.cache[id] = e
+		}
+		p.mu.Unlock()
+
+		if !ok {
+			if err := p.create(id, e, createFunc); err != nil {
+				return e, err
+			}
+		} else {
+			<-e.ready
+		}
+
+		p.mu.Lock()
+		// The entry may have been released to zero users and removed
+		// after it was ready, so only use it if it is still cached
+		if e.ok && p.cache[id] == e {
+			use(e)
+			p.touch(id, e)
+			p.mu.Unlock()
+			return e, nil
+		}
+		p.mu.Unlock()
+	}
+}
+
+// touch marks e as used now and, if it has no users, schedules closing it
+// when it is idle for the idle TTL. It must be called with the lock held.
+func (p *Cache[T]) touch(id string, e *cacheEntry[T]) {
+	e.lastUsed = time.Now()
+	if p.idleTTL > 0 && e.refs == 0 && !e.pinned {
+		time.AfterFunc(p.idleTTL, func() {
+			p.evict(id, e)
+		})
+	}
+}
+
+// evict closes the container of e if it is still cached, unpinned, and
+// idle. Timers of entries that were used again in the meantime do nothing.
+// There is no caller to return an error to, so evict reports it on stderr.
+func (p *Cache[T]) evict(id string, e *cacheEntry[T]) {
+	p.mu.Lock()
+	if p.cache[id] != e || e.refs > 0 || e.pinned || time.Since(e.lastUsed) < p.idleTTL {
+		p.mu.Unlock()
+		return
+	}
+	delete(p.cache, id)
+	p.mu.Unlock()
+	if err := e.c.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "integrationtest: could not close idle container %q: %v\n", id, err)
+	}
+}
+
+// create runs createFunc for the entry e with the given id, and removes it
+// from the cache again if createFunc fails or does not return.
+func (p *Cache[T]) create(id string, e *cacheEntry[T], createFunc func() (T, error)) (err error) {
+	defer func() {
+		if !e.ok {
+			p.mu.Lock()
+			if p.cache[id] == e {
+				delete(p.cache, id)
+			}
+			p.mu.Unlock()
+		}
+		close(e.ready)
+	}()
+	c, err := createFunc()
+	if err != nil {
+		return err
+	}
+	e.c, e.ok = c, true
+	return nil
+}
+
+// Get returns the container cached under id, if any. It waits for the
+// container if it is being created.
+func (p *Cache[T]) Get(id string) (T, bool) {
+	p.mu.Lock()
+	e, ok := p.cache[id]
+	p.mu.Unlock()
+
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	<-e.ready
+	return e.c, e.ok
+}
+
+// Delete removes the container cached under id from the cache, without
+// closing it.
+func (p *Cache[T]) Delete(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.cache, id)
+}
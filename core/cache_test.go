@@ -0,0 +1,282 @@
+package core_test
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+// fakeContainer counts how often it is closed.
+type fakeContainer struct {
+	mu     sync.Mutex
+	closed int
+}
+
+func (c *fakeContainer) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed++
+	return nil
+}
+
+func (c *fakeContainer) closes() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+func TestCache(t *testing.T) {
+	cache := core.NewCache[*fakeContainer]()
+
+	var created int
+	create := func() *fakeContainer {
+		created++
+		return &fakeContainer{}
+	}
+	c1 := cache.GetOrCreate("one", create)
+	c2 := cache.GetOrCreate("one", create)
+	if c1 != c2 {
+		t.Fatal("expected same container, got different")
+	}
+	c3 := cache.GetOrCreate("two", create)
+	if c1 == c3 {
+		t.Fatal("expected different containers for different ids")
+	}
+	if want, have := 2, created; want != have {
+		t.Fatalf("want %d containers created, have %d", want, have)
+	}
+
+	if c, ok := cache.Get("one"); !ok || c != c1 {
+		t.Fatalf("want Get to return the cached container, have %v, %v", c, ok)
+	}
+	cache.Delete("one")
+	if _, ok := cache.Get("one"); ok {
+		t.Fatal("want container to be deleted from the cache")
+	}
+	if want, have := 0, c1.closes(); want != have {
+		t.Fatalf("want Delete not to close the container, have %d closes", have)
+	}
+
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, c3.closes(); want != have {
+		t.Fatalf("want container to be closed %d time, have %d", want, have)
+	}
+}
+
+func TestCache_GetOrCreateConcurrently(t *testing.T) {
+	cache := core.NewCache[*fakeContainer]()
+	defer cache.Close()
+
+	// Containers with different ids are created in parallel: both
+	// createFuncs wait for each other
+	var wg sync.WaitGroup
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	for _, id := range []string{"one", "two"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			cache.GetOrCreate(id, func() *fakeContainer {
+				started <- struct{}{}
+				<-release
+				return &fakeContainer{}
+			})
+		}()
+	}
+	for range 2 {
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("want containers with different ids to be created in parallel")
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	// Concurrent calls with the same id share one container
+	var created atomic.Int32
+	results := make([]*fakeContainer, 10)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = cache.GetOrCreate("three", func() *fakeContainer {
+				created.Add(1)
+				time.Sleep(10 * time.Millisecond)
+				return &fakeContainer{}
+			})
+		}()
+	}
+	wg.Wait()
+	if want, have := int32(1), created.Load(); want != have {
+		t.Fatalf("want %d container created, have %d", want, have)
+	}
+	for _, c := range results {
+		if c != results[0] {
+			t.Fatal("expected same container, got different")
+		}
+	}
+}
+
+func TestCache_GetOrCreateAfterGoexit(t *testing.T) {
+	cache := core.NewCache[*fakeContainer]()
+	defer cache.Close()
+
+	// createFunc stops its goroutine like testing.TB.Fatal does
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cache.GetOrCreate("one", func() *fakeContainer {
+			runtime.Goexit()
+			return nil
+		})
+	}()
+	<-done
+
+	if _, ok := cache.Get("one"); ok {
+		t.Fatal("want failed container not to be cached")
+	}
+	c := cache.GetOrCreate("one", func() *fakeContainer {
+		return &fakeContainer{}
+	})
+	if c == nil {
+		t.Fatal("want container to be created again")
+	}
+}
+
+func TestCache_GetOrCreateE(t *testing.T) {
+	cache := core.NewCache[*fakeContainer]()
+	defer cache.Close()
+
+	errStart := errors.New("could not start")
+	_, err := cache.GetOrCreateE("one", func() (*fakeContainer, error) {
+		return nil, errStart
+	})
+	if !errors.Is(err, errStart) {
+		t.Fatalf("want error %v, have %v", errStart, err)
+	}
+	if _, ok := cache.Get("one"); ok {
+		t.Fatal("want failed container not to be cached")
+	}
+
+	c, err := cache.GetOrCreateE("one", func() (*fakeContainer, error) {
+		return &fakeContainer{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if have, _ := cache.Get("one"); have != c {
+		t.Fatal("want container to be cached")
+	}
+}
+
+func TestCache_Acquire(t *testing.T) {
+	cache := core.NewCache[*fakeContainer]()
+	defer cache.Close()
+
+	create := func() (*fakeContainer, error) {
+		return &fakeContainer{}, nil
+	}
+	c1, release1, err := cache.Acquire("one", create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, release2, err := cache.Acquire("one", create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1 != c2 {
+		t.Fatal("expected same container, got different")
+	}
+
+	// The container lives until the last user releases it
+	release1()
+	release1() // no effect
+	if want, have := 0, c1.closes(); want != have {
+		t.Fatalf("want container to be open, have %d closes", have)
+	}
+	release2()
+	if want, have := 1, c1.closes(); want != have {
+		t.Fatalf("want container to be closed %d time, have %d", want, have)
+	}
+
+	// The next user gets a new container
+	c3, release3, err := cache.Acquire("one", create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer release3()
+	if c3 == c1 {
+		t.Fatal("want a new container after the last release")
+	}
+}
+
+func TestCache_AcquireClosedByCache(t *testing.T) {
+	cache := core.NewCache[*fakeContainer]()
+
+	c, release, err := cache.Acquire("one", func() (*fakeContainer, error) {
+		return &fakeContainer{}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+	release()
+	if want, have := 1, c.closes(); want != have {
+		t.Fatalf("want container to be closed %d time, have %d", want, have)
+	}
+}
+
+func TestCache_WithIdleTTL(t *testing.T) {
+	cache := core.NewCache[*fakeContainer](core.WithIdleTTL(50 * time.Millisecond))
+	defer cache.Close()
+
+	create := func() (*fakeContainer, error) {
+		return &fakeContainer{}, nil
+	}
+	acquired, release, err := cache.Acquire("acquired", create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idle, releaseIdle, err := cache.Acquire("idle", create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseIdle()
+	pinned := cache.GetOrCreate("pinned", func() *fakeContainer {
+		return &fakeContainer{}
+	})
+
+	// Containers that are held or pinned are not evicted
+	time.Sleep(200 * time.Millisecond)
+	if _, ok := cache.Get("idle"); ok {
+		t.Fatal("want idle container to be evicted")
+	}
+	if want, have := 1, idle.closes(); want != have {
+		t.Fatalf("want idle container to be closed %d time, have %d", want, have)
+	}
+	if want, have := 0, acquired.closes(); want != have {
+		t.Fatalf("want acquired container to be open, have %d closes", have)
+	}
+	if c, ok := cache.Get("pinned"); !ok || c != pinned {
+		t.Fatal("want pinned container to stay cached")
+	}
+	if want, have := 0, pinned.closes(); want != have {
+		t.Fatalf("want pinned container to be open, have %d closes", have)
+	}
+
+	// Released containers stay cached until they are idle for the TTL
+	release()
+	if c, _, err := cache.Acquire("acquired", create); err != nil || c != acquired {
+		t.Fatalf("want released container to be reused, have %v, %v", c, err)
+	}
+}
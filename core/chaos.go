@@ -0,0 +1,252 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// chaosTimeout is the time that Chaos waits for Toxiproxy to inject or
+// remove a fault.
+const chaosTimeout = 10 * time.Second
+
+// errNoToxiproxy is returned by the methods of Chaos without a proxy.
+var errNoToxiproxy = errors.New("core: chaos needs a Toxiproxy, e.g. start the container with WithToxiproxy")
+
+// Fault is a fault of the link to a container that Chaos injects, see
+// LatencyFault, BandwidthFault, and PartitionFault.
+type Fault struct {
+	name   string
+	inject func(ctx context.Context, t *Toxiproxy) error
+	remove func(ctx context.Context, t *Toxiproxy) error
+}
+
+// String returns a description of the fault, e.g. "latency 100ms±10ms".
+func (f Fault) String() string {
+	return f.name
+}
+
+// LatencyFault delays all data from the container by d, plus or minus
+// a random jitter.
+func LatencyFault(d, jitter time.Duration) Fault {
+	const name = "chaos_latency"
+	return Fault{
+		name: fmt.Sprintf("latency %v±%v", d, jitter),
+		inject: func(ctx context.Context, t *Toxiproxy) error {
+			t.RemoveToxic(ctx, name) // replace an earlier latency, if any
+			return t.AddToxic(ctx, Toxic{
+				Name: name,
+				Type: "latency",
+				Attributes: map[string]any{
+					"latency": d.Milliseconds(),
+					"jitter":  jitter.Milliseconds(),
+				},
+			})
+		},
+		remove: func(ctx context.Context, t *Toxiproxy) error {
+			return t.RemoveToxic(ctx, name)
+		},
+	}
+}
+
+// BandwidthFault limits the bandwidth of the link in both directions to
+// kbps kilobytes per second.
+func BandwidthFault(kbps int) Fault {
+	streams := []string{"downstream", "upstream"}
+	return Fault{
+		name: fmt.Sprintf("bandwidth %dKB/s", kbps),
+		inject: func(ctx context.Context, t *Toxiproxy) error {
+			for _, stream := range streams {
+				name := "chaos_bandwidth_" + stream
+				t.RemoveToxic(ctx, name) // replace an earlier limit, if any
+				err := t.AddToxic(ctx, Toxic{
+					Name:       name,
+					Type:       "bandwidth",
+					Stream:     stream,
+					Attributes: map[string]any{"rate": kbps},
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		remove: func(ctx context.Context, t *Toxiproxy) error {
+			var errs []error
+			for _, stream := range streams {
+				errs = append(errs, t.RemoveToxic(ctx, "chaos_bandwidth_"+stream))
+			}
+			return errors.Join(errs...)
+		},
+	}
+}
+
+// PartitionFault cuts the link: it closes all connections and refuses new
+// ones.
+func PartitionFault() Fault {
+	return Fault{
+		name: "partition",
+		inject: func(ctx context.Context, t *Toxiproxy) error {
+			return t.Disable(ctx)
+		},
+		remove: func(ctx context.Context, t *Toxiproxy) error {
+			return t.Enable(ctx)
+		},
+	}
+}
+
+// Chaos injects faults into the link to a container through its
+// Toxiproxy, to exercise the timeouts and retries of the code under test
+// deterministically, e.g.:
+//
+//	c := postgres.Start(t, postgres.WithToxiproxy())
+//	if err := c.Chaos().AddLatency(2*time.Second, 0); err != nil {
+//		t.Fatal(err)
+//	}
+//	// The query of the code under test times out after 1s
+//
+// Window injects a fault for a while in the background, e.g. to check
+// that the code under test recovers after a partition heals.
+type Chaos struct {
+	proxy *Toxiproxy
+
+	mu     sync.Mutex
+	wg     sync.WaitGroup
+	errs   []error
+	done   chan struct{}
+	closed bool
+}
+
+// NewChaos returns the Chaos of the link through proxy. The methods of
+// Chaos return an error if proxy is nil, e.g. because the container was
+// started without WithToxiproxy.
+func NewChaos(proxy *Toxiproxy) *Chaos {
+	return &Chaos{
+		proxy: proxy,
+		done:  make(chan struct{}),
+	}
+}
+
+// Inject injects fault until Remove or Reset.
+func (ch *Chaos) Inject(fault Fault) error {
+	return ch.apply(fault.inject)
+}
+
+// Remove removes fault.
+func (ch *Chaos) Remove(fault Fault) error {
+	return ch.apply(fault.remove)
+}
+
+// AddLatency delays all data from the container by d, plus or minus
+// a random jitter, see LatencyFault.
+func (ch *Chaos) AddLatency(d, jitter time.Duration) error {
+	return ch.Inject(LatencyFault(d, jitter))
+}
+
+// LimitBandwidth limits the bandwidth of the link to kbps kilobytes per
+// second, see BandwidthFault.
+func (ch *Chaos) LimitBandwidth(kbps int) error {
+	return ch.Inject(BandwidthFault(kbps))
+}
+
+// Partition cuts the link until Heal, see PartitionFault.
+func (ch *Chaos) Partition() error {
+	return ch.Inject(PartitionFault())
+}
+
+// Heal restores the link after Partition. Other faults remain.
+func (ch *Chaos) Heal() error {
+	return ch.Remove(PartitionFault())
+}
+
+// Reset removes all faults.
+func (ch *Chaos) Reset() error {
+	return ch.apply(func(ctx context.Context, t *Toxiproxy) error {
+		return t.Reset(ctx)
+	})
+}
+
+// Window injects fault in the background after the given delay, and
+// removes it after duration, e.g. to cut the link for 2s after 1s:
+//
+//	c.Chaos().Window(time.Second, 2*time.Second, core.PartitionFault())
+//
+// Wait waits until all windows ended. Close ends them early.
+func (ch *Chaos) Window(after, duration time.Duration, fault Fault) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.closed {
+		return
+	}
+	ch.wg.Add(1)
+	go func() {
+		defer ch.wg.Done()
+
+		if !ch.sleep(after) {
+			return
+		}
+		if err := ch.Inject(fault); err != nil {
+			ch.fail(fmt.Errorf("could not inject %v: %w", fault, err))
+			return
+		}
+		ch.sleep(duration)
+		if err := ch.Remove(fault); err != nil {
+			ch.fail(fmt.Errorf("could not remove %v: %w", fault, err))
+		}
+	}()
+}
+
+// Wait waits until all windows ended, and returns the errors of injecting
+// or removing their faults.
+func (ch *Chaos) Wait() error {
+	ch.wg.Wait()
+
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return errors.Join(ch.errs...)
+}
+
+// Close ends all windows early, removing their faults, and waits for
+// them. It does not remove faults injected otherwise. It is safe to call
+// Close more than once.
+func (ch *Chaos) Close() error {
+	ch.mu.Lock()
+	if !ch.closed {
+		ch.closed = true
+		close(ch.done)
+	}
+	ch.mu.Unlock()
+	return ch.Wait()
+}
+
+// apply runs f with the proxy.
+func (ch *Chaos) apply(f func(ctx context.Context, t *Toxiproxy) error) error {
+	if ch.proxy == nil {
+		return errNoToxiproxy
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), chaosTimeout)
+	defer cancel()
+	return f(ctx, ch.proxy)
+}
+
+// sleep waits for d, and returns false if Close was called before.
+func (ch *Chaos) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ch.done:
+		return false
+	}
+}
+
+// fail records the error of a window.
+func (ch *Chaos) fail(err error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.errs = append(ch.errs, err)
+}
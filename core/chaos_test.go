@@ -0,0 +1,56 @@
+package core_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+func TestFault_String(t *testing.T) {
+	tests := []struct {
+		fault core.Fault
+		want  string
+	}{
+		{core.LatencyFault(100*time.Millisecond, 10*time.Millisecond), "latency 100ms±10ms"},
+		{core.BandwidthFault(64), "bandwidth 64KB/s"},
+		{core.PartitionFault(), "partition"},
+	}
+	for _, tt := range tests {
+		if have := tt.fault.String(); tt.want != have {
+			t.Errorf("want %q, have %q", tt.want, have)
+		}
+	}
+}
+
+func TestChaos_NoToxiproxy(t *testing.T) {
+	chaos := core.NewChaos(nil)
+	if err := chaos.AddLatency(time.Second, 0); err == nil {
+		t.Fatal("want error without Toxiproxy")
+	}
+	chaos.Window(0, 0, core.PartitionFault())
+	if err := chaos.Wait(); err == nil {
+		t.Fatal("want error of the window without Toxiproxy")
+	}
+}
+
+func TestChaos_Close(t *testing.T) {
+	chaos := core.NewChaos(nil)
+	chaos.Window(time.Hour, time.Hour, core.PartitionFault())
+
+	done := make(chan error, 1)
+	go func() {
+		done <- chaos.Close()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("want window to end before its fault, have %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("want Close to end the window early")
+	}
+	if err := chaos.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
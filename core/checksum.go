@@ -0,0 +1,57 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"slices"
+)
+
+// Checksum returns the SHA-256 checksum of values in hex, e.g. of the
+// options that the data of a container depends on. Unlike a checksum of
+// the concatenated values, Checksum("ab", "c") differs from
+// Checksum("a", "bc").
+func Checksum(values ...string) string {
+	h := sha256.New()
+	for _, v := range values {
+		binary.Write(h, binary.BigEndian, uint64(len(v)))
+		h.Write([]byte(v))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ChecksumFS returns the checksum of the names and contents of the files
+// in fsys that match patterns (see fs.Glob), e.g. of the migrations and
+// fixtures that seed a container:
+//
+//	checksum, err := core.ChecksumFS(os.DirFS("testdata"), "migrations/*.sql", "fixtures/*.json")
+//
+// Use it to invalidate reused containers when the files change, e.g.
+// with postgres.WithReuse. It fails if a pattern matches no files.
+func ChecksumFS(fsys fs.FS, patterns ...string) (string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return "", fmt.Errorf("could not match %s: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return "", fmt.Errorf("no files match %s", pattern)
+		}
+		files = append(files, matches...)
+	}
+	slices.Sort(files)
+	files = slices.Compact(files)
+
+	values := make([]string, 0, 2*len(files))
+	for _, name := range files {
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return "", err
+		}
+		values = append(values, name, string(data))
+	}
+	return Checksum(values...), nil
+}
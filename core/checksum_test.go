@@ -0,0 +1,41 @@
+package core_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+func TestChecksum(t *testing.T) {
+	if core.Checksum("ab", "c") == core.Checksum("a", "bc") {
+		t.Error("want checksums of different values to differ")
+	}
+	if want, have := core.Checksum("a", "b"), core.Checksum("a", "b"); want != have {
+		t.Errorf("want checksum %s, have %s", want, have)
+	}
+}
+
+func TestChecksumFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/001.sql": {Data: []byte("CREATE TABLE users (id int);")},
+		"migrations/002.sql": {Data: []byte("ALTER TABLE users ADD name text;")},
+		"fixtures/users.sql": {Data: []byte("INSERT INTO users VALUES (1, 'a');")},
+	}
+	sum, err := core.ChecksumFS(fsys, "migrations/*.sql", "fixtures/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again, _ := core.ChecksumFS(fsys, "fixtures/*.sql", "migrations/*.sql", "migrations/001.sql"); again != sum {
+		t.Errorf("want checksum independent of the order of patterns, have %s and %s", sum, again)
+	}
+
+	fsys["migrations/002.sql"] = &fstest.MapFile{Data: []byte("ALTER TABLE users ADD email text;")}
+	if changed, _ := core.ChecksumFS(fsys, "migrations/*.sql", "fixtures/*.sql"); changed == sum {
+		t.Error("want checksum to change with the contents of a file")
+	}
+
+	if _, err := core.ChecksumFS(fsys, "seeds/*.sql"); err == nil {
+		t.Error("want error for a pattern without files")
+	}
+}
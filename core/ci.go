@@ -0,0 +1,65 @@
+package core
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// CIServicesEnv is the environment variable that disables the detection
+// of service containers of CI systems if it is "false", see CIService.
+const CIServicesEnv = "INTEGRATIONTEST_CI_SERVICES"
+
+// CI systems that CI detects.
+const (
+	GitHubActions = "github-actions"
+	GitLabCI      = "gitlab-ci"
+)
+
+// CI returns the CI system that runs the tests, i.e. GitHubActions or
+// GitLabCI, as told by their well-known environment variables, or "" if
+// there is none.
+func CI() string {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return GitHubActions
+	case os.Getenv("GITLAB_CI") == "true":
+		return GitLabCI
+	}
+	return ""
+}
+
+// CIService returns the address of the service container that the CI
+// system runs for the tests, e.g. "postgres:5432", given the alias of the
+// service and its port, and true if the address accepts connections.
+// CI systems reach services by their alias, e.g. "postgres" for the image
+// postgres:16 in GitLab CI, or the name of the service in GitHub Actions
+// if the job runs in a container.
+//
+// The container packages use it to test against the service containers
+// in CI and start containers otherwise, so the same tests run in both.
+// CIService only probes the address if CI reports a CI system and the
+// alias resolves, so other servers on the port, e.g. on localhost, never
+// count as the service. Jobs that map the ports of services to the host
+// set the URL of the service for the container package instead, e.g.
+// INTEGRATIONTEST_POSTGRES_URL. CIService returns false if CIServicesEnv
+// is "false".
+func CIService(alias string, port int) (string, bool) {
+	if enabled, err := strconv.ParseBool(os.Getenv(CIServicesEnv)); err == nil && !enabled {
+		return "", false
+	}
+	if CI() == "" {
+		return "", false
+	}
+	if _, err := net.LookupHost(alias); err != nil {
+		return "", false
+	}
+	addr := net.JoinHostPort(alias, strconv.Itoa(port))
+	conn, err := net.DialTimeout("tcp", addr, 2*time.Second)
+	if err != nil {
+		return "", false
+	}
+	conn.Close()
+	return addr, true
+}
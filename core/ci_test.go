@@ -0,0 +1,57 @@
+package core_test
+
+import (
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+func TestCI(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "")
+	t.Setenv("GITLAB_CI", "")
+	if have := core.CI(); have != "" {
+		t.Fatalf("want no CI, have %q", have)
+	}
+	t.Setenv("GITLAB_CI", "true")
+	if want, have := core.GitLabCI, core.CI(); want != have {
+		t.Fatalf("want CI %q, have %q", want, have)
+	}
+}
+
+func TestCIService(t *testing.T) {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	// The listener stands in for a service with the alias localhost
+	t.Setenv("GITHUB_ACTIONS", "")
+	t.Setenv("GITLAB_CI", "")
+	t.Setenv(core.CIServicesEnv, "")
+	if addr, ok := core.CIService("localhost", port); ok {
+		t.Fatalf("want no service outside of CI, have %s", addr)
+	}
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	addr, ok := core.CIService("localhost", port)
+	if !ok {
+		t.Fatal("want service by its alias")
+	}
+	if want, have := net.JoinHostPort("localhost", strconv.Itoa(port)), addr; want != have {
+		t.Fatalf("want address %s, have %s", want, have)
+	}
+
+	// Servers on the port only count if the alias resolves
+	if addr, ok := core.CIService("integrationtest-unknown-service.invalid", port); ok {
+		t.Fatalf("want no service for an alias that does not resolve, have %s", addr)
+	}
+
+	t.Setenv(core.CIServicesEnv, "false")
+	if addr, ok := core.CIService("localhost", port); ok {
+		t.Fatalf("want detection to be disabled with %s=false, have %s", core.CIServicesEnv, addr)
+	}
+}
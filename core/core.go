@@ -0,0 +1,231 @@
+// Package core defines what the container packages of integrationtest,
+// e.g. postgres and elasticsearch, have in common. Tooling like caches,
+// reapers, or reporters uses it to work with containers of any package.
+package core
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Container is implemented by the containers of all packages, e.g.
+// *postgres.Container and *elasticsearch.Container.
+type Container interface {
+	// Close stops and removes the container. It is safe to call Close
+	// more than once.
+	Close() error
+
+	// Endpoint returns the address that clients connect to, e.g. the URL
+	// of Elasticsearch or the DSN of PostgreSQL.
+	Endpoint() string
+
+	// Logs returns the output of the container so far.
+	Logs(ctx context.Context) (string, error)
+
+	// WaitUntilReady blocks until the service in the container accepts
+	// requests, or ctx is done.
+	WaitUntilReady(ctx context.Context) error
+}
+
+// Config holds the options that all container packages support. Pass
+// them to a package with its WithOptions, e.g.
+//
+//	postgres.Start(t, postgres.WithOptions(core.WithTimeout(time.Minute)))
+type Config struct {
+	// Timeout to wait for the container to become ready. Zero means the
+	// default of the package.
+	Timeout time.Duration
+
+	// MaxLifetime after which the container is killed, in case the test
+	// process dies before it can remove the container. Zero means
+	// DefaultMaxLifetime, and a negative value means no limit.
+	MaxLifetime time.Duration
+
+	// Version of the image to start. Empty means the default of the
+	// package.
+	Version string
+
+	// Env are additional environment variables of the container in the
+	// form "KEY=value".
+	Env []string
+
+	// PullPolicy decides when to pull the image. Empty means
+	// PullIfNotPresent.
+	PullPolicy PullPolicy
+
+	// Networks the container joins in addition to those of the package.
+	Networks []NetworkAttachment
+
+	// CPULimit is the number of CPUs the container may use, e.g. 1.5.
+	// Zero means the default of the package.
+	CPULimit float64
+
+	// MemoryLimit is the memory limit of the container in bytes. Zero
+	// means the default of the package.
+	MemoryLimit int64
+
+	// ShmSize is the size of /dev/shm of the container in bytes. Zero
+	// means the default of Docker, i.e. 64MB.
+	ShmSize int64
+
+	// Logger receives the lifecycle events of the container: pull, start,
+	// ready, and close, with their durations and the container ID. Nil
+	// means no logging.
+	Logger *slog.Logger
+
+	// RetryPolicy decides how readiness checks are retried while the
+	// container starts. The zero value means the default of the package.
+	RetryPolicy RetryPolicy
+
+	// Keep keeps the container after the test, see WithKeepContainer.
+	Keep bool
+
+	// Build is the image to build instead of pulling the image of the
+	// package, see WithBuiltImage. Nil means to pull it.
+	Build *Build
+
+	// PortRetries is how often to retry starting the container if a host
+	// port is already in use, e.g. by a parallel job on a shared runner.
+	// Zero means DefaultPortRetries, and a negative value means to fail
+	// at the first conflict.
+	PortRetries int
+
+	// WaitFor are the strategies that decide when the container is ready,
+	// replacing the default of the package. Empty means the default.
+	WaitFor []WaitStrategy
+}
+
+// Option configures a Config.
+type Option func(*Config)
+
+// NewConfig returns a Config with the given options applied.
+func NewConfig(options ...Option) Config {
+	var cfg Config
+	for _, o := range options {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// DefaultMaxLifetime is the time after which containers are killed by
+// default, generous enough for long test suites with shared containers.
+const DefaultMaxLifetime = time.Hour
+
+// DefaultPortRetries is how often containers are started again by default
+// if a host port is already in use.
+const DefaultPortRetries = 3
+
+// WithTimeout sets the time to wait for the container to become ready.
+// It is the same as WithStartupTimeout.
+func WithTimeout(timeout time.Duration) Option {
+	return WithStartupTimeout(timeout)
+}
+
+// WithStartupTimeout sets the time to wait for the container to become
+// ready.
+func WithStartupTimeout(timeout time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.Timeout = timeout
+	}
+}
+
+// WithMaxLifetime sets the time after which the container is killed, in
+// case the test process dies before it can remove the container. Use a
+// negative value to never kill it.
+func WithMaxLifetime(lifetime time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.MaxLifetime = lifetime
+	}
+}
+
+// WithVersion sets the version of the image to start.
+func WithVersion(version string) Option {
+	return func(cfg *Config) {
+		cfg.Version = version
+	}
+}
+
+// WithEnv adds environment variables in the form "KEY=value".
+func WithEnv(env ...string) Option {
+	return func(cfg *Config) {
+		cfg.Env = append(cfg.Env, env...)
+	}
+}
+
+// WithPullPolicy sets when to pull the image of the container.
+func WithPullPolicy(policy PullPolicy) Option {
+	return func(cfg *Config) {
+		cfg.PullPolicy = policy
+	}
+}
+
+// WithCPULimit limits the number of CPUs the container may use, e.g. 1.5.
+func WithCPULimit(cpus float64) Option {
+	return func(cfg *Config) {
+		cfg.CPULimit = cpus
+	}
+}
+
+// WithMemoryLimit sets the memory limit of the container in bytes.
+func WithMemoryLimit(bytes int64) Option {
+	return func(cfg *Config) {
+		cfg.MemoryLimit = bytes
+	}
+}
+
+// WithShmSize sets the size of /dev/shm of the container in bytes.
+func WithShmSize(bytes int64) Option {
+	return func(cfg *Config) {
+		cfg.ShmSize = bytes
+	}
+}
+
+// WithLogger logs the lifecycle events of the container to logger, e.g.
+// to find out why a container starts slowly.
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *Config) {
+		cfg.Logger = logger
+	}
+}
+
+// WithRetryPolicy retries readiness checks up to maxAttempts times, or
+// until the timeout elapses if maxAttempts is zero, waiting initialBackoff
+// after the first failed attempt and doubling the wait up to maxBackoff,
+// e.g. to allow for slow CI machines or to fail faster on a laptop.
+func WithRetryPolicy(maxAttempts int, initialBackoff, maxBackoff time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.RetryPolicy = RetryPolicy{
+			MaxAttempts:    maxAttempts,
+			InitialBackoff: initialBackoff,
+			MaxBackoff:     maxBackoff,
+		}
+	}
+}
+
+// WithWaitFor sets the strategies that decide when the container is
+// ready, replacing the default of the package. The container is ready
+// when all strategies succeed, in order.
+func WithWaitFor(strategies ...WaitStrategy) Option {
+	return func(cfg *Config) {
+		cfg.WaitFor = append(cfg.WaitFor, strategies...)
+	}
+}
+
+// WithPortRetries sets how often to retry starting the container if a host
+// port is already in use: with a new port if Docker assigns it, or with
+// the same port after a backoff if it was requested explicitly. Use
+// a negative value to fail at the first conflict.
+func WithPortRetries(retries int) Option {
+	return func(cfg *Config) {
+		cfg.PortRetries = retries
+	}
+}
+
+// WithNetwork connects the container to network, where other containers
+// reach it by the given aliases, e.g. "db".
+func WithNetwork(network *Network, aliases ...string) Option {
+	return func(cfg *Config) {
+		cfg.Networks = append(cfg.Networks, NetworkAttachment{Network: network, Aliases: aliases})
+	}
+}
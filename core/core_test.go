@@ -0,0 +1,54 @@
+package core_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+func TestNewConfig(t *testing.T) {
+	cfg := core.NewConfig(
+		core.WithTimeout(time.Minute),
+		core.WithVersion("16"),
+		core.WithEnv("A=1"),
+		core.WithEnv("B=2"),
+		core.WithPullPolicy(core.PullAlways),
+		core.WithRetryPolicy(10, time.Second, 4*time.Second),
+		core.WithMaxLifetime(2*time.Hour),
+		core.WithPortRetries(5),
+	)
+	if want, have := time.Minute, cfg.Timeout; want != have {
+		t.Errorf("want Timeout=%v, have %v", want, have)
+	}
+	if want, have := "16", cfg.Version; want != have {
+		t.Errorf("want Version=%q, have %q", want, have)
+	}
+	if want, have := []string{"A=1", "B=2"}, cfg.Env; !slices.Equal(want, have) {
+		t.Errorf("want Env=%v, have %v", want, have)
+	}
+	if want, have := core.PullAlways, cfg.PullPolicy; want != have {
+		t.Errorf("want PullPolicy=%q, have %q", want, have)
+	}
+	if want, have := 2*time.Hour, cfg.MaxLifetime; want != have {
+		t.Errorf("want MaxLifetime=%v, have %v", want, have)
+	}
+	if want, have := 5, cfg.PortRetries; want != have {
+		t.Errorf("want PortRetries=%d, have %d", want, have)
+	}
+	want := core.RetryPolicy{MaxAttempts: 10, InitialBackoff: time.Second, MaxBackoff: 4 * time.Second}
+	if have := cfg.RetryPolicy; want != have {
+		t.Errorf("want RetryPolicy=%+v, have %+v", want, have)
+	}
+}
+
+func TestWithStartupTimeout(t *testing.T) {
+	cfg := core.NewConfig(core.WithStartupTimeout(time.Minute))
+	if want, have := time.Minute, cfg.Timeout; want != have {
+		t.Errorf("want Timeout=%v, have %v", want, have)
+	}
+	if have := cfg.MaxLifetime; have != 0 {
+		t.Errorf("want startup timeout not to limit the lifetime, have MaxLifetime=%v", have)
+	}
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// ServiceFunc starts a service of a Fixture with the given options, which
+// connect it to the network of the fixture under the name of the service,
+// see WithNetwork, and pass the environment that its dependencies export,
+// see WithEnv. Pass them to the package, e.g. with postgres.WithOptions.
+//
+// It returns the container and the environment variables in the form
+// "KEY=value" that it exports to the services that depend on it, e.g.
+// "DATABASE_URL=postgres://postgres:postgres@db:5432/app".
+type ServiceFunc func(tb testing.TB, options ...Option) (Container, []string)
+
+// Fixture is a set of named services that depend on each other, e.g. an
+// application image that connects to PostgreSQL and Elasticsearch:
+//
+//	f := core.NewFixture().
+//		Add("db", startPostgres).
+//		Add("es", startElasticsearch).
+//		Add("app", startApp, "db", "es")
+//	services := f.Start(t)
+type Fixture struct {
+	services []*service
+}
+
+type service struct {
+	name      string
+	start     ServiceFunc
+	dependsOn []string
+}
+
+// NewFixture returns an empty Fixture.
+func NewFixture() *Fixture {
+	return &Fixture{}
+}
+
+// Add adds the service name, started by start after the services it
+// depends on. It returns f to chain calls.
+func (f *Fixture) Add(name string, start ServiceFunc, dependsOn ...string) *Fixture {
+	f.services = append(f.services, &service{
+		name:      name,
+		start:     start,
+		dependsOn: dependsOn,
+	})
+	return f
+}
+
+// Start starts the services on a new network, each after the services it
+// depends on, with the environment they export. It returns the containers
+// by service name. The containers are closed in reverse order when the
+// test finishes, before the network is removed.
+//
+// Start fails the test if a service depends on a missing service or the
+// dependencies form a cycle.
+func (f *Fixture) Start(tb testing.TB) map[string]Container {
+	tb.Helper()
+
+	order, err := f.order()
+	if err != nil {
+		tb.Fatalf("core: %v", err)
+	}
+
+	network := NewNetwork(tb, "fixture")
+	containers := make(map[string]Container, len(order))
+	exports := make(map[string][]string, len(order))
+	for _, s := range order {
+		var env []string
+		for _, dep := range s.dependsOn {
+			env = append(env, exports[dep]...)
+		}
+		c, exported := s.start(tb, WithNetwork(network, s.name), WithEnv(env...))
+		// Cleanups run in reverse order, so dependent services are
+		// closed before their dependencies
+		tb.Cleanup(func() {
+			c.Close()
+		})
+		containers[s.name] = c
+		exports[s.name] = exported
+	}
+	return containers
+}
+
+// order returns the services in the order to start them, i.e. every
+// service after its dependencies and otherwise in the order they were
+// added.
+func (f *Fixture) order() ([]*service, error) {
+	byName := make(map[string]*service, len(f.services))
+	for _, s := range f.services {
+		if _, ok := byName[s.name]; ok {
+			return nil, fmt.Errorf("service %q is added twice", s.name)
+		}
+		byName[s.name] = s
+	}
+	for _, s := range f.services {
+		for _, dep := range s.dependsOn {
+			if _, ok := byName[dep]; !ok {
+				return nil, fmt.Errorf("service %q depends on missing service %q", s.name, dep)
+			}
+		}
+	}
+
+	var order []*service
+	started := make(map[string]bool, len(f.services))
+	for len(order) < len(f.services) {
+		n := len(order)
+		for _, s := range f.services {
+			if started[s.name] {
+				continue
+			}
+			ready := !slices.ContainsFunc(s.dependsOn, func(dep string) bool {
+				return !started[dep]
+			})
+			if ready {
+				order = append(order, s)
+				started[s.name] = true
+			}
+		}
+		if len(order) == n {
+			var cycle []string
+			for _, s := range f.services {
+				if !started[s.name] {
+					cycle = append(cycle, s.name)
+				}
+			}
+			return nil, fmt.Errorf("services %v depend on each other", cycle)
+		}
+	}
+	return order, nil
+}
@@ -0,0 +1,128 @@
+package core_test
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+// fatalTB records the message of Fatalf instead of failing the actual
+// test. Like the testing package, Fatalf stops the goroutine that calls it.
+type fatalTB struct {
+	*testing.T
+	fatal string
+}
+
+func (tb *fatalTB) Fatalf(format string, args ...any) {
+	tb.fatal = fmt.Sprintf(format, args...)
+	runtime.Goexit()
+}
+
+type fakeService struct {
+	name   string
+	closed *[]string
+}
+
+func (c fakeService) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return nil
+}
+
+func (c fakeService) Endpoint() string                         { return c.name }
+func (c fakeService) Logs(ctx context.Context) (string, error) { return "", nil }
+func (c fakeService) WaitUntilReady(ctx context.Context) error { return nil }
+
+func TestFixture_Start(t *testing.T) {
+	core.SkipIfUnavailable(t)
+
+	var started, closed []string
+	env := make(map[string][]string)
+	service := func(name string, exports ...string) core.ServiceFunc {
+		return func(tb testing.TB, options ...core.Option) (core.Container, []string) {
+			cfg := core.NewConfig(options...)
+			if want, have := 1, len(cfg.Networks); want != have {
+				tb.Fatalf("want %d network, have %d", want, have)
+			}
+			if want, have := []string{name}, cfg.Networks[0].Aliases; !slices.Equal(want, have) {
+				tb.Fatalf("want aliases %v, have %v", want, have)
+			}
+			started = append(started, name)
+			env[name] = cfg.Env
+			return fakeService{name: name, closed: &closed}, exports
+		}
+	}
+
+	t.Run("start", func(t *testing.T) {
+		containers := core.NewFixture().
+			Add("app", service("app"), "db", "es").
+			Add("es", service("es", "ELASTICSEARCH_URL=http://es:9200")).
+			Add("db", service("db", "DATABASE_URL=postgres://db:5432/app")).
+			Start(t)
+		if want, have := 3, len(containers); want != have {
+			t.Fatalf("want %d containers, have %d", want, have)
+		}
+		if want, have := "db", containers["db"].Endpoint(); want != have {
+			t.Fatalf("want container of db, have %q", have)
+		}
+	})
+
+	if want, have := []string{"es", "db", "app"}, started; !slices.Equal(want, have) {
+		t.Fatalf("want services started in order %v, have %v", want, have)
+	}
+	if want, have := []string{"DATABASE_URL=postgres://db:5432/app", "ELASTICSEARCH_URL=http://es:9200"}, env["app"]; !slices.Equal(want, have) {
+		t.Fatalf("want env %v, have %v", want, have)
+	}
+	if have := env["db"]; len(have) != 0 {
+		t.Fatalf("want no env for db, have %v", have)
+	}
+	if want, have := []string{"app", "db", "es"}, closed; !slices.Equal(want, have) {
+		t.Fatalf("want services closed in order %v, have %v", want, have)
+	}
+}
+
+func TestFixture_Invalid(t *testing.T) {
+	noop := func(tb testing.TB, options ...core.Option) (core.Container, []string) {
+		t.Fatal("want no service to be started")
+		return nil, nil
+	}
+	tests := []struct {
+		name    string
+		fixture *core.Fixture
+		want    string
+	}{
+		{
+			name:    "missing",
+			fixture: core.NewFixture().Add("app", noop, "db"),
+			want:    `service "app" depends on missing service "db"`,
+		},
+		{
+			name:    "duplicate",
+			fixture: core.NewFixture().Add("db", noop).Add("db", noop),
+			want:    `service "db" is added twice`,
+		},
+		{
+			name:    "cycle",
+			fixture: core.NewFixture().Add("db", noop).Add("a", noop, "b", "db").Add("b", noop, "a"),
+			want:    `services [a b] depend on each other`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tb := &fatalTB{T: t}
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				tt.fixture.Start(tb)
+			}()
+			<-done
+			if !strings.Contains(tb.fatal, tt.want) {
+				t.Fatalf("want fatal error %q, have %q", tt.want, tb.fatal)
+			}
+		})
+	}
+}
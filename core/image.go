@@ -0,0 +1,65 @@
+package core
+
+import (
+	"os"
+	"strings"
+)
+
+// RegistryMirrorEnv is the environment variable with the registry that
+// images are pulled from instead of their original registry, e.g.
+// "mirror.example.com:5000" in air-gapped CI systems.
+const RegistryMirrorEnv = "INTEGRATIONTEST_REGISTRY_MIRROR"
+
+// ImageEnv returns the environment variable that overrides the image of
+// the given package, e.g. INTEGRATIONTEST_IMAGE_POSTGRES for "postgres".
+func ImageEnv(pkg string) string {
+	return "INTEGRATIONTEST_IMAGE_" + strings.ToUpper(pkg)
+}
+
+// Image returns the repository and tag of the image that the package pkg
+// starts, given its default repository and tag.
+//
+// The environment variable ImageEnv(pkg) replaces the repository, e.g.
+// "registry.example.com/postgres", and also the tag if it has one, e.g.
+// "registry.example.com/postgres:16-custom". Otherwise, if
+// RegistryMirrorEnv is set, the image is pulled from the mirror: images of
+// Docker Hub like "postgres" become "mirror.example.com/library/postgres",
+// and images of other registries like "docker.elastic.co/elasticsearch/
+// elasticsearch" keep their registry as the first path element, i.e.
+// "mirror.example.com/docker.elastic.co/elasticsearch/elasticsearch".
+func Image(pkg, repository, tag string) (string, string) {
+	if image := os.Getenv(ImageEnv(pkg)); image != "" {
+		repo, t := splitTag(image)
+		if t == "" {
+			t = tag
+		}
+		return repo, t
+	}
+	if mirror := strings.TrimSuffix(os.Getenv(RegistryMirrorEnv), "/"); mirror != "" {
+		return mirror + "/" + hubPath(repository), tag
+	}
+	return repository, tag
+}
+
+// splitTag splits image into its repository and tag, if any.
+func splitTag(image string) (string, string) {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		// The colon is part of the host and port of the registry
+		return image, ""
+	}
+	return image[:i], image[i+1:]
+}
+
+// hubPath returns the path of repository relative to a registry mirror.
+// Official images of Docker Hub are in the library namespace.
+func hubPath(repository string) string {
+	first, _, found := strings.Cut(repository, "/")
+	if !found {
+		return "library/" + repository
+	}
+	if first == "docker.io" {
+		return hubPath(strings.TrimPrefix(repository, "docker.io/"))
+	}
+	return repository
+}
@@ -0,0 +1,34 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+func TestImage(t *testing.T) {
+	tests := []struct {
+		image, mirror string
+		repository    string
+		tag           string
+		wantRepo      string
+		wantTag       string
+	}{
+		{"", "", "postgres", "16-alpine", "postgres", "16-alpine"},
+		{"registry.example.com/postgres", "", "postgres", "16-alpine", "registry.example.com/postgres", "16-alpine"},
+		{"registry.example.com:5000/postgres", "", "postgres", "16-alpine", "registry.example.com:5000/postgres", "16-alpine"},
+		{"registry.example.com:5000/postgres:16-custom", "", "postgres", "16-alpine", "registry.example.com:5000/postgres", "16-custom"},
+		{"", "mirror.example.com", "postgres", "16-alpine", "mirror.example.com/library/postgres", "16-alpine"},
+		{"", "mirror.example.com/", "docker.io/bitnami/redis", "7", "mirror.example.com/bitnami/redis", "7"},
+		{"", "mirror.example.com", "docker.elastic.co/elasticsearch/elasticsearch", "8.12.2", "mirror.example.com/docker.elastic.co/elasticsearch/elasticsearch", "8.12.2"},
+		{"registry.example.com/postgres", "mirror.example.com", "postgres", "16", "registry.example.com/postgres", "16"},
+	}
+	for i, tt := range tests {
+		t.Setenv(core.ImageEnv("postgres"), tt.image)
+		t.Setenv(core.RegistryMirrorEnv, tt.mirror)
+		repo, tag := core.Image("postgres", tt.repository, tt.tag)
+		if tt.wantRepo != repo || tt.wantTag != tag {
+			t.Errorf("#%d: want %s:%s, have %s:%s", i, tt.wantRepo, tt.wantTag, repo, tag)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// CleanupOnInterrupt removes the containers and networks of this process
+// with PurgeOwnContainers when it receives SIGINT or SIGTERM, and exits.
+// Without it, interrupting go test, e.g. with Ctrl-C, leaks the
+// containers of running tests, as their cleanup functions never run.
+//
+// Call it in TestMain before m.Run. It returns a function that removes
+// the handler again.
+func CleanupOnInterrupt() (stop func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			removed, err := PurgeOwnContainers(ctx)
+			cancel()
+			fmt.Fprintf(os.Stderr, "integrationtest: %v: removed %d containers\n", sig, len(removed))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "integrationtest: could not remove containers: %v\n", err)
+			}
+			os.Exit(1)
+		case <-done:
+		}
+	}()
+	return sync.OnceFunc(func() {
+		signal.Stop(signals)
+		close(done)
+	})
+}
+
+// PurgeOwnContainers removes the containers of this process, see
+// ListOwnContainers, and the networks of this process, i.e. networks
+// created with Labels, e.g. by NewNetwork.
+// Containers kept with WithKeepContainer are not removed. It returns the
+// IDs of the removed containers.
+func PurgeOwnContainers(ctx context.Context) ([]string, error) {
+	pool, err := dockerutil.Pool()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to Docker: %w", err)
+	}
+	containers, err := ListOwnContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []string
+	var errs []error
+	for _, c := range containers {
+		if c.Labels[LabelKeep] != "" {
+			continue
+		}
+		err := pool.Client.RemoveContainer(docker.RemoveContainerOptions{
+			ID:            c.ID,
+			Force:         true,
+			RemoveVolumes: true,
+			Context:       ctx,
+		})
+		var notFound *docker.NoSuchContainer
+		if err != nil && !errors.As(err, &notFound) {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, c.ID)
+	}
+
+	networks, err := pool.Client.FilteredListNetworks(docker.NetworkFilterOpts{
+		"label": {LabelSession + "=" + SessionID(): true},
+	})
+	if err != nil {
+		return removed, errors.Join(append(errs, err)...)
+	}
+	for _, n := range networks {
+		var notFound *docker.NoSuchNetwork
+		if err := pool.Client.RemoveNetwork(n.ID); err != nil && !errors.As(err, &notFound) {
+			errs = append(errs, err)
+		}
+	}
+	return removed, errors.Join(errs...)
+}
@@ -0,0 +1,107 @@
+package core_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+func TestCleanupOnInterrupt(t *testing.T) {
+	if os.Getenv("INTEGRATIONTEST_INTERRUPT_HELPER") != "" {
+		core.CleanupOnInterrupt()
+		os.Stdout.WriteString("ready\n")
+		time.Sleep(time.Minute)
+		return
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("cannot send SIGINT on Windows")
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestCleanupOnInterrupt$")
+	cmd.Env = append(os.Environ(), "INTEGRATIONTEST_INTERRUPT_HELPER=1", "DOCKER_HOST=tcp://127.0.0.1:1")
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bufio.NewReader(stdout).ReadString('\n'); err != nil {
+		t.Fatalf("helper did not start: %v", err)
+	}
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 1 {
+		t.Fatalf("want exit code 1, have %v", err)
+	}
+	if !strings.Contains(stderr.String(), "integrationtest: interrupt") {
+		t.Errorf("want cleanup message, have:\n%s", stderr.String())
+	}
+}
+
+func TestCleanupOnInterrupt_Stop(t *testing.T) {
+	stop := core.CleanupOnInterrupt()
+	stop()
+	stop()
+}
+
+func TestPurgeOwnContainers(t *testing.T) {
+	pool, err := dockerutil.NewPool()
+	if err != nil {
+		t.Fatalf("unable to connect to Docker: %v", err)
+	}
+	if err := pool.Client.Ping(); err != nil {
+		t.Fatalf("could not connect to docker: %v", err)
+	}
+
+	run := func(labels map[string]string) *dockertest.Resource {
+		resource, err := pool.RunWithOptions(&dockertest.RunOptions{
+			Repository: "alpine",
+			Tag:        "3.19",
+			Cmd:        []string{"sleep", "60"},
+			Labels:     labels,
+		}, func(config *docker.HostConfig) {
+			config.AutoRemove = true
+		})
+		if err != nil {
+			t.Fatalf("could not start container: %v", err)
+		}
+		t.Cleanup(func() { pool.Purge(resource) })
+		return resource
+	}
+	own := run(core.Labels("core", t.Name()))
+	labels := core.Labels("core", t.Name())
+	labels[core.LabelKeep] = "true"
+	kept := run(labels)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	removed, err := core.PurgeOwnContainers(ctx)
+	if err != nil {
+		t.Fatalf("could not purge containers: %v", err)
+	}
+	if !slices.Contains(removed, own.Container.ID) {
+		t.Errorf("want container of this process to be removed, have %v", removed)
+	}
+	if slices.Contains(removed, kept.Container.ID) {
+		t.Errorf("want kept container to be kept, have %v", removed)
+	}
+}
@@ -0,0 +1,28 @@
+package core
+
+import (
+	"os"
+	"strconv"
+)
+
+// KeepEnv is the environment variable that keeps the containers of all
+// tests after they finish when set to a true value, e.g.
+// INTEGRATIONTEST_KEEP=1, as if WithKeepContainer was given.
+const KeepEnv = "INTEGRATIONTEST_KEEP"
+
+// WithKeepContainer keeps the container after the test, e.g. to inspect
+// the state of a database after a failing test. The container is neither
+// removed on Close nor killed after its timeout, and it is not reaped.
+// Close prints how to connect to it and how to remove it.
+func WithKeepContainer() Option {
+	return func(cfg *Config) {
+		cfg.Keep = true
+	}
+}
+
+// KeepContainers returns true if KeepEnv asks to keep the containers of
+// all tests.
+func KeepContainers() bool {
+	keep, _ := strconv.ParseBool(os.Getenv(KeepEnv))
+	return keep
+}
@@ -0,0 +1,36 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+func TestKeepContainers(t *testing.T) {
+	tests := []struct {
+		env  string
+		want bool
+	}{
+		{"", false},
+		{"0", false},
+		{"false", false},
+		{"1", true},
+		{"true", true},
+		{"yes", false},
+	}
+	for _, tt := range tests {
+		t.Setenv(core.KeepEnv, tt.env)
+		if have := core.KeepContainers(); tt.want != have {
+			t.Errorf("%s=%q: want %v, have %v", core.KeepEnv, tt.env, tt.want, have)
+		}
+	}
+}
+
+func TestWithKeepContainer(t *testing.T) {
+	if core.NewConfig().Keep {
+		t.Fatal("want containers to be removed by default")
+	}
+	if !core.NewConfig(core.WithKeepContainer()).Keep {
+		t.Fatal("want container to be kept")
+	}
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// Labels of the Docker containers that the container packages create.
+// They identify the test process that created a container, so that
+// containers left behind by crashed runs can be found and removed.
+const (
+	LabelSession  = "org.olivere.integrationtest.session"
+	LabelPackage  = "org.olivere.integrationtest.package"
+	LabelStarted  = "org.olivere.integrationtest.started"
+	LabelHost     = "org.olivere.integrationtest.host"
+	LabelPID      = "org.olivere.integrationtest.pid"
+	LabelTest     = "org.olivere.integrationtest.test"
+	LabelVersion  = "org.olivere.integrationtest.version"
+	LabelKeep     = "org.olivere.integrationtest.keep"
+	LabelChecksum = "org.olivere.integrationtest.checksum"
+)
+
+// modulePath is the path of this module in the build info.
+const modulePath = "github.com/olivere/integrationtest"
+
+var session struct {
+	once sync.Once
+	id   string
+}
+
+// SessionID returns the random ID of the current process, which is the
+// value of LabelSession of the containers it creates.
+func SessionID() string {
+	session.once.Do(func() {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			session.id = strconv.FormatInt(time.Now().UnixNano(), 16)
+			return
+		}
+		session.id = hex.EncodeToString(b)
+	})
+	return session.id
+}
+
+// Labels returns the labels of a container that the given package, e.g.
+// "postgres", starts now for the test testName, which may be empty.
+func Labels(pkg, testName string) map[string]string {
+	host, _ := os.Hostname()
+	labels := map[string]string{
+		LabelSession: SessionID(),
+		LabelPackage: pkg,
+		LabelStarted: time.Now().UTC().Format(time.RFC3339),
+		LabelHost:    host,
+		LabelPID:     strconv.Itoa(os.Getpid()),
+		LabelVersion: Version(),
+	}
+	if testName != "" {
+		labels[LabelTest] = testName
+	}
+	return labels
+}
+
+// Version returns the version of this module that the test binary is
+// built with, e.g. "v0.3.0", or "(devel)" if it is built from a checkout
+// of the module itself.
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Path == modulePath {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			if dep.Replace != nil {
+				return dep.Replace.Version
+			}
+			return dep.Version
+		}
+	}
+	return "unknown"
+}
+
+// ListOwnContainers returns the containers, running or not, that this
+// process has started in the current session, e.g. to report which
+// containers a test suite left behind.
+func ListOwnContainers(ctx context.Context) ([]docker.APIContainers, error) {
+	pool, err := dockerutil.Pool()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to Docker: %w", err)
+	}
+	return pool.Client.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {LabelSession + "=" + SessionID()}},
+		Context: ctx,
+	})
+}
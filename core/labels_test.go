@@ -0,0 +1,45 @@
+package core_test
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+func TestLabels(t *testing.T) {
+	labels := core.Labels("postgres", "TestUsers")
+
+	if want, have := core.SessionID(), labels[core.LabelSession]; want == "" || want != have {
+		t.Errorf("want session %q, have %q", want, have)
+	}
+	if want, have := "postgres", labels[core.LabelPackage]; want != have {
+		t.Errorf("want package %q, have %q", want, have)
+	}
+	if want, have := strconv.Itoa(os.Getpid()), labels[core.LabelPID]; want != have {
+		t.Errorf("want pid %q, have %q", want, have)
+	}
+	if want, have := "TestUsers", labels[core.LabelTest]; want != have {
+		t.Errorf("want test %q, have %q", want, have)
+	}
+	if want, have := core.Version(), labels[core.LabelVersion]; want == "" || want != have {
+		t.Errorf("want version %q, have %q", want, have)
+	}
+	started, err := time.Parse(time.RFC3339, labels[core.LabelStarted])
+	if err != nil {
+		t.Fatalf("want start time in RFC3339, have %q", labels[core.LabelStarted])
+	}
+	if time.Since(started) > time.Minute {
+		t.Errorf("want start time of now, have %v", started)
+	}
+}
+
+func TestLabels_NoTestName(t *testing.T) {
+	labels := core.Labels("compose", "")
+
+	if have, ok := labels[core.LabelTest]; ok {
+		t.Errorf("want no test label, have %q", have)
+	}
+}
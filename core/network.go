@@ -0,0 +1,84 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// Network is a user-defined bridge network that containers of different
+// packages join to reach each other by their aliases, e.g. an application
+// container that connects to PostgreSQL as "db:5432".
+type Network struct {
+	network *dockertest.Network
+}
+
+// NetworkAttachment connects a container to a network, see WithNetwork.
+type NetworkAttachment struct {
+	Network *Network
+	Aliases []string
+}
+
+// NewNetwork creates a network that is removed when the test finishes.
+// The name of the network is prefixed with name and unique, so tests can
+// create networks with the same name in parallel.
+func NewNetwork(tb testing.TB, name string) *Network {
+	tb.Helper()
+
+	pool, err := dockerutil.Pool()
+	if err != nil {
+		tb.Fatalf("unable to connect to Docker: %v", err)
+	}
+	network, err := pool.CreateNetwork(name+"_"+dockerutil.Suffix(), func(config *docker.CreateNetworkOptions) {
+		config.Driver = "bridge"
+		config.Labels = Labels("network", tb.Name())
+	})
+	if err != nil {
+		tb.Fatalf("could not create network %s: %v", name, err)
+	}
+	n := &Network{network: network}
+	// Cleanups run in reverse order, so containers that were started
+	// after the network are closed before it is removed
+	tb.Cleanup(func() {
+		n.Close()
+	})
+	return n
+}
+
+// ID returns the ID of the network.
+func (n *Network) ID() string {
+	return n.network.Network.ID
+}
+
+// Name returns the name of the network.
+func (n *Network) Name() string {
+	return n.network.Network.Name
+}
+
+// Docker returns the network to pass to options of dockertest, or of
+// packages that take one, e.g. postgres.WithNetwork.
+func (n *Network) Docker() *dockertest.Network {
+	return n.network
+}
+
+// Connect connects the container with the given ID to the network, where
+// other containers reach it by its name and the given aliases.
+func (n *Network) Connect(client *docker.Client, containerID string, aliases ...string) error {
+	err := client.ConnectNetwork(n.ID(), docker.NetworkConnectionOptions{
+		Container:      containerID,
+		EndpointConfig: &docker.EndpointConfig{Aliases: aliases},
+	})
+	if err != nil {
+		return fmt.Errorf("could not connect container to network %s: %w", n.Name(), err)
+	}
+	return nil
+}
+
+// Close removes the network. Containers must be disconnected, i.e.
+// closed, before.
+func (n *Network) Close() error {
+	return n.network.Close()
+}
@@ -0,0 +1,14 @@
+package core
+
+import (
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/ory/dockertest/v3"
+)
+
+// Pool returns the pool that all container packages share to talk to
+// the Docker daemon configured by the environment, e.g. to start a custom
+// container next to them. It is created and pinged once per daemon. The
+// pool is shared, so do not change it, e.g. its MaxWait.
+func Pool() (*dockertest.Pool, error) {
+	return dockerutil.Pool()
+}
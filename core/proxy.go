@@ -0,0 +1,62 @@
+package core
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// proxyVars are the environment variables that configure HTTP proxies.
+// Tools disagree on their case, so both are passed.
+var proxyVars = []string{
+	"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY",
+	"http_proxy", "https_proxy", "no_proxy",
+}
+
+// ProxyEnv returns the proxy environment variables of this process, e.g.
+// "HTTPS_PROXY=http://proxy.example.com:3128", in the form "KEY=value".
+func ProxyEnv() []string {
+	var env []string
+	for _, key := range proxyVars {
+		if value, ok := os.LookupEnv(key); ok {
+			env = append(env, key+"="+value)
+		}
+	}
+	return env
+}
+
+// WithProxyEnv passes proxy environment variables in the form "KEY=value"
+// into the container, e.g. so that it can install packages behind
+// a corporate proxy. Without env, it passes ProxyEnv.
+//
+// A proxy on localhost is not reachable from within the container, so
+// use an address of the host that containers can reach instead.
+func WithProxyEnv(env ...string) Option {
+	if len(env) == 0 {
+		env = ProxyEnv()
+	}
+	return WithEnv(env...)
+}
+
+// proxyHint explains why pulling an image may have failed behind a proxy:
+// the Docker daemon pulls images itself, so the proxy environment of this
+// process does not apply to pulls. It returns "" if there is nothing to
+// explain.
+func proxyHint(client *docker.Client) string {
+	var key string
+	for _, k := range []string{"HTTPS_PROXY", "https_proxy", "HTTP_PROXY", "http_proxy"} {
+		if os.Getenv(k) != "" {
+			key = k
+			break
+		}
+	}
+	if key == "" {
+		return ""
+	}
+	info, err := client.Info()
+	if err != nil || info.HTTPProxy != "" || info.HTTPSProxy != "" {
+		return ""
+	}
+	return fmt.Sprintf("%s is set, but the Docker daemon, which pulls the image, has no proxy configured", key)
+}
@@ -0,0 +1,37 @@
+package core_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+func TestProxyEnv(t *testing.T) {
+	for _, key := range []string{"HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY", "http_proxy", "https_proxy", "no_proxy"} {
+		t.Setenv(key, "")
+	}
+	t.Setenv("HTTPS_PROXY", "http://proxy.example.com:3128")
+	t.Setenv("no_proxy", "localhost")
+
+	env := core.ProxyEnv()
+	for _, want := range []string{"HTTPS_PROXY=http://proxy.example.com:3128", "no_proxy=localhost"} {
+		if !slices.Contains(env, want) {
+			t.Errorf("want %q in %v", want, env)
+		}
+	}
+}
+
+func TestWithProxyEnv(t *testing.T) {
+	t.Setenv("HTTPS_PROXY", "http://proxy.example.com:3128")
+
+	cfg := core.NewConfig(core.WithProxyEnv())
+	if !slices.Contains(cfg.Env, "HTTPS_PROXY=http://proxy.example.com:3128") {
+		t.Errorf("want proxy of this process, have %v", cfg.Env)
+	}
+
+	cfg = core.NewConfig(core.WithProxyEnv("HTTPS_PROXY=http://172.17.0.1:3128"))
+	if want, have := []string{"HTTPS_PROXY=http://172.17.0.1:3128"}, cfg.Env; !slices.Equal(want, have) {
+		t.Errorf("want Env=%v, have %v", want, have)
+	}
+}
@@ -0,0 +1,87 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// PullPolicy decides when the container packages pull the image of a
+// container.
+type PullPolicy string
+
+const (
+	// PullIfNotPresent pulls the image unless it exists locally. It is
+	// the default.
+	PullIfNotPresent PullPolicy = "IfNotPresent"
+	// PullAlways pulls the image before every start, e.g. to get updates
+	// of a moving tag like "latest".
+	PullAlways PullPolicy = "Always"
+	// PullNever never pulls the image, and fails to start the container
+	// if it does not exist locally, e.g. for images built by a previous
+	// CI step.
+	PullNever PullPolicy = "Never"
+)
+
+// Pull pulls the image with the given repository and tag according to
+// policy. Unlike the implicit pull of dockertest, it stops when ctx is
+// done, so that the pull can be limited separately from the lifetime of
+// the container.
+func Pull(ctx context.Context, client *docker.Client, repository, tag string, policy PullPolicy) error {
+	image := repository + ":" + tag
+	if policy != PullAlways {
+		if _, err := client.InspectImage(image); err == nil {
+			return nil
+		}
+		if policy == PullNever {
+			return fmt.Errorf("image %s does not exist and the pull policy is %s", image, policy)
+		}
+	}
+	err := client.PullImage(docker.PullImageOptions{
+		Repository: repository,
+		Tag:        tag,
+		Context:    ctx,
+	}, docker.AuthConfiguration{})
+	if err != nil {
+		if hint := proxyHint(client); hint != "" {
+			return fmt.Errorf("could not pull image %s: %w (%s)", image, err, hint)
+		}
+		return fmt.Errorf("could not pull image %s: %w", image, err)
+	}
+	return nil
+}
+
+// PullImages pulls the given images, e.g. "postgres:16-alpine", in
+// parallel unless they exist locally. Call it in TestMain to pull the
+// images before the tests start, so that pulling them on a fresh machine
+// does not count against the timeouts of the tests.
+//
+// The images are pulled as given. Use the Image function of the container
+// packages to get the images they start, which respects the image
+// overrides of the environment (see Image).
+func PullImages(ctx context.Context, images ...string) error {
+	pool, err := dockerutil.Pool()
+	if err != nil {
+		return fmt.Errorf("unable to connect to Docker: %w", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(images))
+	for i, image := range images {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			repository, tag := splitTag(image)
+			if tag == "" {
+				tag = "latest"
+			}
+			errs[i] = Pull(ctx, pool.Client, repository, tag, PullIfNotPresent)
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,38 @@
+package core_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
+)
+
+func TestPullImages(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	if err := core.PullImages(ctx, "alpine:3.19", "busybox"); err != nil {
+		t.Fatalf("could not pull images: %v", err)
+	}
+
+	pool, err := dockerutil.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, image := range []string{"alpine:3.19", "busybox:latest"} {
+		if _, err := pool.Client.InspectImage(image); err != nil {
+			t.Errorf("want image %s to exist, have %v", image, err)
+		}
+	}
+
+	// Never pulls missing images
+	err = core.Pull(ctx, pool.Client, "alpine", "0.0-does-not-exist", core.PullNever)
+	if err == nil {
+		t.Fatal("want error for missing image with pull policy Never")
+	}
+	if err := core.Pull(ctx, pool.Client, "alpine", "3.19", core.PullNever); err != nil {
+		t.Fatalf("want existing image with pull policy Never, have %v", err)
+	}
+}
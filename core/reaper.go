@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// Reap removes the containers that were created by test processes on this
+// host that are no longer running, e.g. because they were killed before
+// they could clean up. It returns the IDs of the removed containers.
+//
+// Reap is best-effort: containers of other hosts are never removed, as
+// there is no way to tell whether their processes are still running.
+// Containers kept with WithKeepContainer are never removed either.
+func Reap(ctx context.Context, client *docker.Client) ([]string, error) {
+	containers, err := client.ListContainers(docker.ListContainersOptions{
+		All:     true,
+		Filters: map[string][]string{"label": {LabelSession}},
+		Context: ctx,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	host, _ := os.Hostname()
+	var removed []string
+	for _, c := range containers {
+		if c.Labels[LabelSession] == SessionID() || c.Labels[LabelHost] != host || c.Labels[LabelKeep] != "" {
+			continue
+		}
+		pid, err := strconv.Atoi(c.Labels[LabelPID])
+		if err != nil || processAlive(pid) {
+			continue
+		}
+		err = client.RemoveContainer(docker.RemoveContainerOptions{
+			ID:            c.ID,
+			Force:         true,
+			RemoveVolumes: true,
+			Context:       ctx,
+		})
+		var notFound *docker.NoSuchContainer
+		if err != nil && !errors.As(err, &notFound) {
+			return removed, err
+		}
+		removed = append(removed, c.ID)
+	}
+	return removed, nil
+}
+
+var reapOnce sync.Once
+
+// ReapOnce runs Reap the first time it is called in a process and ignores
+// its errors. The container packages call it before they start
+// a container.
+func ReapOnce(client *docker.Client) {
+	reapOnce.Do(func() {
+		Reap(context.Background(), client)
+	})
+}
+
+// processAlive returns false if the process with the given ID does not
+// exist. It returns true if that cannot be determined.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = p.Signal(syscall.Signal(0))
+	return !errors.Is(err, os.ErrProcessDone) && !errors.Is(err, syscall.ESRCH)
+}
@@ -0,0 +1,130 @@
+package core_test
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+func TestReap(t *testing.T) {
+	pool, err := dockerutil.NewPool()
+	if err != nil {
+		t.Fatalf("unable to connect to Docker: %v", err)
+	}
+	if err := pool.Client.Ping(); err != nil {
+		t.Fatalf("could not connect to docker: %v", err)
+	}
+
+	// A process that is no longer running
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	labels := core.Labels("core", t.Name())
+	labels[core.LabelSession] = "crashed"
+	labels[core.LabelPID] = strconv.Itoa(cmd.Process.Pid)
+
+	orphan, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "alpine",
+		Tag:        "3.19",
+		Cmd:        []string{"sleep", "60"},
+		Labels:     labels,
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+	})
+	if err != nil {
+		t.Fatalf("could not start container: %v", err)
+	}
+	defer pool.Purge(orphan)
+
+	// A container of this process
+	own, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "alpine",
+		Tag:        "3.19",
+		Cmd:        []string{"sleep", "60"},
+		Labels:     core.Labels("core", t.Name()),
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+	})
+	if err != nil {
+		t.Fatalf("could not start container: %v", err)
+	}
+	defer pool.Purge(own)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	removed, err := core.Reap(ctx, pool.Client)
+	if err != nil {
+		t.Fatalf("could not reap: %v", err)
+	}
+	if !slices.Contains(removed, orphan.Container.ID) {
+		t.Errorf("want orphaned container to be removed, have %v", removed)
+	}
+	if slices.Contains(removed, own.Container.ID) {
+		t.Errorf("want container of this process to be kept, have %v", removed)
+	}
+}
+
+func TestListOwnContainers(t *testing.T) {
+	pool, err := dockerutil.NewPool()
+	if err != nil {
+		t.Fatalf("unable to connect to Docker: %v", err)
+	}
+	if err := pool.Client.Ping(); err != nil {
+		t.Fatalf("could not connect to docker: %v", err)
+	}
+
+	labels := core.Labels("core", t.Name())
+	labels[core.LabelSession] = "other"
+	other, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "alpine",
+		Tag:        "3.19",
+		Cmd:        []string{"sleep", "60"},
+		Labels:     labels,
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+	})
+	if err != nil {
+		t.Fatalf("could not start container: %v", err)
+	}
+	defer pool.Purge(other)
+
+	own, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "alpine",
+		Tag:        "3.19",
+		Cmd:        []string{"sleep", "60"},
+		Labels:     core.Labels("core", t.Name()),
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+	})
+	if err != nil {
+		t.Fatalf("could not start container: %v", err)
+	}
+	defer pool.Purge(own)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	containers, err := core.ListOwnContainers(ctx)
+	if err != nil {
+		t.Fatalf("could not list containers: %v", err)
+	}
+	var ids []string
+	for _, c := range containers {
+		ids = append(ids, c.ID)
+	}
+	if !slices.Contains(ids, own.Container.ID) {
+		t.Errorf("want container of this process to be listed, have %v", ids)
+	}
+	if slices.Contains(ids, other.Container.ID) {
+		t.Errorf("want container of other session to be omitted, have %v", ids)
+	}
+}
@@ -0,0 +1,170 @@
+package core
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/olivere/integrationtest/internal/lifecycle"
+)
+
+// Report is the timing report of the containers that a test binary
+// started: how long pulling, starting, seeding, and closing them took.
+// See WriteReport.
+type Report struct {
+	// Package is the name of the test binary, e.g. "postgres" for
+	// postgres.test.
+	Package string `json:"package"`
+
+	// Events are the lifecycle events of the containers, in order.
+	Events []ReportEvent `json:"events"`
+
+	// Totals sum up the events per package and event.
+	Totals []ReportTotal `json:"totals"`
+}
+
+// ReportEvent is a lifecycle event of a container in a Report.
+type ReportEvent struct {
+	// Package of the container, e.g. "postgres".
+	Package string `json:"package"`
+	// Event is "pull", "start", "ready", "seed", or "close". The duration
+	// of "ready" is the startup time, from the start of the container
+	// until it accepted connections, "seed" is the time of the post-start
+	// operations, and "close" the teardown time.
+	Event string `json:"event"`
+	// Container is the ID of the container, if known.
+	Container string `json:"container,omitempty"`
+	// Start of the event.
+	Start time.Time `json:"start"`
+	// Seconds is the duration of the event.
+	Seconds float64 `json:"seconds"`
+	// Error tells why the event failed, or is empty.
+	Error string `json:"error,omitempty"`
+}
+
+// ReportTotal sums up the events of a package in a Report.
+type ReportTotal struct {
+	Package string `json:"package"`
+	Event   string `json:"event"`
+	// Count is the number of events.
+	Count int `json:"count"`
+	// Seconds is the sum of the durations of the events.
+	Seconds float64 `json:"seconds"`
+	// Max is the duration of the longest event.
+	Max float64 `json:"max_seconds"`
+	// Failed is the number of failed events.
+	Failed int `json:"failed"`
+}
+
+// NewReport returns the Report of the containers started so far.
+func NewReport() Report {
+	exe := filepath.Base(os.Args[0])
+	r := Report{
+		Package: strings.TrimSuffix(strings.TrimSuffix(exe, ".exe"), ".test"),
+		Events:  []ReportEvent{},
+		Totals:  []ReportTotal{},
+	}
+	index := make(map[[2]string]int)
+	for _, rec := range lifecycle.Records() {
+		seconds := rec.Duration.Seconds()
+		r.Events = append(r.Events, ReportEvent{
+			Package:   rec.Package,
+			Event:     rec.Event,
+			Container: rec.Container,
+			Start:     rec.Start.UTC(),
+			Seconds:   seconds,
+			Error:     rec.Error,
+		})
+		key := [2]string{rec.Package, rec.Event}
+		i, ok := index[key]
+		if !ok {
+			i = len(r.Totals)
+			index[key] = i
+			r.Totals = append(r.Totals, ReportTotal{Package: rec.Package, Event: rec.Event})
+		}
+		total := &r.Totals[i]
+		total.Count++
+		total.Seconds += seconds
+		total.Max = max(total.Max, seconds)
+		if rec.Error != "" {
+			total.Failed++
+		}
+	}
+	return r
+}
+
+// WriteReport writes the Report of the containers started so far to the
+// file at path, as JUnit XML with the totals as properties of a test suite
+// if path ends in ".xml", and as JSON otherwise. Call it in TestMain after
+// the tests ran, so that CI dashboards can track the cost of fixtures over
+// time:
+//
+//	func TestMain(m *testing.M) {
+//		code := m.Run()
+//		if err := core.WriteReport("fixtures.json"); err != nil {
+//			log.Print(err)
+//		}
+//		os.Exit(code)
+//	}
+func WriteReport(path string) error {
+	r := NewReport()
+	var (
+		data []byte
+		err  error
+	)
+	if strings.EqualFold(filepath.Ext(path), ".xml") {
+		data, err = r.junit()
+	} else {
+		data, err = json.MarshalIndent(r, "", "  ")
+	}
+	if err != nil {
+		return fmt.Errorf("could not encode report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("could not write report: %w", err)
+	}
+	return nil
+}
+
+// junit encodes the report as a JUnit test suite with the totals as
+// properties, e.g. "integrationtest.postgres.ready.seconds".
+func (r Report) junit() ([]byte, error) {
+	type property struct {
+		Name  string `xml:"name,attr"`
+		Value string `xml:"value,attr"`
+	}
+	type testsuite struct {
+		Name       string     `xml:"name,attr"`
+		Tests      int        `xml:"tests,attr"`
+		Properties []property `xml:"properties>property"`
+	}
+	type testsuites struct {
+		XMLName xml.Name    `xml:"testsuites"`
+		Suites  []testsuite `xml:"testsuite"`
+	}
+
+	format := func(seconds float64) string {
+		return strconv.FormatFloat(seconds, 'f', 3, 64)
+	}
+	suite := testsuite{Name: r.Package}
+	for _, t := range r.Totals {
+		prefix := "integrationtest." + t.Package + "." + t.Event + "."
+		suite.Properties = append(suite.Properties,
+			property{Name: prefix + "count", Value: strconv.Itoa(t.Count)},
+			property{Name: prefix + "seconds", Value: format(t.Seconds)},
+			property{Name: prefix + "max_seconds", Value: format(t.Max)},
+			property{Name: prefix + "failed", Value: strconv.Itoa(t.Failed)},
+		)
+	}
+
+	data, err := xml.MarshalIndent(testsuites{Suites: []testsuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
@@ -0,0 +1,75 @@
+package core_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/lifecycle"
+)
+
+// reportRuns gives every run of TestWriteReport a package name of its own,
+// as the lifecycle records are global and survive -count.
+var reportRuns atomic.Int32
+
+func TestWriteReport(t *testing.T) {
+	pkg := fmt.Sprintf("report%d", reportRuns.Add(1))
+	lifecycle.Log(nil, pkg, lifecycle.Ready, time.Now().Add(-2*time.Second), nil, "container", "a")
+	lifecycle.Log(nil, pkg, lifecycle.Ready, time.Now().Add(-time.Second), errors.New("timeout"), "container", "b")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.json")
+	if err := core.WriteReport(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var r core.Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "core", r.Package; want != have {
+		t.Errorf("want Package=%q, have %q", want, have)
+	}
+	var total *core.ReportTotal
+	for i := range r.Totals {
+		if r.Totals[i].Package == pkg && r.Totals[i].Event == lifecycle.Ready {
+			total = &r.Totals[i]
+		}
+	}
+	if total == nil {
+		t.Fatalf("want total of ready events, have %+v", r.Totals)
+	}
+	if want, have := 2, total.Count; want != have {
+		t.Errorf("want Count=%d, have %d", want, have)
+	}
+	if want, have := 1, total.Failed; want != have {
+		t.Errorf("want Failed=%d, have %d", want, have)
+	}
+	if total.Seconds < 3 || total.Max < 2 {
+		t.Errorf("want Seconds>=3 and Max>=2, have %+v", total)
+	}
+
+	path = filepath.Join(dir, "report.xml")
+	if err := core.WriteReport(path); err != nil {
+		t.Fatal(err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<testsuite name="core"`, `<property name="integrationtest.` + pkg + `.ready.count" value="2">`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("want %q in %s", want, data)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package core
+
+import (
+	"os"
+	"testing"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+)
+
+// RequireDockerEnv is the environment variable that makes
+// SkipIfUnavailable fail tests instead of skipping them, e.g. in CI where
+// a missing daemon is a misconfiguration.
+const RequireDockerEnv = "INTEGRATIONTEST_REQUIRE_DOCKER"
+
+// SkipIfUnavailable skips the test if the Docker daemon is missing or does
+// not answer, e.g. in environments that only run unit tests. If
+// RequireDockerEnv is set, it fails the test instead.
+func SkipIfUnavailable(tb testing.TB) {
+	tb.Helper()
+
+	if err := DockerAvailable(); err != nil {
+		if os.Getenv(RequireDockerEnv) != "" {
+			tb.Fatalf("Docker is unavailable: %v", err)
+		}
+		tb.Skipf("Docker is unavailable: %v", err)
+	}
+}
+
+// DockerAvailable returns an error if the Docker daemon is missing or
+// does not answer.
+func DockerAvailable() error {
+	_, err := dockerutil.Pool()
+	return err
+}
@@ -0,0 +1,22 @@
+package core_test
+
+import (
+	"testing"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+func TestSkipIfUnavailable(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://127.0.0.1:1")
+	t.Setenv(core.RequireDockerEnv, "")
+
+	var sub *testing.T
+	t.Run("unavailable", func(t *testing.T) {
+		sub = t
+		core.SkipIfUnavailable(t)
+		t.Fatal("want test to be skipped")
+	})
+	if !sub.Skipped() {
+		t.Fatal("want test to be skipped")
+	}
+}
@@ -0,0 +1,150 @@
+package core
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// StartAll calls starters concurrently, e.g. to start PostgreSQL and
+// Elasticsearch in parallel, and returns their containers in the order
+// of starters once all of them are ready:
+//
+//	containers := core.StartAll(t,
+//		func(tb testing.TB) core.Container { return postgres.Start(tb) },
+//		func(tb testing.TB) core.Container { return elasticsearch.Start(tb) },
+//	)
+//
+// StartAll fails the test as soon as the first starter fails. Containers
+// that are still starting then are closed when the test finishes.
+func StartAll(tb testing.TB, starters ...func(testing.TB) Container) []Container {
+	tb.Helper()
+
+	type result struct {
+		index     int
+		container Container
+		failure   string
+		skipped   bool
+	}
+	var wg sync.WaitGroup
+	// Starters register their cleanups when they return, which may be
+	// after a failure of another starter ended the test. Cleanups that
+	// are registered while cleanups run are run as well.
+	tb.Cleanup(wg.Wait)
+
+	results := make(chan result, len(starters))
+	for i, start := range starters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			stb := &startTB{TB: tb}
+			r := result{index: i}
+			// Deferred functions run when Fatalf stops the goroutine
+			defer func() {
+				r.failure, r.skipped = stb.result()
+				results <- r
+			}()
+			r.container = start(stb)
+		}()
+	}
+
+	containers := make([]Container, len(starters))
+	for range starters {
+		r := <-results
+		switch {
+		case r.skipped:
+			tb.Skip(r.failure)
+		case r.failure != "":
+			tb.Fatalf("core: starter %d failed: %s", r.index, r.failure)
+		}
+		containers[r.index] = r.container
+	}
+	return containers
+}
+
+// startTB is the testing.TB of a starter of StartAll. It records failures
+// instead of failing the test from a goroutine other than the test's.
+type startTB struct {
+	testing.TB
+
+	mu       sync.Mutex
+	failed   bool
+	skipped  bool
+	messages []string
+}
+
+func (tb *startTB) result() (string, bool) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	failure := strings.Join(tb.messages, "; ")
+	if tb.failed && failure == "" {
+		failure = "failed"
+	}
+	return failure, tb.skipped
+}
+
+func (tb *startTB) record(failed, skipped bool, message string) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.failed = tb.failed || failed
+	tb.skipped = tb.skipped || skipped
+	if message != "" {
+		tb.messages = append(tb.messages, strings.TrimSpace(message))
+	}
+}
+
+func (tb *startTB) Fail() {
+	tb.record(true, false, "")
+}
+
+func (tb *startTB) Error(args ...any) {
+	tb.record(true, false, fmt.Sprint(args...))
+}
+
+func (tb *startTB) Errorf(format string, args ...any) {
+	tb.record(true, false, fmt.Sprintf(format, args...))
+}
+
+func (tb *startTB) FailNow() {
+	tb.Fail()
+	runtime.Goexit()
+}
+
+func (tb *startTB) Fatal(args ...any) {
+	tb.Error(args...)
+	runtime.Goexit()
+}
+
+func (tb *startTB) Fatalf(format string, args ...any) {
+	tb.Errorf(format, args...)
+	runtime.Goexit()
+}
+
+func (tb *startTB) SkipNow() {
+	tb.record(false, true, "")
+	runtime.Goexit()
+}
+
+func (tb *startTB) Skip(args ...any) {
+	tb.record(false, true, fmt.Sprint(args...))
+	runtime.Goexit()
+}
+
+func (tb *startTB) Skipf(format string, args ...any) {
+	tb.record(false, true, fmt.Sprintf(format, args...))
+	runtime.Goexit()
+}
+
+func (tb *startTB) Failed() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.failed
+}
+
+func (tb *startTB) Skipped() bool {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.skipped
+}
@@ -0,0 +1,77 @@
+package core_test
+
+import (
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+func TestStartAll(t *testing.T) {
+	var closed []string
+	// Both starters only return when the other one runs as well
+	var started sync.WaitGroup
+	started.Add(2)
+	starter := func(name string) func(testing.TB) core.Container {
+		return func(tb testing.TB) core.Container {
+			started.Done()
+			started.Wait()
+			c := fakeService{name: name, closed: &closed}
+			tb.Cleanup(func() {
+				c.Close()
+			})
+			return c
+		}
+	}
+
+	t.Run("start", func(t *testing.T) {
+		containers := core.StartAll(t, starter("db"), starter("es"))
+		var names []string
+		for _, c := range containers {
+			names = append(names, c.Endpoint())
+		}
+		if want, have := []string{"db", "es"}, names; !slices.Equal(want, have) {
+			t.Fatalf("want containers %v, have %v", want, have)
+		}
+	})
+	if want, have := 2, len(closed); want != have {
+		t.Fatalf("want %d containers closed, have %d", want, have)
+	}
+}
+
+func TestStartAll_FailFast(t *testing.T) {
+	var closed []string
+	release := make(chan struct{})
+	slow := func(tb testing.TB) core.Container {
+		<-release
+		c := fakeService{name: "slow", closed: &closed}
+		tb.Cleanup(func() {
+			c.Close()
+		})
+		return c
+	}
+	broken := func(tb testing.TB) core.Container {
+		tb.Fatalf("could not start container: %s", "boom")
+		return nil
+	}
+
+	t.Run("fail", func(t *testing.T) {
+		tb := &fatalTB{T: t}
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			core.StartAll(tb, slow, broken)
+		}()
+		// The slow starter is still blocked when StartAll fails
+		<-done
+		if want := "core: starter 1 failed: could not start container: boom"; !strings.Contains(tb.fatal, want) {
+			t.Fatalf("want fatal error %q, have %q", want, tb.fatal)
+		}
+		close(release)
+	})
+	if want, have := []string{"slow"}, closed; !slices.Equal(want, have) {
+		t.Fatalf("want slow container to be closed when the test finishes, have %v", have)
+	}
+}
@@ -0,0 +1,221 @@
+package core
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/olivere/integrationtest/internal/lifecycle"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// ImageContainer is a container of any image, e.g. of the application
+// under test, started by StartImage.
+type ImageContainer struct {
+	pool        *dockertest.Pool
+	resource    *dockertest.Resource
+	port        string
+	timeout     time.Duration
+	retryPolicy RetryPolicy
+	waitFor     WaitStrategy
+	logger      *slog.Logger
+	keep        bool
+
+	mu     sync.Mutex
+	closed bool
+}
+
+var _ Container = (*ImageContainer)(nil)
+
+// StartImage starts a container of image, e.g. "app:test", that serves on
+// port, e.g. "8080/tcp", and waits until the port accepts connections, or
+// until the strategies of WithWaitFor succeed. With WithBuiltImage, it
+// builds image from a Dockerfile first, e.g. to test the service binary
+// end to end with the databases it depends on:
+//
+//	app := core.StartImage(t, "app:test", "8080/tcp",
+//		core.WithBuiltImage("..", "Dockerfile"),
+//		core.WithEnv("DATABASE_URL="+dsn),
+//		core.WithWaitFor(core.ForHTTPStatus("8080/tcp", "/health", http.StatusOK)),
+//	)
+//
+// All options of Config apply except Version. The container is removed
+// when the test finishes.
+func StartImage(tb testing.TB, image, port string, options ...Option) *ImageContainer {
+	tb.Helper()
+
+	c, err := startImage(context.Background(), tb.Name(), image, port, options...)
+	if c != nil {
+		tb.Cleanup(func() {
+			c.Close()
+		})
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
+
+// startImage starts a container of image. If it returns an error along
+// with a non-nil ImageContainer, the caller is responsible for closing it.
+func startImage(ctx context.Context, testName, image, port string, options ...Option) (*ImageContainer, error) {
+	cfg := NewConfig(options...)
+	c := &ImageContainer{
+		port:        port,
+		timeout:     cmp.Or(cfg.Timeout, 60*time.Second),
+		retryPolicy: cfg.RetryPolicy,
+		logger:      cfg.Logger,
+		keep:        cfg.Keep || KeepContainers(),
+	}
+
+	var err error
+	c.pool, err = dockerutil.Pool()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to Docker: %w", err)
+	}
+	ReapOnce(c.pool.Client)
+
+	repository, tag := splitTag(image)
+	tag = cmp.Or(tag, "latest")
+	pullStart := time.Now()
+	if cfg.Build != nil {
+		err = BuildImage(ctx, cfg.Build.Dir, cfg.Build.Dockerfile, repository+":"+tag)
+	} else {
+		err = Pull(ctx, c.pool.Client, repository, tag, cfg.PullPolicy)
+	}
+	lifecycle.Log(c.logger, "image", lifecycle.Pull, pullStart, err, "image", repository+":"+tag)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the events of the container to explain why it failed to
+	// become ready, e.g. because it exited
+	events := dockerutil.RecordEvents(c.pool.Client)
+	defer events.Close()
+
+	started := time.Now()
+	newName := func() string {
+		return dockerutil.Name(path.Base(repository), testName)
+	}
+	labels := Labels("image", testName)
+	if c.keep {
+		labels[LabelKeep] = "true"
+	}
+	c.resource, err = dockerutil.RunUnique(c.pool, newName, cmp.Or(cfg.PortRetries, DefaultPortRetries), &dockertest.RunOptions{
+		Repository:   repository,
+		Tag:          tag,
+		Env:          cfg.Env,
+		ExposedPorts: []string{port},
+		Labels:       labels,
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = !c.keep
+		config.RestartPolicy = docker.NeverRestart()
+		dockerutil.SetLimits(config, cfg.CPULimit, cfg.MemoryLimit, cfg.ShmSize)
+	})
+	if err != nil {
+		lifecycle.Log(c.logger, "image", lifecycle.Start, started, err)
+		return nil, fmt.Errorf("unable to start container of %s: %w", image, err)
+	}
+	lifecycle.Log(c.logger, "image", lifecycle.Start, started, nil, "container", c.resource.Container.ID)
+	for _, a := range cfg.Networks {
+		if err := a.Network.Connect(c.pool.Client, c.resource.Container.ID, a.Aliases...); err != nil {
+			return c, err
+		}
+	}
+
+	// Tell docker to hard kill the container after its lifetime, unless
+	// it is kept for debugging
+	if lifetime := cmp.Or(cfg.MaxLifetime, DefaultMaxLifetime); lifetime > 0 && !c.keep {
+		if err := c.resource.Expire(uint(lifetime.Seconds())); err != nil {
+			return c, err
+		}
+	}
+
+	waitFor := cfg.WaitFor
+	if len(waitFor) == 0 {
+		waitFor = []WaitStrategy{ForListeningPort(port)}
+	}
+	c.waitFor = ForAll(c.forRunning(), ForAll(waitFor...))
+	err = WaitUntil(ctx, c.timeout, c.retryPolicy, c.waitTarget(), c.waitFor)
+	lifecycle.Log(c.logger, "image", lifecycle.Ready, started, err, "container", c.resource.Container.ID)
+	if err != nil {
+		diagnosis := dockerutil.Diagnose(context.Background(), c.pool.Client, c.resource.Container.ID, events)
+		return c, fmt.Errorf("could not wait for container of %s: %w\n%s", image, err, diagnosis)
+	}
+	return c, nil
+}
+
+// Close stops and removes the container. It is safe to call Close more
+// than once.
+func (c *ImageContainer) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+	if c.keep {
+		lifecycle.Kept(os.Stderr, "image", c.Endpoint(), c.Name())
+	} else {
+		closeStart := time.Now()
+		err := c.pool.Purge(c.resource)
+		lifecycle.Log(c.logger, "image", lifecycle.Close, closeStart, err, "container", c.resource.Container.ID)
+		if err != nil {
+			return fmt.Errorf("could not purge container: %w", err)
+		}
+	}
+	c.closed = true
+	return nil
+}
+
+// Name returns the name of the Docker container. Other containers on
+// a shared network (see WithNetwork) can use it as the hostname.
+func (c *ImageContainer) Name() string {
+	return strings.TrimPrefix(c.resource.Container.Name, "/")
+}
+
+// Endpoint returns the address on the host that the port of the container
+// is published on, e.g. "127.0.0.1:32768". It implements Container.
+func (c *ImageContainer) Endpoint() string {
+	return c.HostPort(c.port)
+}
+
+// HostPort returns the address on the host that the given port of the
+// container, e.g. "8080/tcp", is published on.
+func (c *ImageContainer) HostPort(port string) string {
+	return dockerutil.HostPort(c.resource, port)
+}
+
+// Logs returns the output of the container so far. It implements
+// Container.
+func (c *ImageContainer) Logs(ctx context.Context) (string, error) {
+	return dockerutil.Logs(ctx, c.pool.Client, c.resource.Container.ID)
+}
+
+// WaitUntilReady waits until the strategies that decided when the
+// container was ready at start succeed again. It implements Container.
+func (c *ImageContainer) WaitUntilReady(ctx context.Context) error {
+	return WaitUntil(ctx, c.timeout, c.retryPolicy, c.waitTarget(), c.waitFor)
+}
+
+// waitTarget returns the target of the wait strategies.
+func (c *ImageContainer) waitTarget() WaitTarget {
+	return DockerTarget(c.pool.Client, c.resource)
+}
+
+// forRunning fails permanently if the container is no longer running,
+// e.g. because it crashed, instead of waiting for it until the timeout.
+func (c *ImageContainer) forRunning() WaitStrategy {
+	return func(ctx context.Context, _ WaitTarget) error {
+		return dockerutil.Running(ctx, c.pool.Client, c.resource.Container.ID)
+	}
+}
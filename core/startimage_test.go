@@ -0,0 +1,52 @@
+package core_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+func TestStartImage(t *testing.T) {
+	dir := t.TempDir()
+	dockerfile := `FROM busybox
+RUN mkdir /www && echo hello > /www/index.html
+CMD ["httpd", "-f", "-p", "8080", "-h", "/www"]
+`
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(dockerfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := core.StartImage(t, "integrationtest-app:test", "8080/tcp",
+		core.WithBuiltImage(dir, ""),
+		core.WithWaitFor(core.ForHTTPStatus("8080/tcp", "/", http.StatusOK)),
+	)
+	res, err := http.Get("http://" + c.Endpoint() + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "hello", strings.TrimSpace(string(body)); want != have {
+		t.Fatalf("want body %q, have %q", want, have)
+	}
+}
+
+func TestBuildImage_Error(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "Dockerfile"), []byte("FROM busybox\nRUN exit 3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := core.BuildImage(context.Background(), dir, "", "integrationtest-broken:test")
+	if err == nil {
+		t.Fatal("want error, have nil")
+	}
+}
@@ -0,0 +1,277 @@
+package core
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/olivere/integrationtest/internal/lifecycle"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// ToxiproxyImage is the image of the sidecar that StartToxiproxy starts.
+const ToxiproxyImage = "ghcr.io/shopify/toxiproxy:2.9.0"
+
+const (
+	// toxiproxyAPIPort is the port of the HTTP API of Toxiproxy.
+	toxiproxyAPIPort = "8474/tcp"
+	// toxiproxyProxyPort is the port that the proxy listens on.
+	toxiproxyProxyPort = "8666/tcp"
+	// toxiproxyName is the name of the proxy, and the alias of the
+	// proxied container on the network of the sidecar.
+	toxiproxyName = "upstream"
+)
+
+// Toxiproxy is a Toxiproxy sidecar that proxies a port of a container,
+// e.g. of PostgreSQL, so that resilience tests can degrade the link with
+// toxics without touching the service itself. See WithToxiproxy of the
+// container packages, which point their connection strings at Addr.
+type Toxiproxy struct {
+	pool        *dockertest.Pool
+	resource    *dockertest.Resource
+	network     *dockertest.Network
+	containerID string
+	api         string
+	addr        string
+	logger      *slog.Logger
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// Toxic degrades the link through a Toxiproxy, see Toxiproxy.AddToxic and
+// https://github.com/Shopify/toxiproxy#toxics for the types and their
+// attributes.
+type Toxic struct {
+	// Name of the toxic, to remove it with RemoveToxic. Empty means
+	// "<type>_<stream>", e.g. "latency_downstream".
+	Name string `json:"name,omitempty"`
+
+	// Type of the toxic, e.g. "latency", "bandwidth", "timeout", or
+	// "reset_peer".
+	Type string `json:"type"`
+
+	// Stream is the direction that the toxic applies to: "downstream"
+	// from the service to the client, or "upstream" from the client to
+	// the service. Empty means "downstream".
+	Stream string `json:"stream,omitempty"`
+
+	// Toxicity is the probability that the toxic applies to
+	// a connection, between 0 and 1. Zero means 1, i.e. always.
+	Toxicity float64 `json:"toxicity,omitempty"`
+
+	// Attributes of the toxic, e.g. {"latency": 100, "jitter": 10} in
+	// milliseconds for "latency".
+	Attributes map[string]any `json:"attributes,omitempty"`
+}
+
+// StartToxiproxy starts a Toxiproxy sidecar that proxies port, e.g.
+// "5432/tcp", of the container with the given ID. The sidecar reaches the
+// container on a network of its own, and clients on the host reach the
+// proxy at Addr. Of the options, Timeout, PullPolicy, RetryPolicy, Logger,
+// MaxLifetime, and PortRetries apply.
+//
+// If it returns an error along with a non-nil Toxiproxy, the caller is
+// responsible for closing it. The caller must close it before the
+// proxied container.
+func StartToxiproxy(ctx context.Context, pool *dockertest.Pool, testName, containerID, port string, options ...Option) (*Toxiproxy, error) {
+	cfg := NewConfig(options...)
+	t := &Toxiproxy{
+		pool:        pool,
+		containerID: containerID,
+		logger:      cfg.Logger,
+	}
+
+	repository, tag := splitTag(ToxiproxyImage)
+	pullStart := time.Now()
+	err := Pull(ctx, pool.Client, repository, tag, cfg.PullPolicy)
+	lifecycle.Log(t.logger, "toxiproxy", lifecycle.Pull, pullStart, err, "image", ToxiproxyImage)
+	if err != nil {
+		return nil, err
+	}
+
+	t.network, err = pool.CreateNetwork(dockerutil.Name("toxiproxy", ""), func(config *docker.CreateNetworkOptions) {
+		config.Driver = "bridge"
+		config.Labels = Labels("network", testName)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not create network of Toxiproxy: %w", err)
+	}
+	err = pool.Client.ConnectNetwork(t.network.Network.ID, docker.NetworkConnectionOptions{
+		Container:      containerID,
+		EndpointConfig: &docker.EndpointConfig{Aliases: []string{toxiproxyName}},
+	})
+	if err != nil {
+		t.network.Close()
+		return nil, fmt.Errorf("could not connect container to network of Toxiproxy: %w", err)
+	}
+
+	started := time.Now()
+	newName := func() string {
+		return dockerutil.Name("toxiproxy", testName)
+	}
+	t.resource, err = dockerutil.RunUnique(pool, newName, cmp.Or(cfg.PortRetries, DefaultPortRetries), &dockertest.RunOptions{
+		Repository:   repository,
+		Tag:          tag,
+		ExposedPorts: []string{toxiproxyAPIPort, toxiproxyProxyPort},
+		Networks:     []*dockertest.Network{t.network},
+		Labels:       Labels("toxiproxy", testName),
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = true
+		config.RestartPolicy = docker.NeverRestart()
+	})
+	if err != nil {
+		lifecycle.Log(t.logger, "toxiproxy", lifecycle.Start, started, err)
+		t.close()
+		return nil, fmt.Errorf("unable to start Toxiproxy container: %w", err)
+	}
+	lifecycle.Log(t.logger, "toxiproxy", lifecycle.Start, started, nil, "container", t.resource.Container.ID)
+	t.api = "http://" + dockerutil.HostPort(t.resource, toxiproxyAPIPort)
+	t.addr = dockerutil.HostPort(t.resource, toxiproxyProxyPort)
+
+	if lifetime := cmp.Or(cfg.MaxLifetime, DefaultMaxLifetime); lifetime > 0 {
+		if err := t.resource.Expire(uint(lifetime.Seconds())); err != nil {
+			return t, err
+		}
+	}
+
+	timeout := cmp.Or(cfg.Timeout, 30*time.Second)
+	err = WaitUntil(ctx, timeout, cfg.RetryPolicy, DockerTarget(pool.Client, t.resource), ForHTTPStatus(toxiproxyAPIPort, "/version", http.StatusOK))
+	lifecycle.Log(t.logger, "toxiproxy", lifecycle.Ready, started, err, "container", t.resource.Container.ID)
+	if err != nil {
+		return t, fmt.Errorf("could not wait for Toxiproxy container: %w", err)
+	}
+
+	err = t.do(ctx, http.MethodPost, "/proxies", map[string]any{
+		"name":     toxiproxyName,
+		"listen":   "0.0.0.0:" + strings.TrimSuffix(toxiproxyProxyPort, "/tcp"),
+		"upstream": toxiproxyName + ":" + strings.TrimSuffix(port, "/tcp"),
+		"enabled":  true,
+	})
+	if err != nil {
+		return t, fmt.Errorf("could not create proxy: %w", err)
+	}
+	return t, nil
+}
+
+// Addr returns the address on the host that clients connect to instead
+// of the proxied port, e.g. "127.0.0.1:32768".
+func (t *Toxiproxy) Addr() string {
+	return t.addr
+}
+
+// AddToxic adds a toxic to the proxy, e.g. to delay all responses by
+// 100ms:
+//
+//	err := c.Toxiproxy().AddToxic(ctx, core.Toxic{
+//		Type:       "latency",
+//		Attributes: map[string]any{"latency": 100},
+//	})
+func (t *Toxiproxy) AddToxic(ctx context.Context, toxic Toxic) error {
+	return t.do(ctx, http.MethodPost, "/proxies/"+toxiproxyName+"/toxics", toxic)
+}
+
+// RemoveToxic removes the toxic with the given name from the proxy.
+func (t *Toxiproxy) RemoveToxic(ctx context.Context, name string) error {
+	return t.do(ctx, http.MethodDelete, "/proxies/"+toxiproxyName+"/toxics/"+name, nil)
+}
+
+// Disable closes all connections through the proxy and refuses new ones,
+// like a network partition, until Enable or Reset.
+func (t *Toxiproxy) Disable(ctx context.Context) error {
+	return t.do(ctx, http.MethodPost, "/proxies/"+toxiproxyName, map[string]any{"enabled": false})
+}
+
+// Enable accepts connections through the proxy again after Disable.
+func (t *Toxiproxy) Enable(ctx context.Context) error {
+	return t.do(ctx, http.MethodPost, "/proxies/"+toxiproxyName, map[string]any{"enabled": true})
+}
+
+// Reset removes all toxics and enables the proxy, e.g. between subtests
+// that share the container.
+func (t *Toxiproxy) Reset(ctx context.Context) error {
+	return t.do(ctx, http.MethodPost, "/reset", nil)
+}
+
+// do sends a request with the JSON encoding of body, if not nil, to the
+// API of Toxiproxy.
+func (t *Toxiproxy) do(ctx context.Context, method, path string, body any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, t.api+path, r)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("toxiproxy: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 4096))
+		return fmt.Errorf("toxiproxy: %s %s: %s: %s", method, path, res.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// Close removes the sidecar and its network, and disconnects the proxied
+// container from it. It is safe to call Close more than once.
+func (t *Toxiproxy) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return nil
+	}
+	if err := t.close(); err != nil {
+		return err
+	}
+	t.closed = true
+	return nil
+}
+
+// close removes what StartToxiproxy created so far.
+func (t *Toxiproxy) close() error {
+	if t.resource != nil {
+		closeStart := time.Now()
+		err := t.pool.Purge(t.resource)
+		lifecycle.Log(t.logger, "toxiproxy", lifecycle.Close, closeStart, err, "container", t.resource.Container.ID)
+		if err != nil {
+			return fmt.Errorf("could not purge Toxiproxy container: %w", err)
+		}
+		t.resource = nil
+	}
+	if t.network != nil {
+		err := t.pool.Client.DisconnectNetwork(t.network.Network.ID, docker.NetworkConnectionOptions{
+			Container: t.containerID,
+			Force:     true,
+		})
+		if err != nil {
+			return fmt.Errorf("could not disconnect container from network of Toxiproxy: %w", err)
+		}
+		if err := t.network.Close(); err != nil {
+			return fmt.Errorf("could not remove network of Toxiproxy: %w", err)
+		}
+		t.network = nil
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/olivere/integrationtest/internal/wait"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// WaitTarget is the container that a WaitStrategy waits for.
+type WaitTarget interface {
+	// HostPort returns the address on the host that the given port of the
+	// container, e.g. "5432/tcp", is published on.
+	HostPort(port string) string
+
+	// Logs returns the output of the container so far.
+	Logs(ctx context.Context) (string, error)
+
+	// Exec runs cmd in the container and returns its exit code and
+	// output.
+	Exec(ctx context.Context, cmd ...string) (int, string, error)
+}
+
+// DockerTarget returns the WaitTarget of a Docker container.
+func DockerTarget(client *docker.Client, resource *dockertest.Resource) WaitTarget {
+	return dockerTarget{client: client, resource: resource}
+}
+
+type dockerTarget struct {
+	client   *docker.Client
+	resource *dockertest.Resource
+}
+
+func (t dockerTarget) HostPort(port string) string {
+	return dockerutil.HostPort(t.resource, port)
+}
+
+func (t dockerTarget) Logs(ctx context.Context) (string, error) {
+	return dockerutil.Logs(ctx, t.client, t.resource.Container.ID)
+}
+
+func (t dockerTarget) Exec(ctx context.Context, cmd ...string) (int, string, error) {
+	return dockerutil.Exec(ctx, t.client, t.resource.Container.ID, cmd...)
+}
+
+// RetryPolicy decides how WaitUntil retries a WaitStrategy. The zero value
+// retries with exponential backoff from 100ms to 5s until the timeout
+// elapses.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts. Zero means no limit.
+	MaxAttempts int
+
+	// InitialBackoff is the time to wait after the first failed attempt.
+	// It doubles after each attempt. Zero means 100ms.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum time to wait between attempts. Zero
+	// means 5s.
+	MaxBackoff time.Duration
+}
+
+// WaitStrategy decides when a container is ready. It returns nil if the
+// container is ready, and an error describing why it is not ready
+// otherwise. WaitUntil calls it repeatedly until it succeeds.
+type WaitStrategy func(ctx context.Context, target WaitTarget) error
+
+// WaitUntil calls strategy with the backoff of policy until it succeeds,
+// timeout elapses, policy.MaxAttempts is reached, or ctx is done. It
+// returns the last error of strategy in the latter cases.
+func WaitUntil(ctx context.Context, timeout time.Duration, policy RetryPolicy, target WaitTarget, strategy WaitStrategy) error {
+	return wait.Policy(policy).Until(ctx, timeout, func(ctx context.Context) error {
+		return strategy(ctx, target)
+	})
+}
+
+// WithAttemptTimeout returns a WaitStrategy that fails an attempt of s
+// after timeout, e.g. when a connection attempt hangs while the service
+// in the container starts.
+func (s WaitStrategy) WithAttemptTimeout(timeout time.Duration) WaitStrategy {
+	return func(ctx context.Context, target WaitTarget) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return s(ctx, target)
+	}
+}
+
+// ForAll waits until all strategies succeed, in order.
+func ForAll(strategies ...WaitStrategy) WaitStrategy {
+	return func(ctx context.Context, target WaitTarget) error {
+		for _, s := range strategies {
+			if err := s(ctx, target); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// ForListeningPort waits until the given port of the container, e.g.
+// "5432/tcp", accepts TCP connections.
+func ForListeningPort(port string) WaitStrategy {
+	return func(ctx context.Context, target WaitTarget) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", target.HostPort(port))
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// ForHTTPStatus waits until a GET request to the given path, e.g.
+// "/health", on the given port of the container returns the given HTTP
+// status code.
+func ForHTTPStatus(port, path string, status int) WaitStrategy {
+	return func(ctx context.Context, target WaitTarget) error {
+		url := "http://" + target.HostPort(port) + path
+		return wait.HTTPStatus(http.DefaultClient, url, status)(ctx)
+	}
+}
+
+// ForLog waits until the log of the container matches re, e.g.
+// regexp.MustCompile(`ready to accept connections`).
+func ForLog(re *regexp.Regexp) WaitStrategy {
+	return func(ctx context.Context, target WaitTarget) error {
+		return wait.LogMatch(target.Logs, re)(ctx)
+	}
+}
+
+// ForExec waits until cmd, e.g. []string{"pg_isready"}, exits with code 0
+// in the container.
+func ForExec(cmd ...string) WaitStrategy {
+	return func(ctx context.Context, target WaitTarget) error {
+		code, output, err := target.Exec(ctx, cmd...)
+		if err != nil {
+			return fmt.Errorf("could not run %s: %w", strings.Join(cmd, " "), err)
+		}
+		if code != 0 {
+			return fmt.Errorf("%s exited with code %d: %s", strings.Join(cmd, " "), code, strings.TrimSpace(output))
+		}
+		return nil
+	}
+}
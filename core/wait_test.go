@@ -0,0 +1,151 @@
+package core_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+// fakeTarget implements core.WaitTarget for tests.
+type fakeTarget struct {
+	hostPort string
+	logs     string
+	code     int
+	output   string
+}
+
+func (t fakeTarget) HostPort(port string) string {
+	return t.hostPort
+}
+
+func (t fakeTarget) Logs(ctx context.Context) (string, error) {
+	return t.logs, nil
+}
+
+func (t fakeTarget) Exec(ctx context.Context, cmd ...string) (int, string, error) {
+	return t.code, t.output, nil
+}
+
+func TestForListeningPort(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+
+	strategy := core.ForListeningPort("5432/tcp")
+	if err := strategy(context.Background(), fakeTarget{hostPort: addr}); err != nil {
+		t.Fatalf("want no error, have %v", err)
+	}
+	l.Close()
+	if err := strategy(context.Background(), fakeTarget{hostPort: addr}); err == nil {
+		t.Fatal("want error after the listener is closed, have nil")
+	}
+}
+
+func TestForHTTPStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/health" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+	target := fakeTarget{hostPort: strings.TrimPrefix(srv.URL, "http://")}
+
+	if err := core.ForHTTPStatus("80/tcp", "/health", http.StatusOK)(context.Background(), target); err != nil {
+		t.Fatalf("want no error, have %v", err)
+	}
+	if err := core.ForHTTPStatus("80/tcp", "/", http.StatusOK)(context.Background(), target); err == nil {
+		t.Fatal("want error for status 404, have nil")
+	}
+}
+
+func TestForLog(t *testing.T) {
+	strategy := core.ForLog(regexp.MustCompile(`ready to accept connections`))
+	if err := strategy(context.Background(), fakeTarget{logs: "starting\n"}); err == nil {
+		t.Fatal("want error, have nil")
+	}
+	if err := strategy(context.Background(), fakeTarget{logs: "ready to accept connections\n"}); err != nil {
+		t.Fatalf("want no error, have %v", err)
+	}
+}
+
+func TestForExec(t *testing.T) {
+	strategy := core.ForExec("pg_isready")
+	err := strategy(context.Background(), fakeTarget{code: 2, output: "no response\n"})
+	if err == nil || !strings.Contains(err.Error(), "no response") {
+		t.Fatalf("want error with output, have %v", err)
+	}
+	if err := strategy(context.Background(), fakeTarget{}); err != nil {
+		t.Fatalf("want no error, have %v", err)
+	}
+}
+
+func TestForAll(t *testing.T) {
+	cause := errors.New("second")
+	var calls int
+	strategy := core.ForAll(
+		func(ctx context.Context, target core.WaitTarget) error { calls++; return nil },
+		func(ctx context.Context, target core.WaitTarget) error { calls++; return cause },
+		func(ctx context.Context, target core.WaitTarget) error { calls++; return nil },
+	)
+	if err := strategy(context.Background(), fakeTarget{}); !errors.Is(err, cause) {
+		t.Fatalf("want second error, have %v", err)
+	}
+	if want, have := 2, calls; want != have {
+		t.Fatalf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestWaitStrategy_WithAttemptTimeout(t *testing.T) {
+	strategy := core.WaitStrategy(func(ctx context.Context, target core.WaitTarget) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}).WithAttemptTimeout(10 * time.Millisecond)
+
+	err := strategy(context.Background(), fakeTarget{})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, have %v", err)
+	}
+}
+
+func TestWaitUntil(t *testing.T) {
+	var calls int
+	err := core.WaitUntil(context.Background(), 5*time.Second, core.RetryPolicy{}, fakeTarget{}, func(ctx context.Context, target core.WaitTarget) error {
+		if calls++; calls < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("want no error, have %v", err)
+	}
+	if want, have := 3, calls; want != have {
+		t.Fatalf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestWaitUntil_RetryPolicy(t *testing.T) {
+	var calls int
+	policy := core.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+	err := core.WaitUntil(context.Background(), time.Minute, policy, fakeTarget{}, func(ctx context.Context, target core.WaitTarget) error {
+		calls++
+		return errors.New("not yet")
+	})
+	if err == nil {
+		t.Fatal("want error, have nil")
+	}
+	if want, have := 3, calls; want != have {
+		t.Fatalf("want %d calls, have %d", want, have)
+	}
+}
@@ -0,0 +1,57 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Token is a token as returned by the Analyze API.
+type Token struct {
+	Token       string `json:"token"`
+	StartOffset int    `json:"start_offset"`
+	EndOffset   int    `json:"end_offset"`
+	Type        string `json:"type"`
+	Position    int    `json:"position"`
+}
+
+// Analyze returns the tokens that the given analyzer produces for text.
+// The analyzer is looked up in the settings of index, or is a built-in
+// analyzer such as "standard" if index is empty.
+func Analyze(ctx context.Context, es *elasticsearch.Client, index, analyzer, text string) ([]Token, error) {
+	body, err := json.Marshal(struct {
+		Analyzer string `json:"analyzer"`
+		Text     string `json:"text"`
+	}{
+		Analyzer: analyzer,
+		Text:     text,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	options := []func(*esapi.IndicesAnalyzeRequest){
+		es.Indices.Analyze.WithContext(ctx),
+		es.Indices.Analyze.WithBody(bytes.NewReader(body)),
+	}
+	if index != "" {
+		options = append(options, es.Indices.Analyze.WithIndex(index))
+	}
+	res, err := es.Indices.Analyze(options...)
+	if err := ParseError(res, err); err != nil {
+		return nil, fmt.Errorf("could not analyze with %s: %w", analyzer, err)
+	}
+	defer res.Body.Close()
+
+	var resp struct {
+		Tokens []Token `json:"tokens"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, err
+	}
+	return resp.Tokens, nil
+}
@@ -0,0 +1,63 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestAnalyze(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithConfigFiles(os.DirFS("testdata"), "analysis/*.txt"),
+		elasticsearch.WithIndices(map[string]elasticsearch.IndexSpec{
+			"products": {
+				Settings: json.RawMessage(`{
+					"analysis": {
+						"filter": {
+							"synonyms": {"type": "synonym", "synonyms_path": "analysis/synonyms.txt"}
+						},
+						"analyzer": {
+							"product_name": {"tokenizer": "standard", "filter": ["lowercase", "synonyms"]}
+						}
+					}
+				}`),
+			},
+		}),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Built-in analyzer
+	tokens, err := elasticsearch.Analyze(ctx, c.Client(), "", "standard", "Hello World")
+	if err != nil {
+		t.Fatalf("could not analyze: %v", err)
+	}
+	if want, have := []string{"hello", "world"}, tokenStrings(tokens); !slices.Equal(want, have) {
+		t.Fatalf("want tokens %v, have %v", want, have)
+	}
+
+	// Custom analyzer with synonyms from the config directory
+	tokens, err = elasticsearch.Analyze(ctx, c.Client(), "products", "product_name", "Laptop")
+	if err != nil {
+		t.Fatalf("could not analyze: %v", err)
+	}
+	if want, have := []string{"laptop", "notebook"}, tokenStrings(tokens); !slices.Equal(want, have) {
+		t.Fatalf("want tokens %v, have %v", want, have)
+	}
+}
+
+func tokenStrings(tokens []elasticsearch.Token) []string {
+	var s []string
+	for _, token := range tokens {
+		s = append(s, token.Token)
+	}
+	return s
+}
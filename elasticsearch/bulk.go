@@ -0,0 +1,203 @@
+package elasticsearch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"sort"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// bulkBatchSize is the maximum number of actions that LoadBulk sends in
+// a single bulk request.
+const bulkBatchSize = 1000
+
+// BulkError is returned by LoadBulk if some items of the bulk requests
+// failed.
+type BulkError struct {
+	Items []*BulkItemError
+}
+
+// BulkItemError describes a failed item of a bulk request.
+type BulkItemError struct {
+	Action string        `json:"-"`
+	Index  string        `json:"_index"`
+	ID     string        `json:"_id"`
+	Status int           `json:"status"`
+	Error  *ErrorDetails `json:"error,omitempty"`
+}
+
+// Error returns a string representation of the error.
+func (e *BulkError) Error() string {
+	if len(e.Items) == 0 {
+		return "elasticsearch: bulk request failed"
+	}
+	item := e.Items[0]
+	var reason string
+	if item.Error != nil {
+		reason = fmt.Sprintf(": %s [type=%s]", item.Error.Reason, item.Error.Type)
+	}
+	return fmt.Sprintf("elasticsearch: %d bulk item(s) failed, first: %s %s/%s: status %d%s",
+		len(e.Items), item.Action, item.Index, item.ID, item.Status, reason)
+}
+
+// WithBulkFixtures loads the NDJSON files of fsys that match glob
+// (see fs.Glob) through the Bulk API, after indices are created and before
+// any post-start operations run. See LoadBulk for details.
+func WithBulkFixtures(fsys fs.FS, glob string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.setup = append(cfg.setup, func(ctx context.Context, tb testing.TB, c *Container) error {
+			matches, err := fs.Glob(fsys, glob)
+			if err != nil {
+				return err
+			}
+			for _, match := range matches {
+				if err := loadBulkFile(ctx, c.Client(), fsys, match); err != nil {
+					return fmt.Errorf("could not load %s: %w", match, err)
+				}
+			}
+			return nil
+		})
+	}
+}
+
+func loadBulkFile(ctx context.Context, es *elasticsearch.Client, fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return LoadBulk(ctx, es, f)
+}
+
+// LoadBulk streams the NDJSON in r through the Bulk API, in batches of
+// up to 1000 actions, and refreshes all indices it touched afterwards.
+// The format of r is the one of the body of a bulk request, i.e. action
+// lines like {"index": {"_index": "users", "_id": "1"}}, each followed by
+// the document source unless it is a delete action.
+//
+// LoadBulk returns a *BulkError if any of the items failed. It processes
+// all of r even if items fail.
+func LoadBulk(ctx context.Context, es *elasticsearch.Client, r io.Reader) error {
+	var (
+		batch   bytes.Buffer
+		actions int
+		failed  []*BulkItemError
+		touched = make(map[string]bool)
+	)
+
+	flush := func() error {
+		if actions == 0 {
+			return nil
+		}
+		items, err := bulk(ctx, es, &batch, touched)
+		if err != nil {
+			return err
+		}
+		failed = append(failed, items...)
+		batch.Reset()
+		actions = 0
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for lineno := 1; scanner.Scan(); lineno++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var action map[string]json.RawMessage
+		if err := json.Unmarshal(line, &action); err != nil || len(action) != 1 {
+			return fmt.Errorf("line %d: invalid bulk action: %s", lineno, line)
+		}
+		batch.Write(line)
+		batch.WriteByte('\n')
+
+		if _, isDelete := action["delete"]; !isDelete {
+			// The next line is the source of the document
+			if !scanner.Scan() {
+				return fmt.Errorf("line %d: missing document source", lineno)
+			}
+			lineno++
+			batch.Write(scanner.Bytes())
+			batch.WriteByte('\n')
+		}
+
+		if actions++; actions >= bulkBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	// Make the documents visible for search
+	if len(touched) > 0 {
+		indices := make([]string, 0, len(touched))
+		for index := range touched {
+			indices = append(indices, index)
+		}
+		sort.Strings(indices)
+		if err := Refresh(ctx, es, indices...); err != nil {
+			return err
+		}
+	}
+
+	if len(failed) > 0 {
+		return &BulkError{Items: failed}
+	}
+	return nil
+}
+
+// bulk sends a single bulk request, records the indices it touched, and
+// returns the failed items.
+func bulk(ctx context.Context, es *elasticsearch.Client, body io.Reader, touched map[string]bool) ([]*BulkItemError, error) {
+	res, err := es.Bulk(body, es.Bulk.WithContext(ctx))
+	if err := ParseError(res, err); err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var resp struct {
+		Errors bool                        `json:"errors"`
+		Items  []map[string]*BulkItemError `json:"items"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("could not decode bulk response: %w", err)
+	}
+
+	var failed []*BulkItemError
+	for _, item := range resp.Items {
+		for action, result := range item {
+			if result == nil {
+				continue
+			}
+			result.Action = action
+			if result.Index != "" {
+				touched[result.Index] = true
+			}
+			if result.Error != nil || result.Status >= http.StatusMultipleChoices {
+				failed = append(failed, result)
+			}
+		}
+	}
+	if resp.Errors && len(failed) == 0 {
+		return nil, errors.New("bulk request reported errors without failed items")
+	}
+	return failed, nil
+}
@@ -0,0 +1,68 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestWithBulkFixtures(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithBulkFixtures(os.DirFS("testdata"), "fixtures/*.ndjson"),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Documents are searchable right away
+	es := c.Client()
+	res, err := es.Count(es.Count.WithContext(ctx), es.Count.WithIndex("users"))
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not count documents: %v", err)
+	}
+	defer res.Body.Close()
+
+	var count struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&count); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, count.Count; want != have {
+		t.Fatalf("want count=%d, have %d", want, have)
+	}
+}
+
+func TestLoadBulk_ItemErrors(t *testing.T) {
+	c := elasticsearch.Start(t, elasticsearch.WithTimeout(60*time.Second))
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Creating the same document twice fails with a version conflict
+	err := elasticsearch.LoadBulk(ctx, c.Client(), strings.NewReader(`
+{"create":{"_index":"users","_id":"1"}}
+{"name":"Alice"}
+{"create":{"_index":"users","_id":"1"}}
+{"name":"Alice"}
+`))
+	var bulkErr *elasticsearch.BulkError
+	if !errors.As(err, &bulkErr) {
+		t.Fatalf("want *elasticsearch.BulkError, have %T (%v)", err, err)
+	}
+	if want, have := 1, len(bulkErr.Items); want != have {
+		t.Fatalf("want %d failed items, have %d", want, have)
+	}
+	if want, have := 409, bulkErr.Items[0].Status; want != have {
+		t.Fatalf("want status %d, have %d", want, have)
+	}
+}
@@ -0,0 +1,180 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esutil"
+)
+
+// BulkStats summarizes a run of BulkIndex.
+type BulkStats struct {
+	// Indexed is the number of documents indexed successfully.
+	Indexed uint64
+	// Failed is the number of documents that could not be indexed.
+	Failed uint64
+	// Requests is the number of bulk requests sent to Elasticsearch.
+	Requests uint64
+	// Duration of the run, including the final refresh.
+	Duration time.Duration
+}
+
+type bulkIndexConfig struct {
+	workers       int
+	flushBytes    int
+	flushInterval time.Duration
+	documentID    func(doc any) string
+	refresh       bool
+}
+
+type bulkIndexOption func(*bulkIndexConfig)
+
+// WithWorkers sets the number of workers that send bulk requests in
+// parallel. It defaults to the number of CPUs.
+func WithWorkers(n int) bulkIndexOption {
+	return func(cfg *bulkIndexConfig) {
+		cfg.workers = n
+	}
+}
+
+// WithFlushBytes sets the size of a bulk request in bytes after which
+// it is sent. It defaults to 5MB.
+func WithFlushBytes(n int) bulkIndexOption {
+	return func(cfg *bulkIndexConfig) {
+		cfg.flushBytes = n
+	}
+}
+
+// WithFlushInterval sets the interval after which pending documents are
+// sent, even if the bulk request is not full yet. It defaults to 1s.
+func WithFlushInterval(d time.Duration) bulkIndexOption {
+	return func(cfg *bulkIndexConfig) {
+		cfg.flushInterval = d
+	}
+}
+
+// WithDocumentID sets a function that returns the ID of a document.
+// By default, or if the function returns an empty string, Elasticsearch
+// generates an ID.
+func WithDocumentID(f func(doc any) string) bulkIndexOption {
+	return func(cfg *bulkIndexConfig) {
+		cfg.documentID = f
+	}
+}
+
+// WithRefresh sets whether BulkIndex refreshes the index at the end.
+// It defaults to true, making all documents visible to search.
+func WithRefresh(refresh bool) bulkIndexOption {
+	return func(cfg *bulkIndexConfig) {
+		cfg.refresh = refresh
+	}
+}
+
+// BulkIndex indexes the documents of docs into index through a bulk
+// indexer that sends requests in parallel, and refreshes the index at the
+// end. Documents are serialized with encoding/json, except for
+// json.RawMessage and []byte, which are sent as is.
+//
+// BulkIndex returns a *BulkError if any of the documents failed. It indexes
+// all documents even if some fail, unless ctx is canceled.
+func BulkIndex(ctx context.Context, es *elasticsearch.Client, index string, docs iter.Seq[any], options ...bulkIndexOption) (BulkStats, error) {
+	cfg := bulkIndexConfig{
+		refresh: true,
+	}
+	for _, o := range options {
+		o(&cfg)
+	}
+
+	start := time.Now()
+
+	var (
+		mu     sync.Mutex
+		failed []*BulkItemError
+	)
+	onFailure := func(ctx context.Context, item esutil.BulkIndexerItem, res esutil.BulkIndexerResponseItem, err error) {
+		e := &BulkItemError{
+			Action: item.Action,
+			Index:  res.Index,
+			ID:     res.DocumentID,
+			Status: res.Status,
+		}
+		if err != nil {
+			e.Error = &ErrorDetails{Reason: err.Error()}
+		} else {
+			e.Error = &ErrorDetails{
+				Type:   res.Error.Type,
+				Reason: res.Error.Reason,
+			}
+		}
+		mu.Lock()
+		failed = append(failed, e)
+		mu.Unlock()
+	}
+
+	bi, err := esutil.NewBulkIndexer(esutil.BulkIndexerConfig{
+		Client:        es,
+		Index:         index,
+		NumWorkers:    cfg.workers,
+		FlushBytes:    cfg.flushBytes,
+		FlushInterval: cfg.flushInterval,
+	})
+	if err != nil {
+		return BulkStats{}, err
+	}
+
+	for doc := range docs {
+		var body []byte
+		switch v := doc.(type) {
+		case json.RawMessage:
+			body = v
+		case []byte:
+			body = v
+		default:
+			body, err = json.Marshal(doc)
+			if err != nil {
+				bi.Close(ctx)
+				return BulkStats{}, fmt.Errorf("could not serialize document: %w", err)
+			}
+		}
+		item := esutil.BulkIndexerItem{
+			Action:    "index",
+			Body:      bytes.NewReader(body),
+			OnFailure: onFailure,
+		}
+		if cfg.documentID != nil {
+			item.DocumentID = cfg.documentID(doc)
+		}
+		if err := bi.Add(ctx, item); err != nil {
+			bi.Close(ctx)
+			return BulkStats{}, err
+		}
+	}
+	if err := bi.Close(ctx); err != nil {
+		return BulkStats{}, err
+	}
+
+	s := bi.Stats()
+	stats := BulkStats{
+		Indexed:  s.NumIndexed,
+		Failed:   s.NumFailed,
+		Requests: s.NumRequests,
+	}
+
+	if cfg.refresh {
+		if err := Refresh(ctx, es, index); err != nil {
+			return stats, err
+		}
+	}
+	stats.Duration = time.Since(start)
+
+	if len(failed) > 0 {
+		return stats, &BulkError{Items: failed}
+	}
+	return stats, nil
+}
@@ -0,0 +1,81 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestBulkIndex(t *testing.T) {
+	c := elasticsearch.Start(t, elasticsearch.WithTimeout(60*time.Second))
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	docs := func(yield func(any) bool) {
+		for i := range 2500 {
+			if !yield(map[string]any{"id": fmt.Sprint(i), "name": fmt.Sprintf("user %d", i)}) {
+				return
+			}
+		}
+	}
+	stats, err := elasticsearch.BulkIndex(ctx, c.Client(), "users", docs,
+		elasticsearch.WithWorkers(2),
+		elasticsearch.WithDocumentID(func(doc any) string {
+			return doc.(map[string]any)["id"].(string)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("could not index documents: %v", err)
+	}
+	if want, have := uint64(2500), stats.Indexed; want != have {
+		t.Fatalf("want %d indexed documents, have %d", want, have)
+	}
+	if want, have := uint64(0), stats.Failed; want != have {
+		t.Fatalf("want %d failed documents, have %d", want, have)
+	}
+
+	// Documents are searchable right away
+	if want, have := 2500, countDocuments(t, c, "users"); want != have {
+		t.Fatalf("want %d documents, have %d", want, have)
+	}
+}
+
+func TestBulkIndex_Failures(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithIndices(map[string]elasticsearch.IndexSpec{
+			"users": {Mappings: json.RawMessage(`{"properties":{"age":{"type":"integer"}}}`)},
+		}),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	docs := func(yield func(any) bool) {
+		_ = yield(json.RawMessage(`{"age":42}`)) &&
+			yield([]byte(`{"age":"not a number"}`)) &&
+			yield("not an object")
+	}
+	stats, err := elasticsearch.BulkIndex(ctx, c.Client(), "users", docs)
+	var bulkErr *elasticsearch.BulkError
+	if !errors.As(err, &bulkErr) {
+		t.Fatalf("want *elasticsearch.BulkError, have %T (%v)", err, err)
+	}
+	if want, have := uint64(1), stats.Indexed; want != have {
+		t.Fatalf("want %d indexed documents, have %d", want, have)
+	}
+	if want, have := uint64(2), stats.Failed; want != have {
+		t.Fatalf("want %d failed documents, have %d", want, have)
+	}
+	if want, have := 2, len(bulkErr.Items); want != have {
+		t.Fatalf("want %d failed items, have %d", want, have)
+	}
+}
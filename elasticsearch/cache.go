@@ -1,50 +1,59 @@
 package elasticsearch
 
 import (
-	"sync"
+	"context"
+	"testing"
+
+	"github.com/olivere/integrationtest/core"
 )
 
 // ContainerCache is a thread-safe cache for elasticsearch containers.
 type ContainerCache struct {
-	mu    sync.Mutex
-	cache map[string]*Container
+	*core.Cache[*Container]
 }
 
-// NewContainerCache returns a new ContainerCache.
-func NewContainerCache() *ContainerCache {
+// NewContainerCache returns a new ContainerCache, e.g. with
+// core.WithIdleTTL to close idle containers returned by Acquire.
+func NewContainerCache(options ...core.CacheOption) *ContainerCache {
 	return &ContainerCache{
-		cache: make(map[string]*Container),
+		Cache: core.NewCache[*Container](options...),
 	}
 }
 
-// Close stops all containers in the cache.
-func (p *ContainerCache) Close() error {
-	p.mu.Lock()
-	defer p.mu.Unlock()
+// Start returns the container cached under id, or starts it with the given
+// options if there is none or it is closed. Options that seed the
+// container, like WithIndices or WithPostStart, only run when it starts.
+//
+// Start fails the test if the cached container was started with a
+// different version, plugins, or security settings than options ask for,
+// e.g. because two tests use the same id for different configurations.
+// The container is pinned: it lives until the cache is closed, even with
+// core.WithIdleTTL.
+func (p *ContainerCache) Start(tb testing.TB, id string, options ...startConfigFunc) *Container {
+	tb.Helper()
+
+	startCfg := newStartConfig(options...)
+	if startCfg.skipNoDocker && startCfg.useExternalURL() == "" && !startCfg.useKube() {
+		core.SkipIfUnavailable(tb)
+	}
+	want := startCfg.fingerprint()
 
-	for _, c := range p.cache {
-		if err := c.Close(); err != nil {
-			return err
+	if c, ok := p.Get(id); ok && c.isClosed() {
+		p.Delete(id)
+	}
+	c, err := p.GetOrCreateE(id, func() (*Container, error) {
+		c, err := start(context.Background(), nil, 1, options...)
+		if err != nil && c != nil {
+			c.Close()
 		}
+		return c, err
+	})
+	if err != nil {
+		tb.Fatalf("elasticsearch: could not start container %q: %v", id, err)
 	}
-
-	p.cache = make(map[string]*Container)
-
-	return nil
-}
-
-// GetOrCreate starts a new container if none is running, otherwise returns
-// the pooled container.
-func (p *ContainerCache) GetOrCreate(id string, createFunc func() *Container) *Container {
-	p.mu.Lock()
-	defer p.mu.Unlock()
-
-	if c, ok := p.cache[id]; ok {
-		return c
+	if c.config != want {
+		tb.Fatalf("elasticsearch: cached container %q has configuration %q, want %q", id, c.config, want)
 	}
 
-	c := createFunc()
-	p.cache[id] = c
-
 	return c
 }
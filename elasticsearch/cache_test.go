@@ -79,3 +79,35 @@ func TestContainerCache_Start(t *testing.T) {
 		}
 	}
 }
+
+func TestContainerCache_StartWithConfiguration(t *testing.T) {
+	cache := elasticsearch.NewContainerCache()
+	defer cache.Close()
+
+	var seeded int
+	seed := elasticsearch.WithPostStart(func(ctx context.Context, tb testing.TB, c *elasticsearch.Container) error {
+		seeded++
+		return nil
+	})
+
+	c1 := cache.Start(t, "one", elasticsearch.WithTimeout(60*time.Second), elasticsearch.WithVersion("8.12.2"), seed)
+	c2 := cache.Start(t, "one", elasticsearch.WithTimeout(60*time.Second), seed)
+	if c1 != c2 {
+		t.Fatalf("expected same container, got different")
+	}
+	if want, have := 1, seeded; want != have {
+		t.Fatalf("want seed to run %d time, have %d", want, have)
+	}
+
+	// A different configuration under the same id fails the test
+	tb := &recordingTB{T: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cache.Start(tb, "one", elasticsearch.WithSecurity("s3cr3t-passw0rd"))
+	}()
+	<-done
+	if !tb.fatal {
+		t.Fatal("want cached container with different configuration to fail the test")
+	}
+}
@@ -0,0 +1,133 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/olivere/integrationtest/internal/wait"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// withClusterName sets the name of the cluster, which is also the host
+// name of its nodes.
+func withClusterName(name string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.clusterName = name
+	}
+}
+
+// withNetwork connects the nodes to network, in addition to the network
+// of the cluster. The network is not removed when the container is closed.
+func withNetwork(network *dockertest.Network) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.network = network
+	}
+}
+
+// StartCCS starts two single-node clusters on a shared Docker network for
+// cross-cluster search. The remote cluster is registered on the local
+// cluster under alias, so that the local cluster can search the index logs
+// of the remote cluster as alias+":logs". Both clusters are started with
+// the given options, and StartCCS does not support security. StartCCS
+// always starts containers, even if an external cluster is configured,
+// as they must share a network.
+func StartCCS(tb testing.TB, options ...startConfigFunc) (local, remote *Container, alias string) {
+	tb.Helper()
+
+	startCfg := newStartConfig(options...)
+	if startCfg.security {
+		tb.Fatal("elasticsearch: StartCCS does not support security")
+	}
+	if startCfg.skipNoDocker {
+		core.SkipIfUnavailable(tb)
+	}
+
+	pool, err := dockerutil.Pool()
+	if err != nil {
+		tb.Fatalf("unable to connect to Docker: %v", err)
+	}
+	network, err := pool.CreateNetwork(dockerutil.Name("elasticsearch_ccs", tb.Name()), func(config *docker.CreateNetworkOptions) {
+		config.Labels = core.Labels("network", tb.Name())
+	})
+	if err != nil {
+		tb.Fatalf("could not create network: %v", err)
+	}
+	// Cleanups run in reverse order, so the network is removed after
+	// the containers have been closed
+	tb.Cleanup(func() {
+		network.Close()
+	})
+
+	ctx := context.Background()
+	startNode := func(name string) *Container {
+		c, err := start(ctx, tb, 1, append(options, withClusterName(name), withNetwork(network), withContainer())...)
+		if c != nil {
+			tb.Cleanup(func() {
+				c.Close()
+			})
+		}
+		if err != nil {
+			tb.Fatalf("could not start cluster %s: %v", name, err)
+		}
+		return c
+	}
+	remote = startNode("remote")
+	local = startNode("local")
+	alias = "remote"
+
+	// Docker does not resolve host names, only container names, so seed
+	// the remote cluster by its address in the network
+	address := remote.resource.GetIPInNetwork(network) + ":9300"
+	if err := connectRemote(ctx, local, alias, address); err != nil {
+		tb.Fatal(err)
+	}
+	return local, remote, alias
+}
+
+// connectRemote registers the cluster at the given transport address as
+// remote cluster of c under alias, and waits until c is connected to it.
+func connectRemote(ctx context.Context, c *Container, alias, address string) error {
+	body, err := json.Marshal(map[string]any{
+		"persistent": map[string]any{
+			"cluster.remote." + alias + ".seeds": []string{address},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	es := c.Client()
+	res, err := es.Cluster.PutSettings(bytes.NewReader(body), es.Cluster.PutSettings.WithContext(ctx))
+	if err := ParseError(res, err); err != nil {
+		return fmt.Errorf("could not register remote cluster %s: %w", alias, err)
+	}
+	res.Body.Close()
+
+	err = wait.Policy(c.retryPolicy).Until(ctx, c.timeout, func(ctx context.Context) error {
+		res, err := es.Cluster.RemoteInfo(es.Cluster.RemoteInfo.WithContext(ctx))
+		if err := ParseError(res, err); err != nil {
+			return err
+		}
+		defer res.Body.Close()
+
+		var info map[string]struct {
+			Connected bool `json:"connected"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+			return err
+		}
+		if !info[alias].Connected {
+			return fmt.Errorf("remote cluster %s is not connected", alias)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not connect to remote cluster %s: %w", alias, err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestStartCCS(t *testing.T) {
+	local, remote, alias := elasticsearch.StartCCS(t,
+		elasticsearch.WithTimeout(90*time.Second),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Seed the remote cluster only
+	f, err := os.Open("testdata/fixtures/users.ndjson")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := elasticsearch.LoadBulk(ctx, remote.Client(), f); err != nil {
+		t.Fatalf("could not load fixtures: %v", err)
+	}
+
+	es := local.Client()
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(alias+":users"),
+		es.Search.WithBody(strings.NewReader(`{"query":{"match_all":{}}}`)),
+	)
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not search remote cluster: %v", err)
+	}
+	defer res.Body.Close()
+	var resp struct {
+		Hits struct {
+			Total struct {
+				Value int `json:"value"`
+			} `json:"total"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, resp.Hits.Total.Value; want != have {
+		t.Fatalf("want %d hits, have %d", want, have)
+	}
+}
+
+func TestStartCCS_External(t *testing.T) {
+	// StartCCS needs containers on a shared network, so it ignores an
+	// external cluster
+	t.Setenv("INTEGRATIONTEST_ELASTICSEARCH_URL", "http://localhost:1")
+
+	local, remote, _ := elasticsearch.StartCCS(t,
+		elasticsearch.WithTimeout(90*time.Second),
+	)
+	for _, c := range []*elasticsearch.Container{local, remote} {
+		if c.URL() == "http://localhost:1" {
+			t.Fatalf("want a container, have the external cluster %s", c.URL())
+		}
+	}
+}
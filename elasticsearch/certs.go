@@ -0,0 +1,84 @@
+package elasticsearch
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"time"
+)
+
+// certificates is a CA and a certificate for the Elasticsearch node,
+// signed by the CA. All fields are PEM-encoded.
+type certificates struct {
+	caCert   []byte
+	nodeCert []byte
+	nodeKey  []byte
+}
+
+// generateCertificates creates a self-signed CA and a node certificate
+// valid for the given DNS names and IP addresses.
+func generateCertificates(hosts []string, validFor time.Duration) (*certificates, error) {
+	notBefore := time.Now().Add(-time.Minute)
+	notAfter := notBefore.Add(validFor)
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "integrationtest CA"},
+		NotBefore:             notBefore,
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	nodeKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	nodeTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "elasticsearch"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			nodeTemplate.IPAddresses = append(nodeTemplate.IPAddresses, ip)
+		} else {
+			nodeTemplate.DNSNames = append(nodeTemplate.DNSNames, h)
+		}
+	}
+	nodeDER, err := x509.CreateCertificate(rand.Reader, nodeTemplate, ca, &nodeKey.PublicKey, caKey)
+	if err != nil {
+		return nil, err
+	}
+	nodeKeyDER, err := x509.MarshalPKCS8PrivateKey(nodeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &certificates{
+		caCert:   pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		nodeCert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: nodeDER}),
+		nodeKey:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: nodeKeyDER}),
+	}, nil
+}
@@ -3,9 +3,12 @@ package elasticsearch
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"sync/atomic"
 	"time"
 
 	"github.com/elastic/elastic-transport-go/v8/elastictransport"
@@ -14,9 +17,12 @@ import (
 )
 
 type connectConfig struct {
-	username string
-	password string
-	debug    bool
+	addresses []string
+	username  string
+	password  string
+	apiKey    string
+	caCert    []byte
+	debug     bool
 }
 
 type connectOption func(*connectConfig)
@@ -35,6 +41,31 @@ func WithPassword(password string) connectOption {
 	}
 }
 
+// WithAPIKey sets the API key for the elasticsearch connection, i.e. the
+// base64-encoded "id:api_key" that the Create API Key API returns as
+// "encoded". It takes precedence over username and password.
+func WithAPIKey(apiKey string) connectOption {
+	return func(c *connectConfig) {
+		c.apiKey = apiKey
+	}
+}
+
+// WithCACert sets the PEM-encoded CA certificate to verify the
+// certificate of the Elasticsearch server with. By default, the client
+// accepts any certificate.
+func WithCACert(caCert []byte) connectOption {
+	return func(c *connectConfig) {
+		c.caCert = caCert
+	}
+}
+
+// withAddresses adds URLs of other nodes of the cluster to connect to.
+func withAddresses(urls ...string) connectOption {
+	return func(c *connectConfig) {
+		c.addresses = append(c.addresses, urls...)
+	}
+}
+
 // WithDebug sets the debug mode for the elasticsearch connection.
 func WithDebug(debug bool) connectOption {
 	return func(c *connectConfig) {
@@ -44,15 +75,56 @@ func WithDebug(debug bool) connectOption {
 
 // Connect to Elasticsearch.
 func Connect(ctx context.Context, elasticsearchURL string, options ...connectOption) (*elasticsearch.Client, error) {
+	cfg, err := newConfig(elasticsearchURL, options...)
+	if err != nil {
+		return nil, err
+	}
+	es, err := elasticsearch.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+// ConnectTyped is like Connect but returns a client for the typed API.
+func ConnectTyped(ctx context.Context, elasticsearchURL string, options ...connectOption) (*elasticsearch.TypedClient, error) {
+	cfg, err := newConfig(elasticsearchURL, options...)
+	if err != nil {
+		return nil, err
+	}
+	es, err := elasticsearch.NewTypedClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+// newConfig returns the client configuration shared by Connect and
+// ConnectTyped.
+func newConfig(elasticsearchURL string, options ...connectOption) (elasticsearch.Config, error) {
 	config := &connectConfig{}
 	for _, option := range options {
 		option(config)
 	}
 
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: true, // accept self-signed certs
+	}
+	if len(config.caCert) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(config.caCert) {
+			return elasticsearch.Config{}, errors.New("could not parse CA certificate")
+		}
+		tlsConfig = &tls.Config{
+			RootCAs: pool,
+		}
+	}
+
 	cfg := elasticsearch.Config{
-		Addresses:     []string{elasticsearchURL},
+		Addresses:     append([]string{elasticsearchURL}, config.addresses...),
 		Username:      config.username,
 		Password:      config.password,
+		APIKey:        config.apiKey,
 		RetryOnStatus: []int{429, 502, 503, 504},
 		MaxRetries:    5,
 		RetryBackoff: func(i int) time.Duration {
@@ -60,9 +132,7 @@ func Connect(ctx context.Context, elasticsearchURL string, options ...connectOpt
 		},
 		// CompressRequestBody:  true,
 		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true, // accept self-signed certs
-			},
+			TLSClientConfig: tlsConfig,
 		},
 	}
 	if config.debug {
@@ -73,11 +143,79 @@ func Connect(ctx context.Context, elasticsearchURL string, options ...connectOpt
 			EnableResponseBody: true,
 		}
 	}
-	es, err := elasticsearch.NewClient(cfg)
+	return cfg, nil
+}
+
+// ErrClosed is returned by the clients of a container after it is closed.
+var ErrClosed = errors.New("elasticsearch: container is closed")
+
+// closableTransport is the transport of the clients of a container. After
+// it is closed, its idle connections are closed and all requests fail
+// with ErrClosed.
+type closableTransport struct {
+	rt     *http.Transport
+	closed atomic.Bool
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *closableTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.closed.Load() {
+		return nil, ErrClosed
+	}
+	return t.rt.RoundTrip(req)
+}
+
+// Close closes the transport and its idle connections.
+func (t *closableTransport) Close() {
+	t.closed.Store(true)
+	t.rt.CloseIdleConnections()
+}
+
+// connect creates the clients of the container, sharing a transport that
+// is closed with the container.
+func (c *Container) connect(options ...connectOption) error {
+	cfg, err := newConfig(c.url, options...)
+	if err != nil {
+		return err
+	}
+	c.rt = &closableTransport{rt: cfg.Transport.(*http.Transport)}
+	cfg.Transport = c.rt
+	cfg.RetryOnError = retryOnError
+	c.c, err = elasticsearch.NewClient(cfg)
+	if err != nil {
+		return err
+	}
+	c.tc, err = elasticsearch.NewTypedClient(cfg)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+// newClient returns a client of the container that authenticates with the
+// given options instead of the credentials of the container. It shares the
+// transport of the container.
+func (c *Container) newClient(options ...connectOption) (*elasticsearch.Client, error) {
+	options = append([]connectOption{withAddresses(c.urls[1:]...), WithCACert(c.caCert)}, options...)
+	cfg, err := newConfig(c.url, options...)
 	if err != nil {
 		return nil, err
 	}
-	return es, nil
+	cfg.Transport = c.rt
+	cfg.RetryOnError = retryOnError
+	return elasticsearch.NewClient(cfg)
+}
+
+// retryOnError retries failed requests unless the container is closed.
+func retryOnError(req *http.Request, err error) bool {
+	return !errors.Is(err, ErrClosed)
+}
+
+// closeTransport closes the transport of the clients of the container.
+func (c *Container) closeTransport() {
+	if c.rt != nil {
+		c.rt.Close()
+	}
 }
 
 // Ping the Elasticsearch server.
@@ -85,13 +223,17 @@ func Ping(ctx context.Context, es *elasticsearch.Client) error {
 	req := esapi.PingRequest{
 		Pretty: true,
 	}
-	resp, err := req.Do(context.Background(), es)
+	resp, err := req.Do(ctx, es)
 	if err != nil {
 		return fmt.Errorf("pinging: %w", err)
 	}
+	defer resp.Body.Close()
 	switch resp.StatusCode {
 	default:
-		return fmt.Errorf("checking state: %w [StatusCode=%d]", err, resp.StatusCode)
+		if err := ParseError(resp, nil); err != nil {
+			return fmt.Errorf("checking state: %w", err)
+		}
+		return fmt.Errorf("checking state: unexpected status code %d", resp.StatusCode)
 	case http.StatusOK:
 		return nil // OK
 	}
@@ -0,0 +1,51 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		StatusCode int
+		WantStatus int
+	}{
+		{StatusCode: http.StatusOK},
+		{StatusCode: http.StatusUnauthorized, WantStatus: http.StatusUnauthorized},
+		{StatusCode: http.StatusNoContent},
+	}
+	for i, tc := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Elastic-Product", "Elasticsearch")
+			w.WriteHeader(tc.StatusCode)
+		}))
+		es, err := elasticsearch.Connect(context.Background(), srv.URL)
+		if err != nil {
+			srv.Close()
+			t.Fatal(err)
+		}
+		err = elasticsearch.Ping(context.Background(), es)
+		srv.Close()
+
+		var e *elasticsearch.Error
+		switch {
+		case tc.StatusCode == http.StatusOK:
+			if err != nil {
+				t.Errorf("#%d: want no error, have %v", i, err)
+			}
+		case tc.WantStatus != 0:
+			if !errors.As(err, &e) || e.Status != tc.WantStatus {
+				t.Errorf("#%d: want *Error with status %d, have %v", i, tc.WantStatus, err)
+			}
+		default:
+			if err == nil || errors.As(err, &e) {
+				t.Errorf("#%d: want status error, have %v", i, err)
+			}
+		}
+	}
+}
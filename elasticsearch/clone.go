@@ -0,0 +1,131 @@
+package elasticsearch
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// CloneIndices clones all indices of the container, except system indices
+// and earlier clones, to new indices with the given prefix, e.g. to give
+// each test its own copy of the indices seeded at startup. Cloning is much
+// faster than seeding again. It returns a map from the name of each source
+// index, as passed to Index, to the name of its clone, and a function to delete the clones.
+// The clones are also deleted when the test finishes.
+//
+// The Clone Index API requires its source to be read-only, so the source
+// indices are write-blocked from then on and serve as templates only,
+// much like in StartFromTemplate of the postgres package.
+func (c *Container) CloneIndices(tb testing.TB, prefix string) (map[string]string, func()) {
+	tb.Helper()
+
+	if prefix == "" {
+		tb.Fatal("could not clone indices: prefix is empty")
+	}
+
+	ctx := context.Background()
+	es := c.Client()
+
+	indices, err := c.userIndices(ctx)
+	if err != nil {
+		tb.Fatalf("could not list indices: %v", err)
+	}
+
+	c.mu.Lock()
+	var sources []string
+	for _, index := range indices {
+		if !c.clones[index] {
+			sources = append(sources, index)
+		}
+	}
+	c.mu.Unlock()
+
+	clones := make(map[string]string, len(sources))
+	var once sync.Once
+	cleanup := func() {
+		once.Do(func() {
+			names := make([]string, 0, len(clones))
+			for _, clone := range clones {
+				names = append(names, clone)
+			}
+			if len(names) > 0 {
+				res, err := es.Indices.Delete(names,
+					es.Indices.Delete.WithContext(ctx),
+					es.Indices.Delete.WithIgnoreUnavailable(true),
+				)
+				if err := closeResponse(res, err); err != nil {
+					tb.Logf("could not delete clones: %v", err)
+				}
+			}
+			c.mu.Lock()
+			for _, name := range names {
+				delete(c.clones, name)
+			}
+			c.mu.Unlock()
+		})
+	}
+	tb.Cleanup(cleanup)
+
+	if len(sources) == 0 {
+		return clones, cleanup
+	}
+
+	// Sources must be read-only to be cloned
+	res, err := es.Indices.PutSettings(strings.NewReader(`{"index.blocks.write":true}`),
+		es.Indices.PutSettings.WithContext(ctx),
+		es.Indices.PutSettings.WithIndex(sources...),
+	)
+	if err := closeResponse(res, err); err != nil {
+		tb.Fatalf("could not make indices read-only: %v", err)
+	}
+
+	for _, source := range sources {
+		name := strings.TrimPrefix(source, c.indexPrefix)
+		target := c.Index(prefix + name)
+		res, err := es.Indices.Clone(source, target,
+			es.Indices.Clone.WithContext(ctx),
+			es.Indices.Clone.WithBody(strings.NewReader(`{"settings":{"index.blocks.write":false}}`)),
+			es.Indices.Clone.WithWaitForActiveShards("all"),
+		)
+		if err := closeResponse(res, err); err != nil {
+			tb.Fatalf("could not clone index %s to %s: %v", source, target, err)
+		}
+		clones[name] = target
+
+		c.mu.Lock()
+		if c.clones == nil {
+			c.clones = make(map[string]bool)
+		}
+		c.clones[target] = true
+		c.mu.Unlock()
+	}
+
+	return clones, cleanup
+}
+
+// userIndices returns the names of all indices of the container, i.e.
+// all but system indices, and only those with the prefix of the container
+// on an external cluster.
+func (c *Container) userIndices(ctx context.Context) ([]string, error) {
+	es := c.Client()
+	res, err := es.Cat.Indices(
+		es.Cat.Indices.WithContext(ctx),
+		es.Cat.Indices.WithFormat("json"),
+		es.Cat.Indices.WithH("index"),
+		es.Cat.Indices.WithExpandWildcards("open,closed"),
+	)
+	var resp []struct {
+		Index string `json:"index"`
+	}
+	if err := decodeResponse(res, err, &resp); err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, index := range resp {
+		if c.ownsIndex(index.Index) {
+			names = append(names, index.Index)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,60 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestContainer_CloneIndices(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithBulkFixtures(os.DirFS("testdata"), "fixtures/*.ndjson"),
+	)
+	defer c.Close()
+
+	clones, cleanup := c.CloneIndices(t, "test1-")
+	if want, have := "test1-users", clones["users"]; want != have {
+		t.Fatalf("want clone %q, have %q", want, have)
+	}
+	if want, have := 3, countDocuments(t, c, "test1-users"); want != have {
+		t.Fatalf("want %d documents, have %d", want, have)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Clones are writable
+	es := c.Client()
+	res, err := es.Index("test1-users", strings.NewReader(`{"name":"Dave"}`),
+		es.Index.WithContext(ctx),
+		es.Index.WithRefresh("true"),
+	)
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not index into clone: %v", err)
+	}
+	res.Body.Close()
+
+	// Other clones start from the seeded state
+	clones2, _ := c.CloneIndices(t, "test2-")
+	if want, have := 1, len(clones2); want != have {
+		t.Fatalf("want %d clones, have %d", want, have)
+	}
+	if want, have := 3, countDocuments(t, c, clones2["users"]); want != have {
+		t.Fatalf("want %d documents, have %d", want, have)
+	}
+
+	cleanup()
+	res, err = es.Indices.Exists([]string{"test1-users"}, es.Indices.Exists.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if !elasticsearch.IsNotFound(res) {
+		t.Fatalf("want clone to be deleted, have status %d", res.StatusCode)
+	}
+}
@@ -1,48 +1,466 @@
 package elasticsearch
 
 import (
+	"bytes"
+	"cmp"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
 	_ "embed"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
+	"github.com/elastic/elastic-transport-go/v8/elastictransport"
 	"github.com/elastic/go-elasticsearch/v8"
 	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/olivere/integrationtest/internal/lifecycle"
+	"github.com/olivere/integrationtest/internal/wait"
+	"github.com/olivere/integrationtest/kube"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 )
 
 type Container struct {
 	c        *elasticsearch.Client
+	tc       *elasticsearch.TypedClient
+	rt       *closableTransport
 	timeout  time.Duration
 	hostPort string
+	url      string
+	username string
+	password string
+	caCert   []byte
+	tempDirs []string
+	repo     string
+	urls     []string
 	pool     *dockertest.Pool
 	resource *dockertest.Resource
+	network  *dockertest.Network
+	logger   *slog.Logger
+
+	// resources of all nodes, the first one being resource
+	resources  []*dockertest.Resource
+	logWaiters []docker.CloseWaiter
+
+	// external is true if the container uses an external cluster
+	// configured via environment variables, with indexPrefix for isolation
+	external    bool
+	indexPrefix string
+
+	// pod is the pod that the node runs in instead of a container, see
+	// kube.Enabled
+	pod *kube.Pod
+
+	// tb and exportDir are used to export the data on failure
+	tb        testing.TB
+	exportDir string
+
+	// config describes the configuration the container was started with
+	config string
+
+	// waitFor are the strategies that decide when the container is ready,
+	// retried with retryPolicy
+	waitFor     []WaitStrategy
+	retryPolicy core.RetryPolicy
+
+	// keep is true if the containers are kept after the test
+	keep bool
+
+	// toxiproxy is the sidecar that the connections go through, see
+	// WithToxiproxy
+	toxiproxy *core.Toxiproxy
+	chaos     *core.Chaos
 
 	mu     sync.Mutex
 	closed bool
+	clones map[string]bool
 }
 
 type startConfig struct {
-	timeout   time.Duration
-	postStart []postStartFunc
+	timeout       time.Duration
+	maxLifetime   time.Duration
+	version       string
+	security      bool
+	password      string
+	tls           bool
+	plugins       []string
+	license       string
+	attachment    bool
+	heap          string
+	memory        int64
+	cpus          float64
+	shmSize       int64
+	logger        *slog.Logger
+	env           []string
+	exportDir     string
+	clientOptions []connectOption
+	logWriter     io.Writer
+	logsToTesting bool
+	configFiles   []configFiles
+	diskThreshold bool
+	waitFor       []WaitStrategy
+	retryPolicy   core.RetryPolicy
+	portRetries   int
+	keep          bool
+	hostPort      int
+	keystore      map[string]string
+	clusterName   string
+	network       *dockertest.Network
+	attachments   []core.NetworkAttachment
+	skipNoDocker  bool
+	toxiproxy     bool
+	container     bool
+	pullPolicy    core.PullPolicy
+	build         *core.Build
+	status        string
+	statusTimeout time.Duration
+	setup         []postStartFunc
+	postStart     []postStartFunc
 }
 
 type startConfigFunc func(*startConfig)
 
-type postStartFunc func(*Container) error
+var _ core.Container = (*Container)(nil)
+
+// WithOptions applies the options that all container packages support,
+// see core.Config.
+func WithOptions(options ...core.Option) startConfigFunc {
+	return func(cfg *startConfig) {
+		coreCfg := core.NewConfig(options...)
+		if coreCfg.Timeout != 0 {
+			cfg.timeout = coreCfg.Timeout
+		}
+		if coreCfg.MaxLifetime != 0 {
+			cfg.maxLifetime = coreCfg.MaxLifetime
+		}
+		if coreCfg.Version != "" {
+			cfg.version = coreCfg.Version
+		}
+		cfg.env = append(cfg.env, coreCfg.Env...)
+		if coreCfg.PullPolicy != "" {
+			cfg.pullPolicy = coreCfg.PullPolicy
+		}
+		cfg.attachments = append(cfg.attachments, coreCfg.Networks...)
+		if coreCfg.CPULimit != 0 {
+			cfg.cpus = coreCfg.CPULimit
+		}
+		if coreCfg.MemoryLimit != 0 {
+			cfg.memory = coreCfg.MemoryLimit
+		}
+		if coreCfg.ShmSize != 0 {
+			cfg.shmSize = coreCfg.ShmSize
+		}
+		if coreCfg.Logger != nil {
+			cfg.logger = coreCfg.Logger
+		}
+		if coreCfg.RetryPolicy != (core.RetryPolicy{}) {
+			cfg.retryPolicy = coreCfg.RetryPolicy
+		}
+		if coreCfg.PortRetries != 0 {
+			cfg.portRetries = coreCfg.PortRetries
+		}
+		if coreCfg.Keep {
+			cfg.keep = true
+		}
+		if coreCfg.Build != nil {
+			cfg.build = coreCfg.Build
+		}
+		for _, s := range coreCfg.WaitFor {
+			cfg.waitFor = append(cfg.waitFor, ForEachNode(s))
+		}
+	}
+}
+
+// configFiles are the files of fsys that match glob.
+type configFiles struct {
+	fsys fs.FS
+	glob string
+}
 
+// configPath is the config directory of Elasticsearch in the container.
+const configPath = "/usr/share/elasticsearch/config"
+
+// postStartFunc is an operation that runs after the container started.
+// tb is the test that started the container, or nil with StartE.
+type postStartFunc func(ctx context.Context, tb testing.TB, c *Container) error
+
+// WithTimeout sets the time to wait for the container to become ready.
+// It is the same as WithStartupTimeout.
 func WithTimeout(timeout time.Duration) startConfigFunc {
+	return WithStartupTimeout(timeout)
+}
+
+// WithStartupTimeout sets the time to wait for the container to become
+// ready. It defaults to 60 seconds.
+func WithStartupTimeout(timeout time.Duration) startConfigFunc {
 	return func(cfg *startConfig) {
 		cfg.timeout = timeout
 	}
 }
 
+// WithMaxLifetime sets the time after which Docker kills the container,
+// in case the test process dies before it can remove the container. It
+// defaults to core.DefaultMaxLifetime. Use a negative value to never kill
+// it.
+func WithMaxLifetime(lifetime time.Duration) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.maxLifetime = lifetime
+	}
+}
+
+// WithPortRetries sets how often to retry starting the container if a host
+// port is already in use, see core.WithPortRetries. By default, it is
+// retried core.DefaultPortRetries times.
+func WithPortRetries(retries int) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.portRetries = retries
+	}
+}
+
+// WithBuiltImage builds the image of the nodes from the Dockerfile in dir
+// instead of pulling it, e.g. an Elasticsearch image with plugins
+// installed. See core.WithBuiltImage.
+func WithBuiltImage(dir, dockerfile string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.build = &core.Build{Dir: dir, Dockerfile: dockerfile}
+	}
+}
+
+// WithKeepContainer keeps the containers of the nodes after the test,
+// e.g. to inspect the indices after a failing test. See
+// core.WithKeepContainer.
+func WithKeepContainer() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.keep = true
+	}
+}
+
+// WithRetryPolicy sets how readiness checks are retried while the
+// container starts, see core.WithRetryPolicy. By default, they are retried
+// with exponential backoff from 100ms to 5s until the timeout elapses.
+func WithRetryPolicy(maxAttempts int, initialBackoff, maxBackoff time.Duration) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.retryPolicy = core.RetryPolicy{
+			MaxAttempts:    maxAttempts,
+			InitialBackoff: initialBackoff,
+			MaxBackoff:     maxBackoff,
+		}
+	}
+}
+
+// WithVersion sets the version of Elasticsearch to start, e.g. "7.17.18"
+// or "8.12.2". Images are only tagged with full versions. It defaults to
+// "8.12.2".
+//
+// The clients of the container are the version 8 clients, which also work
+// with Elasticsearch 7.17 but reject OpenSearch.
+func WithVersion(version string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.version = version
+	}
+}
+
+// WithSecurity enables X-Pack security with the given password for the
+// built-in elastic superuser. The client of the container authenticates
+// as that user. HTTP stays unencrypted; use WithTLS to enable HTTPS.
+func WithSecurity(password string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.security = true
+		cfg.password = password
+	}
+}
+
+// WithTLS enables HTTPS with a certificate signed by a CA that is
+// generated for the container. WithTLS implies WithSecurity; if no password
+// is set, the password of the elastic user is "integrationtest".
+//
+// The client of the container verifies the server certificate. Use CACert
+// or TLSClientConfig to configure other clients.
+func WithTLS() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.tls = true
+	}
+}
+
+// WithPlugins installs the given plugins, e.g. "analysis-icu", before
+// the node starts. Start fails if any of them is not loaded afterwards.
+// Plugins are downloaded when the container starts, so consider raising
+// the timeout.
+func WithPlugins(plugins ...string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.plugins = append(cfg.plugins, plugins...)
+	}
+}
+
+// WithKeystoreSettings adds the given secure settings to the keystore of
+// Elasticsearch before the node starts, e.g. "s3.client.default.access_key"
+// to use an S3 repository. The settings are test credentials: they are
+// visible in the configuration of the container.
+func WithKeystoreSettings(settings map[string]string) startConfigFunc {
+	return func(cfg *startConfig) {
+		if cfg.keystore == nil {
+			cfg.keystore = make(map[string]string)
+		}
+		for k, v := range settings {
+			cfg.keystore[k] = v
+		}
+	}
+}
+
+// WithHeap sets the JVM heap size of Elasticsearch, e.g. "512m" or "2g".
+// By default, Elasticsearch sizes the heap based on the memory limit of
+// the container.
+func WithHeap(heap string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.heap = heap
+	}
+}
+
+// WithMemoryLimit sets the memory limit of the container in bytes.
+// It defaults to 1GB.
+func WithMemoryLimit(bytes int64) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.memory = bytes
+	}
+}
+
+// WithCPULimit limits the number of CPUs the container may use, e.g. 1.5.
+func WithCPULimit(cpus float64) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.cpus = cpus
+	}
+}
+
+// WithShmSize sets the size of /dev/shm of the container in bytes.
+func WithShmSize(bytes int64) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.shmSize = bytes
+	}
+}
+
+// WithEnv adds environment variables of the form "key=value" to the
+// container, e.g. "action.destructive_requires_name=false". Variables with
+// the same key as a default replace the default.
+func WithEnv(kv ...string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.env = append(cfg.env, kv...)
+	}
+}
+
+// WithWaitForStatus makes Start block until the cluster health reports
+// at least the given status, i.e. "yellow" or "green". Start fails if the
+// status is not reached within timeout. A successful ping alone doesn't
+// mean that all shards are ready to serve requests.
+func WithWaitForStatus(status string, timeout time.Duration) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.status = status
+		cfg.statusTimeout = timeout
+	}
+}
+
+// WithClientOptions configures the client of the container with the
+// given options, e.g. WithDebug(true) to log requests and responses
+// including their bodies while diagnosing a test:
+//
+//	elasticsearch.Start(t, elasticsearch.WithClientOptions(elasticsearch.WithDebug(true)))
+//
+// The options are applied after those derived from the container, such as
+// its credentials.
+func WithClientOptions(options ...connectOption) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.clientOptions = append(cfg.clientOptions, options...)
+	}
+}
+
+// WithConfigFiles puts the files of fsys that match glob (see fs.Glob)
+// into the config directory of Elasticsearch, keeping their path relative
+// to fsys. For example, WithConfigFiles(os.DirFS("testdata"), "analysis/*.txt")
+// allows an analyzer to use "synonyms_path": "analysis/synonyms.txt".
+// The files are mounted read-only.
+func WithConfigFiles(fsys fs.FS, glob string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.configFiles = append(cfg.configFiles, configFiles{fsys: fsys, glob: glob})
+	}
+}
+
+// WithDiskThreshold enables or disables the disk-based shard allocation
+// of Elasticsearch, i.e. the disk watermarks. It is disabled by default:
+// otherwise, Elasticsearch makes indices read-only when the disk of the
+// Docker host is nearly full, failing tests with errors that are hard to
+// track down. See IsFloodStageBlock.
+func WithDiskThreshold(enabled bool) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.diskThreshold = enabled
+	}
+}
+
+// WithHostPort binds the HTTP port of Elasticsearch to the given port on
+// the Docker host instead of a random one, e.g. for tools under test that
+// expect a static endpoint. Start fails right away if the port is in use.
+// With StartCluster, only the first node uses the port.
+func WithHostPort(port int) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.hostPort = port
+	}
+}
+
+// WithLogWriter writes the output of the container, i.e. the log of
+// Elasticsearch, to w until the container is closed. Writes to w are
+// serialized.
+func WithLogWriter(w io.Writer) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.logWriter = w
+	}
+}
+
+// WithLogsToTesting writes the output of the container line by line to
+// the log of the test, e.g. to find out why a node fails its bootstrap
+// checks instead of just timing out.
+func WithLogsToTesting() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.logsToTesting = true
+	}
+}
+
+// WithPullPolicy sets when to pull the image. It defaults to
+// core.PullIfNotPresent.
+func WithPullPolicy(policy core.PullPolicy) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.pullPolicy = policy
+	}
+}
+
+// WithSkipIfNoDocker skips the test, instead of failing it, if the Docker
+// daemon is unavailable and no external cluster is configured. See
+// core.SkipIfUnavailable.
+func WithSkipIfNoDocker() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.skipNoDocker = true
+	}
+}
+
 // WithPostStart adds a post-startup operation to the container.
 // This can be used to install extensions, create tables, seed data etc.
+// Operations get the context of the start, e.g. of StartContext, and the
+// test to log progress to; the test is nil with StartE.
 func WithPostStart(funcs ...postStartFunc) startConfigFunc {
 	return func(cfg *startConfig) {
 		cfg.postStart = funcs
@@ -50,107 +468,597 @@ func WithPostStart(funcs ...postStartFunc) startConfigFunc {
 }
 
 // Start an Elasticsearch cluster/node.
+//
+// If INTEGRATIONTEST_ELASTICSEARCH_URL is set, or the tests run in CI
+// with an elasticsearch service container (see core.CIService), Start
+// connects to that cluster instead of starting a container,
+// authenticating with INTEGRATIONTEST_ELASTICSEARCH_USERNAME and
+// INTEGRATIONTEST_ELASTICSEARCH_PASSWORD if set. As the cluster is
+// shared, the container gets a unique index prefix; use Index to name
+// indices. Indices with the prefix are deleted on Close. Options that
+// configure the container itself, e.g. WithPlugins or WithHeap, have no
+// effect then.
+//
+// If INTEGRATIONTEST_BACKEND=kube, Start runs the node as a pod in
+// Kubernetes instead of a Docker container, see kube.Enabled.
 func Start(tb testing.TB, options ...startConfigFunc) *Container {
 	tb.Helper()
+	return StartContext(context.Background(), tb, options...)
+}
+
+// StartContext is like Start but stops pulling the image and waiting for
+// the node to become ready when ctx is done, in addition to the timeout.
+func StartContext(ctx context.Context, tb testing.TB, options ...startConfigFunc) *Container {
+	tb.Helper()
+
+	c, err := start(ctx, tb, 1, options...)
+	if c != nil {
+		tb.Cleanup(func() {
+			c.Close()
+		})
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
+
+// StartE is like Start but returns an error instead of failing a test,
+// e.g. to start a container in TestMain or in a program. The caller is
+// responsible for closing the container. WithLogsToTesting and
+// WithExportOnFailure need a test and can't be used with StartE.
+func StartE(options ...startConfigFunc) (*Container, error) {
+	return startE(context.Background(), options...)
+}
+
+// Run starts a single-node cluster outside of tests, e.g. for a local
+// development server that uses the same options, like WithIndices, as the
+// tests. Unlike StartE, the node is not killed after
+// core.DefaultMaxLifetime unless WithMaxLifetime says so. The caller must
+// close the container.
+//
+// If ctx is done before the cluster is ready, Run removes the container
+// and returns an error.
+func Run(ctx context.Context, options ...startConfigFunc) (*Container, error) {
+	return startE(ctx, append([]startConfigFunc{WithMaxLifetime(-1)}, options...)...)
+}
+
+// startE starts a single-node cluster without a test, and closes it if it
+// fails to start.
+func startE(ctx context.Context, options ...startConfigFunc) (*Container, error) {
+	c, err := start(ctx, nil, 1, options...)
+	if err != nil {
+		if c != nil {
+			c.Close()
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// StartCluster starts an Elasticsearch cluster of the given number of
+// nodes on a shared Docker network. All nodes are master-eligible data
+// nodes. Start returns when all nodes have joined the cluster. The client
+// of the container sends requests to all nodes; see URLs to configure
+// other clients, e.g. for sniffing.
+//
+// Nodes of a cluster run in production mode and enforce the bootstrap
+// checks of Elasticsearch, so the Docker host needs vm.max_map_count of
+// at least 262144. If security is enabled, the transport layer between
+// nodes uses TLS with the certificate of the container. With more than
+// one node, StartCluster always starts containers, even if an external
+// cluster is configured.
+func StartCluster(tb testing.TB, nodes int, options ...startConfigFunc) *Container {
+	tb.Helper()
+	if nodes < 1 {
+		tb.Fatalf("cluster needs at least one node, have %d", nodes)
+	}
+
+	if nodes > 1 {
+		// An external cluster has the nodes it has
+		options = append(slices.Clip(options), withContainer())
+	}
+	c, err := start(context.Background(), tb, nodes, options...)
+	if c != nil {
+		tb.Cleanup(func() {
+			c.Close()
+		})
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
 
-	startCfg := startConfig{}
+// newStartConfig returns the defaults with options applied.
+func newStartConfig(options ...startConfigFunc) startConfig {
+	startCfg := startConfig{
+		version:     "8.12.2",
+		license:     "basic",
+		clusterName: "elasticsearch-test",
+		memory:      1 * 1024 * 1024 * 1024, // 1GB
+		keep:        core.KeepContainers(),
+	}
 	for _, o := range options {
 		o(&startCfg)
 	}
+	if startCfg.attachment && versionBefore(startCfg.version, 8, 4) {
+		startCfg.plugins = append(startCfg.plugins, "ingest-attachment")
+	}
+	return startCfg
+}
+
+// image returns the repository and tag of the image to start.
+func (cfg startConfig) image() (string, string) {
+	if cfg.build != nil {
+		return cfg.build.Image("elasticsearch")
+	}
+	return core.Image("elasticsearch", "docker.elastic.co/elasticsearch/elasticsearch", cfg.version)
+}
+
+// Image returns the image that Start starts with the given options, e.g.
+// "docker.elastic.co/elasticsearch/elasticsearch:8.12.2", to pull it in
+// advance with core.PullImages.
+func Image(options ...startConfigFunc) string {
+	repository, tag := newStartConfig(options...).image()
+	return repository + ":" + tag
+}
+
+// start an Elasticsearch cluster of the given number of nodes. tb is
+// used for logging and may be nil. If it returns an error along with a
+// non-nil Container, the caller is responsible for closing it.
+func start(ctx context.Context, tb testing.TB, nodes int, options ...startConfigFunc) (*Container, error) {
+	startCfg := newStartConfig(options...)
 
 	timeout := startCfg.timeout
 	if timeout == 0 {
 		timeout = 60 * time.Second
 	}
+	lifetime := cmp.Or(startCfg.maxLifetime, core.DefaultMaxLifetime)
 
 	c := &Container{
-		timeout: timeout,
+		timeout:     timeout,
+		tb:          tb,
+		exportDir:   startCfg.exportDir,
+		config:      startCfg.fingerprint(),
+		logger:      startCfg.logger,
+		retryPolicy: startCfg.retryPolicy,
+		keep:        startCfg.keep,
+	}
+
+	// Use an external cluster if configured
+	if url := startCfg.useExternalURL(); url != "" {
+		if err := c.startExternal(ctx, url, startCfg); err != nil {
+			return c, err
+		}
+		return c, c.setUp(ctx, startCfg)
+	}
+	// Run as a pod if configured, e.g. in CI jobs without Docker
+	if startCfg.useKube() {
+		return c, c.startKube(ctx, startCfg)
+	}
+	if tb != nil && startCfg.skipNoDocker {
+		core.SkipIfUnavailable(tb)
 	}
 
 	var err error
-	c.pool, err = dockertest.NewPool("")
+	c.pool, err = dockerutil.Pool()
 	if err != nil {
-		tb.Fatalf("unable to connect to Docker: %v", err)
+		return nil, fmt.Errorf("unable to connect to Docker: %w", err)
 	}
-	if err = c.pool.Client.Ping(); err != nil {
-		tb.Fatalf(`could not connect to docker: %v`, err)
+	core.ReapOnce(c.pool.Client)
+
+	var testName string
+	if tb != nil {
+		testName = tb.Name()
 	}
+	name := dockerutil.Name("elasticsearch", testName)
 
+	// Host names of all nodes, which are also the node names
+	hostnames := []string{startCfg.clusterName}
+	if nodes > 1 {
+		hostnames = make([]string, nodes)
+		for i := range hostnames {
+			hostnames[i] = fmt.Sprintf("%s-%d", startCfg.clusterName, i)
+		}
+	}
+
+	if startCfg.tls {
+		startCfg.security = true
+		if startCfg.password == "" {
+			startCfg.password = "integrationtest"
+		}
+	}
+
+	env := startCfg.nodeEnv(hostnames)
+
+	// Nodes of a secured cluster need certificates for the transport layer
+	var mounts []string
+	if startCfg.tls || (startCfg.security && nodes > 1) {
+		hosts := append([]string{"localhost", "127.0.0.1", "::1"}, hostnames...)
+		if remote := dockerutil.RemoteHost(); remote != "" {
+			hosts = append(hosts, remote)
+		}
+		// The certificates must outlive the nodes
+		validity := max(lifetime, timeout) + time.Hour
+		if lifetime < 0 || c.keep {
+			validity = 30 * 24 * time.Hour
+		}
+		certs, err := generateCertificates(hosts, validity)
+		if err != nil {
+			return c, fmt.Errorf("could not generate certificates: %w", err)
+		}
+		c.caCert = certs.caCert
+		certsDir, err := writeCertificates(certs)
+		if certsDir != "" {
+			c.tempDirs = append(c.tempDirs, certsDir)
+		}
+		if err != nil {
+			return c, fmt.Errorf("could not write certificates: %w", err)
+		}
+		mounts = append(mounts, certsDir+":"+configPath+"/certs:ro")
+	}
+
+	// Files to put into the config directory, e.g. synonyms
+	if len(startCfg.configFiles) > 0 {
+		files := make(map[string][]byte)
+		for _, cf := range startCfg.configFiles {
+			matches, err := fs.Glob(cf.fsys, cf.glob)
+			if err != nil {
+				return c, err
+			}
+			for _, match := range matches {
+				data, err := fs.ReadFile(cf.fsys, match)
+				if err != nil {
+					return c, fmt.Errorf("could not read config file: %w", err)
+				}
+				files[match] = data
+			}
+		}
+		dir, err := writeFiles("integrationtest-elasticsearch-config-", files)
+		if dir != "" {
+			c.tempDirs = append(c.tempDirs, dir)
+		}
+		if err != nil {
+			return c, fmt.Errorf("could not write config files: %w", err)
+		}
+		for name := range files {
+			mounts = append(mounts, filepath.Join(dir, name)+":"+path.Join(configPath, name)+":ro")
+		}
+	}
+
+	if startCfg.security {
+		c.username = "elastic"
+		c.password = startCfg.password
+	}
+
+	// Commands to run in the container before starting Elasticsearch
+	var bootstrap []string
+	if len(startCfg.plugins) > 0 {
+		cmd := "bin/elasticsearch-plugin install --batch"
+		for _, plugin := range startCfg.plugins {
+			cmd += " " + shellQuote(plugin)
+		}
+		bootstrap = append(bootstrap, cmd)
+	}
+	if len(startCfg.keystore) > 0 {
+		bootstrap = append(bootstrap, "[ -f config/elasticsearch.keystore ] || bin/elasticsearch-keystore create")
+		keys := make([]string, 0, len(startCfg.keystore))
+		for key := range startCfg.keystore {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			bootstrap = append(bootstrap, fmt.Sprintf("printf '%%s' %s | bin/elasticsearch-keystore add --stdin --force %s",
+				shellQuote(startCfg.keystore[key]), shellQuote(key)))
+		}
+	}
+	var entrypoint []string
+	if len(bootstrap) > 0 {
+		script := "set -e\n" + strings.Join(bootstrap, "\n") + "\nexec /bin/tini -- /usr/local/bin/docker-entrypoint.sh eswrapper"
+		entrypoint = []string{"/bin/bash", "-c", script}
+	}
+
+	var networks []*dockertest.Network
+	if nodes > 1 {
+		c.network, err = c.pool.CreateNetwork(name, func(config *docker.CreateNetworkOptions) {
+			config.Labels = core.Labels("network", testName)
+		})
+		if err != nil {
+			return c, fmt.Errorf("could not create network: %w", err)
+		}
+		networks = append(networks, c.network)
+	}
+	if startCfg.network != nil {
+		networks = append(networks, startCfg.network)
+	}
+	scheme := "http"
+	if startCfg.tls {
+		scheme = "https"
+	}
+	if startCfg.hostPort != 0 {
+		if err := checkPortAvailable(startCfg.hostPort); err != nil {
+			return c, err
+		}
+	}
+
+	repository, tag := startCfg.image()
+	pullStart := time.Now()
+	if startCfg.build != nil {
+		err = core.BuildImage(ctx, startCfg.build.Dir, startCfg.build.Dockerfile, repository+":"+tag)
+	} else {
+		err = core.Pull(ctx, c.pool.Client, repository, tag, startCfg.pullPolicy)
+	}
+	lifecycle.Log(c.logger, "elasticsearch", lifecycle.Pull, pullStart, err, "image", repository+":"+tag)
+	if err != nil {
+		return c, fmt.Errorf("could not get Elasticsearch image: %w", err)
+	}
+
+	labels := core.Labels("elasticsearch", testName)
+	if c.keep {
+		labels[core.LabelKeep] = "true"
+	}
+
+	// Record the events of the nodes to explain why they failed to become
+	// ready, e.g. because they ran out of memory
+	events := dockerutil.RecordEvents(c.pool.Client)
+	defer events.Close()
+
+	started := time.Now()
+
+	for i, hostname := range hostnames {
+		// The name is used unless it is taken, e.g. by a container of
+		// a parallel test
+		nodeName := name
+		if nodes > 1 {
+			nodeName = fmt.Sprintf("%s_%d", name, i)
+		}
+		newName := func() string {
+			n := nodeName
+			nodeName = fmt.Sprintf("%s_%d", dockerutil.Name("elasticsearch", testName), i)
+			return n
+		}
+		var portBindings map[docker.Port][]docker.PortBinding
+		if i == 0 && startCfg.hostPort != 0 {
+			portBindings = map[docker.Port][]docker.PortBinding{
+				"9200/tcp": {{HostIP: "0.0.0.0", HostPort: strconv.Itoa(startCfg.hostPort)}},
+			}
+		}
+		nodeStart := time.Now()
+		resource, err := dockerutil.RunUnique(c.pool, newName, cmp.Or(startCfg.portRetries, core.DefaultPortRetries), &dockertest.RunOptions{
+			Repository:   repository,
+			Tag:          tag,
+			Hostname:     hostname,
+			Env:          mergeEnv(append([]string{"node.name=" + hostname}, env...), startCfg.env),
+			Entrypoint:   entrypoint,
+			Mounts:       mounts,
+			Networks:     networks,
+			PortBindings: portBindings,
+			Labels:       labels,
+		}, func(config *docker.HostConfig) {
+			config.AutoRemove = !c.keep
+			config.RestartPolicy = docker.NeverRestart()
+			dockerutil.SetLimits(config, startCfg.cpus, startCfg.memory, startCfg.shmSize)
+			config.Ulimits = []docker.ULimit{
+				{
+					Name: "memlock",
+					Soft: -1,
+					Hard: -1,
+				},
+			}
+		})
+		if err != nil {
+			lifecycle.Log(c.logger, "elasticsearch", lifecycle.Start, nodeStart, err, "node", hostname)
+			return c, fmt.Errorf("unable to start Elasticsearch container: %w", err)
+		}
+		lifecycle.Log(c.logger, "elasticsearch", lifecycle.Start, nodeStart, nil, "node", hostname, "container", resource.Container.ID)
+		c.resources = append(c.resources, resource)
+		for _, a := range startCfg.attachments {
+			if err := a.Network.Connect(c.pool.Client, resource.Container.ID, a.Aliases...); err != nil {
+				return c, err
+			}
+		}
+
+		// Configure logging from Docker container
+		w, err := logWriter(tb, startCfg, hostname, nodes > 1)
+		if err != nil {
+			return c, err
+		}
+		if w != nil {
+			waiter, err := c.pool.Client.AttachToContainerNonBlocking(docker.AttachToContainerOptions{
+				Container:    resource.Container.ID,
+				OutputStream: w,
+				ErrorStream:  w,
+				Stdout:       true,
+				Stderr:       true,
+				Stream:       true,
+				Logs:         true,
+			})
+			if err != nil {
+				return c, fmt.Errorf("could not connect to Elasticsearch container log output: %w", err)
+			}
+			c.logWaiters = append(c.logWaiters, waiter)
+		}
+
+		// Tell docker to hard kill the container after its lifetime,
+		// unless it is kept for debugging
+		if lifetime > 0 && !c.keep {
+			if err := resource.Expire(uint(lifetime.Seconds())); err != nil {
+				return c, err
+			}
+		}
+
+		c.urls = append(c.urls, fmt.Sprintf("%s://%s", scheme, dockerutil.HostPort(resource, "9200/tcp")))
+	}
+	c.resource = c.resources[0]
+
+	c.hostPort = dockerutil.HostPort(c.resource, "9200/tcp")
+	c.url = c.urls[0]
+	addresses := c.urls[1:]
+	if startCfg.toxiproxy {
+		c.toxiproxy, err = core.StartToxiproxy(ctx, c.pool, testName, c.resource.Container.ID, "9200/tcp",
+			core.WithTimeout(timeout),
+			core.WithPullPolicy(startCfg.pullPolicy),
+			core.WithRetryPolicy(startCfg.retryPolicy.MaxAttempts, startCfg.retryPolicy.InitialBackoff, startCfg.retryPolicy.MaxBackoff),
+			core.WithLogger(c.logger),
+			core.WithMaxLifetime(startCfg.maxLifetime),
+			core.WithPortRetries(startCfg.portRetries),
+		)
+		if err != nil {
+			return c, err
+		}
+		c.hostPort = c.toxiproxy.Addr()
+		c.url = fmt.Sprintf("%s://%s", scheme, c.hostPort)
+		addresses = nil
+	}
+
+	connectOptions := []connectOption{withAddresses(addresses...)}
+	if startCfg.security {
+		connectOptions = append(connectOptions, WithUsername(c.username), WithPassword(c.password))
+	}
+	if startCfg.tls {
+		connectOptions = append(connectOptions, WithCACert(c.caCert))
+	}
+	connectOptions = append(connectOptions, startCfg.clientOptions...)
+	if err := c.connect(connectOptions...); err != nil {
+		return c, fmt.Errorf("could not connect to Elasticsearch container: %w", err)
+	}
+	waitFor := startCfg.waitFor
+	if len(waitFor) == 0 {
+		waitFor = []WaitStrategy{ForHTTPStatus("/", http.StatusOK)}
+	}
+	c.waitFor = waitFor
+	if err := c.waitUntilReady(ctx, waitFor); err != nil {
+		lifecycle.Log(c.logger, "elasticsearch", lifecycle.Ready, started, err, "container", c.resource.Container.ID)
+		return c, fmt.Errorf("could not wait for Elasticsearch container: %w\n%s", err, c.diagnose(events))
+	}
+
+	// Wait for all nodes to join the cluster
+	if nodes > 1 {
+		err = wait.Policy(c.retryPolicy).Until(ctx, timeout, func(ctx context.Context) error {
+			return waitForNodes(ctx, c.c, nodes)
+		})
+		if err != nil {
+			lifecycle.Log(c.logger, "elasticsearch", lifecycle.Ready, started, err, "container", c.resource.Container.ID)
+			return c, fmt.Errorf("could not form cluster: %w\n%s", err, c.diagnose(events))
+		}
+	}
+	lifecycle.Log(c.logger, "elasticsearch", lifecycle.Ready, started, nil, "container", c.resource.Container.ID, "nodes", nodes)
+
+	return c, c.setUp(ctx, startCfg)
+}
+
+// nodeEnv returns the environment of the nodes with the given host names,
+// without the node name and the environment of WithEnv.
+func (cfg startConfig) nodeEnv(hostnames []string) []string {
 	env := []string{
-		"node.name=elasticsearch-test",
-		"cluster.name=elasticsearch-test",
-		"discovery.type=single-node",
+		"cluster.name=" + cfg.clusterName,
 		"logger.org.elasticsearch=warn",
 		"bootstrap.memory_lock=true",
-		"xpack.security.enabled=false",
-		"xpack.license.self_generated.type=basic",
+		"xpack.license.self_generated.type=" + cfg.license,
 		"ingest.geoip.downloader.enabled=false",
+		"path.repo=" + snapshotsPath,
+	}
+	if len(hostnames) > 1 {
+		seeds := strings.Join(hostnames, ",")
+		env = append(env,
+			"discovery.seed_hosts="+seeds,
+			"cluster.initial_master_nodes="+seeds,
+		)
+	} else {
+		env = append(env, "discovery.type=single-node")
+	}
+	if !cfg.diskThreshold {
+		env = append(env, "cluster.routing.allocation.disk.threshold_enabled=false")
+	}
+	if cfg.heap != "" {
+		env = append(env, fmt.Sprintf("ES_JAVA_OPTS=-Xms%[1]s -Xmx%[1]s", cfg.heap))
 	}
 
-	c.resource, err = c.pool.RunWithOptions(&dockertest.RunOptions{
-		Name:       fmt.Sprintf("elasticsearch_%09d", time.Now().UnixNano()),
-		Repository: "docker.elastic.co/elasticsearch/elasticsearch",
-		Tag:        "8.12.2",
-		Env:        env,
-	}, func(config *docker.HostConfig) {
-		config.AutoRemove = true
-		config.RestartPolicy = docker.NeverRestart()
-		config.Memory = 1 * 1024 * 1024 * 1024 // 1GB
-		config.Ulimits = []docker.ULimit{
-			{
-				Name: "memlock",
-				Soft: -1,
-				Hard: -1,
-			},
+	if cfg.security {
+		env = append(env,
+			"xpack.security.enabled=true",
+			"ELASTIC_PASSWORD="+cfg.password,
+		)
+		if len(hostnames) > 1 {
+			env = append(env,
+				"xpack.security.transport.ssl.enabled=true",
+				"xpack.security.transport.ssl.verification_mode=certificate",
+				"xpack.security.transport.ssl.certificate=certs/node.crt",
+				"xpack.security.transport.ssl.key=certs/node.key",
+				"xpack.security.transport.ssl.certificate_authorities=certs/ca.crt",
+			)
+		} else {
+			env = append(env, "xpack.security.transport.ssl.enabled=false")
 		}
-	})
-	if err != nil {
-		tb.Fatalf("unable to start Elasticsearch container: %v", err)
+		if cfg.tls {
+			env = append(env,
+				"xpack.security.http.ssl.enabled=true",
+				"xpack.security.http.ssl.certificate=certs/node.crt",
+				"xpack.security.http.ssl.key=certs/node.key",
+				"xpack.security.http.ssl.certificate_authorities=certs/ca.crt",
+			)
+		} else {
+			env = append(env, "xpack.security.http.ssl.enabled=false")
+		}
+	} else {
+		env = append(env, "xpack.security.enabled=false")
 	}
-	tb.Cleanup(func() {
-		c.Close()
-	})
+	return env
+}
 
-	// Tell docker to hard kill the container in "timeout" seconds
-	if err := c.resource.Expire(uint(timeout.Seconds())); err != nil {
-		tb.Fatal(err)
+// diagnose describes the nodes for the error message of a cluster that
+// failed to become ready, see dockerutil.Diagnose.
+func (c *Container) diagnose(events *dockerutil.Events) string {
+	diagnoses := make([]string, 0, len(c.resources))
+	for _, resource := range c.resources {
+		diagnoses = append(diagnoses, dockerutil.Diagnose(context.Background(), c.pool.Client, resource.Container.ID, events))
 	}
-	c.pool.MaxWait = timeout
-
-	c.hostPort = c.resource.GetHostPort("9200/tcp")
+	return strings.Join(diagnoses, "\n")
+}
 
-	c.c, err = Connect(context.Background(), fmt.Sprintf("http://%s", c.hostPort))
-	if err != nil {
-		tb.Fatalf("could not connect to Elasticsearch container: %v", err)
+// setUp prepares a running cluster for the test, e.g. installs templates.
+func (c *Container) setUp(ctx context.Context, startCfg startConfig) error {
+	// Wait for the cluster to reach the requested health status
+	if startCfg.status != "" {
+		if err := waitForStatus(ctx, c.c, startCfg.status, startCfg.statusTimeout); err != nil {
+			return fmt.Errorf("could not wait for cluster health: %w", err)
+		}
 	}
-	err = c.pool.Retry(func() (err error) {
-		req := esapi.PingRequest{
-			Pretty: true,
+
+	// Make sure all plugins are loaded
+	if len(startCfg.plugins) > 0 {
+		if err := checkPlugins(ctx, c.c, startCfg.plugins); err != nil {
+			return fmt.Errorf("could not install plugins: %w", err)
 		}
-		resp, err := req.Do(context.Background(), c.c)
+	}
+
+	// Run all setup operations, e.g. to install templates
+	for _, f := range startCfg.setup {
+		err := f(ctx, c.tb, c)
 		if err != nil {
-			return fmt.Errorf("pinging: %w", err)
-		}
-		switch resp.StatusCode {
-		default:
-			return fmt.Errorf("checking state: %w [StatusCode=%d]", err, resp.StatusCode)
-		case http.StatusOK:
-			return nil // OK
+			return fmt.Errorf("could not set up Elasticsearch container: %w", err)
 		}
-	})
-	if err != nil {
-		tb.Fatalf("could not ping Elasticsearch container: %v", err)
 	}
 
 	// Run all post-startup operations
+	if len(startCfg.postStart) == 0 {
+		return nil
+	}
+	seedStart := time.Now()
+	var err error
 	for _, f := range startCfg.postStart {
-		err = f(c)
-		if err != nil {
-			tb.Fatalf("could not run post-startup operation: %v", err)
+		if err = f(ctx, c.tb, c); err != nil {
+			err = fmt.Errorf("could not run post-startup operation: %w", err)
+			break
 		}
 	}
-
-	return c
+	var args []any
+	if c.resource != nil {
+		args = append(args, "container", c.resource.Container.ID)
+	}
+	lifecycle.Log(c.logger, "elasticsearch", lifecycle.Seed, seedStart, err, args...)
+	return err
 }
 
 func (c *Container) Close() error {
@@ -161,16 +1069,439 @@ func (c *Container) Close() error {
 		return nil
 	}
 
-	err := c.pool.Purge(c.resource)
-	if err != nil {
-		return fmt.Errorf("could not purge containers: %w", err)
+	if c.external {
+		if err := c.deleteExternalIndices(); err != nil {
+			return fmt.Errorf("could not delete indices: %w", err)
+		}
+		c.closeTransport()
+		c.closed = true
+		return nil
+	}
+	if c.pod != nil {
+		return c.closeKube()
+	}
+
+	if c.chaos != nil {
+		// Tests get the errors of the fault windows from Chaos.Wait
+		c.chaos.Close()
+	}
+	if c.exportDir != "" && c.tb != nil && c.tb.Failed() {
+		if c.toxiproxy != nil {
+			// Export through a healthy link
+			c.toxiproxy.Reset(context.Background())
+		}
+		if err := c.export(c.exportDir); err != nil {
+			c.tb.Logf("could not export data of Elasticsearch container: %v", err)
+		}
+	}
+
+	if c.toxiproxy != nil {
+		if err := c.toxiproxy.Close(); err != nil {
+			return err
+		}
 	}
 
+	for _, waiter := range c.logWaiters {
+		if err := waiter.Close(); err != nil {
+			return fmt.Errorf("could not close container logs: %w", err)
+		}
+		if err := waiter.Wait(); err != nil {
+			return fmt.Errorf("could not wait for container logs to close: %w", err)
+		}
+	}
+	c.logWaiters = nil
+
+	if c.keep {
+		// Keep the network and the certificates that the nodes use, too
+		names := make([]string, 0, len(c.resources))
+		for _, resource := range c.resources {
+			names = append(names, strings.TrimPrefix(resource.Container.Name, "/"))
+		}
+		lifecycle.Kept(os.Stderr, "elasticsearch", c.url, names...)
+		c.closeTransport()
+		c.closed = true
+		return nil
+	}
+
+	for _, resource := range c.resources {
+		closeStart := time.Now()
+		err := c.pool.Purge(resource)
+		lifecycle.Log(c.logger, "elasticsearch", lifecycle.Close, closeStart, err, "container", resource.Container.ID)
+		if err != nil {
+			return fmt.Errorf("could not purge containers: %w", err)
+		}
+	}
+	c.resources = nil
+
+	if c.network != nil {
+		if err := c.network.Close(); err != nil {
+			return fmt.Errorf("could not remove network: %w", err)
+		}
+		c.network = nil
+	}
+
+	for _, dir := range c.tempDirs {
+		os.RemoveAll(dir)
+	}
+	c.tempDirs = nil
+
+	c.closeTransport()
 	c.closed = true
 
 	return nil
 }
 
+// isClosed returns true if the container is closed.
+func (c *Container) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
 func (c *Container) Client() *elasticsearch.Client {
 	return c.c
 }
+
+// TypedClient returns a client for the typed API of Elasticsearch,
+// configured like Client.
+func (c *Container) TypedClient() *elasticsearch.TypedClient {
+	return c.tc
+}
+
+// Transport returns the transport of Client. It takes care of retries,
+// authentication, and TLS, and can be used to perform raw requests.
+func (c *Container) Transport() elastictransport.Interface {
+	return c.c.Transport
+}
+
+// HTTPClient returns an http.Client that sends requests through Transport.
+// Requests may use a relative URL like "/_cat/health"; scheme and host
+// are always set to those of the Elasticsearch node.
+func (c *Container) HTTPClient() *http.Client {
+	return &http.Client{
+		Transport: roundTripperFunc(c.c.Transport.Perform),
+	}
+}
+
+// roundTripperFunc adapts a func to the http.RoundTripper interface.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip implements http.RoundTripper.
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// URL returns the URL of the Elasticsearch node, e.g. "http://localhost:32768".
+func (c *Container) URL() string {
+	return c.url
+}
+
+// Endpoint returns the URL of Elasticsearch, like URL. It implements
+// core.Container.
+func (c *Container) Endpoint() string {
+	return c.URL()
+}
+
+// Logs returns the output of Elasticsearch so far. The lines of a cluster
+// are prefixed with the node name, and the output of an external cluster
+// is not available. It implements core.Container.
+func (c *Container) Logs(ctx context.Context) (string, error) {
+	if c.external {
+		return "", errors.New("elasticsearch: no logs of an external cluster")
+	}
+	if c.pod != nil {
+		return c.pod.Logs(ctx)
+	}
+	if len(c.resources) == 1 {
+		return dockerutil.Logs(ctx, c.pool.Client, c.resource.Container.ID)
+	}
+	var sb strings.Builder
+	for _, resource := range c.resources {
+		logs, err := dockerutil.Logs(ctx, c.pool.Client, resource.Container.ID)
+		if err != nil {
+			return sb.String(), err
+		}
+		node := resource.Container.Config.Hostname
+		for _, line := range strings.SplitAfter(logs, "\n") {
+			if line != "" {
+				sb.WriteString(node + ": " + line)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// WaitUntilReady waits until the strategies that decided when the
+// container was ready at start succeed again, see WithWaitFor. It
+// implements core.Container.
+func (c *Container) WaitUntilReady(ctx context.Context) error {
+	waitFor := c.waitFor
+	if len(waitFor) == 0 {
+		waitFor = []WaitStrategy{ForHTTPStatus("/", http.StatusOK)}
+	}
+	return c.waitUntilReady(ctx, waitFor)
+}
+
+// URLs returns the URLs of all nodes of the cluster. It has a single
+// element unless the container is started with StartCluster.
+func (c *Container) URLs() []string {
+	return c.urls
+}
+
+// HostPort returns the host and port of the Elasticsearch node,
+// e.g. "localhost:32768".
+func (c *Container) HostPort() string {
+	return c.hostPort
+}
+
+// Credentials returns the username and password that the client of the
+// container uses. Both are empty if security is disabled.
+func (c *Container) Credentials() (username, password string) {
+	return c.username, c.password
+}
+
+// CACert returns the PEM-encoded certificate of the CA that signed the
+// certificate of the container. It is nil unless WithTLS is used.
+func (c *Container) CACert() []byte {
+	return c.caCert
+}
+
+// TLSClientConfig returns a TLS configuration that trusts the certificate
+// of the container. It is nil unless WithTLS is used.
+func (c *Container) TLSClientConfig() *tls.Config {
+	if c.caCert == nil {
+		return nil
+	}
+	pool := x509.NewCertPool()
+	pool.AppendCertsFromPEM(c.caCert)
+	return &tls.Config{
+		RootCAs: pool,
+	}
+}
+
+// writeCertificates writes the certificates into a new temporary directory
+// that can be mounted into the container.
+func writeCertificates(certs *certificates) (string, error) {
+	return writeFiles("integrationtest-elasticsearch-certs-", map[string][]byte{
+		"ca.crt":   certs.caCert,
+		"node.crt": certs.nodeCert,
+		"node.key": certs.nodeKey,
+	})
+}
+
+// writeFiles writes the given files, keyed by slash-separated path, into
+// a new temporary directory that can be mounted into the container. It
+// returns the directory even on error, so the caller can remove it.
+func writeFiles(pattern string, files map[string][]byte) (string, error) {
+	dir, err := os.MkdirTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	// Elasticsearch runs as a different user in the container
+	if err := os.Chmod(dir, 0o755); err != nil {
+		return dir, err
+	}
+	for name, data := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			return dir, err
+		}
+		if err := os.WriteFile(filename, data, 0o644); err != nil {
+			return dir, err
+		}
+	}
+	return dir, nil
+}
+
+// checkPlugins returns an error if not all of the given plugins are
+// loaded on every node.
+func checkPlugins(ctx context.Context, es *elasticsearch.Client, plugins []string) error {
+	res, err := es.Cat.Plugins(
+		es.Cat.Plugins.WithContext(ctx),
+		es.Cat.Plugins.WithFormat("json"),
+	)
+	if err := ParseError(res, err); err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var loaded []struct {
+		Name      string `json:"name"`
+		Component string `json:"component"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&loaded); err != nil {
+		return err
+	}
+	for _, plugin := range plugins {
+		if strings.ContainsAny(plugin, "/:") {
+			// Plugins installed from a URL or file have no well-known name
+			continue
+		}
+		found := false
+		for _, p := range loaded {
+			if p.Component == plugin {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("plugin %q is not loaded", plugin)
+		}
+	}
+	return nil
+}
+
+// waitForStatus blocks until the cluster health reports at least the
+// given status, or the timeout expires.
+func waitForStatus(ctx context.Context, es *elasticsearch.Client, status string, timeout time.Duration) error {
+	options := []func(*esapi.ClusterHealthRequest){
+		es.Cluster.Health.WithContext(ctx),
+		es.Cluster.Health.WithWaitForStatus(status),
+	}
+	if timeout > 0 {
+		options = append(options, es.Cluster.Health.WithTimeout(timeout))
+	}
+	res, err := es.Cluster.Health(options...)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	// Elasticsearch returns 408 if the status isn't reached in time
+	var health struct {
+		Status   string `json:"status"`
+		TimedOut bool   `json:"timed_out"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&health); err != nil {
+		return err
+	}
+	if health.TimedOut || IsTimeout(res) {
+		return fmt.Errorf("cluster status is %q after %v, want %q", health.Status, timeout, status)
+	}
+	if res.IsError() {
+		return fmt.Errorf("checking cluster health: [StatusCode=%d]", res.StatusCode)
+	}
+	return nil
+}
+
+// logWriter returns the writer for the output of the given node, or nil
+// if the output is discarded.
+func logWriter(tb testing.TB, cfg startConfig, node string, prefix bool) (io.Writer, error) {
+	var writers []io.Writer
+	if cfg.logWriter != nil {
+		writers = append(writers, cfg.logWriter)
+	}
+	if cfg.logsToTesting {
+		if tb == nil {
+			return nil, errors.New("WithLogsToTesting requires a test")
+		}
+		writers = append(writers, &lineWriter{fn: func(line string) {
+			if prefix {
+				tb.Logf("%s: %s", node, line)
+			} else {
+				tb.Log(line)
+			}
+		}})
+	}
+	if len(writers) == 0 {
+		return nil, nil
+	}
+	return &syncWriter{w: io.MultiWriter(writers...)}, nil
+}
+
+// syncWriter serializes writes to w.
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write implements io.Writer.
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// lineWriter calls fn for every complete line written to it.
+type lineWriter struct {
+	buf []byte
+	fn  func(line string)
+}
+
+// Write implements io.Writer.
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.fn(strings.TrimSuffix(string(w.buf[:i]), "\r"))
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+// checkPortAvailable returns an error if the given port is in use on
+// the host.
+func checkPortAvailable(port int) error {
+	l, err := net.Listen("tcp", net.JoinHostPort("", strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("host port %d is already in use: %w", port, err)
+	}
+	return l.Close()
+}
+
+// waitForNodes returns an error unless the given number of nodes have
+// joined the cluster.
+func waitForNodes(ctx context.Context, es *elasticsearch.Client, nodes int) error {
+	res, err := es.Cluster.Health(es.Cluster.Health.WithContext(ctx))
+	if err := ParseError(res, err); err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	var health struct {
+		NumberOfNodes int `json:"number_of_nodes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&health); err != nil {
+		return err
+	}
+	if health.NumberOfNodes < nodes {
+		return fmt.Errorf("%d of %d nodes joined the cluster", health.NumberOfNodes, nodes)
+	}
+	return nil
+}
+
+// shellQuote quotes s for use in a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// mergeEnv adds the variables in overrides to env, replacing variables
+// with the same key.
+func mergeEnv(env, overrides []string) []string {
+	for _, kv := range overrides {
+		key, _, _ := strings.Cut(kv, "=")
+		replaced := false
+		for i, existing := range env {
+			if k, _, _ := strings.Cut(existing, "="); k == key {
+				env[i] = kv
+				replaced = true
+			}
+		}
+		if !replaced {
+			env = append(env, kv)
+		}
+	}
+	return env
+}
+
+// fingerprint describes the parts of the configuration that a cached
+// container must match to be reused, see ContainerCache.Start.
+func (cfg startConfig) fingerprint() string {
+	plugins := slices.Clone(cfg.plugins)
+	sort.Strings(plugins)
+	return fmt.Sprintf("version=%s license=%s plugins=%v security=%t tls=%t toxiproxy=%t",
+		cfg.version, cfg.license, plugins, cfg.security, cfg.tls, cfg.toxiproxy)
+}
@@ -2,9 +2,16 @@ package elasticsearch_test
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/olivere/integrationtest/core"
 	"github.com/olivere/integrationtest/elasticsearch"
 )
 
@@ -14,6 +21,10 @@ func TestContainer_Start(t *testing.T) {
 
 	es := c.Client()
 
+	if want, have := "http://"+c.HostPort(), c.URL(); want != have {
+		t.Fatalf("want URL=%q, have %q", want, have)
+	}
+
 	// Ping database
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -33,3 +44,547 @@ func TestContainer_Start(t *testing.T) {
 		t.Fatalf("expected error, got nil")
 	}
 }
+
+func TestContainer_WithSecurity(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithSecurity("s3cr3t-passw0rd"),
+	)
+	defer c.Close()
+
+	username, password := c.Credentials()
+	if want, have := "elastic", username; want != have {
+		t.Fatalf("want username=%q, have %q", want, have)
+	}
+	if want, have := "s3cr3t-passw0rd", password; want != have {
+		t.Fatalf("want password=%q, have %q", want, have)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The container client is authenticated
+	if err := elasticsearch.Ping(ctx, c.Client()); err != nil {
+		t.Fatalf("could not ping: %v", err)
+	}
+
+	// Anonymous requests are rejected
+	es, err := elasticsearch.Connect(ctx, c.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := es.Info(es.Info.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if !elasticsearch.IsUnauthorized(res) {
+		t.Fatalf("want status %d, have %d", http.StatusUnauthorized, res.StatusCode)
+	}
+}
+
+func TestContainer_WithTLS(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithTLS(),
+	)
+	defer c.Close()
+
+	if len(c.CACert()) == 0 {
+		t.Fatal("expected CA certificate, got none")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// The container client verifies the certificate
+	if err := elasticsearch.Ping(ctx, c.Client()); err != nil {
+		t.Fatalf("could not ping: %v", err)
+	}
+
+	if want, have := "https://"+c.HostPort(), c.URL(); want != have {
+		t.Fatalf("want URL=%q, have %q", want, have)
+	}
+
+	// A client that trusts the CA can connect
+	username, password := c.Credentials()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.URL(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.SetBasicAuth(username, password)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: c.TLSClientConfig()}}
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("could not connect with TLS client config: %v", err)
+	}
+	res.Body.Close()
+	if want, have := http.StatusOK, res.StatusCode; want != have {
+		t.Fatalf("want status %d, have %d", want, have)
+	}
+
+	// A client that doesn't trust the CA can't
+	if _, err := http.DefaultClient.Do(req); err == nil {
+		t.Fatal("expected certificate error, got nil")
+	}
+}
+
+func TestContainer_WithPlugins(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(3*time.Minute),
+		elasticsearch.WithPlugins("analysis-icu"),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	es := c.Client()
+	res, err := es.Indices.Create("icu",
+		es.Indices.Create.WithContext(ctx),
+		es.Indices.Create.WithBody(strings.NewReader(`{
+			"settings": {
+				"analysis": {
+					"analyzer": {
+						"folding": {"tokenizer": "icu_tokenizer", "filter": ["icu_folding"]}
+					}
+				}
+			}
+		}`)),
+	)
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not create index with ICU analyzer: %v", err)
+	}
+	res.Body.Close()
+}
+
+func TestContainer_WithHeap(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithHeap("256m"),
+		elasticsearch.WithMemoryLimit(768*1024*1024),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	es := c.Client()
+	res, err := es.Nodes.Stats(
+		es.Nodes.Stats.WithContext(ctx),
+		es.Nodes.Stats.WithMetric("jvm"),
+	)
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not get node stats: %v", err)
+	}
+	defer res.Body.Close()
+
+	var stats struct {
+		Nodes map[string]struct {
+			JVM struct {
+				Mem struct {
+					HeapMaxInBytes int64 `json:"heap_max_in_bytes"`
+				} `json:"mem"`
+			} `json:"jvm"`
+		} `json:"nodes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	for _, node := range stats.Nodes {
+		if want, have := int64(256*1024*1024), node.JVM.Mem.HeapMaxInBytes; want != have {
+			t.Fatalf("want heap max=%d, have %d", want, have)
+		}
+	}
+}
+
+func TestContainer_WithEnv(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithEnv("cluster.name=custom-cluster", "action.destructive_requires_name=false"),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	es := c.Client()
+	res, err := es.Info(es.Info.WithContext(ctx))
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not get info: %v", err)
+	}
+	defer res.Body.Close()
+
+	var info struct {
+		ClusterName string `json:"cluster_name"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "custom-cluster", info.ClusterName; want != have {
+		t.Fatalf("want cluster_name=%q, have %q", want, have)
+	}
+}
+
+func TestContainer_TypedClient(t *testing.T) {
+	c := elasticsearch.Start(t, elasticsearch.WithTimeout(60*time.Second))
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	info, err := c.TypedClient().Info().Do(ctx)
+	if err != nil {
+		t.Fatalf("could not get info: %v", err)
+	}
+	if want, have := "elasticsearch-test", info.ClusterName; want != have {
+		t.Fatalf("want cluster_name=%q, have %q", want, have)
+	}
+}
+
+func TestContainer_HTTPClient(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithSecurity("s3cr3t-passw0rd"),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Authentication is handled by the transport
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "/_cluster/health", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := c.HTTPClient().Do(req)
+	if err := elasticsearch.ParseHTTPResponse(res, err); err != nil {
+		t.Fatalf("could not get cluster health: %v", err)
+	}
+	defer res.Body.Close()
+	if want, have := http.StatusOK, res.StatusCode; want != have {
+		t.Fatalf("want status %d, have %d", want, have)
+	}
+}
+
+func TestContainer_WithWaitForStatus(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithWaitForStatus("green", 30*time.Second),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	es := c.Client()
+	res, err := es.Cluster.Health(es.Cluster.Health.WithContext(ctx))
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not get cluster health: %v", err)
+	}
+	defer res.Body.Close()
+	var health struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&health); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "green", health.Status; want != have {
+		t.Fatalf("want status=%q, have %q", want, have)
+	}
+}
+
+func TestStartCluster(t *testing.T) {
+	c := elasticsearch.StartCluster(t, 3,
+		elasticsearch.WithTimeout(120*time.Second),
+		elasticsearch.WithHeap("256m"),
+		elasticsearch.WithMemoryLimit(768*1024*1024),
+	)
+	defer c.Close()
+
+	if want, have := 3, len(c.URLs()); want != have {
+		t.Fatalf("want %d URLs, have %d", want, have)
+	}
+	if want, have := c.URLs()[0], c.URL(); want != have {
+		t.Fatalf("want URL=%q, have %q", want, have)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	es := c.Client()
+	res, err := es.Cat.Nodes(es.Cat.Nodes.WithContext(ctx), es.Cat.Nodes.WithFormat("json"))
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not list nodes: %v", err)
+	}
+	defer res.Body.Close()
+	var nodes []struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&nodes); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, len(nodes); want != have {
+		t.Fatalf("want %d nodes, have %d", want, have)
+	}
+
+	// Every node serves requests
+	for _, url := range c.URLs() {
+		node, err := elasticsearch.Connect(ctx, url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := elasticsearch.Ping(ctx, node); err != nil {
+			t.Fatalf("could not ping node %s: %v", url, err)
+		}
+	}
+}
+
+func TestContainer_WithClientOptions(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithClientOptions(elasticsearch.WithDebug(true)),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := elasticsearch.Ping(ctx, c.Client()); err != nil {
+		t.Fatalf("could not ping: %v", err)
+	}
+}
+
+func TestContainer_WithLogWriter(t *testing.T) {
+	var buf strings.Builder
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithEnv("logger.org.elasticsearch=info"),
+		elasticsearch.WithLogWriter(&buf),
+		elasticsearch.WithLogsToTesting(),
+	)
+	if err := c.Close(); err != nil {
+		t.Fatalf("could not close container: %v", err)
+	}
+
+	// The log is written by the node
+	if !strings.Contains(buf.String(), "elasticsearch-test") {
+		t.Fatalf("want log output of the node, have %q", buf.String())
+	}
+}
+
+func TestStartE(t *testing.T) {
+	c, err := elasticsearch.StartE(elasticsearch.WithTimeout(60 * time.Second))
+	if err != nil {
+		t.Fatalf("could not start container: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := elasticsearch.Ping(ctx, c.Client()); err != nil {
+		t.Fatalf("could not ping: %v", err)
+	}
+}
+
+func TestStartContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	c := elasticsearch.StartContext(ctx, t, elasticsearch.WithTimeout(60*time.Second))
+	defer c.Close()
+
+	if err := elasticsearch.Ping(ctx, c.Client()); err != nil {
+		t.Fatalf("could not ping: %v", err)
+	}
+}
+
+func TestContainer_WithWaitFor(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		// Elasticsearch logs that it started at INFO level
+		elasticsearch.WithEnv("logger.org.elasticsearch=info"),
+		elasticsearch.WithWaitFor(
+			elasticsearch.ForLog(regexp.MustCompile(`started`)),
+			elasticsearch.ForHTTPStatus("/_cluster/health", http.StatusOK),
+			elasticsearch.ForClusterHealth("yellow"),
+			elasticsearch.ForEachNode(core.ForListeningPort("9200/tcp")),
+		),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := elasticsearch.Ping(ctx, c.Client()); err != nil {
+		t.Fatalf("could not ping: %v", err)
+	}
+}
+
+func TestContainer_WithHostPort(t *testing.T) {
+	l, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+
+	// Start fails if the port is in use
+	_, err = elasticsearch.StartE(elasticsearch.WithHostPort(port))
+	if err == nil || !strings.Contains(err.Error(), "already in use") {
+		t.Fatalf("want port to be in use, have %v", err)
+	}
+	l.Close()
+
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithHostPort(port),
+	)
+	defer c.Close()
+
+	if want, have := fmt.Sprintf("localhost:%d", port), c.HostPort(); want != have {
+		t.Fatalf("want HostPort=%q, have %q", want, have)
+	}
+}
+
+func TestContainer_WithPostStart(t *testing.T) {
+	var called bool
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithPostStart(func(ctx context.Context, tb testing.TB, c *elasticsearch.Container) error {
+			called = true
+			tb.Logf("seeding %s", c.URL())
+			return elasticsearch.CreateIndex(ctx, c.Client(), "users", elasticsearch.IndexSpec{})
+		}),
+	)
+	defer c.Close()
+
+	if !called {
+		t.Fatal("want post-start operation to be called")
+	}
+	if want, have := 0, countDocuments(t, c, "users"); want != have {
+		t.Fatalf("want %d documents, have %d", want, have)
+	}
+}
+
+func TestContainer_WithKeystoreSettings(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithKeystoreSettings(map[string]string{
+			"s3.client.default.access_key": "minio",
+			"s3.client.default.secret_key": "it's a secret",
+		}),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Secure settings are reloadable and reported per node
+	es := c.Client()
+	res, err := es.Nodes.ReloadSecureSettings(es.Nodes.ReloadSecureSettings.WithContext(ctx))
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not reload secure settings: %v", err)
+	}
+	defer res.Body.Close()
+	var resp struct {
+		Nodes map[string]struct {
+			ReloadException *struct {
+				Reason string `json:"reason"`
+			} `json:"reload_exception"`
+		} `json:"nodes"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(resp.Nodes); want != have {
+		t.Fatalf("want %d nodes, have %d", want, have)
+	}
+	for id, node := range resp.Nodes {
+		if node.ReloadException != nil {
+			t.Fatalf("node %s could not reload secure settings: %s", id, node.ReloadException.Reason)
+		}
+	}
+}
+
+func TestContainer_Core(t *testing.T) {
+	var c core.Container = elasticsearch.Start(t,
+		elasticsearch.WithOptions(core.WithTimeout(60*time.Second), core.WithVersion("8.12.2")),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := c.WaitUntilReady(ctx); err != nil {
+		t.Fatalf("could not wait until ready: %v", err)
+	}
+	if !strings.HasPrefix(c.Endpoint(), "http://") {
+		t.Fatalf("want URL as endpoint, have %q", c.Endpoint())
+	}
+	logs, err := c.Logs(ctx)
+	if err != nil {
+		t.Fatalf("could not get logs: %v", err)
+	}
+	if !strings.Contains(logs, "elasticsearch-test") {
+		t.Fatalf("want logs of Elasticsearch, have:\n%s", logs)
+	}
+}
+
+func TestContainer_WithSkipIfNoDocker(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://127.0.0.1:1")
+	t.Setenv("INTEGRATIONTEST_ELASTICSEARCH_URL", "")
+	t.Setenv(core.RequireDockerEnv, "")
+
+	var sub *testing.T
+	t.Run("start", func(t *testing.T) {
+		sub = t
+		elasticsearch.Start(t, elasticsearch.WithSkipIfNoDocker())
+		t.Fatal("want test to be skipped")
+	})
+	if !sub.Skipped() {
+		t.Fatal("want test to be skipped")
+	}
+}
+
+func TestImage(t *testing.T) {
+	t.Setenv(core.ImageEnv("elasticsearch"), "")
+	t.Setenv(core.RegistryMirrorEnv, "")
+
+	if want, have := "docker.elastic.co/elasticsearch/elasticsearch:8.12.2", elasticsearch.Image(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+
+	t.Setenv(core.ImageEnv("elasticsearch"), "registry.example.com/elasticsearch")
+	if want, have := "registry.example.com/elasticsearch:7.17.18", elasticsearch.Image(elasticsearch.WithVersion("7.17.18")); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestStartE_Diagnosis(t *testing.T) {
+	// The JVM rejects the heap size and exits right away
+	c, err := elasticsearch.StartE(elasticsearch.WithHeap("1x"), elasticsearch.WithTimeout(2*time.Minute))
+	if c != nil {
+		defer c.Close()
+	}
+	if err == nil {
+		t.Fatal("want error, have nil")
+	}
+	for _, want := range []string{"container", "exit"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("want %q in error, have %v", want, err)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	c, err := elasticsearch.Run(ctx)
+	if err != nil {
+		t.Fatalf("could not run container: %v", err)
+	}
+	defer c.Close()
+
+	if err := elasticsearch.Ping(ctx, c.Client()); err != nil {
+		t.Fatalf("could not ping: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/olivere/integrationtest/internal/wait"
+)
+
+// Count returns the number of documents in index that match query, e.g.
+// map[string]any{"term": map[string]any{"status": "done"}}. A nil query
+// matches all documents.
+func Count(ctx context.Context, es *elasticsearch.Client, index string, query map[string]any) (int, error) {
+	options := []func(*esapi.CountRequest){
+		es.Count.WithContext(ctx),
+		es.Count.WithIndex(index),
+	}
+	if query != nil {
+		body, err := json.Marshal(map[string]any{"query": query})
+		if err != nil {
+			return 0, err
+		}
+		options = append(options, es.Count.WithBody(bytes.NewReader(body)))
+	}
+	res, err := es.Count(options...)
+	if err := ParseError(res, err); err != nil {
+		return 0, fmt.Errorf("could not count documents in %s: %w", index, err)
+	}
+	defer res.Body.Close()
+
+	var resp struct {
+		Count int `json:"count"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+// WaitForCount polls the number of documents in index that match query,
+// with backoff, until it is want or ctx is done. The documents need to be
+// refreshed by the indexer or the refresh interval of index to be counted.
+func WaitForCount(ctx context.Context, es *elasticsearch.Client, index string, query map[string]any, want int) error {
+	timeout := time.Duration(math.MaxInt64)
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	err := wait.Until(ctx, timeout, func(ctx context.Context) error {
+		have, err := Count(ctx, es, index, query)
+		if err != nil {
+			return err
+		}
+		if have != want {
+			return fmt.Errorf("have %d documents in %s, want %d", have, index, want)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("elasticsearch: waiting for count: %w", err)
+	}
+	return nil
+}
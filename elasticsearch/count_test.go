@@ -0,0 +1,56 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestWaitForCount(t *testing.T) {
+	// The fake cluster counts one more document on every request, like an
+	// asynchronous indexer would
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		var body struct {
+			Query map[string]any `json:"query"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Query["term"] == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":400}`))
+			return
+		}
+		fmt.Fprintf(w, `{"count":%d}`, requests.Add(1))
+	}))
+	defer srv.Close()
+
+	es, err := elasticsearch.Connect(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := map[string]any{"term": map[string]any{"status": "done"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := elasticsearch.WaitForCount(ctx, es, "jobs", query, 3); err != nil {
+		t.Fatalf("could not wait for count: %v", err)
+	}
+	if want, have := int32(3), requests.Load(); want != have {
+		t.Fatalf("want %d requests, have %d", want, have)
+	}
+
+	// The count of 3 has passed and is never reached again
+	ctx, cancel = context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	if err := elasticsearch.WaitForCount(ctx, es, "jobs", query, 3); err == nil {
+		t.Fatal("want error when the count is not reached")
+	}
+}
@@ -0,0 +1,185 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// WithILMPolicies installs the index lifecycle management policies in the
+// JSON files of fsys that match glob (see fs.Glob), before any post-start
+// operations run. Use it before WithIndexTemplates if templates refer to
+// the policies. See PutILMPolicies for details.
+func WithILMPolicies(fsys fs.FS, glob string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.setup = append(cfg.setup, func(ctx context.Context, tb testing.TB, c *Container) error {
+			return PutILMPolicies(ctx, c.Client(), fsys, glob)
+		})
+	}
+}
+
+// PutILMPolicies installs the index lifecycle management policies in the
+// JSON files of fsys that match glob, e.g. "testdata/ilm/*.json". Every
+// file contains the body of a put lifecycle request, i.e. {"policy": {...}},
+// and the policy is named after the file without the .json extension.
+func PutILMPolicies(ctx context.Context, es *elasticsearch.Client, fsys fs.FS, glob string) error {
+	matches, err := fs.Glob(fsys, glob)
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		body, err := fs.ReadFile(fsys, match)
+		if err != nil {
+			return err
+		}
+		name := strings.TrimSuffix(path.Base(match), path.Ext(match))
+		res, err := es.ILM.PutLifecycle(name,
+			es.ILM.PutLifecycle.WithContext(ctx),
+			es.ILM.PutLifecycle.WithBody(bytes.NewReader(body)),
+		)
+		if err := ParseError(res, err); err != nil {
+			return fmt.Errorf("could not put ILM policy %s: %w", name, err)
+		}
+		res.Body.Close()
+	}
+	return nil
+}
+
+// WithDataStreams creates the given data streams after templates are
+// installed and before any post-start operations run. See CreateDataStream
+// for details.
+func WithDataStreams(names ...string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.setup = append(cfg.setup, func(ctx context.Context, tb testing.TB, c *Container) error {
+			for _, name := range names {
+				if err := CreateDataStream(ctx, c.Client(), name); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// CreateDataStream creates a data stream. It requires an index template
+// that matches the name and enables data streams, e.g. one installed with
+// PutTemplates that contains "data_stream": {}.
+func CreateDataStream(ctx context.Context, es *elasticsearch.Client, name string) error {
+	res, err := es.Indices.CreateDataStream(name,
+		es.Indices.CreateDataStream.WithContext(ctx),
+	)
+	if err := ParseError(res, err); err != nil {
+		return fmt.Errorf("could not create data stream %s: %w", name, err)
+	}
+	return res.Body.Close()
+}
+
+// Rollover forces a rollover of the given data stream or index alias,
+// regardless of the conditions of its lifecycle policy. It returns the
+// name of the new write index.
+func Rollover(ctx context.Context, es *elasticsearch.Client, target string) (string, error) {
+	res, err := es.Indices.Rollover(target,
+		es.Indices.Rollover.WithContext(ctx),
+	)
+	if err := ParseError(res, err); err != nil {
+		return "", fmt.Errorf("could not roll over %s: %w", target, err)
+	}
+	defer res.Body.Close()
+
+	var resp struct {
+		NewIndex string `json:"new_index"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return "", err
+	}
+	return resp.NewIndex, nil
+}
+
+// ForceRollover is like Rollover with the client of the container. alias
+// is the name of a data stream or index alias as passed to Index.
+func (c *Container) ForceRollover(ctx context.Context, alias string) (string, error) {
+	return Rollover(ctx, c.Client(), c.Index(alias))
+}
+
+// WithILMPollInterval sets how often index lifecycle management checks
+// whether indices meet the conditions of their policies, e.g. "1s". It
+// defaults to 10 minutes in Elasticsearch, which is too slow for tests
+// that wait for phase transitions by age or size.
+func WithILMPollInterval(interval time.Duration) startConfigFunc {
+	return WithEnv(fmt.Sprintf("indices.lifecycle.poll_interval=%dms", interval.Milliseconds()))
+}
+
+// LifecycleStep is the step of an index in its lifecycle policy as
+// reported by the explain lifecycle API.
+type LifecycleStep struct {
+	Policy string `json:"policy"`
+	Phase  string `json:"phase"`
+	Action string `json:"action"`
+	Step   string `json:"step"`
+}
+
+// ExplainLifecycle returns the current lifecycle step of index. It
+// returns an error if index is not managed by a lifecycle policy.
+func ExplainLifecycle(ctx context.Context, es *elasticsearch.Client, index string) (LifecycleStep, error) {
+	res, err := es.ILM.ExplainLifecycle(index, es.ILM.ExplainLifecycle.WithContext(ctx))
+	if err := ParseError(res, err); err != nil {
+		return LifecycleStep{}, fmt.Errorf("could not explain lifecycle of %s: %w", index, err)
+	}
+	defer res.Body.Close()
+
+	var resp struct {
+		Indices map[string]struct {
+			Managed bool `json:"managed"`
+			LifecycleStep
+		} `json:"indices"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return LifecycleStep{}, err
+	}
+	explained, ok := resp.Indices[index]
+	if !ok || !explained.Managed {
+		return LifecycleStep{}, fmt.Errorf("index %s is not managed by a lifecycle policy", index)
+	}
+	return explained.LifecycleStep, nil
+}
+
+// MoveToPhase moves index from its current lifecycle step to the first
+// step of the given phase, e.g. "delete", regardless of the min_age of the
+// phase. This makes retention logic testable without waiting for the clock.
+// ILM runs the actions of the phase asynchronously, see WithILMPollInterval.
+func MoveToPhase(ctx context.Context, es *elasticsearch.Client, index, phase string) error {
+	current, err := ExplainLifecycle(ctx, es, index)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]any{
+		"current_step": map[string]string{
+			"phase":  current.Phase,
+			"action": current.Action,
+			"name":   current.Step,
+		},
+		"next_step": map[string]string{
+			"phase": phase,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	res, err := es.ILM.MoveToStep(index,
+		es.ILM.MoveToStep.WithContext(ctx),
+		es.ILM.MoveToStep.WithBody(bytes.NewReader(body)),
+	)
+	if err := ParseError(res, err); err != nil {
+		return fmt.Errorf("could not move %s to phase %s: %w", index, phase, err)
+	}
+	return res.Body.Close()
+}
@@ -0,0 +1,138 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestDataStreams(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithILMPolicies(os.DirFS("testdata"), "ilm/*.json"),
+		elasticsearch.WithIndexTemplates(os.DirFS("testdata"), "datastreams/*.json"),
+		elasticsearch.WithDataStreams("logs-app-default"),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	es := c.Client()
+	before := backingIndices(t, c, "logs-app-default")
+	if want, have := 1, len(before); want != have {
+		t.Fatalf("want %d backing indices, have %d", want, have)
+	}
+
+	newIndex, err := elasticsearch.Rollover(ctx, es, "logs-app-default")
+	if err != nil {
+		t.Fatalf("could not roll over: %v", err)
+	}
+	after := backingIndices(t, c, "logs-app-default")
+	if want, have := 2, len(after); want != have {
+		t.Fatalf("want %d backing indices, have %d", want, have)
+	}
+	if want, have := after[len(after)-1], newIndex; want != have {
+		t.Fatalf("want new write index %q, have %q", want, have)
+	}
+
+	// The backing indices are managed by the policy
+	res, err := es.ILM.ExplainLifecycle(newIndex, es.ILM.ExplainLifecycle.WithContext(ctx))
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not explain lifecycle: %v", err)
+	}
+	defer res.Body.Close()
+	var explain struct {
+		Indices map[string]struct {
+			Policy string `json:"policy"`
+		} `json:"indices"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&explain); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "logs-app", explain.Indices[newIndex].Policy; want != have {
+		t.Fatalf("want policy=%q, have %q", want, have)
+	}
+}
+
+func TestMoveToPhase(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithILMPollInterval(time.Second),
+		elasticsearch.WithILMPolicies(os.DirFS("testdata"), "ilm/*.json"),
+		elasticsearch.WithIndexTemplates(os.DirFS("testdata"), "datastreams/*.json"),
+		elasticsearch.WithDataStreams("logs-app-default"),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	es := c.Client()
+	old := backingIndices(t, c, "logs-app-default")[0]
+	if _, err := c.ForceRollover(ctx, "logs-app-default"); err != nil {
+		t.Fatalf("could not roll over: %v", err)
+	}
+
+	// Skip the min_age of 1d of the delete phase. Moving fails if ILM
+	// changes the step in the meantime, so retry until the index is gone.
+	for slices.Contains(backingIndices(t, c, "logs-app-default"), old) {
+		step, err := elasticsearch.ExplainLifecycle(ctx, es, old)
+		if err != nil {
+			if !slices.Contains(backingIndices(t, c, "logs-app-default"), old) {
+				break // deleted in the meantime
+			}
+			t.Fatalf("could not explain lifecycle: %v", err)
+		}
+		if step.Phase != "delete" {
+			if err := elasticsearch.MoveToPhase(ctx, es, old, "delete"); err != nil {
+				t.Logf("could not move to delete phase: %v", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			t.Fatalf("want %s to be deleted, have step %+v", old, step)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+func backingIndices(t *testing.T, c *elasticsearch.Container, name string) []string {
+	t.Helper()
+
+	es := c.Client()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	res, err := es.Indices.GetDataStream(
+		es.Indices.GetDataStream.WithContext(ctx),
+		es.Indices.GetDataStream.WithName(name),
+	)
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not get data stream: %v", err)
+	}
+	defer res.Body.Close()
+
+	var resp struct {
+		DataStreams []struct {
+			Indices []struct {
+				IndexName string `json:"index_name"`
+			} `json:"indices"`
+		} `json:"data_streams"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	var indices []string
+	for _, ds := range resp.DataStreams {
+		for _, index := range ds.Indices {
+			indices = append(indices, index.IndexName)
+		}
+	}
+	return indices
+}
@@ -3,9 +3,14 @@ package elasticsearch
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/elastic/go-elasticsearch/v8/esapi"
 )
@@ -16,8 +21,9 @@ import (
 // 1. If err is not nil, return err.
 // 2. If res is nil, return nil.
 // 3. If res is not an error, return nil.
-// 4. If res cannot be decoded into an ES error, return the decoding error.
-// 4. Return an *Error instance.
+// 4. Return an *Error instance with the HTTP status of res, and the
+// details of the body if it can be decoded. The body of e.g. a HEAD
+// request is always empty.
 func ParseError(res *esapi.Response, err error) error {
 	if err != nil {
 		return err
@@ -25,11 +31,7 @@ func ParseError(res *esapi.Response, err error) error {
 	if res == nil || !res.IsError() {
 		return nil
 	}
-	var e Error
-	if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
-		return err
-	}
-	return &e
+	return decodeError(res.StatusCode, res.Header, res.Body)
 }
 
 // ParseHTTPResponse is like ParseError but expects a raw http.Response.
@@ -40,17 +42,70 @@ func ParseHTTPResponse(res *http.Response, err error) error {
 	if res == nil || res.StatusCode < 300 {
 		return nil
 	}
+	return decodeError(res.StatusCode, res.Header, res.Body)
+}
+
+// decodeError returns an *Error with the given HTTP status and header,
+// and the details decoded from body, if any.
+func decodeError(status int, header http.Header, body io.Reader) *Error {
 	var e Error
-	if err := json.NewDecoder(res.Body).Decode(&e); err != nil {
-		return &e
+	if err := json.NewDecoder(body).Decode(&e); err != nil {
+		e = Error{}
+	}
+	if e.Status == 0 {
+		e.Status = status
 	}
-	return err
+	e.Header = header
+	return &e
+}
+
+var (
+	// ErrClientError is matched by errors.Is for all errors with an
+	// HTTP status of 4xx.
+	ErrClientError = errors.New("elasticsearch: client error")
+	// ErrServerError is matched by errors.Is for all errors with an
+	// HTTP status of 5xx.
+	ErrServerError = errors.New("elasticsearch: server error")
+
+	// ErrBadRequest is matched by errors.Is for errors with HTTP status 400.
+	ErrBadRequest = errors.New("elasticsearch: bad request")
+	// ErrUnauthorized is matched by errors.Is for errors with HTTP status 401.
+	ErrUnauthorized = errors.New("elasticsearch: unauthorized")
+	// ErrForbidden is matched by errors.Is for errors with HTTP status 403.
+	ErrForbidden = errors.New("elasticsearch: forbidden")
+	// ErrNotFound is matched by errors.Is for errors with HTTP status 404.
+	ErrNotFound = errors.New("elasticsearch: not found")
+	// ErrTimeout is matched by errors.Is for errors with HTTP status 408.
+	ErrTimeout = errors.New("elasticsearch: timeout")
+	// ErrConflict is matched by errors.Is for errors with HTTP status 409.
+	ErrConflict = errors.New("elasticsearch: conflict")
+	// ErrTooManyRequests is matched by errors.Is for errors with HTTP
+	// status 429.
+	ErrTooManyRequests = errors.New("elasticsearch: too many requests")
+	// ErrServiceUnavailable is matched by errors.Is for errors with HTTP
+	// status 503.
+	ErrServiceUnavailable = errors.New("elasticsearch: service unavailable")
+)
+
+// statusErrors maps HTTP status codes to the errors matching them.
+var statusErrors = map[int]error{
+	http.StatusBadRequest:         ErrBadRequest,
+	http.StatusUnauthorized:       ErrUnauthorized,
+	http.StatusForbidden:          ErrForbidden,
+	http.StatusNotFound:           ErrNotFound,
+	http.StatusRequestTimeout:     ErrTimeout,
+	http.StatusConflict:           ErrConflict,
+	http.StatusTooManyRequests:    ErrTooManyRequests,
+	http.StatusServiceUnavailable: ErrServiceUnavailable,
 }
 
 // Error encapsulates error details as returned from Elasticsearch.
 type Error struct {
 	Status  int           `json:"status"`
 	Details *ErrorDetails `json:"error,omitempty"`
+
+	// Header of the HTTP response, e.g. to read Retry-After.
+	Header http.Header `json:"-"`
 }
 
 // ErrorDetails encapsulate error details from Elasticsearch.
@@ -85,26 +140,52 @@ type ScriptErrorPosition struct {
 
 // Error returns a string representation of the error.
 func (e *Error) Error() string {
+	if IsFloodStageBlock(e) {
+		return fmt.Sprintf("elasticsearch: Error %d (%s): %s [type=%s] (the disk of the Docker host is nearly full, see WithDiskThreshold)", e.Status, http.StatusText(e.Status), e.Details.Reason, e.Details.Type)
+	}
 	if e.Details != nil && e.Details.Reason != "" {
 		return fmt.Sprintf("elasticsearch: Error %d (%s): %s [type=%s]", e.Status, http.StatusText(e.Status), e.Details.Reason, e.Details.Type)
 	}
 	return fmt.Sprintf("elasticsearch: Error %d (%s)", e.Status, http.StatusText(e.Status))
 }
 
+// Is reports whether the error matches target, i.e. the status class
+// ErrClientError or ErrServerError, or the error for its status, e.g.
+// ErrNotFound. It is used by errors.Is.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrClientError:
+		return e.Status >= 400 && e.Status < 500
+	case ErrServerError:
+		return e.Status >= 500 && e.Status < 600
+	}
+	return target != nil && statusErrors[e.Status] == target
+}
+
 // ErrorReason returns the reason of an error that Elasticsearch reported,
-// if err is of kind Error and has ErrorDetails with a Reason. Any other
+// if err is or wraps an *Error with ErrorDetails with a Reason. Any other
 // value of err will return an empty string.
 func ErrorReason(err error) string {
-	if err == nil {
-		return ""
-	}
-	e, ok := err.(*Error)
-	if !ok || e == nil || e.Details == nil {
+	var e *Error
+	if !errors.As(err, &e) || e == nil || e.Details == nil {
 		return ""
 	}
 	return e.Details.Reason
 }
 
+// IsFloodStageBlock returns true if the given error indicates that an
+// index is read-only because the disk usage of a node exceeded the
+// flood-stage watermark. Elasticsearch reports this as a cluster block
+// with HTTP status 403 or 429, depending on the version.
+func IsFloodStageBlock(err error) bool {
+	var e *Error
+	if !errors.As(err, &e) || e.Details == nil {
+		return false
+	}
+	return e.Details.Type == "cluster_block_exception" &&
+		(strings.Contains(e.Details.Reason, "flood-stage") || strings.Contains(e.Details.Reason, "read_only_allow_delete"))
+}
+
 // IsContextErr returns true if the error is from a context that was canceled or deadline exceeded
 func IsContextErr(err error) bool {
 	if err == context.Canceled || err == context.DeadlineExceeded {
@@ -161,21 +242,85 @@ func IsForbidden(err interface{}) bool {
 	return IsStatusCode(err, http.StatusForbidden)
 }
 
+// IsBadRequest returns true if the given error indicates that Elasticsearch
+// returned HTTP status 400, e.g. due to an invalid query or mapping.
+// The err parameter can be of type *elastic.Error, elastic.Error,
+// *http.Response or int (indicating the HTTP status code).
+func IsBadRequest(err interface{}) bool {
+	return IsStatusCode(err, http.StatusBadRequest)
+}
+
+// IsTooManyRequests returns true if the given error indicates that
+// Elasticsearch returned HTTP status 429, i.e. rejected the request due
+// to back pressure. Use RetryAfter to find out when to retry.
+// The err parameter can be of type *elastic.Error, elastic.Error,
+// *http.Response or int (indicating the HTTP status code).
+func IsTooManyRequests(err interface{}) bool {
+	return IsStatusCode(err, http.StatusTooManyRequests)
+}
+
+// IsServiceUnavailable returns true if the given error indicates that
+// Elasticsearch returned HTTP status 503, e.g. while the cluster has no
+// elected master. The err parameter can be of type *elastic.Error,
+// elastic.Error, *http.Response or int (indicating the HTTP status code).
+func IsServiceUnavailable(err interface{}) bool {
+	return IsStatusCode(err, http.StatusServiceUnavailable)
+}
+
+// RetryAfter returns the duration to wait before retrying, as specified
+// by the Retry-After header in the response, and true if the header is set.
+// The header can contain seconds or an HTTP date. The err parameter can be
+// of type *esapi.Response, *http.Response, *Error, or Error.
+func RetryAfter(err interface{}) (time.Duration, bool) {
+	var header http.Header
+	switch e := err.(type) {
+	case *esapi.Response:
+		if e != nil {
+			header = e.Header
+		}
+	case *http.Response:
+		if e != nil {
+			header = e.Header
+		}
+	case *Error:
+		if e != nil {
+			header = e.Header
+		}
+	case Error:
+		header = e.Header
+	}
+	v := header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(0, time.Until(t)), true
+	}
+	return 0, false
+}
+
 // IsStatusCode returns true if the given error indicates that the Elasticsearch
 // operation returned the specified HTTP status code. The err parameter can be of
-// type *http.Response, *Error, Error, or int (indicating the HTTP status code).
+// type *http.Response, *Error, Error, int (indicating the HTTP status code), or
+// an error that wraps an *Error.
 func IsStatusCode(err interface{}, code int) bool {
 	switch e := err.(type) {
 	case *esapi.Response:
-		return e.StatusCode == code
+		return e != nil && e.StatusCode == code
 	case *http.Response:
-		return e.StatusCode == code
+		return e != nil && e.StatusCode == code
 	case *Error:
-		return e.Status == code
+		return e != nil && e.Status == code
 	case Error:
 		return e.Status == code
 	case int:
 		return e == code
+	case error:
+		var ee *Error
+		return errors.As(e, &ee) && ee.Status == code
 	}
 	return false
 }
@@ -0,0 +1,199 @@
+package elasticsearch_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestParseError(t *testing.T) {
+	res := &esapi.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"3"}},
+		Body: io.NopCloser(strings.NewReader(`{
+			"status": 429,
+			"error": {"type": "es_rejected_execution_exception", "reason": "rejected execution"}
+		}`)),
+	}
+	err := elasticsearch.ParseError(res, nil)
+	if err == nil {
+		t.Fatal("want error, have nil")
+	}
+	if !elasticsearch.IsTooManyRequests(err) {
+		t.Errorf("want IsTooManyRequests, have %v", err)
+	}
+	if want, have := "rejected execution", elasticsearch.ErrorReason(err); want != have {
+		t.Errorf("want reason=%q, have %q", want, have)
+	}
+	d, ok := elasticsearch.RetryAfter(err)
+	if !ok {
+		t.Fatal("want Retry-After")
+	}
+	if want, have := 3*time.Second, d; want != have {
+		t.Errorf("want Retry-After=%v, have %v", want, have)
+	}
+}
+
+func TestParseHTTPResponse(t *testing.T) {
+	res := &http.Response{
+		StatusCode: http.StatusBadRequest,
+		Body:       io.NopCloser(strings.NewReader(`{"error": {"type": "parsing_exception", "reason": "unknown query"}}`)),
+	}
+	err := elasticsearch.ParseHTTPResponse(res, nil)
+	if !elasticsearch.IsBadRequest(err) {
+		t.Fatalf("want IsBadRequest, have %v", err)
+	}
+}
+
+func TestError_Is(t *testing.T) {
+	tests := []struct {
+		Status int
+		Client bool
+		Server bool
+	}{
+		{Status: http.StatusBadRequest, Client: true},
+		{Status: http.StatusNotFound, Client: true},
+		{Status: http.StatusTooManyRequests, Client: true},
+		{Status: http.StatusServiceUnavailable, Server: true},
+		{Status: http.StatusGatewayTimeout, Server: true},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("wrapped: %w", &elasticsearch.Error{Status: tt.Status})
+		if want, have := tt.Client, errors.Is(err, elasticsearch.ErrClientError); want != have {
+			t.Errorf("status %d: want ErrClientError=%v, have %v", tt.Status, want, have)
+		}
+		if want, have := tt.Server, errors.Is(err, elasticsearch.ErrServerError); want != have {
+			t.Errorf("status %d: want ErrServerError=%v, have %v", tt.Status, want, have)
+		}
+	}
+}
+
+func TestError_IsStatus(t *testing.T) {
+	tests := []struct {
+		Status int
+		Target error
+	}{
+		{Status: http.StatusBadRequest, Target: elasticsearch.ErrBadRequest},
+		{Status: http.StatusUnauthorized, Target: elasticsearch.ErrUnauthorized},
+		{Status: http.StatusForbidden, Target: elasticsearch.ErrForbidden},
+		{Status: http.StatusNotFound, Target: elasticsearch.ErrNotFound},
+		{Status: http.StatusRequestTimeout, Target: elasticsearch.ErrTimeout},
+		{Status: http.StatusConflict, Target: elasticsearch.ErrConflict},
+		{Status: http.StatusTooManyRequests, Target: elasticsearch.ErrTooManyRequests},
+		{Status: http.StatusServiceUnavailable, Target: elasticsearch.ErrServiceUnavailable},
+	}
+	for _, tt := range tests {
+		err := fmt.Errorf("wrapped: %w", &elasticsearch.Error{Status: tt.Status})
+		if !errors.Is(err, tt.Target) {
+			t.Errorf("status %d: want errors.Is(%v)", tt.Status, tt.Target)
+		}
+		if !elasticsearch.IsStatusCode(err, tt.Status) {
+			t.Errorf("status %d: want IsStatusCode for wrapped error", tt.Status)
+		}
+	}
+	if errors.Is(&elasticsearch.Error{Status: http.StatusNotFound}, elasticsearch.ErrConflict) {
+		t.Error("want status 404 not to match ErrConflict")
+	}
+}
+
+func TestParseError_EmptyBody(t *testing.T) {
+	// HEAD requests, e.g. to check if an index exists, have no body
+	res := &esapi.Response{
+		StatusCode: http.StatusNotFound,
+		Body:       io.NopCloser(strings.NewReader("")),
+	}
+	err := elasticsearch.ParseError(res, nil)
+	if !errors.Is(err, elasticsearch.ErrNotFound) {
+		t.Fatalf("want ErrNotFound, have %v", err)
+	}
+	if !elasticsearch.IsNotFound(err) {
+		t.Fatalf("want IsNotFound, have %v", err)
+	}
+}
+
+func TestIsServiceUnavailable(t *testing.T) {
+	if !elasticsearch.IsServiceUnavailable(&elasticsearch.Error{Status: http.StatusServiceUnavailable}) {
+		t.Error("want IsServiceUnavailable for *Error")
+	}
+	if !elasticsearch.IsServiceUnavailable(http.StatusServiceUnavailable) {
+		t.Error("want IsServiceUnavailable for int")
+	}
+	if elasticsearch.IsServiceUnavailable(http.StatusBadGateway) {
+		t.Error("want no IsServiceUnavailable for 502")
+	}
+}
+
+func TestIsStatusCode_Nil(t *testing.T) {
+	// Failed transports return typed nil responses
+	var res *esapi.Response
+	if elasticsearch.IsNotFound(res) {
+		t.Error("want no IsNotFound for nil *esapi.Response")
+	}
+	var hres *http.Response
+	if elasticsearch.IsStatusCode(hres, http.StatusNotFound) {
+		t.Error("want no IsStatusCode for nil *http.Response")
+	}
+	var eerr *elasticsearch.Error
+	if elasticsearch.IsStatusCode(eerr, http.StatusNotFound) {
+		t.Error("want no IsStatusCode for nil *Error")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		Header string
+		OK     bool
+		Min    time.Duration
+		Max    time.Duration
+	}{
+		{Header: "", OK: false},
+		{Header: "invalid", OK: false},
+		{Header: "0", OK: true},
+		{Header: "120", OK: true, Min: 2 * time.Minute, Max: 2 * time.Minute},
+		{Header: time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), OK: true, Min: 58 * time.Second, Max: time.Minute},
+		{Header: "Mon, 02 Jan 2006 15:04:05 GMT", OK: true},
+	}
+	for _, tt := range tests {
+		res := &http.Response{Header: http.Header{}}
+		if tt.Header != "" {
+			res.Header.Set("Retry-After", tt.Header)
+		}
+		d, ok := elasticsearch.RetryAfter(res)
+		if want, have := tt.OK, ok; want != have {
+			t.Errorf("%q: want ok=%v, have %v", tt.Header, want, have)
+		}
+		if d < tt.Min || d > tt.Max {
+			t.Errorf("%q: want Retry-After in [%v,%v], have %v", tt.Header, tt.Min, tt.Max, d)
+		}
+	}
+}
+
+func TestIsFloodStageBlock(t *testing.T) {
+	res := &esapi.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Body: io.NopCloser(strings.NewReader(`{
+			"status": 429,
+			"error": {
+				"type": "cluster_block_exception",
+				"reason": "index [users] blocked by: [TOO_MANY_REQUESTS/12/disk usage exceeded flood-stage watermark, index has read-only-allow-delete block];"
+			}
+		}`)),
+	}
+	err := elasticsearch.ParseError(res, nil)
+	if !elasticsearch.IsFloodStageBlock(fmt.Errorf("wrapped: %w", err)) {
+		t.Fatalf("want IsFloodStageBlock, have %v", err)
+	}
+	if !strings.Contains(err.Error(), "WithDiskThreshold") {
+		t.Errorf("want hint in error message, have %q", err.Error())
+	}
+	if elasticsearch.IsFloodStageBlock(&elasticsearch.Error{Status: http.StatusForbidden}) {
+		t.Error("want no IsFloodStageBlock without details")
+	}
+}
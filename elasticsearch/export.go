@@ -0,0 +1,73 @@
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// dataPath is the data directory of Elasticsearch in the container.
+const dataPath = "/usr/share/elasticsearch/data"
+
+// WithExportOnFailure exports the data directory of every node as a tar
+// archive into dir when the container is closed and the test has failed,
+// e.g. to keep the cluster state that led to a failure as a CI artifact.
+// Archives are named after the test and the container, e.g.
+// "TestSearch_elasticsearch_1712345678.tar". The directory is created if
+// necessary.
+func WithExportOnFailure(dir string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.exportDir = dir
+	}
+}
+
+// export writes the data directories of all nodes into dir.
+func (c *Container) export(dir string) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	// Write everything from the transaction log into the index files
+	// before taking the copy; the cluster may well be broken, though
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := Flush(ctx, c.Client()); err != nil {
+		c.tb.Logf("could not flush before export: %v", err)
+	}
+
+	testName := strings.NewReplacer("/", "_", " ", "_").Replace(c.tb.Name())
+	for _, resource := range c.resources {
+		name := strings.TrimPrefix(resource.Container.Name, "/")
+		filename := filepath.Join(dir, fmt.Sprintf("%s_%s.tar", testName, name))
+		if err := c.exportNode(resource.Container.ID, filename); err != nil {
+			return fmt.Errorf("could not export %s: %w", name, err)
+		}
+		c.tb.Logf("exported data of Elasticsearch container to %s", filename)
+	}
+	return nil
+}
+
+// exportNode writes the data directory of the container with the given
+// ID into a tar archive.
+func (c *Container) exportNode(id, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	err = c.pool.Client.DownloadFromContainer(id, docker.DownloadFromContainerOptions{
+		Path:         dataPath,
+		OutputStream: f,
+	})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(filename)
+	}
+	return err
+}
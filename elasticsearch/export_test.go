@@ -0,0 +1,61 @@
+package elasticsearch_test
+
+import (
+	"archive/tar"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+// failedTB reports a test as failed without failing the actual test.
+type failedTB struct {
+	*testing.T
+}
+
+func (failedTB) Failed() bool { return true }
+
+func TestWithExportOnFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	c := elasticsearch.Start(failedTB{t},
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithBulkFixtures(os.DirFS("testdata"), "fixtures/*.ndjson"),
+		elasticsearch.WithExportOnFailure(dir),
+	)
+	if err := c.Close(); err != nil {
+		t.Fatalf("could not close container: %v", err)
+	}
+
+	matches, err := filepath.Glob(filepath.Join(dir, "TestWithExportOnFailure_*.tar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(matches); want != have {
+		t.Fatalf("want %d archives, have %d", want, have)
+	}
+
+	f, err := os.Open(matches[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tr := tar.NewReader(f)
+	var files int
+	for {
+		_, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("could not read archive: %v", err)
+		}
+		files++
+	}
+	if files == 0 {
+		t.Fatal("want files in archive, have none")
+	}
+}
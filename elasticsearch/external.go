@@ -0,0 +1,123 @@
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
+)
+
+// Environment variables to use an external cluster instead of starting
+// a Docker container, e.g. in CI environments without a Docker socket or
+// to test against Elastic Cloud.
+const (
+	externalURLEnv      = "INTEGRATIONTEST_ELASTICSEARCH_URL"
+	externalUsernameEnv = "INTEGRATIONTEST_ELASTICSEARCH_USERNAME"
+	externalPasswordEnv = "INTEGRATIONTEST_ELASTICSEARCH_PASSWORD"
+)
+
+// externalURL returns the URL of the external cluster to use, if any: the
+// URL in externalURLEnv, or the URL of the elasticsearch service container
+// of the CI system, see core.CIService.
+func externalURL() string {
+	if rawURL := os.Getenv(externalURLEnv); rawURL != "" {
+		return rawURL
+	}
+	if addr, ok := core.CIService("elasticsearch", 9200); ok {
+		return "http://" + addr
+	}
+	return ""
+}
+
+// useExternalURL returns the URL of the external cluster that the options
+// allow to use, if any. Clusters that need a container, e.g. to proxy it
+// with WithToxiproxy or to join it to a network with StartCCS, never use
+// the external cluster.
+func (cfg startConfig) useExternalURL() string {
+	if cfg.toxiproxy || cfg.container {
+		return ""
+	}
+	return externalURL()
+}
+
+// withContainer starts a container even if an external cluster is
+// configured, see useExternalURL.
+func withContainer() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.container = true
+	}
+}
+
+// startExternal connects to the external cluster at the given URL
+// instead of starting a container.
+func (c *Container) startExternal(ctx context.Context, rawURL string, startCfg startConfig) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", externalURLEnv, err)
+	}
+
+	c.external = true
+	c.indexPrefix = "integrationtest-" + dockerutil.Suffix() + "-"
+	c.url = strings.TrimSuffix(rawURL, "/")
+	c.urls = []string{c.url}
+	c.hostPort = u.Host
+	c.username = os.Getenv(externalUsernameEnv)
+	c.password = os.Getenv(externalPasswordEnv)
+
+	var connectOptions []connectOption
+	if c.username != "" || c.password != "" {
+		connectOptions = append(connectOptions, WithUsername(c.username), WithPassword(c.password))
+	}
+	connectOptions = append(connectOptions, startCfg.clientOptions...)
+	if err := c.connect(connectOptions...); err != nil {
+		return fmt.Errorf("could not connect to Elasticsearch at %s: %w", c.url, err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	if err := Ping(ctx, c.c); err != nil {
+		return fmt.Errorf("could not ping Elasticsearch at %s: %w", c.url, err)
+	}
+	return nil
+}
+
+// Index returns the name to use for the index with the given name. It is
+// the name itself, unless the container uses an external cluster, where
+// it has a prefix that is unique to the container.
+func (c *Container) Index(name string) string {
+	return c.indexPrefix + name
+}
+
+// ownsIndex returns true if the index with the given name belongs to the
+// container, i.e. is no system index and has the prefix of the container.
+func (c *Container) ownsIndex(name string) bool {
+	return !isSystemName(name) && strings.HasPrefix(name, c.indexPrefix)
+}
+
+// deleteExternalIndices deletes all indices with the prefix of the
+// container.
+func (c *Container) deleteExternalIndices() error {
+	if c.c == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	names, err := c.userIndices(ctx)
+	if err != nil {
+		return err
+	}
+	for len(names) > 0 {
+		n := min(len(names), 100)
+		res, err := c.c.Indices.Delete(names[:n], c.c.Indices.Delete.WithContext(ctx))
+		if err := closeResponse(res, err); err != nil {
+			return err
+		}
+		names = names[n:]
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+// fakeCluster is a minimal Elasticsearch that supports creating, listing,
+// and deleting indices.
+type fakeCluster struct {
+	mu      sync.Mutex
+	indices map[string]bool
+}
+
+func (f *fakeCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	w.Header().Set("X-Elastic-Product", "Elasticsearch")
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.URL.Path == "/":
+		w.Write([]byte(`{"version":{"number":"8.12.2"}}`))
+	case r.Method == http.MethodGet && r.URL.Path == "/_cat/indices":
+		var indices []map[string]string
+		for name := range f.indices {
+			indices = append(indices, map[string]string{"index": name})
+		}
+		json.NewEncoder(w).Encode(indices)
+	case r.Method == http.MethodPut:
+		f.indices[strings.TrimPrefix(r.URL.Path, "/")] = true
+		w.Write([]byte(`{"acknowledged":true}`))
+	case r.Method == http.MethodDelete:
+		for _, name := range strings.Split(strings.TrimPrefix(r.URL.Path, "/"), ",") {
+			delete(f.indices, name)
+		}
+		w.Write([]byte(`{"acknowledged":true}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status":404}`))
+	}
+}
+
+func TestStart_External(t *testing.T) {
+	cluster := &fakeCluster{
+		indices: map[string]bool{"other": true, ".system": true},
+	}
+	srv := httptest.NewServer(cluster)
+	defer srv.Close()
+
+	t.Setenv("INTEGRATIONTEST_ELASTICSEARCH_URL", srv.URL)
+
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(5*time.Second),
+		elasticsearch.WithIndices(map[string]elasticsearch.IndexSpec{
+			"users": {},
+		}),
+	)
+
+	if want, have := srv.URL, c.URL(); want != have {
+		t.Fatalf("want URL=%q, have %q", want, have)
+	}
+	index := c.Index("users")
+	if index == "users" || !strings.HasSuffix(index, "users") {
+		t.Fatalf("want prefixed index name, have %q", index)
+	}
+	cluster.mu.Lock()
+	created := cluster.indices[index]
+	cluster.mu.Unlock()
+	if !created {
+		t.Fatalf("want index %s to be created", index)
+	}
+
+	// Close deletes the indices of the container only
+	if err := c.Close(); err != nil {
+		t.Fatalf("could not close container: %v", err)
+	}
+	// Clients of the container can't be used anymore
+	if err := elasticsearch.Ping(context.Background(), c.Client()); !errors.Is(err, elasticsearch.ErrClosed) {
+		t.Errorf("want ErrClosed after Close, have %v", err)
+	}
+
+	cluster.mu.Lock()
+	defer cluster.mu.Unlock()
+	if cluster.indices[index] {
+		t.Errorf("want index %s to be deleted", index)
+	}
+	if !cluster.indices["other"] || !cluster.indices[".system"] {
+		t.Errorf("want other indices to be kept, have %v", cluster.indices)
+	}
+}
+
+func TestStart_CIService(t *testing.T) {
+	// The test runs in CI jobs with an elasticsearch service container
+	if core.CI() == "" {
+		t.Skip("not running in CI")
+	}
+	if _, ok := core.CIService("elasticsearch", 9200); !ok {
+		t.Skip("no elasticsearch service container")
+	}
+	t.Setenv("INTEGRATIONTEST_ELASTICSEARCH_URL", "")
+
+	c := elasticsearch.Start(t, elasticsearch.WithTimeout(5*time.Second))
+	if want, have := "http://elasticsearch:9200", c.URL(); want != have {
+		t.Fatalf("want URL of the service container %q, have %q", want, have)
+	}
+	if c.Index("users") == "users" {
+		t.Fatal("want prefixed index name for the shared service container")
+	}
+}
+
+func TestStartCluster_External(t *testing.T) {
+	// An external cluster has the nodes it has, so clusters of more than
+	// one node start containers
+	t.Setenv("INTEGRATIONTEST_ELASTICSEARCH_URL", "http://localhost:1")
+
+	c := elasticsearch.StartCluster(t, 2, elasticsearch.WithTimeout(90*time.Second))
+	if c.URL() == "http://localhost:1" {
+		t.Fatalf("want a container, have the external cluster %s", c.URL())
+	}
+}
@@ -0,0 +1,200 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// goldenScoreDigits is the number of decimal digits that scores are rounded
+// to before they are compared with a golden file.
+const goldenScoreDigits = 4
+
+// AssertSearchGolden runs the search request in query against index and
+// compares the response with the golden file at goldenPath.
+//
+// Volatile parts of the response are normalized first: "took" is removed,
+// scores are rounded to 4 decimal digits, and hits that rank equally are
+// ordered by their _id.
+//
+// If the test binary has a boolean -update flag that is set, or
+// INTEGRATIONTEST_UPDATE is true, AssertSearchGolden writes the golden file
+// instead of comparing it.
+func AssertSearchGolden(tb testing.TB, es *elasticsearch.Client, index, query, goldenPath string) {
+	tb.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(index),
+		es.Search.WithBody(strings.NewReader(query)),
+	)
+	if err := ParseError(res, err); err != nil {
+		tb.Fatalf("could not search %s: %v", index, err)
+	}
+	defer res.Body.Close()
+
+	var resp map[string]any
+	dec := json.NewDecoder(res.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&resp); err != nil {
+		tb.Fatalf("could not decode search response: %v", err)
+	}
+	normalizeSearchResponse(resp)
+	have, err := json.MarshalIndent(resp, "", "  ")
+	if err != nil {
+		tb.Fatal(err)
+	}
+	have = append(have, '\n')
+
+	if updateGolden() {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0755); err != nil {
+			tb.Fatalf("could not create directory of golden file: %v", err)
+		}
+		if err := os.WriteFile(goldenPath, have, 0644); err != nil {
+			tb.Fatalf("could not write golden file: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(goldenPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		tb.Fatalf("golden file %s does not exist; run with -update or INTEGRATIONTEST_UPDATE=1 to create it", goldenPath)
+	}
+	if err != nil {
+		tb.Fatalf("could not read golden file: %v", err)
+	}
+	if !bytes.Equal(want, have) {
+		tb.Errorf("search response of %s differs from %s (-want +have):\n%s", index, goldenPath, diffLines(string(want), string(have)))
+	}
+}
+
+// updateGolden reports whether golden files should be written instead of
+// compared.
+func updateGolden() bool {
+	if f := flag.Lookup("update"); f != nil {
+		if getter, ok := f.Value.(flag.Getter); ok {
+			if update, ok := getter.Get().(bool); ok && update {
+				return true
+			}
+		}
+	}
+	update, _ := strconv.ParseBool(os.Getenv("INTEGRATIONTEST_UPDATE"))
+	return update
+}
+
+// normalizeSearchResponse removes the volatile parts of a search response.
+func normalizeSearchResponse(resp map[string]any) {
+	delete(resp, "took")
+
+	hits, ok := resp["hits"].(map[string]any)
+	if !ok {
+		return
+	}
+	hits["max_score"] = roundScore(hits["max_score"])
+	list, ok := hits["hits"].([]any)
+	if !ok {
+		return
+	}
+
+	// Hits with the same score and sort values may come back in any
+	// order, so order each run of equally ranked hits by _id
+	rank := func(hit any) string {
+		m, _ := hit.(map[string]any)
+		if m == nil {
+			return ""
+		}
+		m["_score"] = roundScore(m["_score"])
+		key, _ := json.Marshal([]any{m["_score"], m["sort"]})
+		return string(key)
+	}
+	ranks := make([]string, len(list))
+	for i, hit := range list {
+		ranks[i] = rank(hit)
+	}
+	for start := 0; start < len(list); {
+		end := start + 1
+		for end < len(list) && ranks[end] == ranks[start] {
+			end++
+		}
+		run := list[start:end]
+		sort.SliceStable(run, func(i, j int) bool {
+			return hitID(run[i]) < hitID(run[j])
+		})
+		start = end
+	}
+}
+
+// roundScore rounds a score of a search response to goldenScoreDigits.
+func roundScore(score any) any {
+	n, ok := score.(json.Number)
+	if !ok {
+		return score
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return score
+	}
+	return json.Number(strconv.FormatFloat(f, 'f', goldenScoreDigits, 64))
+}
+
+func hitID(hit any) string {
+	m, _ := hit.(map[string]any)
+	id, _ := m["_id"].(string)
+	return id
+}
+
+// diffLines returns a line-based diff of want and have, with removed lines
+// prefixed by "-" and added lines prefixed by "+".
+func diffLines(want, have string) string {
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(have, "\n"), "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var sb strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			sb.WriteString("  " + a[i] + "\n")
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] >= lcs[i+1][j]):
+			sb.WriteString("+ " + b[j] + "\n")
+			j++
+		default:
+			sb.WriteString("- " + a[i] + "\n")
+			i++
+		}
+	}
+	return sb.String()
+}
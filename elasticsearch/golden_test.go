@@ -0,0 +1,93 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+// recordingTB records errors instead of failing the actual test. Like
+// the testing package, Fatalf stops the goroutine that calls it.
+type recordingTB struct {
+	*testing.T
+	errors []string
+	fatal  bool
+}
+
+func (tb *recordingTB) Errorf(format string, args ...any) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func (tb *recordingTB) Fatalf(format string, args ...any) {
+	tb.Errorf(format, args...)
+	tb.fatal = true
+	runtime.Goexit()
+}
+
+func TestAssertSearchGolden(t *testing.T) {
+	// The fake cluster answers with a different took and a different
+	// order of equally ranked hits on every request
+	var (
+		requests int
+		hits     = []string{
+			`{"_index":"users","_id":"1","_score":1.2345678}`,
+			`{"_index":"users","_id":"2","_score":0.50000001}`,
+			`{"_index":"users","_id":"3","_score":0.49999999}`,
+		}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Elastic-Product", "Elasticsearch")
+		w.Header().Set("Content-Type", "application/json")
+		requests++
+		order := []string{hits[0], hits[1], hits[2]}
+		if requests%2 == 0 {
+			order = []string{hits[0], hits[2], hits[1]}
+		}
+		fmt.Fprintf(w, `{"took":%d,"timed_out":false,"hits":{"total":{"value":3,"relation":"eq"},"max_score":1.2345678,"hits":[%s]}}`,
+			requests, strings.Join(order, ","))
+	}))
+	defer srv.Close()
+
+	es, err := elasticsearch.Connect(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join(t.TempDir(), "testdata", "users.golden.json")
+
+	t.Setenv("INTEGRATIONTEST_UPDATE", "1")
+	elasticsearch.AssertSearchGolden(t, es, "users", `{"query":{"match_all":{}}}`, golden)
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("want golden file to be written, have %v", err)
+	}
+	if strings.Contains(string(data), `"took"`) {
+		t.Fatalf("want took to be removed, have:\n%s", data)
+	}
+	if !strings.Contains(string(data), `"_score": 1.2346`) {
+		t.Fatalf("want scores to be rounded, have:\n%s", data)
+	}
+
+	t.Setenv("INTEGRATIONTEST_UPDATE", "")
+	elasticsearch.AssertSearchGolden(t, es, "users", `{"query":{"match_all":{}}}`, golden)
+
+	// Change the golden file
+	if err := os.WriteFile(golden, []byte(strings.Replace(string(data), `"value": 3`, `"value": 4`, 1)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tb := &recordingTB{T: t}
+	elasticsearch.AssertSearchGolden(tb, es, "users", `{"query":{"match_all":{}}}`, golden)
+	if want, have := 1, len(tb.errors); want != have {
+		t.Fatalf("want %d error, have %d", want, have)
+	}
+	if !strings.Contains(tb.errors[0], `-       "value": 4`) || !strings.Contains(tb.errors[0], `+       "value": 3`) {
+		t.Fatalf("want diff in error, have:\n%s", tb.errors[0])
+	}
+}
@@ -0,0 +1,117 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// IndexSpec specifies the settings and mappings of an index.
+//
+// Either set Mappings and/or Settings, or set FS and Path to read
+// the complete body of the create index request from a JSON file,
+// e.g. {"settings": {...}, "mappings": {...}, "aliases": {...}}.
+type IndexSpec struct {
+	// Mappings of the index as JSON.
+	Mappings json.RawMessage
+	// Settings of the index as JSON.
+	Settings json.RawMessage
+	// FS to read the file at Path from.
+	FS fs.FS
+	// Path of the JSON file in FS.
+	Path string
+}
+
+// body returns the body of the create index request.
+func (spec IndexSpec) body() ([]byte, error) {
+	if spec.FS != nil {
+		return fs.ReadFile(spec.FS, spec.Path)
+	}
+	return json.Marshal(struct {
+		Settings json.RawMessage `json:"settings,omitempty"`
+		Mappings json.RawMessage `json:"mappings,omitempty"`
+	}{
+		Settings: spec.Settings,
+		Mappings: spec.Mappings,
+	})
+}
+
+// WithIndices creates the given indices, keyed by name, after templates
+// are installed and before any post-start operations run. The indices
+// are named as returned by Index. Start fails with
+// the error returned by Elasticsearch if e.g. the mappings are invalid.
+func WithIndices(indices map[string]IndexSpec) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.setup = append(cfg.setup, func(ctx context.Context, tb testing.TB, c *Container) error {
+			names := make([]string, 0, len(indices))
+			for name := range indices {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				if err := CreateIndex(ctx, c.Client(), c.Index(name), indices[name]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+}
+
+// CreateIndex creates an index with the given settings and mappings.
+func CreateIndex(ctx context.Context, es *elasticsearch.Client, name string, spec IndexSpec) error {
+	body, err := spec.body()
+	if err != nil {
+		return fmt.Errorf("could not read spec of index %s: %w", name, err)
+	}
+	res, err := es.Indices.Create(name,
+		es.Indices.Create.WithContext(ctx),
+		es.Indices.Create.WithBody(bytes.NewReader(body)),
+	)
+	if err := ParseError(res, err); err != nil {
+		return fmt.Errorf("could not create index %s: %w", name, err)
+	}
+	return res.Body.Close()
+}
+
+// Refresh refreshes the given indices, making all operations performed
+// on them visible to search. Without indices, all indices are refreshed.
+func Refresh(ctx context.Context, es *elasticsearch.Client, indices ...string) error {
+	res, err := es.Indices.Refresh(
+		es.Indices.Refresh.WithContext(ctx),
+		es.Indices.Refresh.WithIndex(indices...),
+	)
+	if err := ParseError(res, err); err != nil {
+		return fmt.Errorf("could not refresh %s: %w", indexNames(indices), err)
+	}
+	return res.Body.Close()
+}
+
+// Flush flushes the given indices, writing all data in the transaction log
+// to the index. Without indices, all indices are flushed.
+func Flush(ctx context.Context, es *elasticsearch.Client, indices ...string) error {
+	res, err := es.Indices.Flush(
+		es.Indices.Flush.WithContext(ctx),
+		es.Indices.Flush.WithIndex(indices...),
+	)
+	if err := ParseError(res, err); err != nil {
+		return fmt.Errorf("could not flush %s: %w", indexNames(indices), err)
+	}
+	return res.Body.Close()
+}
+
+// indexNames returns the given indices for use in error messages.
+func indexNames(indices []string) string {
+	if len(indices) == 0 {
+		return "all indices"
+	}
+	return strings.Join(indices, ",")
+}
@@ -0,0 +1,93 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestWithIndices(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithIndices(map[string]elasticsearch.IndexSpec{
+			"users": {
+				Settings: json.RawMessage(`{"number_of_replicas": 0}`),
+				Mappings: json.RawMessage(`{"properties": {"email": {"type": "keyword"}}}`),
+			},
+			"products": {
+				FS:   os.DirFS("testdata"),
+				Path: "indices/products.json",
+			},
+		}),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	es := c.Client()
+	for _, index := range []string{"users", "products"} {
+		res, err := es.Indices.Exists([]string{index}, es.Indices.Exists.WithContext(ctx))
+		if err := elasticsearch.ParseError(res, err); err != nil {
+			t.Fatalf("could not check index %s: %v", index, err)
+		}
+		res.Body.Close()
+	}
+
+	// Invalid mappings return the error from Elasticsearch
+	err := elasticsearch.CreateIndex(ctx, es, "invalid", elasticsearch.IndexSpec{
+		Mappings: json.RawMessage(`{"properties": {"email": {"type": "no-such-type"}}}`),
+	})
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if want, have := "mapper_parsing_exception", errorType(err); want != have {
+		t.Fatalf("want error type %q, have %q (%v)", want, have, err)
+	}
+}
+
+// errorType returns the type of an Elasticsearch error.
+func errorType(err error) string {
+	var e *elasticsearch.Error
+	if !errors.As(err, &e) || e.Details == nil {
+		return ""
+	}
+	return e.Details.Type
+}
+
+func TestRefreshAndFlush(t *testing.T) {
+	c := elasticsearch.Start(t, elasticsearch.WithTimeout(60*time.Second))
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	es := c.Client()
+	res, err := es.Index("users", strings.NewReader(`{"name":"Alice"}`),
+		es.Index.WithContext(ctx),
+		es.Index.WithRefresh("false"),
+	)
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not index document: %v", err)
+	}
+	res.Body.Close()
+
+	if err := elasticsearch.Refresh(ctx, es, "users"); err != nil {
+		t.Fatalf("could not refresh: %v", err)
+	}
+	if err := elasticsearch.Flush(ctx, es, "users"); err != nil {
+		t.Fatalf("could not flush: %v", err)
+	}
+
+	// Refreshing a missing index returns the error from Elasticsearch
+	err = elasticsearch.Refresh(ctx, es, "no-such-index")
+	if want, have := "index_not_found_exception", errorType(err); want != have {
+		t.Fatalf("want error type %q, have %q (%v)", want, have, err)
+	}
+}
@@ -0,0 +1,129 @@
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/lifecycle"
+	"github.com/olivere/integrationtest/kube"
+)
+
+// useKube returns true if the node runs as a pod in Kubernetes, see
+// kube.Enabled. Like with useExternalURL, clusters that need containers
+// never do.
+func (cfg startConfig) useKube() bool {
+	return kube.Enabled() && !cfg.toxiproxy && !cfg.container
+}
+
+// kubeUnsupported returns the options of cfg that need Docker, and hence
+// fail with a pod.
+func (cfg startConfig) kubeUnsupported() []string {
+	var options []string
+	if cfg.tls {
+		options = append(options, "WithTLS")
+	}
+	if len(cfg.plugins) > 0 {
+		options = append(options, "WithPlugins")
+	}
+	if len(cfg.keystore) > 0 {
+		options = append(options, "WithKeystoreSettings")
+	}
+	if len(cfg.configFiles) > 0 {
+		options = append(options, "WithConfigFiles")
+	}
+	if cfg.hostPort != 0 {
+		options = append(options, "WithHostPort")
+	}
+	if cfg.logWriter != nil {
+		options = append(options, "WithLogWriter")
+	}
+	if cfg.logsToTesting {
+		options = append(options, "WithLogsToTesting")
+	}
+	if cfg.exportDir != "" {
+		options = append(options, "WithExportOnFailure")
+	}
+	if cfg.network != nil || len(cfg.attachments) > 0 {
+		options = append(options, "WithNetwork")
+	}
+	if cfg.build != nil {
+		options = append(options, "WithBuiltImage")
+	}
+	return options
+}
+
+// startKube starts a single node as a pod instead of a container, and
+// connects to it via its service. Resource limits have no effect on pods.
+func (c *Container) startKube(ctx context.Context, startCfg startConfig) error {
+	if options := startCfg.kubeUnsupported(); len(options) > 0 {
+		return fmt.Errorf("elasticsearch: %s not supported with %s=kube", strings.Join(options, ", "), kube.BackendEnv)
+	}
+
+	env := mergeEnv(append([]string{"node.name=" + startCfg.clusterName}, startCfg.nodeEnv([]string{startCfg.clusterName})...), startCfg.env)
+	repository, tag := startCfg.image()
+	lifetime := startCfg.maxLifetime
+	if c.keep {
+		lifetime = -1
+	}
+
+	started := time.Now()
+	var err error
+	c.pod, err = kube.Run(ctx, repository+":"+tag,
+		kube.WithOptions(core.WithRetryPolicy(c.retryPolicy.MaxAttempts, c.retryPolicy.InitialBackoff, c.retryPolicy.MaxBackoff)),
+		kube.WithEnv(env...),
+		kube.WithPorts(9200),
+		kube.WithTimeout(c.timeout),
+		kube.WithMaxLifetime(lifetime),
+	)
+	if err != nil {
+		lifecycle.Log(c.logger, "elasticsearch", lifecycle.Start, started, err)
+		return fmt.Errorf("unable to start Elasticsearch pod: %w", err)
+	}
+	lifecycle.Log(c.logger, "elasticsearch", lifecycle.Start, started, nil, "pod", c.pod.Name())
+
+	c.hostPort = c.pod.Address(9200)
+	c.url = "http://" + c.hostPort
+	c.urls = []string{c.url}
+
+	var connectOptions []connectOption
+	if startCfg.security {
+		c.username = "elastic"
+		c.password = startCfg.password
+		connectOptions = append(connectOptions, WithUsername(c.username), WithPassword(c.password))
+	}
+	connectOptions = append(connectOptions, startCfg.clientOptions...)
+	if err := c.connect(connectOptions...); err != nil {
+		return fmt.Errorf("could not connect to Elasticsearch pod: %w", err)
+	}
+	waitFor := startCfg.waitFor
+	if len(waitFor) == 0 {
+		waitFor = []WaitStrategy{ForHTTPStatus("/", http.StatusOK)}
+	}
+	c.waitFor = waitFor
+	err = c.waitUntilReady(ctx, waitFor)
+	lifecycle.Log(c.logger, "elasticsearch", lifecycle.Ready, started, err, "pod", c.pod.Name())
+	if err != nil {
+		return fmt.Errorf("could not wait for Elasticsearch pod: %w", err)
+	}
+	return c.setUp(ctx, startCfg)
+}
+
+// closeKube deletes the pod, unless it is kept.
+func (c *Container) closeKube() error {
+	defer c.closeTransport()
+
+	c.closed = true
+	if c.keep {
+		lifecycle.Kept(os.Stderr, "elasticsearch", c.url, c.pod.Name())
+		return nil
+	}
+	closeStart := time.Now()
+	err := c.pod.Close()
+	lifecycle.Log(c.logger, "elasticsearch", lifecycle.Close, closeStart, err, "pod", c.pod.Name())
+	return err
+}
@@ -0,0 +1,36 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+	"github.com/olivere/integrationtest/kube"
+)
+
+// withoutKubeconfig makes the tests find no Kubernetes cluster.
+func withoutKubeconfig(t *testing.T) {
+	t.Setenv(kube.BackendEnv, "kube")
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("KUBECONFIG", "")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+}
+
+func TestRun_Kube(t *testing.T) {
+	withoutKubeconfig(t)
+
+	_, err := elasticsearch.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "Elasticsearch pod") {
+		t.Fatalf("want to start a pod instead of a container, have %v", err)
+	}
+}
+
+func TestRun_KubeUnsupported(t *testing.T) {
+	withoutKubeconfig(t)
+
+	_, err := elasticsearch.Run(context.Background(), elasticsearch.WithTLS())
+	if err == nil || !strings.Contains(err.Error(), "WithTLS") {
+		t.Fatalf("want WithTLS to be unsupported, have %v", err)
+	}
+}
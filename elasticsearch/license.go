@@ -0,0 +1,50 @@
+package elasticsearch
+
+import (
+	"strconv"
+	"strings"
+)
+
+// WithTrialLicense starts Elasticsearch with a self-generated 30-day trial
+// license instead of a basic license, enabling features like machine
+// learning, cross-cluster replication, or the enrich processor.
+func WithTrialLicense() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.license = "trial"
+	}
+}
+
+// WithTrialFeatures is a preset to test ingest pipelines that use the
+// attachment and inference processors. It enables a trial license (see
+// WithTrialLicense), the ingest-attachment plugin for versions that do not
+// bundle it as a module, i.e. before 8.4, and machine learning with a
+// memory limit of 2GB.
+func WithTrialFeatures() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.license = "trial"
+		cfg.attachment = true
+		cfg.memory = max(cfg.memory, 2*1024*1024*1024) // 2GB
+		cfg.env = append(cfg.env,
+			"xpack.ml.enabled=true",
+			"xpack.ml.use_auto_machine_memory_percent=true",
+		)
+	}
+}
+
+// versionBefore returns true if version, e.g. "8.12.2", is before
+// major.minor. Versions that cannot be parsed are considered recent.
+func versionBefore(version string, major, minor int) bool {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return false
+	}
+	vmajor, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return false
+	}
+	vminor, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+	return vmajor < major || (vmajor == major && vminor < minor)
+}
@@ -0,0 +1,67 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestContainer_WithTrialFeatures(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(90*time.Second),
+		elasticsearch.WithTrialFeatures(),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	es := c.Client()
+	res, err := es.License.Get(es.License.Get.WithContext(ctx))
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not get license: %v", err)
+	}
+	defer res.Body.Close()
+	var license struct {
+		License struct {
+			Type string `json:"type"`
+		} `json:"license"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&license); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "trial", license.License.Type; want != have {
+		t.Fatalf("want license %q, have %q", want, have)
+	}
+
+	// The attachment processor extracts text ("Hello" in base64)
+	res, err = es.Ingest.Simulate(strings.NewReader(`{
+		"pipeline": {"processors": [{"attachment": {"field": "data"}}]},
+		"docs": [{"_source": {"data": "SGVsbG8="}}]
+	}`), es.Ingest.Simulate.WithContext(ctx))
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not simulate attachment pipeline: %v", err)
+	}
+	defer res.Body.Close()
+	var simulated struct {
+		Docs []struct {
+			Doc struct {
+				Source struct {
+					Attachment struct {
+						Content string `json:"content"`
+					} `json:"attachment"`
+				} `json:"_source"`
+			} `json:"doc"`
+		} `json:"docs"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&simulated); err != nil {
+		t.Fatal(err)
+	}
+	if len(simulated.Docs) != 1 || simulated.Docs[0].Doc.Source.Attachment.Content != "Hello" {
+		t.Fatalf("want extracted content %q, have %+v", "Hello", simulated.Docs)
+	}
+}
@@ -0,0 +1,37 @@
+package elasticsearch
+
+import (
+	"testing"
+)
+
+// StartMatrix runs fn as a subtest for each of the given Elasticsearch
+// versions, e.g. []string{"7.17.18", "8.12.2"}. Each subtest starts its own
+// container with the given options and the version set via WithVersion.
+//
+// If tb is a *testing.T, the subtests run in parallel. Benchmarks run
+// the versions sequentially.
+func StartMatrix(tb testing.TB, versions []string, fn func(tb testing.TB, c *Container), options ...startConfigFunc) {
+	tb.Helper()
+
+	if len(versions) == 0 {
+		tb.Fatal("no Elasticsearch versions given")
+	}
+
+	for _, version := range versions {
+		opts := append(append([]startConfigFunc{}, options...), WithVersion(version))
+
+		switch tb := tb.(type) {
+		case *testing.T:
+			tb.Run(version, func(t *testing.T) {
+				t.Parallel()
+				fn(t, Start(t, opts...))
+			})
+		case *testing.B:
+			tb.Run(version, func(b *testing.B) {
+				fn(b, Start(b, opts...))
+			})
+		default:
+			tb.Fatalf("StartMatrix requires a *testing.T or *testing.B, got %T", tb)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestStartMatrix(t *testing.T) {
+	elasticsearch.StartMatrix(t, []string{"7.17.18", "8.12.2"}, func(tb testing.TB, c *elasticsearch.Container) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		es := c.Client()
+		res, err := es.Info(es.Info.WithContext(ctx))
+		if err := elasticsearch.ParseError(res, err); err != nil {
+			tb.Fatalf("could not get info: %v", err)
+		}
+		defer res.Body.Close()
+
+		var info struct {
+			Version struct {
+				Number string `json:"number"`
+			} `json:"version"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&info); err != nil {
+			tb.Fatal(err)
+		}
+
+		name := tb.Name()
+		if want, have := name[strings.LastIndex(name, "/")+1:], info.Version.Number; want != have {
+			tb.Fatalf("want version %q, have %q", want, have)
+		}
+	}, elasticsearch.WithTimeout(60*time.Second))
+}
@@ -0,0 +1,105 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// PercolateMatch is a stored query that matches percolated documents.
+type PercolateMatch[T any] struct {
+	// ID of the document that stores the query.
+	ID string
+	// Score of the match.
+	Score float64
+	// Slots are the positions of the percolated documents that the query
+	// matches.
+	Slots []int
+	// Source is the document that stores the query.
+	Source T
+}
+
+// PutPercolatorQuery stores query, e.g. map[string]any{"match":
+// map[string]any{"message": "error"}}, in the percolator field of the
+// document with the given id in index. The document is visible to Percolate
+// when PutPercolatorQuery returns. Use fields to store additional fields
+// with the query, e.g. the name of an alert.
+func PutPercolatorQuery(ctx context.Context, es *elasticsearch.Client, index, field, id string, query map[string]any, fields map[string]any) error {
+	doc := make(map[string]any, len(fields)+1)
+	for k, v := range fields {
+		doc[k] = v
+	}
+	doc[field] = query
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	res, err := es.Index(index, bytes.NewReader(body),
+		es.Index.WithContext(ctx),
+		es.Index.WithDocumentID(id),
+		es.Index.WithRefresh("wait_for"),
+	)
+	if err := ParseError(res, err); err != nil {
+		return fmt.Errorf("could not store percolator query %s: %w", id, err)
+	}
+	res.Body.Close()
+	return nil
+}
+
+// Percolate returns the stored queries in the percolator field of index
+// that match any of documents, ordered by score. The sources of the
+// documents storing the queries are decoded into T.
+func Percolate[T any](ctx context.Context, es *elasticsearch.Client, index, field string, documents ...any) ([]PercolateMatch[T], error) {
+	body, err := json.Marshal(map[string]any{
+		"query": map[string]any{
+			"percolate": map[string]any{
+				"field":     field,
+				"documents": documents,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := es.Search(
+		es.Search.WithContext(ctx),
+		es.Search.WithIndex(index),
+		es.Search.WithBody(bytes.NewReader(body)),
+	)
+	if err := ParseError(res, err); err != nil {
+		return nil, fmt.Errorf("could not percolate: %w", err)
+	}
+	defer res.Body.Close()
+
+	var resp struct {
+		Hits struct {
+			Hits []struct {
+				ID     string  `json:"_id"`
+				Score  float64 `json:"_score"`
+				Source T       `json:"_source"`
+				Fields struct {
+					Slots []int `json:"_percolator_document_slot"`
+				} `json:"fields"`
+			} `json:"hits"`
+		} `json:"hits"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("could not decode percolate response: %w", err)
+	}
+
+	matches := make([]PercolateMatch[T], 0, len(resp.Hits.Hits))
+	for _, hit := range resp.Hits.Hits {
+		matches = append(matches, PercolateMatch[T]{
+			ID:     hit.ID,
+			Score:  hit.Score,
+			Slots:  hit.Fields.Slots,
+			Source: hit.Source,
+		})
+	}
+	return matches, nil
+}
@@ -0,0 +1,67 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestPercolate(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithIndices(map[string]elasticsearch.IndexSpec{
+			"alerts": {
+				Mappings: json.RawMessage(`{
+					"properties": {
+						"query": {"type": "percolator"},
+						"name": {"type": "keyword"},
+						"message": {"type": "text"}
+					}
+				}`),
+			},
+		}),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	es := c.Client()
+	alerts := map[string]string{"errors": "error", "timeouts": "timeout"}
+	for id, term := range alerts {
+		err := elasticsearch.PutPercolatorQuery(ctx, es, "alerts", "query", id,
+			map[string]any{"match": map[string]any{"message": term}},
+			map[string]any{"name": "Alert on " + term},
+		)
+		if err != nil {
+			t.Fatalf("could not store percolator query: %v", err)
+		}
+	}
+
+	type alert struct {
+		Name string `json:"name"`
+	}
+	matches, err := elasticsearch.Percolate[alert](ctx, es, "alerts", "query",
+		map[string]any{"message": "all good"},
+		map[string]any{"message": "error: connection refused"},
+	)
+	if err != nil {
+		t.Fatalf("could not percolate: %v", err)
+	}
+	if want, have := 1, len(matches); want != have {
+		t.Fatalf("want %d match, have %d", want, have)
+	}
+	if want, have := "errors", matches[0].ID; want != have {
+		t.Fatalf("want match %q, have %q", want, have)
+	}
+	if want, have := "Alert on error", matches[0].Source.Name; want != have {
+		t.Fatalf("want name %q, have %q", want, have)
+	}
+	if want, have := []int{1}, matches[0].Slots; !slices.Equal(want, have) {
+		t.Fatalf("want slots %v, have %v", want, have)
+	}
+}
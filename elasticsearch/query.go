@@ -0,0 +1,128 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// Column is a column of the result of an ES|QL or SQL query.
+type Column struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ESQL runs the ES|QL query and scans the rows of its result into dst,
+// which must be a pointer to a slice. Each row is decoded like a JSON object
+// with the column names as keys, so struct fields are matched by their
+// json tags, e.g. `json:"user.name"` for the column user.name. ESQL returns
+// the columns of the result.
+func ESQL(ctx context.Context, es *elasticsearch.Client, query string, dst any) ([]Column, error) {
+	body, err := json.Marshal(map[string]any{"query": query})
+	if err != nil {
+		return nil, err
+	}
+	res, err := es.EsqlQuery(bytes.NewReader(body),
+		es.EsqlQuery.WithContext(ctx),
+		es.EsqlQuery.WithFormat("json"),
+	)
+	if err := ParseError(res, err); err != nil {
+		return nil, fmt.Errorf("could not run ES|QL query: %w", err)
+	}
+	defer res.Body.Close()
+
+	var resp struct {
+		Columns []Column            `json:"columns"`
+		Values  [][]json.RawMessage `json:"values"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("could not decode ES|QL response: %w", err)
+	}
+	if err := scanRows(resp.Columns, resp.Values, dst); err != nil {
+		return nil, err
+	}
+	return resp.Columns, nil
+}
+
+// SQL runs the Elasticsearch SQL query and scans the rows of its result
+// into dst, like ESQL does. SQL follows the cursor of the result, so dst
+// receives all rows, not just the first page.
+func SQL(ctx context.Context, es *elasticsearch.Client, query string, dst any) ([]Column, error) {
+	var (
+		columns []Column
+		rows    [][]json.RawMessage
+		req     = map[string]any{"query": query}
+	)
+	for {
+		body, err := json.Marshal(req)
+		if err != nil {
+			return nil, err
+		}
+		res, err := es.SQL.Query(bytes.NewReader(body),
+			es.SQL.Query.WithContext(ctx),
+			es.SQL.Query.WithFormat("json"),
+		)
+		if err := ParseError(res, err); err != nil {
+			return nil, fmt.Errorf("could not run SQL query: %w", err)
+		}
+		var resp struct {
+			Columns []Column            `json:"columns"`
+			Rows    [][]json.RawMessage `json:"rows"`
+			Cursor  string              `json:"cursor"`
+		}
+		err = json.NewDecoder(res.Body).Decode(&resp)
+		res.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not decode SQL response: %w", err)
+		}
+		if columns == nil {
+			// Only the first page has columns
+			columns = resp.Columns
+		}
+		rows = append(rows, resp.Rows...)
+		if resp.Cursor == "" {
+			break
+		}
+		req = map[string]any{"cursor": resp.Cursor}
+	}
+	if err := scanRows(columns, rows, dst); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// scanRows decodes rows into dst, a pointer to a slice, by decoding each
+// row as a JSON object keyed by the column names.
+func scanRows(columns []Column, rows [][]json.RawMessage, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Slice {
+		return errors.New("elasticsearch: dst must be a pointer to a slice")
+	}
+	slice := v.Elem()
+	slice.SetLen(0)
+
+	for i, row := range rows {
+		if len(row) != len(columns) {
+			return fmt.Errorf("row %d has %d values, want %d", i, len(row), len(columns))
+		}
+		obj := make(map[string]json.RawMessage, len(columns))
+		for j, col := range columns {
+			obj[col.Name] = row[j]
+		}
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return err
+		}
+		elem := reflect.New(slice.Type().Elem())
+		if err := json.Unmarshal(data, elem.Interface()); err != nil {
+			return fmt.Errorf("could not scan row %d: %w", i, err)
+		}
+		slice.Set(reflect.Append(slice, elem.Elem()))
+	}
+	return nil
+}
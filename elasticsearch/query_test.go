@@ -0,0 +1,68 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+type user struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+func TestESQL(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithBulkFixtures(os.DirFS("testdata"), "fixtures/*.ndjson"),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var users []user
+	columns, err := elasticsearch.ESQL(ctx, c.Client(), `FROM users | SORT name.keyword | KEEP name, email`, &users)
+	if err != nil {
+		t.Fatalf("could not run query: %v", err)
+	}
+	if want, have := 2, len(columns); want != have {
+		t.Fatalf("want %d columns, have %d", want, have)
+	}
+	if want, have := 3, len(users); want != have {
+		t.Fatalf("want %d users, have %d", want, have)
+	}
+	if want, have := (user{Name: "Alice", Email: "alice@example.com"}), users[0]; want != have {
+		t.Fatalf("want %+v, have %+v", want, have)
+	}
+}
+
+func TestSQL(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithBulkFixtures(os.DirFS("testdata"), "fixtures/*.ndjson"),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var users []user
+	if _, err := elasticsearch.SQL(ctx, c.Client(), `SELECT name, email FROM users ORDER BY email`, &users); err != nil {
+		t.Fatalf("could not run query: %v", err)
+	}
+	if want, have := 3, len(users); want != have {
+		t.Fatalf("want %d users, have %d", want, have)
+	}
+	if want, have := (user{Name: "Alice", Email: "alice@example.com"}), users[0]; want != have {
+		t.Fatalf("want %+v, have %+v", want, have)
+	}
+
+	// dst must be a pointer to a slice
+	if _, err := elasticsearch.SQL(ctx, c.Client(), `SELECT name FROM users`, users); err == nil {
+		t.Fatal("want error for non-pointer dst")
+	}
+}
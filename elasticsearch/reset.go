@@ -0,0 +1,174 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/elastic/go-elasticsearch/v8/esapi"
+)
+
+// Reset deletes all data streams, indices, index templates, component
+// templates, and ingest pipelines, except system resources (with a name
+// starting with a dot) and those managed by Elasticsearch itself. Use it
+// to reuse a cached or shared container across tests with a clean state.
+//
+// On an external cluster, Reset only deletes the data streams and indices
+// with the prefix of the container; see Index.
+func (c *Container) Reset(ctx context.Context) error {
+	es := c.Client()
+
+	// Data streams, including their backing indices
+	{
+		res, err := es.Indices.GetDataStream(
+			es.Indices.GetDataStream.WithContext(ctx),
+			es.Indices.GetDataStream.WithExpandWildcards("all"),
+		)
+		var resp struct {
+			DataStreams []struct {
+				Name string `json:"name"`
+			} `json:"data_streams"`
+		}
+		if err := decodeResponse(res, err, &resp); err != nil {
+			return fmt.Errorf("could not list data streams: %w", err)
+		}
+		var names []string
+		for _, ds := range resp.DataStreams {
+			if c.ownsIndex(ds.Name) {
+				names = append(names, ds.Name)
+			}
+		}
+		if len(names) > 0 {
+			res, err := es.Indices.DeleteDataStream(names, es.Indices.DeleteDataStream.WithContext(ctx))
+			if err := closeResponse(res, err); err != nil {
+				return fmt.Errorf("could not delete data streams: %w", err)
+			}
+		}
+	}
+
+	// Indices
+	{
+		names, err := c.userIndices(ctx)
+		if err != nil {
+			return fmt.Errorf("could not list indices: %w", err)
+		}
+		// Delete in chunks to keep the URL short
+		for len(names) > 0 {
+			n := min(len(names), 100)
+			res, err := es.Indices.Delete(names[:n], es.Indices.Delete.WithContext(ctx))
+			if err := closeResponse(res, err); err != nil {
+				return fmt.Errorf("could not delete indices: %w", err)
+			}
+			names = names[n:]
+		}
+	}
+
+	// Templates and pipelines of an external cluster are shared
+	if c.external {
+		return nil
+	}
+
+	// Index templates, before the component templates they depend on
+	{
+		res, err := es.Indices.GetIndexTemplate(es.Indices.GetIndexTemplate.WithContext(ctx))
+		var resp struct {
+			IndexTemplates []struct {
+				Name          string      `json:"name"`
+				IndexTemplate managedMeta `json:"index_template"`
+			} `json:"index_templates"`
+		}
+		if err := decodeResponse(res, err, &resp); err != nil {
+			return fmt.Errorf("could not list index templates: %w", err)
+		}
+		for _, t := range resp.IndexTemplates {
+			if isSystemName(t.Name) || t.IndexTemplate.Meta.Managed {
+				continue
+			}
+			res, err := es.Indices.DeleteIndexTemplate(t.Name, es.Indices.DeleteIndexTemplate.WithContext(ctx))
+			if err := closeResponse(res, err); err != nil {
+				return fmt.Errorf("could not delete index template %s: %w", t.Name, err)
+			}
+		}
+	}
+
+	// Component templates
+	{
+		res, err := es.Cluster.GetComponentTemplate(es.Cluster.GetComponentTemplate.WithContext(ctx))
+		var resp struct {
+			ComponentTemplates []struct {
+				Name              string      `json:"name"`
+				ComponentTemplate managedMeta `json:"component_template"`
+			} `json:"component_templates"`
+		}
+		if err := decodeResponse(res, err, &resp); err != nil {
+			return fmt.Errorf("could not list component templates: %w", err)
+		}
+		for _, t := range resp.ComponentTemplates {
+			if isSystemName(t.Name) || t.ComponentTemplate.Meta.Managed {
+				continue
+			}
+			res, err := es.Cluster.DeleteComponentTemplate(t.Name, es.Cluster.DeleteComponentTemplate.WithContext(ctx))
+			if err := closeResponse(res, err); err != nil {
+				return fmt.Errorf("could not delete component template %s: %w", t.Name, err)
+			}
+		}
+	}
+
+	// Ingest pipelines
+	{
+		res, err := es.Ingest.GetPipeline(es.Ingest.GetPipeline.WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("could not list ingest pipelines: %w", err)
+		}
+		var resp map[string]managedMeta
+		if IsNotFound(res) {
+			// No pipelines at all
+			res.Body.Close()
+		} else if err := decodeResponse(res, err, &resp); err != nil {
+			return fmt.Errorf("could not list ingest pipelines: %w", err)
+		}
+		for name, p := range resp {
+			if isSystemName(name) || p.Meta.Managed {
+				continue
+			}
+			res, err := es.Ingest.DeletePipeline(name, es.Ingest.DeletePipeline.WithContext(ctx))
+			if err := closeResponse(res, err); err != nil {
+				return fmt.Errorf("could not delete ingest pipeline %s: %w", name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// managedMeta is used to check whether a resource is managed by
+// Elasticsearch itself, i.e. has "_meta": {"managed": true}.
+type managedMeta struct {
+	Meta struct {
+		Managed bool `json:"managed"`
+	} `json:"_meta"`
+}
+
+// isSystemName returns true for names of system resources.
+func isSystemName(name string) bool {
+	return strings.HasPrefix(name, ".")
+}
+
+// decodeResponse decodes the body of a successful response into v,
+// and closes it.
+func decodeResponse(res *esapi.Response, err error, v any) error {
+	if err := ParseError(res, err); err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// closeResponse returns the error of a response, and closes its body.
+func closeResponse(res *esapi.Response, err error) error {
+	if err := ParseError(res, err); err != nil {
+		return err
+	}
+	return res.Body.Close()
+}
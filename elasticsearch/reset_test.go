@@ -0,0 +1,69 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestContainer_Reset(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithIndexTemplates(os.DirFS("testdata"), "templates/*.json"),
+		elasticsearch.WithBulkFixtures(os.DirFS("testdata"), "fixtures/*.ndjson"),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	es := c.Client()
+	res, err := es.Ingest.PutPipeline("lowercase", strings.NewReader(`{
+		"processors": [{"lowercase": {"field": "name"}}]
+	}`), es.Ingest.PutPipeline.WithContext(ctx))
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not put pipeline: %v", err)
+	}
+	res.Body.Close()
+
+	if err := c.Reset(ctx); err != nil {
+		t.Fatalf("could not reset: %v", err)
+	}
+
+	// All user indices are gone
+	res, err = es.Cat.Indices(es.Cat.Indices.WithContext(ctx), es.Cat.Indices.WithFormat("json"))
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not list indices: %v", err)
+	}
+	defer res.Body.Close()
+	var indices []struct {
+		Index string `json:"index"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&indices); err != nil {
+		t.Fatal(err)
+	}
+	for _, index := range indices {
+		if !strings.HasPrefix(index.Index, ".") {
+			t.Errorf("index %s still exists", index.Index)
+		}
+	}
+
+	// Templates and pipelines are gone
+	res, err = es.Indices.GetIndexTemplate(es.Indices.GetIndexTemplate.WithContext(ctx), es.Indices.GetIndexTemplate.WithName("articles"))
+	if !elasticsearch.IsNotFound(res) {
+		t.Errorf("index template still exists: %v", elasticsearch.ParseError(res, err))
+	}
+	res, err = es.Cluster.GetComponentTemplate(es.Cluster.GetComponentTemplate.WithContext(ctx), es.Cluster.GetComponentTemplate.WithName("mappings"))
+	if !elasticsearch.IsNotFound(res) {
+		t.Errorf("component template still exists: %v", elasticsearch.ParseError(res, err))
+	}
+	res, err = es.Ingest.GetPipeline(es.Ingest.GetPipeline.WithContext(ctx), es.Ingest.GetPipeline.WithPipelineID("lowercase"))
+	if !elasticsearch.IsNotFound(res) {
+		t.Errorf("pipeline still exists: %v", elasticsearch.ParseError(res, err))
+	}
+}
@@ -0,0 +1,105 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// errSecurityDisabled is returned by the security helpers of a container
+// that has security disabled.
+var errSecurityDisabled = errors.New("elasticsearch: security is not enabled, use WithSecurity")
+
+// CreateUser creates the native user with the given password and roles,
+// and returns a client that authenticates as that user. It requires
+// security to be enabled, see WithSecurity.
+func (c *Container) CreateUser(ctx context.Context, username, password string, roles ...string) (*elasticsearch.Client, error) {
+	if c.username == "" {
+		return nil, errSecurityDisabled
+	}
+	if roles == nil {
+		roles = []string{}
+	}
+	body, err := json.Marshal(map[string]any{
+		"password": password,
+		"roles":    roles,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	es := c.Client()
+	res, err := es.Security.PutUser(username, bytes.NewReader(body),
+		es.Security.PutUser.WithContext(ctx),
+		es.Security.PutUser.WithRefresh("wait_for"),
+	)
+	if err := ParseError(res, err); err != nil {
+		return nil, fmt.Errorf("could not create user %s: %w", username, err)
+	}
+	res.Body.Close()
+
+	return c.newClient(WithUsername(username), WithPassword(password))
+}
+
+// CreateRole creates the role with the given name. The spec is the body
+// of the Create Role API, e.g. {"indices": [{"names": ["users"],
+// "privileges": ["read"], "query": {"term": {"team": "a"}}}]} to test
+// document-level security. It requires security to be enabled, see
+// WithSecurity.
+func (c *Container) CreateRole(ctx context.Context, name string, spec json.RawMessage) error {
+	if c.username == "" {
+		return errSecurityDisabled
+	}
+
+	es := c.Client()
+	res, err := es.Security.PutRole(name, bytes.NewReader(spec),
+		es.Security.PutRole.WithContext(ctx),
+		es.Security.PutRole.WithRefresh("wait_for"),
+	)
+	if err := ParseError(res, err); err != nil {
+		return fmt.Errorf("could not create role %s: %w", name, err)
+	}
+	res.Body.Close()
+	return nil
+}
+
+// CreateAPIKey creates an API key with the given name and returns a client
+// that authenticates with it. The key has the privileges of the superuser
+// of the container, limited by roleDescriptors if it is not nil (see the
+// "role_descriptors" of the Create API Key API). It requires security to
+// be enabled, see WithSecurity.
+func (c *Container) CreateAPIKey(ctx context.Context, name string, roleDescriptors json.RawMessage) (*elasticsearch.Client, error) {
+	if c.username == "" {
+		return nil, errSecurityDisabled
+	}
+	req := map[string]any{"name": name}
+	if roleDescriptors != nil {
+		req["role_descriptors"] = roleDescriptors
+	}
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	es := c.Client()
+	res, err := es.Security.CreateAPIKey(bytes.NewReader(body),
+		es.Security.CreateAPIKey.WithContext(ctx),
+		es.Security.CreateAPIKey.WithRefresh("wait_for"),
+	)
+	if err := ParseError(res, err); err != nil {
+		return nil, fmt.Errorf("could not create API key %s: %w", name, err)
+	}
+	defer res.Body.Close()
+
+	var resp struct {
+		Encoded string `json:"encoded"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("could not decode API key: %w", err)
+	}
+	return c.newClient(WithAPIKey(resp.Encoded))
+}
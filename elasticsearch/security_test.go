@@ -0,0 +1,64 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	esv8 "github.com/elastic/go-elasticsearch/v8"
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestContainer_CreateUserAndAPIKey(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithSecurity("s3cr3t-passw0rd"),
+		elasticsearch.WithBulkFixtures(os.DirFS("testdata"), "fixtures/*.ndjson"),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Document-level security: alice may only see her own document
+	err := c.CreateRole(ctx, "alice_only", json.RawMessage(`{
+		"indices": [{
+			"names": ["users"],
+			"privileges": ["read"],
+			"query": {"term": {"email.keyword": "alice@example.com"}}
+		}]
+	}`))
+	if err != nil {
+		t.Fatalf("could not create role: %v", err)
+	}
+	alice, err := c.CreateUser(ctx, "alice", "alice-passw0rd", "alice_only")
+	if err != nil {
+		t.Fatalf("could not create user: %v", err)
+	}
+	if want, have := 1, countWith(t, alice, "users"); want != have {
+		t.Fatalf("want %d documents, have %d", want, have)
+	}
+
+	key, err := c.CreateAPIKey(ctx, "reader", nil)
+	if err != nil {
+		t.Fatalf("could not create API key: %v", err)
+	}
+	if want, have := 3, countWith(t, key, "users"); want != have {
+		t.Fatalf("want %d documents, have %d", want, have)
+	}
+}
+
+func countWith(t *testing.T, es *esv8.Client, index string) int {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	count, err := elasticsearch.Count(ctx, es, index, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
@@ -0,0 +1,178 @@
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// snapshotsPath is the directory in the container that is registered
+// as path.repo, i.e. where shared file system repositories are allowed.
+const snapshotsPath = "/usr/share/elasticsearch/snapshots"
+
+// RegisterFSRepo registers a shared file system repository with the given
+// name. The repository lives in the container and is removed with it.
+// Snapshot and Restore use the repository that is registered last.
+//
+// Shared file system repositories need a location shared by all nodes,
+// so they don't work with clusters started by StartCluster.
+func (c *Container) RegisterFSRepo(ctx context.Context, name string) error {
+	es := c.Client()
+	body := fmt.Sprintf(`{"type":"fs","settings":{"location":%q}}`, path.Join(snapshotsPath, name))
+	res, err := es.Snapshot.CreateRepository(name, strings.NewReader(body),
+		es.Snapshot.CreateRepository.WithContext(ctx),
+	)
+	if err := ParseError(res, err); err != nil {
+		return fmt.Errorf("could not register repository %s: %w", name, err)
+	}
+	if err := res.Body.Close(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.repo = name
+	c.mu.Unlock()
+	return nil
+}
+
+// Snapshot takes a snapshot with the given name of the given indices, or
+// of all indices if none are given, and waits for it to complete. Use it
+// to capture an expensive seeded state once and Restore it between tests.
+//
+// The snapshot is stored in the repository registered with RegisterFSRepo.
+// If no repository is registered yet, a repository named "integrationtest"
+// is registered first.
+func (c *Container) Snapshot(ctx context.Context, name string, indices ...string) error {
+	repo, err := c.snapshotRepo(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(struct {
+		Indices            string `json:"indices,omitempty"`
+		IncludeGlobalState bool   `json:"include_global_state"`
+	}{
+		Indices: strings.Join(indices, ","),
+	})
+	if err != nil {
+		return err
+	}
+
+	es := c.Client()
+	res, err := es.Snapshot.Create(repo, name,
+		es.Snapshot.Create.WithContext(ctx),
+		es.Snapshot.Create.WithBody(strings.NewReader(string(body))),
+		es.Snapshot.Create.WithWaitForCompletion(true),
+	)
+	if err := ParseError(res, err); err != nil {
+		return fmt.Errorf("could not create snapshot %s: %w", name, err)
+	}
+	defer res.Body.Close()
+
+	var resp struct {
+		Snapshot struct {
+			State    string `json:"state"`
+			Failures []struct {
+				Index  string `json:"index"`
+				Reason string `json:"reason"`
+			} `json:"failures"`
+		} `json:"snapshot"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return err
+	}
+	if resp.Snapshot.State != "SUCCESS" {
+		if len(resp.Snapshot.Failures) > 0 {
+			f := resp.Snapshot.Failures[0]
+			return fmt.Errorf("snapshot %s failed for index %s: %s", name, f.Index, f.Reason)
+		}
+		return fmt.Errorf("snapshot %s finished with state %s", name, resp.Snapshot.State)
+	}
+	return nil
+}
+
+// Restore restores all indices of the snapshot with the given name and
+// waits for it to complete. Existing indices in the snapshot are deleted
+// first, so any changes made since the snapshot are lost.
+func (c *Container) Restore(ctx context.Context, name string) error {
+	c.mu.Lock()
+	repo := c.repo
+	c.mu.Unlock()
+	if repo == "" {
+		return errors.New("no snapshot repository registered")
+	}
+
+	es := c.Client()
+
+	// Find the indices of the snapshot
+	res, err := es.Snapshot.Get(repo, []string{name}, es.Snapshot.Get.WithContext(ctx))
+	if err := ParseError(res, err); err != nil {
+		return fmt.Errorf("could not get snapshot %s: %w", name, err)
+	}
+	defer res.Body.Close()
+	var resp struct {
+		Snapshots []struct {
+			Indices []string `json:"indices"`
+		} `json:"snapshots"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		return err
+	}
+	var indices []string
+	for _, s := range resp.Snapshots {
+		indices = append(indices, s.Indices...)
+	}
+
+	// Indices must not exist or be closed to be restored
+	if len(indices) > 0 {
+		res, err := es.Indices.Delete(indices,
+			es.Indices.Delete.WithContext(ctx),
+			es.Indices.Delete.WithIgnoreUnavailable(true),
+		)
+		if err := closeResponse(res, err); err != nil {
+			return fmt.Errorf("could not delete indices of snapshot %s: %w", name, err)
+		}
+	}
+
+	res, err = es.Snapshot.Restore(repo, name,
+		es.Snapshot.Restore.WithContext(ctx),
+		es.Snapshot.Restore.WithWaitForCompletion(true),
+	)
+	if err := ParseError(res, err); err != nil {
+		return fmt.Errorf("could not restore snapshot %s: %w", name, err)
+	}
+	defer res.Body.Close()
+
+	var restore struct {
+		Snapshot struct {
+			Shards struct {
+				Failed int `json:"failed"`
+			} `json:"shards"`
+		} `json:"snapshot"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&restore); err != nil {
+		return err
+	}
+	if n := restore.Snapshot.Shards.Failed; n > 0 {
+		return fmt.Errorf("could not restore %d shard(s) of snapshot %s", n, name)
+	}
+	return nil
+}
+
+// snapshotRepo returns the repository to use for snapshots, registering
+// a default repository if there is none.
+func (c *Container) snapshotRepo(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	repo := c.repo
+	c.mu.Unlock()
+	if repo != "" {
+		return repo, nil
+	}
+	if err := c.RegisterFSRepo(ctx, "integrationtest"); err != nil {
+		return "", err
+	}
+	return "integrationtest", nil
+}
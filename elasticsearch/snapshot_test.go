@@ -0,0 +1,55 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestContainer_SnapshotRestore(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithBulkFixtures(os.DirFS("testdata"), "fixtures/*.ndjson"),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := c.RegisterFSRepo(ctx, "backup"); err != nil {
+		t.Fatalf("could not register repository: %v", err)
+	}
+	if err := c.Snapshot(ctx, "seeded", "users"); err != nil {
+		t.Fatalf("could not take snapshot: %v", err)
+	}
+
+	// Change the state after the snapshot
+	es := c.Client()
+	res, err := es.DeleteByQuery([]string{"users"}, strings.NewReader(`{"query":{"match_all":{}}}`),
+		es.DeleteByQuery.WithContext(ctx),
+		es.DeleteByQuery.WithRefresh(true),
+	)
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not delete documents: %v", err)
+	}
+	res.Body.Close()
+	if want, have := 0, countDocuments(t, c, "users"); want != have {
+		t.Fatalf("want %d documents, have %d", want, have)
+	}
+
+	if err := c.Restore(ctx, "seeded"); err != nil {
+		t.Fatalf("could not restore snapshot: %v", err)
+	}
+	if want, have := 3, countDocuments(t, c, "users"); want != have {
+		t.Fatalf("want %d documents, have %d", want, have)
+	}
+}
+
+func countDocuments(t *testing.T, c *elasticsearch.Container, index string) int {
+	t.Helper()
+	return countWith(t, c.Client(), index)
+}
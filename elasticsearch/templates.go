@@ -0,0 +1,86 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/elastic/go-elasticsearch/v8"
+)
+
+// WithIndexTemplates installs the composable index templates and component
+// templates in the JSON files of fsys that match glob (see fs.Glob),
+// before any post-start operations run. See PutTemplates for details.
+func WithIndexTemplates(fsys fs.FS, glob string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.setup = append(cfg.setup, func(ctx context.Context, tb testing.TB, c *Container) error {
+			return PutTemplates(ctx, c.Client(), fsys, glob)
+		})
+	}
+}
+
+// PutTemplates installs the templates in the JSON files of fsys that
+// match glob, e.g. "testdata/templates/*.json". Every file contains a
+// template, named after the file without the .json extension.
+//
+// Files with an "index_patterns" property are installed as composable
+// index templates, all others as component templates. Component templates
+// are installed first, so index templates can be composed of them.
+func PutTemplates(ctx context.Context, es *elasticsearch.Client, fsys fs.FS, glob string) error {
+	matches, err := fs.Glob(fsys, glob)
+	if err != nil {
+		return err
+	}
+
+	type template struct {
+		name string
+		body []byte
+	}
+	var components, indexTemplates []template
+	for _, match := range matches {
+		body, err := fs.ReadFile(fsys, match)
+		if err != nil {
+			return err
+		}
+		var probe struct {
+			IndexPatterns json.RawMessage `json:"index_patterns"`
+		}
+		if err := json.Unmarshal(body, &probe); err != nil {
+			return fmt.Errorf("could not parse template %s: %w", match, err)
+		}
+		t := template{
+			name: strings.TrimSuffix(path.Base(match), path.Ext(match)),
+			body: body,
+		}
+		if probe.IndexPatterns != nil {
+			indexTemplates = append(indexTemplates, t)
+		} else {
+			components = append(components, t)
+		}
+	}
+
+	for _, t := range components {
+		res, err := es.Cluster.PutComponentTemplate(t.name, bytes.NewReader(t.body),
+			es.Cluster.PutComponentTemplate.WithContext(ctx),
+		)
+		if err := ParseError(res, err); err != nil {
+			return fmt.Errorf("could not put component template %s: %w", t.name, err)
+		}
+		res.Body.Close()
+	}
+	for _, t := range indexTemplates {
+		res, err := es.Indices.PutIndexTemplate(t.name, bytes.NewReader(t.body),
+			es.Indices.PutIndexTemplate.WithContext(ctx),
+		)
+		if err := ParseError(res, err); err != nil {
+			return fmt.Errorf("could not put index template %s: %w", t.name, err)
+		}
+		res.Body.Close()
+	}
+	return nil
+}
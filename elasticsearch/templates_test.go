@@ -0,0 +1,54 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestWithIndexTemplates(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithIndexTemplates(os.DirFS("testdata"), "templates/*.json"),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	es := c.Client()
+
+	// Indices matching the template get its mappings
+	res, err := es.Indices.Create("articles-2024", es.Indices.Create.WithContext(ctx))
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not create index: %v", err)
+	}
+	res.Body.Close()
+
+	res, err = es.Indices.GetMapping(
+		es.Indices.GetMapping.WithContext(ctx),
+		es.Indices.GetMapping.WithIndex("articles-2024"),
+	)
+	if err := elasticsearch.ParseError(res, err); err != nil {
+		t.Fatalf("could not get mapping: %v", err)
+	}
+	defer res.Body.Close()
+
+	var mappings map[string]struct {
+		Mappings struct {
+			Properties map[string]struct {
+				Type string `json:"type"`
+			} `json:"properties"`
+		} `json:"mappings"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&mappings); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "date", mappings["articles-2024"].Mappings.Properties["created_at"].Type; want != have {
+		t.Fatalf("want created_at of type %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,45 @@
+package elasticsearch
+
+import (
+	"github.com/olivere/integrationtest/core"
+)
+
+// WithToxiproxy routes the HTTP connections to Elasticsearch through
+// a Toxiproxy sidecar, so that resilience tests can degrade the link,
+// e.g. add latency or cut connections, without touching the cluster:
+//
+//	c := elasticsearch.Start(t, elasticsearch.WithToxiproxy())
+//	err := c.Toxiproxy().AddToxic(ctx, core.Toxic{
+//		Type:       "timeout",
+//		Attributes: map[string]any{"timeout": 0},
+//	})
+//
+// The URL, HostPort, and clients of the container point at the proxy.
+// With WithNodes, the clients only connect to the first node. Other
+// containers that reach Elasticsearch by its name (see WithNetwork)
+// bypass the proxy. WithToxiproxy always starts a container, even if an
+// external cluster is configured.
+func WithToxiproxy() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.toxiproxy = true
+	}
+}
+
+// Toxiproxy returns the Toxiproxy sidecar that the connections go through,
+// or nil without WithToxiproxy.
+func (c *Container) Toxiproxy() *core.Toxiproxy {
+	return c.toxiproxy
+}
+
+// Chaos returns the Chaos API to inject faults into the link through the
+// Toxiproxy, e.g. to check how the code under test handles a slow
+// cluster. Its methods fail without WithToxiproxy.
+func (c *Container) Chaos() *core.Chaos {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.chaos == nil {
+		c.chaos = core.NewChaos(c.toxiproxy)
+	}
+	return c.chaos
+}
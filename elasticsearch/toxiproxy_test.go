@@ -0,0 +1,38 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/elasticsearch"
+)
+
+func TestContainer_WithToxiproxy(t *testing.T) {
+	c := elasticsearch.Start(t,
+		elasticsearch.WithTimeout(60*time.Second),
+		elasticsearch.WithToxiproxy(),
+	)
+	if want, have := c.Toxiproxy().Addr(), c.HostPort(); want != have {
+		t.Fatalf("want HostPort of the proxy %s, have %s", want, have)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	err := c.Toxiproxy().AddToxic(ctx, core.Toxic{
+		Type:       "latency",
+		Attributes: map[string]any{"latency": 300},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := elasticsearch.Ping(ctx, c.Client()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatalf("want latency of at least 300ms, have %v", elapsed)
+	}
+}
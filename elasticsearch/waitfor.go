@@ -0,0 +1,82 @@
+package elasticsearch
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/olivere/integrationtest/internal/wait"
+)
+
+// WaitStrategy decides when the container is ready. It returns nil if
+// the container is ready, and an error describing why it is not ready
+// otherwise. Start calls it repeatedly until it succeeds or the timeout
+// elapses, and fails with the last error.
+type WaitStrategy func(ctx context.Context, c *Container) error
+
+// WithWaitFor sets the strategies that decide when the container is ready,
+// replacing the default of ForHTTPStatus("/", http.StatusOK). The container
+// is ready when all strategies succeed, in order.
+func WithWaitFor(strategies ...WaitStrategy) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.waitFor = strategies
+	}
+}
+
+// ForHTTPStatus waits until a GET request to the given path, e.g.
+// "/_cluster/health", returns the given HTTP status code.
+func ForHTTPStatus(path string, status int) WaitStrategy {
+	return func(ctx context.Context, c *Container) error {
+		return wait.HTTPStatus(c.HTTPClient(), c.URL()+path, status)(ctx)
+	}
+}
+
+// ForClusterHealth waits until the cluster health reports at least the
+// given status, i.e. "yellow" or "green".
+func ForClusterHealth(status string) WaitStrategy {
+	return func(ctx context.Context, c *Container) error {
+		return waitForStatus(ctx, c.Client(), status, 0)
+	}
+}
+
+// ForLog waits until the log of every node matches re, e.g.
+// regexp.MustCompile(`"message":\s*"started`).
+func ForLog(re *regexp.Regexp) WaitStrategy {
+	return ForEachNode(core.ForLog(re))
+}
+
+// ForEachNode waits until strategy succeeds for every node of the
+// cluster, e.g. ForEachNode(core.ForListeningPort("9300/tcp")). It
+// succeeds immediately for an external cluster.
+func ForEachNode(strategy core.WaitStrategy) WaitStrategy {
+	return func(ctx context.Context, c *Container) error {
+		for _, resource := range c.resources {
+			if err := strategy(ctx, core.DockerTarget(c.pool.Client, resource)); err != nil {
+				return fmt.Errorf("%s: %w", resource.Container.Name, err)
+			}
+		}
+		return nil
+	}
+}
+
+// waitUntilReady waits until all strategies succeed.
+func (c *Container) waitUntilReady(ctx context.Context, strategies []WaitStrategy) error {
+	// Fail right away if a node crashed, e.g. because it ran out of
+	// memory, instead of waiting for it until the timeout
+	checks := []wait.Check{func(ctx context.Context) error {
+		for _, resource := range c.resources {
+			if err := dockerutil.Running(ctx, c.pool.Client, resource.Container.ID); err != nil {
+				return fmt.Errorf("%s: %w", resource.Container.Name, err)
+			}
+		}
+		return nil
+	}}
+	for _, s := range strategies {
+		checks = append(checks, func(ctx context.Context) error {
+			return s(ctx, c)
+		})
+	}
+	return wait.Policy(c.retryPolicy).Until(ctx, c.timeout, wait.All(checks...))
+}
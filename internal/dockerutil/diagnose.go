@@ -0,0 +1,178 @@
+package dockerutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/olivere/integrationtest/internal/wait"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// logTail is the number of log lines that Diagnose includes.
+const logTail = 20
+
+// Events records the engine events of containers, e.g. "oom" or "die",
+// to explain why a container failed to become ready.
+type Events struct {
+	client *docker.Client
+	ch     chan *docker.APIEvents
+	stop   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+
+	mu     sync.Mutex
+	events []*docker.APIEvents
+}
+
+// RecordEvents records the engine events of client until Close is called.
+// Recording is best-effort: if the daemon does not stream events, the
+// returned Events is empty.
+func RecordEvents(client *docker.Client) *Events {
+	e := &Events{
+		client: client,
+		ch:     make(chan *docker.APIEvents, 64),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := client.AddEventListener(e.ch); err != nil {
+		close(e.done)
+		return e
+	}
+	go func() {
+		defer close(e.done)
+		for {
+			select {
+			case <-e.stop:
+				return
+			case event, ok := <-e.ch:
+				// The client closes the channel if the stream fails
+				if !ok || event.Type == "EOF" {
+					return
+				}
+				e.mu.Lock()
+				e.events = append(e.events, event)
+				e.mu.Unlock()
+			}
+		}
+	}()
+	return e
+}
+
+// Of returns the events recorded for the container with the given ID.
+func (e *Events) Of(id string) []*docker.APIEvents {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var events []*docker.APIEvents
+	for _, event := range e.events {
+		if event.Actor.ID == id || event.ID == id {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// Close stops recording events. It is safe to call Close more than once.
+func (e *Events) Close() {
+	e.once.Do(func() {
+		e.client.RemoveEventListener(e.ch)
+		close(e.stop)
+	})
+	<-e.done
+}
+
+// Running returns nil if the container with the given ID is running.
+// Otherwise, it returns a permanent error for wait.Until that tells how
+// the container exited, e.g. because it ran out of memory.
+func Running(ctx context.Context, client *docker.Client, id string) error {
+	container, err := client.InspectContainerWithContext(id, ctx)
+	var notFound *docker.NoSuchContainer
+	if errors.As(err, &notFound) {
+		// Containers with AutoRemove are removed when they exit
+		return wait.Permanent(errors.New("container exited and was removed"))
+	}
+	if err != nil {
+		return err
+	}
+	if container.State.Running {
+		return nil
+	}
+	if container.State.OOMKilled {
+		return wait.Permanent(fmt.Errorf("container ran out of memory and was killed with exit code %d", container.State.ExitCode))
+	}
+	return wait.Permanent(fmt.Errorf("container is %s with exit code %d", container.State.Status, container.State.ExitCode))
+}
+
+// Diagnose describes the container with the given ID for the error
+// message of a container that failed to become ready, with its events
+// recorded in events, which may be nil. See Describe.
+func Diagnose(ctx context.Context, client *docker.Client, id string, events *Events) string {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	container, err := client.InspectContainerWithContext(id, ctx)
+	var notFound *docker.NoSuchContainer
+	if errors.As(err, &notFound) {
+		container = &docker.Container{ID: id, Name: id, State: docker.State{Status: "removed"}}
+	} else if err != nil {
+		return fmt.Sprintf("could not inspect container %s: %v", id, err)
+	}
+	var recorded []*docker.APIEvents
+	if events != nil {
+		recorded = events.Of(id)
+	}
+	logs, _ := Logs(ctx, client, id)
+	return Describe(container, recorded, logs)
+}
+
+// Describe describes the state of container as reported by docker
+// inspect, its events, and the last lines of its logs, e.g. to explain
+// that it exited with code 137 because it ran out of memory.
+func Describe(container *docker.Container, events []*docker.APIEvents, logs string) string {
+	var sb strings.Builder
+	state := container.State
+	fmt.Fprintf(&sb, "container %s is %s", strings.TrimPrefix(container.Name, "/"), state.Status)
+	if !state.Running && !state.FinishedAt.IsZero() {
+		fmt.Fprintf(&sb, " with exit code %d", state.ExitCode)
+	}
+	if state.OOMKilled {
+		sb.WriteString(", killed for running out of memory")
+		if hc := container.HostConfig; hc != nil && hc.Memory > 0 {
+			fmt.Fprintf(&sb, " (limit %d bytes)", hc.Memory)
+		}
+	}
+	if state.Error != "" {
+		fmt.Fprintf(&sb, ", error: %s", state.Error)
+	}
+	if state.Health.Status != "" {
+		fmt.Fprintf(&sb, ", health: %s", state.Health.Status)
+	}
+	sb.WriteString("\n")
+
+	if len(events) > 0 {
+		sb.WriteString("events:\n")
+		for _, event := range events {
+			fmt.Fprintf(&sb, "\t%s %s", time.Unix(0, event.TimeNano).UTC().Format(time.RFC3339), event.Action)
+			if code, ok := event.Actor.Attributes["exitCode"]; ok {
+				fmt.Fprintf(&sb, " (exit code %s)", code)
+			}
+			sb.WriteString("\n")
+		}
+	}
+
+	if logs = strings.TrimRight(logs, "\n"); logs != "" {
+		lines := strings.Split(logs, "\n")
+		if len(lines) > logTail {
+			lines = lines[len(lines)-logTail:]
+		}
+		fmt.Fprintf(&sb, "last %d lines of log:\n", len(lines))
+		for _, line := range lines {
+			sb.WriteString("\t" + line + "\n")
+		}
+	}
+	return strings.TrimRight(sb.String(), "\n")
+}
@@ -0,0 +1,59 @@
+package dockerutil_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+func TestDescribe(t *testing.T) {
+	container := &docker.Container{
+		Name: "/integrationtest_abc",
+		State: docker.State{
+			Status:     "exited",
+			ExitCode:   137,
+			OOMKilled:  true,
+			FinishedAt: time.Now(),
+		},
+		HostConfig: &docker.HostConfig{Memory: 64 * 1024 * 1024},
+	}
+	events := []*docker.APIEvents{
+		{Action: "oom", TimeNano: time.Now().UnixNano()},
+		{Action: "die", TimeNano: time.Now().UnixNano(), Actor: docker.APIActor{Attributes: map[string]string{"exitCode": "137"}}},
+	}
+	var logs strings.Builder
+	for i := 1; i <= 30; i++ {
+		fmt.Fprintf(&logs, "line %d\n", i)
+	}
+
+	s := dockerutil.Describe(container, events, logs.String())
+	for _, want := range []string{
+		"container integrationtest_abc is exited with exit code 137",
+		"killed for running out of memory (limit 67108864 bytes)",
+		" oom\n",
+		" die (exit code 137)",
+		"last 20 lines of log:",
+		"\tline 30",
+	} {
+		if !strings.Contains(s, want) {
+			t.Errorf("want %q in description, have:\n%s", want, s)
+		}
+	}
+	if strings.Contains(s, "line 10\n") {
+		t.Errorf("want only the last lines of the log, have:\n%s", s)
+	}
+}
+
+func TestDescribe_Running(t *testing.T) {
+	container := &docker.Container{
+		Name:  "/integrationtest_abc",
+		State: docker.State{Status: "running", Running: true},
+	}
+	if want, have := "container integrationtest_abc is running", dockerutil.Describe(container, nil, ""); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
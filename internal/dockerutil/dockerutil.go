@@ -0,0 +1,164 @@
+// Package dockerutil implements Docker helpers for the container packages.
+package dockerutil
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// Logs returns the output of the container with the given ID so far,
+// with stdout and stderr interleaved.
+func Logs(ctx context.Context, client *docker.Client, id string) (string, error) {
+	var buf bytes.Buffer
+	err := client.Logs(docker.LogsOptions{
+		Context:      ctx,
+		Container:    id,
+		OutputStream: &buf,
+		ErrorStream:  &buf,
+		Stdout:       true,
+		Stderr:       true,
+	})
+	return buf.String(), err
+}
+
+// Exec runs cmd in the container with the given ID and returns its exit
+// code and output, with stdout and stderr interleaved.
+func Exec(ctx context.Context, client *docker.Client, id string, cmd ...string) (int, string, error) {
+	exec, err := client.CreateExec(docker.CreateExecOptions{
+		Container:    id,
+		Cmd:          cmd,
+		AttachStdout: true,
+		AttachStderr: true,
+		Context:      ctx,
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	var buf bytes.Buffer
+	err = client.StartExec(exec.ID, docker.StartExecOptions{
+		OutputStream: &buf,
+		ErrorStream:  &buf,
+		Context:      ctx,
+	})
+	if err != nil {
+		return 0, buf.String(), err
+	}
+	inspect, err := client.InspectExec(exec.ID)
+	if err != nil {
+		return 0, buf.String(), err
+	}
+	return inspect.ExitCode, buf.String(), nil
+}
+
+// NewPool connects to the Docker daemon configured by the environment,
+// like the docker command: DOCKER_HOST selects the daemon, and
+// DOCKER_TLS_VERIFY and DOCKER_CERT_PATH secure the connection to a remote
+// daemon with the ca.pem, cert.pem, and key.pem of DOCKER_CERT_PATH, which
+// defaults to ~/.docker. Without DOCKER_HOST, it looks for the socket of
+// a running daemon, see DiscoverSocket.
+func NewPool() (*dockertest.Pool, error) {
+	if os.Getenv("DOCKER_TLS_VERIFY") == "" {
+		if !hostConfigured() {
+			endpoint, err := DiscoverSocket(context.Background())
+			if err != nil {
+				return nil, err
+			}
+			return dockertest.NewPool(endpoint)
+		}
+		// dockertest handles DOCKER_HOST and DOCKER_CERT_PATH, but does
+		// not fall back to ~/.docker
+		return dockertest.NewPool("")
+	}
+	certPath := os.Getenv("DOCKER_CERT_PATH")
+	if certPath == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		certPath = filepath.Join(home, ".docker")
+	}
+	// The client would skip the verification without a CA certificate
+	if _, err := os.Stat(filepath.Join(certPath, "ca.pem")); err != nil {
+		return nil, fmt.Errorf("DOCKER_TLS_VERIFY requires a CA certificate: %w", err)
+	}
+	client, err := docker.NewClientFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create client from environment: %w", err)
+	}
+	return &dockertest.Pool{Client: client}, nil
+}
+
+// RemoteHost returns the host name of the Docker daemon if DOCKER_HOST
+// configures a remote daemon via TCP, e.g. "docker.example.com" for
+// "tcp://docker.example.com:2376", and an empty string otherwise.
+func RemoteHost() string {
+	return remoteHost(os.Getenv("DOCKER_HOST"))
+}
+
+func remoteHost(dockerHost string) string {
+	u, err := url.Parse(dockerHost)
+	if err != nil {
+		return ""
+	}
+	switch u.Scheme {
+	case "tcp", "http", "https":
+	default:
+		return ""
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+		return ""
+	}
+	return host
+}
+
+// HostPort returns the address that the given port of resource, e.g.
+// "5432/tcp", is published on, e.g. "localhost:32768". If the container
+// runs on a remote daemon (see RemoteHost), the address is on the remote
+// host instead of localhost.
+func HostPort(resource *dockertest.Resource, portID string) string {
+	return ReplaceHost(resource.GetHostPort(portID))
+}
+
+// ReplaceHost replaces a local or unspecified host in hostPort, e.g.
+// "0.0.0.0:32768", with the remote host of the Docker daemon, if any, or
+// localhost.
+func ReplaceHost(hostPort string) string {
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return hostPort
+	}
+	if ip := net.ParseIP(host); host != "" && host != "localhost" && (ip == nil || !ip.IsLoopback() && !ip.IsUnspecified()) {
+		return hostPort
+	}
+	host = "localhost"
+	if remote := RemoteHost(); remote != "" {
+		host = remote
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// SetLimits limits the resources of a container: cpus is the number of
+// CPUs, e.g. 1.5, memory and shm are the memory limit and the size of
+// /dev/shm in bytes. Zero values keep the defaults of Docker.
+func SetLimits(config *docker.HostConfig, cpus float64, memory, shm int64) {
+	if cpus > 0 {
+		// Like docker run --cpus
+		config.CPUPeriod = 100000
+		config.CPUQuota = int64(cpus * 100000)
+	}
+	if memory > 0 {
+		config.Memory = memory
+	}
+	if shm > 0 {
+		config.ShmSize = shm
+	}
+}
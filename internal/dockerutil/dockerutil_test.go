@@ -0,0 +1,89 @@
+package dockerutil_test
+
+import (
+	"testing"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+func TestRemoteHost(t *testing.T) {
+	tests := []struct {
+		dockerHost string
+		want       string
+	}{
+		{"", ""},
+		{"unix:///var/run/docker.sock", ""},
+		{"npipe:////./pipe/docker_engine", ""},
+		{"tcp://localhost:2375", ""},
+		{"tcp://127.0.0.1:2375", ""},
+		{"tcp://docker.example.com:2376", "docker.example.com"},
+		{"tcp://192.168.99.100:2376", "192.168.99.100"},
+		{"https://[fd00::1]:2376", "fd00::1"},
+	}
+	for _, tt := range tests {
+		t.Setenv("DOCKER_HOST", tt.dockerHost)
+		if have := dockerutil.RemoteHost(); tt.want != have {
+			t.Errorf("DOCKER_HOST=%q: want %q, have %q", tt.dockerHost, tt.want, have)
+		}
+	}
+}
+
+func TestReplaceHost(t *testing.T) {
+	tests := []struct {
+		dockerHost string
+		hostPort   string
+		want       string
+	}{
+		{"", "localhost:32768", "localhost:32768"},
+		{"", "0.0.0.0:32768", "localhost:32768"},
+		{"", "[::]:32768", "localhost:32768"},
+		{"", "192.168.1.5:32768", "192.168.1.5:32768"},
+		{"tcp://docker.example.com:2376", "localhost:32768", "docker.example.com:32768"},
+		{"tcp://docker.example.com:2376", "0.0.0.0:32768", "docker.example.com:32768"},
+		{"tcp://docker.example.com:2376", "127.0.0.1:32768", "docker.example.com:32768"},
+		{"tcp://docker.example.com:2376", "192.168.1.5:32768", "192.168.1.5:32768"},
+		{"tcp://[fd00::1]:2376", "localhost:32768", "[fd00::1]:32768"},
+	}
+	for _, tt := range tests {
+		t.Setenv("DOCKER_HOST", tt.dockerHost)
+		if have := dockerutil.ReplaceHost(tt.hostPort); tt.want != have {
+			t.Errorf("DOCKER_HOST=%q, %q: want %q, have %q", tt.dockerHost, tt.hostPort, tt.want, have)
+		}
+	}
+}
+
+func TestNewPool_TLSVerify(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://docker.example.com:2376")
+	t.Setenv("DOCKER_TLS_VERIFY", "1")
+	t.Setenv("DOCKER_CERT_PATH", t.TempDir())
+
+	// The certificates are missing
+	if _, err := dockerutil.NewPool(); err == nil {
+		t.Fatal("want error for missing certificates")
+	}
+}
+
+func TestSetLimits(t *testing.T) {
+	var config docker.HostConfig
+	dockerutil.SetLimits(&config, 1.5, 512*1024*1024, 256*1024*1024)
+	if want, have := int64(150000), config.CPUQuota; want != have {
+		t.Errorf("want CPU quota %d, have %d", want, have)
+	}
+	if want, have := int64(100000), config.CPUPeriod; want != have {
+		t.Errorf("want CPU period %d, have %d", want, have)
+	}
+	if want, have := int64(512*1024*1024), config.Memory; want != have {
+		t.Errorf("want memory %d, have %d", want, have)
+	}
+	if want, have := int64(256*1024*1024), config.ShmSize; want != have {
+		t.Errorf("want shm size %d, have %d", want, have)
+	}
+
+	// Zero values keep the defaults
+	config = docker.HostConfig{Memory: 1024}
+	dockerutil.SetLimits(&config, 0, 0, 0)
+	if config.CPUQuota != 0 || config.Memory != 1024 || config.ShmSize != 0 {
+		t.Errorf("want defaults, have %+v", config)
+	}
+}
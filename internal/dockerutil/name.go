@@ -0,0 +1,91 @@
+package dockerutil
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// maxNameRetries is how often RunUnique retries with a new name.
+const maxNameRetries = 3
+
+// Suffix returns a random suffix for names that must be unique, e.g.
+// "3f9a1c2b7d4e".
+func Suffix() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		panic(err) // crypto/rand never fails on supported platforms
+	}
+	return hex.EncodeToString(b)
+}
+
+// Name returns a unique name for a container or network of prefix, e.g.
+// "postgres", and the test testName, if not empty, e.g.
+// "postgres_TestUsers-admin_3f9a1c2b7d4e". Characters that Docker does not
+// allow in names are replaced.
+func Name(prefix, testName string) string {
+	parts := []string{prefix}
+	if testName != "" {
+		const maxLen = 40
+		s := sanitize(testName)
+		if len(s) > maxLen {
+			s = s[:maxLen]
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(append(parts, Suffix()), "_")
+}
+
+// sanitize replaces the characters that Docker does not allow in names.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '-'
+		}
+	}, s)
+}
+
+// RunUnique runs a container like pool.RunWithOptions, named by newName.
+// If the name is already in use, it retries with the next name of
+// newName. If a host port is already in use (see IsPortConflict), it
+// removes the container that Docker created but could not start, and
+// retries up to portRetries times: with a port that Docker assigns anew,
+// or, if opts request a port on the host, after a backoff with the same
+// port, so that tests can rely on the port they asked for.
+func RunUnique(pool *dockertest.Pool, newName func() string, portRetries int, opts *dockertest.RunOptions, hcOpts ...func(*docker.HostConfig)) (*dockertest.Resource, error) {
+	var names, ports int
+	for {
+		opts.Name = newName()
+		resource, err := pool.RunWithOptions(opts, hcOpts...)
+		switch {
+		case errors.Is(err, docker.ErrContainerAlreadyExists) && names < maxNameRetries:
+			names++
+			continue
+		case IsPortConflict(err):
+			if rmErr := removeCreated(pool.Client, opts.Name); rmErr != nil {
+				return nil, fmt.Errorf("could not remove container %s after port conflict: %w", opts.Name, errors.Join(err, rmErr))
+			}
+			if ports >= portRetries && ports > 0 {
+				return nil, fmt.Errorf("host port still in use after %d attempts: %w", ports+1, err)
+			}
+			if ports >= portRetries {
+				return nil, err
+			}
+			ports++
+			if requestsHostPort(opts) {
+				time.Sleep(time.Duration(ports) * portConflictBackoff)
+			}
+			continue
+		}
+		return resource, err
+	}
+}
@@ -0,0 +1,35 @@
+package dockerutil_test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+)
+
+func TestName(t *testing.T) {
+	valid := regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]+$`)
+
+	name := dockerutil.Name("postgres", "TestUsers/admin user")
+	if !valid.MatchString(name) {
+		t.Fatalf("want valid Docker name, have %q", name)
+	}
+	if !strings.HasPrefix(name, "postgres_TestUsers-admin-user_") {
+		t.Fatalf("want package and test in name, have %q", name)
+	}
+
+	long := dockerutil.Name("elasticsearch", strings.Repeat("x", 100))
+	if len(long) > len("elasticsearch_")+40+1+12 {
+		t.Fatalf("want test name to be truncated, have %q", long)
+	}
+
+	seen := make(map[string]bool)
+	for range 1000 {
+		name := dockerutil.Name("postgres", "")
+		if seen[name] {
+			t.Fatalf("want unique names, have %q twice", name)
+		}
+		seen[name] = true
+	}
+}
@@ -0,0 +1,63 @@
+package dockerutil
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+)
+
+// poolEnv are the environment variables that select the Docker daemon
+// of NewPool.
+var poolEnv = []string{
+	"DOCKER_HOST",
+	"DOCKER_URL",
+	"DOCKER_MACHINE_NAME",
+	"DOCKER_TLS_VERIFY",
+	"DOCKER_CERT_PATH",
+	"DOCKER_API_VERSION",
+}
+
+var pools struct {
+	mu sync.Mutex
+	m  map[string]*dockertest.Pool
+}
+
+// Pool returns a pool of NewPool for the Docker daemon configured by the
+// environment, which it pings. It creates one pool per daemon and returns
+// it on later calls, so that the containers of a process share their
+// connections to the daemon. Errors are not cached, e.g. to find a daemon
+// that starts later.
+//
+// The pool is shared, so callers must not change it, e.g. its MaxWait.
+func Pool() (*dockertest.Pool, error) {
+	var key strings.Builder
+	for _, name := range poolEnv {
+		key.WriteString(name + "=" + os.Getenv(name) + "\n")
+	}
+
+	pools.mu.Lock()
+	defer pools.mu.Unlock()
+
+	if pool, ok := pools.m[key.String()]; ok {
+		return pool, nil
+	}
+	pool, err := NewPool()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := pool.Client.PingWithContext(ctx); err != nil {
+		return nil, fmt.Errorf("could not connect to docker: %w", err)
+	}
+	if pools.m == nil {
+		pools.m = make(map[string]*dockertest.Pool)
+	}
+	pools.m[key.String()] = pool
+	return pool, nil
+}
@@ -0,0 +1,52 @@
+package dockerutil_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+)
+
+func TestPool(t *testing.T) {
+	var pings atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/_ping") {
+			pings.Add(1)
+			w.Write([]byte("OK"))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(srv.URL, "http://"))
+	t.Setenv("DOCKER_TLS_VERIFY", "")
+
+	first, err := dockerutil.Pool()
+	if err != nil {
+		t.Fatalf("want pool, have %v", err)
+	}
+	second, err := dockerutil.Pool()
+	if err != nil {
+		t.Fatalf("want pool, have %v", err)
+	}
+	if first != second {
+		t.Error("want the same pool for the same daemon")
+	}
+	if want, have := int32(1), pings.Load(); want != have {
+		t.Errorf("want %d ping, have %d", want, have)
+	}
+}
+
+func TestPool_Unavailable(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://127.0.0.1:1")
+	t.Setenv("DOCKER_TLS_VERIFY", "")
+
+	for range 2 {
+		if _, err := dockerutil.Pool(); err == nil {
+			t.Fatal("want error, have nil")
+		}
+	}
+}
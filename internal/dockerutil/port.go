@@ -0,0 +1,63 @@
+package dockerutil
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// portConflictBackoff is the time to wait before retrying a port that was
+// requested explicitly, multiplied by the number of the attempt, e.g. for
+// Docker to release the port of a container that was just removed.
+const portConflictBackoff = 500 * time.Millisecond
+
+// IsPortConflict returns true if err tells that Docker could not start
+// a container because a host port is already in use, e.g. by another
+// process, or by a container of a parallel job on a shared runner.
+func IsPortConflict(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{
+		"port is already allocated",
+		"address already in use",
+		"ports are not available",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestsHostPort returns true if opts bind a port of the container to
+// a given port on the host, instead of letting Docker assign one.
+func requestsHostPort(opts *dockertest.RunOptions) bool {
+	for _, bindings := range opts.PortBindings {
+		for _, b := range bindings {
+			if b.HostPort != "" && b.HostPort != "0" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// removeCreated removes the container with the given name that Docker
+// created but could not start.
+func removeCreated(client *docker.Client, name string) error {
+	err := client.RemoveContainer(docker.RemoveContainerOptions{
+		ID:            name,
+		Force:         true,
+		RemoveVolumes: true,
+	})
+	var noSuchContainer *docker.NoSuchContainer
+	if errors.As(err, &noSuchContainer) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,29 @@
+package dockerutil_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+func TestIsPortConflict(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("no such image"), false},
+		{docker.ErrContainerAlreadyExists, false},
+		{&docker.Error{Status: 500, Message: "driver failed programming external connectivity on endpoint pg: Bind for 0.0.0.0:5432 failed: port is already allocated"}, true},
+		{&docker.Error{Status: 500, Message: "listen tcp4 0.0.0.0:9200: bind: address already in use"}, true},
+		{fmt.Errorf("start: %w", &docker.Error{Status: 500, Message: "Ports are not available: exposing port TCP 0.0.0.0:5432"}), true},
+	}
+	for _, tt := range tests {
+		if have := dockerutil.IsPortConflict(tt.err); tt.want != have {
+			t.Errorf("IsPortConflict(%v): want %v, have %v", tt.err, tt.want, have)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package dockerutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// hostConfigured returns true if the environment selects the Docker
+// daemon, or the platform has no sockets to discover.
+func hostConfigured() bool {
+	for _, key := range []string{"DOCKER_HOST", "DOCKER_URL", "DOCKER_MACHINE_NAME"} {
+		if os.Getenv(key) != "" {
+			return true
+		}
+	}
+	return runtime.GOOS == "windows"
+}
+
+// socketPaths returns the paths of the sockets that DiscoverSocket probes,
+// in order.
+func socketPaths() []string {
+	paths := []string{"/var/run/docker.sock"}
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		// Rootless Docker and Podman
+		paths = append(paths,
+			filepath.Join(dir, "docker.sock"),
+			filepath.Join(dir, "podman", "podman.sock"),
+		)
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		colima := filepath.Join(home, ".colima")
+		if dir := os.Getenv("COLIMA_HOME"); dir != "" {
+			colima = dir
+		}
+		paths = append(paths,
+			// Docker Desktop
+			filepath.Join(home, ".docker", "run", "docker.sock"),
+			filepath.Join(home, ".docker", "desktop", "docker.sock"),
+			// Colima, with its default profile
+			filepath.Join(colima, "default", "docker.sock"),
+			filepath.Join(colima, "docker.sock"),
+			// Lima, with the docker template
+			filepath.Join(home, ".lima", "docker", "sock", "docker.sock"),
+			filepath.Join(home, ".lima", "default", "sock", "docker.sock"),
+			// Rancher Desktop
+			filepath.Join(home, ".rd", "docker.sock"),
+			// OrbStack
+			filepath.Join(home, ".orbstack", "run", "docker.sock"),
+		)
+	}
+	return paths
+}
+
+// DiscoverSocket returns the endpoint, e.g. "unix:///var/run/docker.sock",
+// of the first Docker socket that answers a ping. It probes the default
+// socket, and those of rootless Docker, Docker Desktop, Colima, Lima,
+// Rancher Desktop, and OrbStack. If none answers, the error lists why each
+// of them failed.
+func DiscoverSocket(ctx context.Context) (string, error) {
+	var errs []error
+	for _, path := range socketPaths() {
+		if _, err := os.Stat(path); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			}
+			continue
+		}
+		endpoint := "unix://" + path
+		if err := ping(ctx, endpoint); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		return endpoint, nil
+	}
+	if len(errs) == 0 {
+		return "", fmt.Errorf("unable to find a Docker socket in %s: set DOCKER_HOST",
+			strings.Join(socketPaths(), ", "))
+	}
+	return "", fmt.Errorf("unable to connect to a Docker socket: set DOCKER_HOST: %w", errors.Join(errs...))
+}
+
+func ping(ctx context.Context, endpoint string) error {
+	client, err := docker.NewClient(endpoint)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	return client.PingWithContext(ctx)
+}
@@ -0,0 +1,92 @@
+package dockerutil_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+)
+
+// noDefaultSocket skips the test if the default Docker socket exists, as
+// it would be discovered first.
+func noDefaultSocket(t *testing.T) {
+	t.Helper()
+	if _, err := os.Stat("/var/run/docker.sock"); err == nil {
+		t.Skip("default Docker socket exists")
+	}
+	t.Setenv("DOCKER_HOST", "")
+	t.Setenv("XDG_RUNTIME_DIR", "")
+	t.Setenv("COLIMA_HOME", "")
+}
+
+func TestDiscoverSocket(t *testing.T) {
+	noDefaultSocket(t)
+
+	// Unix socket paths are limited to about 100 bytes, so keep it short
+	home, err := os.MkdirTemp("", "home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(home)
+	t.Setenv("HOME", home)
+
+	// A socket that does not answer comes before the one of Colima
+	if err := os.MkdirAll(filepath.Join(home, ".docker", "run"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".docker", "run", "docker.sock"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(home, ".colima", "default", "docker.sock")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/_ping") {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("OK"))
+	})}
+	go srv.Serve(l)
+	defer srv.Close()
+
+	endpoint, err := dockerutil.DiscoverSocket(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "unix://"+path, endpoint; want != have {
+		t.Fatalf("want endpoint %q, have %q", want, have)
+	}
+
+	pool, err := dockerutil.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "unix://"+path, pool.Client.Endpoint(); want != have {
+		t.Fatalf("want pool endpoint %q, have %q", want, have)
+	}
+}
+
+func TestDiscoverSocket_NotFound(t *testing.T) {
+	noDefaultSocket(t)
+	t.Setenv("HOME", t.TempDir())
+
+	_, err := dockerutil.DiscoverSocket(context.Background())
+	if err == nil {
+		t.Fatal("want error")
+	}
+	if !strings.Contains(err.Error(), "DOCKER_HOST") || !strings.Contains(err.Error(), ".colima") {
+		t.Fatalf("want error to list the probed sockets, have %v", err)
+	}
+}
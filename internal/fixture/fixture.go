@@ -0,0 +1,292 @@
+// Package fixture runs the Docker containers of the packages for a single
+// server, e.g. redis, so that they only add the image, the environment,
+// and the readiness check of their server. It pulls the image, runs and
+// expires the container, waits for it, and removes it, and applies the
+// options of core.Config.
+package fixture
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/olivere/integrationtest/internal/lifecycle"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// Config is the configuration that all packages support. The packages
+// embed it in their startConfig and set it with their options.
+type Config struct {
+	Version      string
+	Networks     []*dockertest.Network
+	Attachments  []core.NetworkAttachment
+	Timeout      time.Duration
+	MaxLifetime  time.Duration
+	Env          []string
+	CPUs         float64
+	Memory       int64
+	ShmSize      int64
+	Logger       *slog.Logger
+	SkipNoDocker bool
+	TestName     string
+	PullPolicy   core.PullPolicy
+	WaitFor      []core.WaitStrategy
+	RetryPolicy  core.RetryPolicy
+	PortRetries  int
+	Keep         bool
+	Build        *core.Build
+}
+
+// Apply applies the options of core.Config, see the WithOptions of the
+// packages.
+func (cfg *Config) Apply(options ...core.Option) {
+	coreCfg := core.NewConfig(options...)
+	if coreCfg.Timeout != 0 {
+		cfg.Timeout = coreCfg.Timeout
+	}
+	if coreCfg.MaxLifetime != 0 {
+		cfg.MaxLifetime = coreCfg.MaxLifetime
+	}
+	if coreCfg.Version != "" {
+		cfg.Version = coreCfg.Version
+	}
+	cfg.Env = append(cfg.Env, coreCfg.Env...)
+	if coreCfg.PullPolicy != "" {
+		cfg.PullPolicy = coreCfg.PullPolicy
+	}
+	cfg.Attachments = append(cfg.Attachments, coreCfg.Networks...)
+	if coreCfg.CPULimit != 0 {
+		cfg.CPUs = coreCfg.CPULimit
+	}
+	if coreCfg.MemoryLimit != 0 {
+		cfg.Memory = coreCfg.MemoryLimit
+	}
+	if coreCfg.ShmSize != 0 {
+		cfg.ShmSize = coreCfg.ShmSize
+	}
+	if coreCfg.Logger != nil {
+		cfg.Logger = coreCfg.Logger
+	}
+	if coreCfg.RetryPolicy != (core.RetryPolicy{}) {
+		cfg.RetryPolicy = coreCfg.RetryPolicy
+	}
+	if coreCfg.PortRetries != 0 {
+		cfg.PortRetries = coreCfg.PortRetries
+	}
+	if coreCfg.Keep {
+		cfg.Keep = true
+	}
+	if coreCfg.Build != nil {
+		cfg.Build = coreCfg.Build
+	}
+	cfg.WaitFor = append(cfg.WaitFor, coreCfg.WaitFor...)
+}
+
+// Spec describes the container of a server.
+type Spec struct {
+	// Package is the name of the package, e.g. "redis", for the labels
+	// and the lifecycle events of the container.
+	Package string
+	// Server is the name of the server in messages, e.g. "Valkey".
+	Server string
+	// Name is the prefix of the name of the container, e.g. "valkey".
+	Name       string
+	Repository string
+	Tag        string
+	Cmd        []string
+	// Env is the environment of the server; Config.Env is appended.
+	Env []string
+	// Port is the port of the server in the container, e.g. "6379/tcp".
+	// Unless Config.WaitFor says otherwise, the container is ready once
+	// it listens on it.
+	Port string
+	// Timeout is the time to wait for the container to become ready
+	// unless Config.Timeout says otherwise.
+	Timeout time.Duration
+	// Ready returns the check that the server is ready, with the address
+	// of Port on the host, e.g. a ping of a client that connects to it.
+	Ready func(hostPort string) core.WaitStrategy
+}
+
+// Container is a running container.
+type Container struct {
+	pool        *dockertest.Pool
+	resource    *dockertest.Resource
+	pkg         string
+	hostPort    string
+	timeout     time.Duration
+	logger      *slog.Logger
+	waitFor     core.WaitStrategy
+	retryPolicy core.RetryPolicy
+	keep        bool
+}
+
+// Start runs the container of spec and waits until it is ready. If it
+// returns an error along with a non-nil Container, the caller is
+// responsible for closing it.
+func Start(ctx context.Context, cfg Config, spec Spec) (*Container, error) {
+	c := &Container{
+		pkg:         spec.Package,
+		timeout:     cmp.Or(cfg.Timeout, spec.Timeout),
+		logger:      cfg.Logger,
+		retryPolicy: cfg.RetryPolicy,
+		keep:        cfg.Keep,
+	}
+
+	var err error
+	c.pool, err = dockerutil.Pool()
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to Docker: %w", err)
+	}
+	core.ReapOnce(c.pool.Client)
+
+	// Pull or build the image first, with its own timeout, so that a slow
+	// pull on a fresh machine does not count against the timeout of the
+	// container
+	pullCtx, cancel := context.WithTimeout(ctx, max(c.timeout, 5*time.Minute))
+	pullStart := time.Now()
+	if cfg.Build != nil {
+		err = core.BuildImage(pullCtx, cfg.Build.Dir, cfg.Build.Dockerfile, spec.Repository+":"+spec.Tag)
+	} else {
+		err = core.Pull(pullCtx, c.pool.Client, spec.Repository, spec.Tag, cfg.PullPolicy)
+	}
+	cancel()
+	lifecycle.Log(c.logger, c.pkg, lifecycle.Pull, pullStart, err, "image", spec.Repository+":"+spec.Tag)
+	if err != nil {
+		return nil, fmt.Errorf("could not get %s image: %w", spec.Server, err)
+	}
+
+	// Record the events of the container to explain why it failed to
+	// become ready, e.g. because it exited
+	events := dockerutil.RecordEvents(c.pool.Client)
+	defer events.Close()
+
+	started := time.Now()
+	newName := func() string {
+		return dockerutil.Name(spec.Name, cfg.TestName)
+	}
+	labels := core.Labels(c.pkg, cfg.TestName)
+	if c.keep {
+		labels[core.LabelKeep] = "true"
+	}
+	c.resource, err = dockerutil.RunUnique(c.pool, newName, cmp.Or(cfg.PortRetries, core.DefaultPortRetries), &dockertest.RunOptions{
+		Repository: spec.Repository,
+		Tag:        spec.Tag,
+		Cmd:        spec.Cmd,
+		Env:        slices.Concat(spec.Env, cfg.Env),
+		Networks:   cfg.Networks,
+		Labels:     labels,
+	}, func(config *docker.HostConfig) {
+		config.AutoRemove = !c.keep
+		config.RestartPolicy = docker.NeverRestart()
+		dockerutil.SetLimits(config, cfg.CPUs, cfg.Memory, cfg.ShmSize)
+	})
+	if err != nil {
+		lifecycle.Log(c.logger, c.pkg, lifecycle.Start, started, err)
+		return nil, fmt.Errorf("unable to start %s container: %w", spec.Server, err)
+	}
+	lifecycle.Log(c.logger, c.pkg, lifecycle.Start, started, nil, "container", c.resource.Container.ID)
+	for _, a := range cfg.Attachments {
+		if err := a.Network.Connect(c.pool.Client, c.resource.Container.ID, a.Aliases...); err != nil {
+			return c, err
+		}
+	}
+
+	// Tell docker to hard kill the container after its lifetime, unless
+	// it is kept for debugging
+	if lifetime := cmp.Or(cfg.MaxLifetime, core.DefaultMaxLifetime); lifetime > 0 && !c.keep {
+		if err := c.resource.Expire(uint(lifetime.Seconds())); err != nil {
+			return c, err
+		}
+	}
+
+	c.hostPort = dockerutil.HostPort(c.resource, spec.Port)
+	waitFor := cfg.WaitFor
+	if len(waitFor) == 0 {
+		waitFor = []core.WaitStrategy{core.ForListeningPort(spec.Port)}
+	}
+	c.waitFor = core.ForAll(
+		c.forRunning(),
+		core.ForAll(waitFor...),
+		spec.Ready(c.hostPort),
+	)
+	err = c.WaitUntilReady(ctx)
+	lifecycle.Log(c.logger, c.pkg, lifecycle.Ready, started, err, "container", c.resource.Container.ID)
+	if err != nil {
+		diagnosis := dockerutil.Diagnose(context.Background(), c.pool.Client, c.resource.Container.ID, events)
+		return c, fmt.Errorf("could not connect to %s container: %w\n%s", spec.Server, err, diagnosis)
+	}
+	return c, nil
+}
+
+// RunPostStart runs the post-startup operations funcs of the package on
+// its container c, which runs in fc.
+func RunPostStart[C any, F ~func(context.Context, C) error](ctx context.Context, fc *Container, c C, funcs []F) error {
+	if len(funcs) == 0 {
+		return nil
+	}
+	seedStart := time.Now()
+	var err error
+	for _, f := range funcs {
+		if err = f(ctx, c); err != nil {
+			err = fmt.Errorf("could not run post-startup operation: %w", err)
+			break
+		}
+	}
+	lifecycle.Log(fc.logger, fc.pkg, lifecycle.Seed, seedStart, err, "container", fc.resource.Container.ID)
+	return err
+}
+
+// Close removes the container, or keeps it with its endpoint printed if
+// it is kept.
+func (c *Container) Close(endpoint string) error {
+	if c.keep {
+		lifecycle.Kept(os.Stderr, c.pkg, endpoint, c.Name())
+		return nil
+	}
+	closeStart := time.Now()
+	err := c.pool.Purge(c.resource)
+	lifecycle.Log(c.logger, c.pkg, lifecycle.Close, closeStart, err, "container", c.resource.Container.ID)
+	if err != nil {
+		return fmt.Errorf("could not purge container: %w", err)
+	}
+	return nil
+}
+
+// Name returns the name of the Docker container.
+func (c *Container) Name() string {
+	return strings.TrimPrefix(c.resource.Container.Name, "/")
+}
+
+// HostPort returns the address of the port of the server on the host,
+// e.g. "localhost:32768".
+func (c *Container) HostPort() string {
+	return c.hostPort
+}
+
+// Logs returns the output of the container so far.
+func (c *Container) Logs(ctx context.Context) (string, error) {
+	return dockerutil.Logs(ctx, c.pool.Client, c.resource.Container.ID)
+}
+
+// WaitUntilReady waits until the strategies that decided when the
+// container was ready at start succeed again.
+func (c *Container) WaitUntilReady(ctx context.Context) error {
+	return core.WaitUntil(ctx, c.timeout, c.retryPolicy, core.DockerTarget(c.pool.Client, c.resource), c.waitFor)
+}
+
+// forRunning fails permanently if the container is no longer running,
+// e.g. because it crashed, instead of waiting for it until the timeout.
+func (c *Container) forRunning() core.WaitStrategy {
+	return func(ctx context.Context, _ core.WaitTarget) error {
+		return dockerutil.Running(ctx, c.pool.Client, c.resource.Container.ID)
+	}
+}
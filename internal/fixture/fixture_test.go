@@ -0,0 +1,39 @@
+package fixture_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/fixture"
+)
+
+func TestConfig_Apply(t *testing.T) {
+	cfg := fixture.Config{
+		Version: "7",
+		Timeout: time.Minute,
+		Env:     []string{"A=1"},
+	}
+	cfg.Apply(
+		core.WithVersion("8"),
+		core.WithEnv("B=2"),
+		core.WithMemoryLimit(1<<30),
+		core.WithRetryPolicy(3, time.Millisecond, time.Second),
+	)
+
+	if want, have := "8", cfg.Version; want != have {
+		t.Errorf("want Version=%q, have %q", want, have)
+	}
+	if want, have := time.Minute, cfg.Timeout; want != have {
+		t.Errorf("want Timeout=%v to be kept, have %v", want, have)
+	}
+	if want, have := 2, len(cfg.Env); want != have {
+		t.Errorf("want %d environment variables, have %v", want, cfg.Env)
+	}
+	if want, have := int64(1<<30), cfg.Memory; want != have {
+		t.Errorf("want Memory=%d, have %d", want, have)
+	}
+	if want, have := 3, cfg.RetryPolicy.MaxAttempts; want != have {
+		t.Errorf("want MaxAttempts=%d, have %d", want, have)
+	}
+}
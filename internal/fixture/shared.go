@@ -0,0 +1,86 @@
+package fixture
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+// Shared is the container that the MainStart of a package shares with all
+// tests of the package, see Get.
+type Shared[C interface {
+	comparable
+	Close() error
+}] struct {
+	// Package is the name of the package, e.g. "redis", and Server the
+	// name of the server in messages, e.g. "Redis".
+	Package string
+	Server  string
+
+	mu sync.Mutex
+	c  C
+	// skip is the reason to skip tests using the shared container
+	skip string
+}
+
+// Main starts the shared container with start, runs the tests, and closes
+// the container afterwards. It returns the exit code of m.Run, or 1 if the
+// container could not be started. With skipNoDocker, Main runs the tests
+// without a container if Docker is unavailable, and Get skips them.
+func (s *Shared[C]) Main(m *testing.M, skipNoDocker bool, start func() (C, error)) int {
+	if skipNoDocker && os.Getenv(core.RequireDockerEnv) == "" {
+		if err := core.DockerAvailable(); err != nil {
+			s.mu.Lock()
+			s.skip = fmt.Sprintf("Docker is unavailable: %v", err)
+			s.mu.Unlock()
+			return m.Run()
+		}
+	}
+
+	var zero C
+	c, err := start()
+	if c != zero {
+		defer func() {
+			if err := c.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "could not close shared %s container: %v\n", s.Server, err)
+			}
+		}()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not start shared %s container: %v\n", s.Server, err)
+		return 1
+	}
+
+	s.mu.Lock()
+	s.c = c
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		s.c = zero
+		s.mu.Unlock()
+	}()
+
+	return m.Run()
+}
+
+// Get returns the container started by Main. It fails the test if Main is
+// not used in TestMain.
+func (s *Shared[C]) Get(tb testing.TB) C {
+	tb.Helper()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.skip != "" {
+		tb.Skip(s.skip)
+	}
+	var zero C
+	if s.c == zero {
+		tb.Fatalf("no shared %s container: call %s.MainStart in TestMain", s.Server, s.Package)
+	}
+	return s.c
+}
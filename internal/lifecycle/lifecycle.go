@@ -0,0 +1,107 @@
+// Package lifecycle logs the lifecycle events of containers, e.g. pulling
+// the image or becoming ready, for the container packages, and records
+// them for the timing report. See core.WithLogger and core.WriteReport.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Events of the lifecycle of a container, in order.
+const (
+	Pull  = "pull"
+	Start = "start"
+	Ready = "ready"
+	Seed  = "seed"
+	Close = "close"
+)
+
+// Record is an event that Log recorded, see Records.
+type Record struct {
+	// Package of the container, e.g. "postgres".
+	Package string
+	// Event, e.g. Ready.
+	Event string
+	// Start of the event.
+	Start time.Time
+	// Duration of the event.
+	Duration time.Duration
+	// Container is the ID of the container, if known.
+	Container string
+	// Error tells why the event failed, or is empty.
+	Error string
+}
+
+var records struct {
+	sync.Mutex
+	list []Record
+}
+
+// Records returns the events that Log recorded so far, in order.
+func Records() []Record {
+	records.Lock()
+	defer records.Unlock()
+	return slices.Clone(records.list)
+}
+
+// Log logs event of a container of the package pkg, e.g. "postgres", with
+// the time since start as its duration. Pass the container ID and other
+// details as args, like for slog.Logger.Info. If err is not nil, the
+// event failed and is logged as an error. logger may be nil. Log records
+// the event either way, see Records.
+func Log(logger *slog.Logger, pkg, event string, start time.Time, err error, args ...any) {
+	duration := time.Since(start)
+	record(pkg, event, start, duration, err, args)
+	if logger == nil {
+		return
+	}
+	level, msg := slog.LevelInfo, "container "+event
+	args = append([]any{
+		slog.String("package", pkg),
+		slog.String("event", event),
+		slog.Duration("duration", duration),
+	}, args...)
+	if err != nil {
+		level, msg = slog.LevelError, "container "+event+" failed"
+		args = append(args, slog.Any("error", err))
+	}
+	logger.Log(context.Background(), level, msg, args...)
+}
+
+// record records an event of Log, with the container ID of its "container"
+// argument, if any.
+func record(pkg, event string, start time.Time, duration time.Duration, err error, args []any) {
+	r := Record{
+		Package:  pkg,
+		Event:    event,
+		Start:    start,
+		Duration: duration,
+	}
+	for i := 0; i+1 < len(args); i++ {
+		if key, ok := args[i].(string); ok && key == "container" {
+			r.Container, _ = args[i+1].(string)
+			break
+		}
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	records.Lock()
+	defer records.Unlock()
+	records.list = append(records.list, r)
+}
+
+// Kept writes to w that the containers with the given names of the
+// package pkg are kept after the test, how to connect to them at
+// endpoint, and how to remove them. See core.WithKeepContainer.
+func Kept(w io.Writer, pkg, endpoint string, names ...string) {
+	fmt.Fprintf(w, "%s: kept container %s\n\tconnect: %s\n\tremove:  docker rm -f -v %s\n",
+		pkg, strings.Join(names, ", "), endpoint, strings.Join(names, " "))
+}
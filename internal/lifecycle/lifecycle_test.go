@@ -0,0 +1,77 @@
+package lifecycle_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/internal/lifecycle"
+)
+
+func TestLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	lifecycle.Log(logger, "postgres", lifecycle.Start, time.Now().Add(-time.Second), nil, "container", "abc")
+	line := buf.String()
+	for _, want := range []string{`msg="container start"`, "package=postgres", "event=start", "duration=1", "container=abc", "level=INFO"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("want %q in %q", want, line)
+		}
+	}
+
+	buf.Reset()
+	lifecycle.Log(logger, "postgres", lifecycle.Ready, time.Now(), errors.New("timeout"))
+	line = buf.String()
+	for _, want := range []string{`msg="container ready failed"`, "level=ERROR", "error=timeout"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("want %q in %q", want, line)
+		}
+	}
+
+	// A nil logger logs nothing
+	lifecycle.Log(nil, "postgres", lifecycle.Close, time.Now(), nil)
+}
+
+func TestRecords(t *testing.T) {
+	start := time.Now().Add(-time.Second)
+	lifecycle.Log(nil, "records", lifecycle.Seed, start, errors.New("boom"), "container", "abc")
+
+	var found bool
+	for _, r := range lifecycle.Records() {
+		if r.Package != "records" {
+			continue
+		}
+		found = true
+		if want, have := lifecycle.Seed, r.Event; want != have {
+			t.Errorf("want Event=%q, have %q", want, have)
+		}
+		if want, have := "abc", r.Container; want != have {
+			t.Errorf("want Container=%q, have %q", want, have)
+		}
+		if want, have := "boom", r.Error; want != have {
+			t.Errorf("want Error=%q, have %q", want, have)
+		}
+		if r.Duration < time.Second {
+			t.Errorf("want Duration of at least 1s, have %v", r.Duration)
+		}
+	}
+	if !found {
+		t.Fatal("want event to be recorded without a logger")
+	}
+}
+
+func TestKept(t *testing.T) {
+	var buf bytes.Buffer
+	lifecycle.Kept(&buf, "elasticsearch", "http://localhost:9200", "es1", "es2")
+
+	out := buf.String()
+	for _, want := range []string{"elasticsearch: kept container es1, es2", "connect: http://localhost:9200", "docker rm -f -v es1 es2"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("want %q in output, have:\n%s", want, out)
+		}
+	}
+}
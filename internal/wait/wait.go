@@ -0,0 +1,136 @@
+// Package wait implements the checks that decide when a container is
+// ready, for use by the container packages.
+package wait
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// Check returns nil if a container is ready, and an error describing why
+// it is not ready otherwise.
+type Check func(ctx context.Context) error
+
+// Policy decides how Until retries a check. The zero value retries with
+// exponential backoff from 100ms to 5s until the timeout elapses.
+type Policy struct {
+	// MaxAttempts is the maximum number of times check is called. Zero
+	// means no limit.
+	MaxAttempts int
+
+	// InitialBackoff is the time to wait after the first failed attempt.
+	// It doubles after each attempt. Zero means 100ms.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum time to wait between attempts. Zero
+	// means 5s.
+	MaxBackoff time.Duration
+}
+
+// Until calls check with exponential backoff until it returns nil, timeout
+// elapses, or ctx is done. It returns the last error of check in the
+// latter cases.
+func Until(ctx context.Context, timeout time.Duration, check Check) error {
+	return Policy{}.Until(ctx, timeout, check)
+}
+
+// Until calls check with the backoff of p until it returns nil, timeout
+// elapses, p.MaxAttempts is reached, or ctx is done. It returns the last
+// error of check in the latter cases. The context passed to check expires
+// at the timeout, so a check that hangs cannot outlive it.
+func (p Policy) Until(ctx context.Context, timeout time.Duration, check Check) error {
+	backoff := cmp.Or(p.InitialBackoff, 100*time.Millisecond)
+	maxBackoff := cmp.Or(p.MaxBackoff, 5*time.Second)
+	deadline := time.Now().Add(timeout)
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+	for attempt := 1; ; attempt++ {
+		err := check(ctx)
+		if err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return fmt.Errorf("not ready after %d attempts: %w", attempt, err)
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return fmt.Errorf("not ready after %v: %w", timeout, err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w", ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+}
+
+// Permanent wraps err so that Until returns it without retrying, e.g.
+// when the container exited and never becomes ready.
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// All returns a Check that succeeds if all checks succeed, in order.
+func All(checks ...Check) Check {
+	return func(ctx context.Context) error {
+		for _, check := range checks {
+			if err := check(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// HTTPStatus returns a Check that succeeds if a GET request to url with
+// the given client returns the given status code.
+func HTTPStatus(client *http.Client, url string, status int) Check {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		io.Copy(io.Discard, res.Body)
+		if res.StatusCode != status {
+			return fmt.Errorf("GET %s returned status %d, want %d", req.URL.Path, res.StatusCode, status)
+		}
+		return nil
+	}
+}
+
+// LogMatch returns a Check that succeeds if the log returned by logs
+// matches re.
+func LogMatch(logs func(ctx context.Context) (string, error), re *regexp.Regexp) Check {
+	return func(ctx context.Context) error {
+		s, err := logs(ctx)
+		if err != nil {
+			return fmt.Errorf("could not read logs: %w", err)
+		}
+		if !re.MatchString(s) {
+			return fmt.Errorf("log does not match %q yet", re)
+		}
+		return nil
+	}
+}
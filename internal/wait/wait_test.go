@@ -0,0 +1,160 @@
+package wait_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/internal/wait"
+)
+
+func TestUntil(t *testing.T) {
+	var calls int32
+	err := wait.Until(context.Background(), 5*time.Second, func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("want no error, have %v", err)
+	}
+	if want, have := int32(3), atomic.LoadInt32(&calls); want != have {
+		t.Fatalf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestUntil_Timeout(t *testing.T) {
+	cause := errors.New("cause")
+	err := wait.Until(context.Background(), 300*time.Millisecond, func(ctx context.Context) error {
+		return cause
+	})
+	if !errors.Is(err, cause) {
+		t.Fatalf("want error to wrap the cause, have %v", err)
+	}
+}
+
+func TestUntil_BlockingCheck(t *testing.T) {
+	start := time.Now()
+	err := wait.Until(context.Background(), 300*time.Millisecond, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("want context.DeadlineExceeded, have %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("want Until to give up after the timeout, took %v", elapsed)
+	}
+}
+
+func TestPolicy_MaxAttempts(t *testing.T) {
+	var calls int32
+	policy := wait.Policy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+	err := policy.Until(context.Background(), time.Minute, func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("not yet")
+	})
+	if err == nil || !strings.Contains(err.Error(), "2 attempts") {
+		t.Fatalf("want error after 2 attempts, have %v", err)
+	}
+	if want, have := int32(2), atomic.LoadInt32(&calls); want != have {
+		t.Fatalf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestPolicy_MaxBackoff(t *testing.T) {
+	var calls int32
+	policy := wait.Policy{InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+	start := time.Now()
+	err := policy.Until(context.Background(), time.Minute, func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) < 10 {
+			return errors.New("not yet")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("want no error, have %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("want backoff to be capped, have %v for %d calls", elapsed, calls)
+	}
+}
+
+func TestUntil_Permanent(t *testing.T) {
+	var calls int32
+	cause := errors.New("exited")
+	err := wait.Until(context.Background(), time.Minute, func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return wait.Permanent(cause)
+	})
+	if err != cause {
+		t.Fatalf("want cause, have %v", err)
+	}
+	if want, have := int32(1), atomic.LoadInt32(&calls); want != have {
+		t.Fatalf("want %d calls, have %d", want, have)
+	}
+}
+
+func TestUntil_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := wait.Until(ctx, time.Minute, func(ctx context.Context) error {
+		return errors.New("not yet")
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("want context.Canceled, have %v", err)
+	}
+}
+
+func TestHTTPStatus(t *testing.T) {
+	var ready atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !ready.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	check := wait.HTTPStatus(srv.Client(), srv.URL+"/health", http.StatusOK)
+	err := check(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("want error with status 503, have %v", err)
+	}
+	ready.Store(true)
+	if err := check(context.Background()); err != nil {
+		t.Fatalf("want no error, have %v", err)
+	}
+}
+
+func TestLogMatch(t *testing.T) {
+	var log string
+	logs := func(ctx context.Context) (string, error) { return log, nil }
+	check := wait.LogMatch(logs, regexp.MustCompile(`started`))
+	if err := check(context.Background()); err == nil {
+		t.Fatal("want error, have nil")
+	}
+	log = "node started\n"
+	if err := check(context.Background()); err != nil {
+		t.Fatalf("want no error, have %v", err)
+	}
+}
+
+func TestAll(t *testing.T) {
+	cause := errors.New("second")
+	check := wait.All(
+		func(ctx context.Context) error { return nil },
+		func(ctx context.Context) error { return cause },
+	)
+	if err := check(context.Background()); !errors.Is(err, cause) {
+		t.Fatalf("want second error, have %v", err)
+	}
+}
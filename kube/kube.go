@@ -0,0 +1,462 @@
+// Package kube starts containers as pods in a Kubernetes namespace, for
+// environments without a Docker daemon, e.g. CI jobs that run in a cluster.
+//
+// Every container is a pod with a service in front of it, so other pods
+// reach it by the DNS name of the service. The cluster is selected like
+// kubectl does, i.e. by KUBECONFIG or ~/.kube/config, or by the service
+// account when running in a pod.
+//
+// The fixtures of the postgres and elasticsearch packages run as pods, too,
+// if INTEGRATIONTEST_BACKEND=kube, with the same API as their Docker
+// containers:
+//
+//	// INTEGRATIONTEST_BACKEND=kube go test ./...
+//	func TestUsers(t *testing.T) {
+//		c := postgres.Start(t)
+//		db := c.DB() // connected to the service of the pod
+//		...
+//	}
+//
+// As they connect to the address of the service, e.g.
+// "integrationtest-0123456789abcdef-42.ci.svc:5432", the tests must run
+// in the cluster, too.
+package kube
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/olivere/integrationtest/internal/wait"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// BackendEnv is the environment variable that makes the fixtures of the
+// other packages run as pods instead of Docker containers when set to
+// "kube", see Enabled.
+const BackendEnv = "INTEGRATIONTEST_BACKEND"
+
+// Enabled returns true if fixtures run as pods, i.e. BackendEnv is "kube".
+func Enabled() bool {
+	return os.Getenv(BackendEnv) == "kube"
+}
+
+// LabelManagedBy is the label of the pods and services that the package
+// creates, with the value "integrationtest". They are also labeled with
+// the session of the test process, see core.LabelSession.
+const LabelManagedBy = "app.kubernetes.io/managed-by"
+
+// Pod is a container that runs as a pod in Kubernetes.
+type Pod struct {
+	client      kubernetes.Interface
+	namespace   string
+	name        string
+	ports       []int32
+	timeout     time.Duration
+	retryPolicy core.RetryPolicy
+
+	mu     sync.Mutex
+	closed bool
+}
+
+type startConfig struct {
+	client      kubernetes.Interface
+	namespace   string
+	command     []string
+	args        []string
+	env         []string
+	ports       []int32
+	probe       *corev1.Probe
+	timeout     time.Duration
+	maxLifetime time.Duration
+	retryPolicy core.RetryPolicy
+}
+
+type startConfigFunc func(*startConfig)
+
+var _ core.Container = (*Pod)(nil)
+
+// WithOptions applies the options that all container packages support,
+// see core.Config. The version is ignored, as the image includes its tag.
+func WithOptions(options ...core.Option) startConfigFunc {
+	return func(cfg *startConfig) {
+		coreCfg := core.NewConfig(options...)
+		if coreCfg.Timeout != 0 {
+			cfg.timeout = coreCfg.Timeout
+		}
+		if coreCfg.MaxLifetime != 0 {
+			cfg.maxLifetime = coreCfg.MaxLifetime
+		}
+		cfg.env = append(cfg.env, coreCfg.Env...)
+		if coreCfg.RetryPolicy != (core.RetryPolicy{}) {
+			cfg.retryPolicy = coreCfg.RetryPolicy
+		}
+	}
+}
+
+// WithClient uses the given client instead of the one configured by the
+// environment, e.g. a fake client in tests.
+func WithClient(client kubernetes.Interface) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.client = client
+	}
+}
+
+// WithNamespace starts the pod in the given namespace. It defaults to
+// INTEGRATIONTEST_KUBE_NAMESPACE, or the namespace of the kubeconfig
+// context, or of the service account when running in a pod.
+func WithNamespace(namespace string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.namespace = namespace
+	}
+}
+
+// WithCommand overrides the entrypoint of the image.
+func WithCommand(command ...string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.command = command
+	}
+}
+
+// WithArgs overrides the command of the image.
+func WithArgs(args ...string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.args = args
+	}
+}
+
+// WithEnv sets environment variables of the container, in the form
+// "KEY=value".
+func WithEnv(env ...string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.env = append(cfg.env, env...)
+	}
+}
+
+// WithPorts exposes the given TCP ports of the container via the service.
+// Unless WithReadinessProbe is used, the pod is ready when it accepts
+// connections on the first port.
+func WithPorts(ports ...int32) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.ports = append(cfg.ports, ports...)
+	}
+}
+
+// WithReadinessProbe sets the probe that decides when the pod is ready,
+// e.g. an exec probe running pg_isready.
+func WithReadinessProbe(probe *corev1.Probe) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.probe = probe
+	}
+}
+
+// WithTimeout sets the time to wait for the pod to become ready. It
+// defaults to 2 minutes.
+func WithTimeout(timeout time.Duration) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.timeout = timeout
+	}
+}
+
+// WithMaxLifetime sets the time after which Kubernetes stops the pod, in
+// case the test process dies before it can delete it. It defaults to
+// core.DefaultMaxLifetime. Use a negative value to never stop it.
+func WithMaxLifetime(lifetime time.Duration) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.maxLifetime = lifetime
+	}
+}
+
+// Start a pod with the given image and wait for it to become ready.
+func Start(tb testing.TB, image string, options ...startConfigFunc) *Pod {
+	tb.Helper()
+
+	p, err := start(context.Background(), image, options...)
+	if p != nil {
+		tb.Cleanup(func() {
+			p.Close()
+		})
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return p
+}
+
+// StartE is like Start but returns an error instead of failing a test.
+// The caller is responsible for closing the pod.
+func StartE(image string, options ...startConfigFunc) (*Pod, error) {
+	return Run(context.Background(), image, options...)
+}
+
+// Run is like StartE but stops waiting for the pod to become ready when
+// ctx is done, in addition to the timeout. The caller must close the pod.
+func Run(ctx context.Context, image string, options ...startConfigFunc) (*Pod, error) {
+	p, err := start(ctx, image, options...)
+	if err != nil {
+		if p != nil {
+			p.Close()
+		}
+		return nil, err
+	}
+	return p, nil
+}
+
+// start a pod. If it returns an error along with a non-nil Pod, the
+// caller is responsible for closing it.
+func start(ctx context.Context, image string, options ...startConfigFunc) (*Pod, error) {
+	startCfg := startConfig{
+		namespace: os.Getenv("INTEGRATIONTEST_KUBE_NAMESPACE"),
+		timeout:   2 * time.Minute,
+	}
+	for _, o := range options {
+		o(&startCfg)
+	}
+
+	client, namespace := startCfg.client, startCfg.namespace
+	if client == nil {
+		clientCfg := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			clientcmd.NewDefaultClientConfigLoadingRules(),
+			&clientcmd.ConfigOverrides{},
+		)
+		restCfg, err := clientCfg.ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("unable to configure Kubernetes client: %w", err)
+		}
+		client, err = kubernetes.NewForConfig(restCfg)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create Kubernetes client: %w", err)
+		}
+		if namespace == "" {
+			namespace, _, err = clientCfg.Namespace()
+			if err != nil {
+				return nil, fmt.Errorf("unable to get Kubernetes namespace: %w", err)
+			}
+		}
+	}
+	if namespace == "" {
+		namespace = metav1.NamespaceDefault
+	}
+
+	p := &Pod{
+		client:      client,
+		namespace:   namespace,
+		name:        "integrationtest-" + core.SessionID() + "-" + dockerutil.Suffix(),
+		ports:       startCfg.ports,
+		timeout:     startCfg.timeout,
+		retryPolicy: startCfg.retryPolicy,
+	}
+
+	labels := map[string]string{
+		LabelManagedBy:    "integrationtest",
+		core.LabelSession: core.SessionID(),
+		"app":             p.name,
+	}
+	container := corev1.Container{
+		Name:           "main",
+		Image:          image,
+		Command:        startCfg.command,
+		Args:           startCfg.args,
+		Env:            envVars(startCfg.env),
+		ReadinessProbe: startCfg.probe,
+	}
+	for _, port := range startCfg.ports {
+		container.Ports = append(container.Ports, corev1.ContainerPort{
+			ContainerPort: port,
+			Protocol:      corev1.ProtocolTCP,
+		})
+	}
+	if container.ReadinessProbe == nil && len(startCfg.ports) > 0 {
+		container.ReadinessProbe = &corev1.Probe{
+			ProbeHandler: corev1.ProbeHandler{
+				TCPSocket: &corev1.TCPSocketAction{Port: intstr.FromInt32(startCfg.ports[0])},
+			},
+			PeriodSeconds: 1,
+		}
+	}
+
+	// Kubernetes stops the pod after its lifetime, like Docker containers
+	// expire
+	var deadline *int64
+	if lifetime := cmp.Or(startCfg.maxLifetime, core.DefaultMaxLifetime); lifetime > 0 {
+		seconds := int64(max(lifetime.Seconds(), 1))
+		deadline = &seconds
+	}
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        p.name,
+			Labels:      labels,
+			Annotations: core.Labels("kube", ""),
+		},
+		Spec: corev1.PodSpec{
+			Containers:            []corev1.Container{container},
+			RestartPolicy:         corev1.RestartPolicyNever,
+			ActiveDeadlineSeconds: deadline,
+		},
+	}
+	if _, err := client.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		return nil, fmt.Errorf("unable to create pod: %w", err)
+	}
+
+	if len(startCfg.ports) > 0 {
+		svc := &corev1.Service{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   p.name,
+				Labels: labels,
+			},
+			Spec: corev1.ServiceSpec{
+				Selector: map[string]string{"app": p.name},
+			},
+		}
+		for _, port := range startCfg.ports {
+			svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{
+				Name:       "tcp-" + strconv.Itoa(int(port)),
+				Port:       port,
+				TargetPort: intstr.FromInt32(port),
+				Protocol:   corev1.ProtocolTCP,
+			})
+		}
+		if _, err := client.CoreV1().Services(namespace).Create(ctx, svc, metav1.CreateOptions{}); err != nil {
+			return p, fmt.Errorf("unable to create service: %w", err)
+		}
+	}
+
+	if err := p.WaitUntilReady(ctx); err != nil {
+		return p, fmt.Errorf("pod %s did not become ready: %w", p.name, err)
+	}
+	return p, nil
+}
+
+// envVars converts env in the form "KEY=value" to environment variables
+// of a container.
+func envVars(env []string) []corev1.EnvVar {
+	var vars []corev1.EnvVar
+	for _, kv := range env {
+		name, value, _ := strings.Cut(kv, "=")
+		vars = append(vars, corev1.EnvVar{Name: name, Value: value})
+	}
+	return vars
+}
+
+// errPodFailed stops waiting for a pod that will never become ready.
+var errPodFailed = errors.New("pod failed")
+
+// WaitUntilReady waits until the pod is ready. It implements
+// core.Container.
+func (p *Pod) WaitUntilReady(ctx context.Context) error {
+	var failed error
+	err := wait.Policy(p.retryPolicy).Until(ctx, p.timeout, func(ctx context.Context) error {
+		pod, err := p.client.CoreV1().Pods(p.namespace).Get(ctx, p.name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if err := podFailure(pod); err != nil {
+			failed = err
+			return nil
+		}
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type == corev1.PodReady && cond.Status == corev1.ConditionTrue {
+				return nil
+			}
+		}
+		return fmt.Errorf("pod is %s", pod.Status.Phase)
+	})
+	if failed != nil {
+		return failed
+	}
+	return err
+}
+
+// podFailure returns an error if pod stopped, or cannot start its
+// container.
+func podFailure(pod *corev1.Pod) error {
+	switch pod.Status.Phase {
+	case corev1.PodFailed, corev1.PodSucceeded:
+		return fmt.Errorf("%w: pod is %s: %s", errPodFailed, pod.Status.Phase, pod.Status.Message)
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if w := status.State.Waiting; w != nil {
+			switch w.Reason {
+			case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "CreateContainerConfigError", "CrashLoopBackOff":
+				return fmt.Errorf("%w: %s: %s", errPodFailed, w.Reason, w.Message)
+			}
+		}
+	}
+	return nil
+}
+
+// Close deletes the pod and its service.
+func (p *Pod) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if len(p.ports) > 0 {
+		err := p.client.CoreV1().Services(p.namespace).Delete(ctx, p.name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return fmt.Errorf("could not delete service: %w", err)
+		}
+	}
+	var grace int64
+	err := p.client.CoreV1().Pods(p.namespace).Delete(ctx, p.name, metav1.DeleteOptions{GracePeriodSeconds: &grace})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return fmt.Errorf("could not delete pod: %w", err)
+	}
+	p.closed = true
+	return nil
+}
+
+// Name returns the name of the pod and its service.
+func (p *Pod) Name() string {
+	return p.name
+}
+
+// Namespace returns the namespace of the pod.
+func (p *Pod) Namespace() string {
+	return p.namespace
+}
+
+// Address returns the address of port of the pod within the cluster, e.g.
+// "integrationtest-0123456789abcdef-42.default.svc:5432".
+func (p *Pod) Address(port int32) string {
+	return net.JoinHostPort(p.name+"."+p.namespace+".svc", strconv.Itoa(int(port)))
+}
+
+// Endpoint returns the address of the first port of the pod, or the name
+// of the pod if it has no ports, and hence no service. It implements
+// core.Container.
+func (p *Pod) Endpoint() string {
+	if len(p.ports) == 0 {
+		return p.name
+	}
+	return p.Address(p.ports[0])
+}
+
+// Logs returns the output of the pod so far. It implements core.Container.
+func (p *Pod) Logs(ctx context.Context) (string, error) {
+	b, err := p.client.CoreV1().Pods(p.namespace).GetLogs(p.name, &corev1.PodLogOptions{}).DoRaw(ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not get logs of pod %s: %w", p.name, err)
+	}
+	return string(b), nil
+}
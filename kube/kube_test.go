@@ -0,0 +1,175 @@
+package kube_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/kube"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// readyOnCreate makes the fake cluster report created pods as ready, or
+// sets status if not nil.
+func readyOnCreate(client *fake.Clientset, status *corev1.PodStatus) {
+	client.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		pod := action.(k8stesting.CreateAction).GetObject().(*corev1.Pod)
+		if status != nil {
+			pod.Status = *status
+			return false, nil, nil
+		}
+		pod.Status = corev1.PodStatus{
+			Phase: corev1.PodRunning,
+			Conditions: []corev1.PodCondition{
+				{Type: corev1.PodReady, Status: corev1.ConditionTrue},
+			},
+		}
+		return false, nil, nil
+	})
+}
+
+func TestStart(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	readyOnCreate(client, nil)
+
+	p := kube.Start(t, "postgres:16-alpine",
+		kube.WithClient(client),
+		kube.WithNamespace("ci"),
+		kube.WithPorts(5432),
+		kube.WithEnv("POSTGRES_PASSWORD=postgres"),
+	)
+
+	pod, err := client.CoreV1().Pods("ci").Get(context.Background(), p.Name(), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("want pod to be created, have %v", err)
+	}
+	if want, have := "integrationtest", pod.Labels[kube.LabelManagedBy]; want != have {
+		t.Errorf("want label %q, have %q", want, have)
+	}
+	container := pod.Spec.Containers[0]
+	if want, have := "postgres:16-alpine", container.Image; want != have {
+		t.Errorf("want image %q, have %q", want, have)
+	}
+	if len(container.Env) != 1 || container.Env[0].Name != "POSTGRES_PASSWORD" || container.Env[0].Value != "postgres" {
+		t.Errorf("want env POSTGRES_PASSWORD, have %v", container.Env)
+	}
+	if container.ReadinessProbe == nil || container.ReadinessProbe.TCPSocket == nil {
+		t.Errorf("want TCP readiness probe, have %v", container.ReadinessProbe)
+	}
+	if pod.Spec.ActiveDeadlineSeconds == nil || *pod.Spec.ActiveDeadlineSeconds != int64(core.DefaultMaxLifetime.Seconds()) {
+		t.Errorf("want pod to have a deadline of %v, have %v", core.DefaultMaxLifetime, pod.Spec.ActiveDeadlineSeconds)
+	}
+
+	if _, err := client.CoreV1().Services("ci").Get(context.Background(), p.Name(), metav1.GetOptions{}); err != nil {
+		t.Fatalf("want service to be created, have %v", err)
+	}
+	if want, have := p.Name()+".ci.svc:5432", p.Endpoint(); want != have {
+		t.Errorf("want endpoint %q, have %q", want, have)
+	}
+
+	logs, err := p.Logs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logs == "" {
+		t.Error("want logs")
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if pods, _ := client.CoreV1().Pods("ci").List(context.Background(), metav1.ListOptions{}); len(pods.Items) != 0 {
+		t.Errorf("want pod to be deleted, have %d pods", len(pods.Items))
+	}
+	if svcs, _ := client.CoreV1().Services("ci").List(context.Background(), metav1.ListOptions{}); len(svcs.Items) != 0 {
+		t.Errorf("want service to be deleted, have %d services", len(svcs.Items))
+	}
+}
+
+func TestStart_ImagePullError(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	readyOnCreate(client, &corev1.PodStatus{
+		Phase: corev1.PodPending,
+		ContainerStatuses: []corev1.ContainerStatus{{
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull", Message: "not found"},
+			},
+		}},
+	})
+
+	start := time.Now()
+	_, err := kube.StartE("does-not-exist:latest",
+		kube.WithClient(client),
+		kube.WithTimeout(time.Minute),
+	)
+	if err == nil || !strings.Contains(err.Error(), "ErrImagePull") {
+		t.Fatalf("want ErrImagePull, have %v", err)
+	}
+	if time.Since(start) > 10*time.Second {
+		t.Fatal("want to fail without waiting for the timeout")
+	}
+	if pods, _ := client.CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{}); len(pods.Items) != 0 {
+		t.Errorf("want pod to be deleted, have %d pods", len(pods.Items))
+	}
+}
+
+func TestStart_WithMaxLifetime(t *testing.T) {
+	tests := []struct {
+		lifetime time.Duration
+		want     int64
+	}{
+		{10 * time.Minute, 600},
+		{-1, 0},
+	}
+	for _, tt := range tests {
+		client := fake.NewSimpleClientset()
+		readyOnCreate(client, nil)
+
+		p := kube.Start(t, "alpine:3.19",
+			kube.WithClient(client),
+			kube.WithTimeout(time.Second),
+			kube.WithMaxLifetime(tt.lifetime),
+		)
+		pod, err := client.CoreV1().Pods(metav1.NamespaceDefault).Get(context.Background(), p.Name(), metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("want pod to be created, have %v", err)
+		}
+		var have int64
+		if pod.Spec.ActiveDeadlineSeconds != nil {
+			have = *pod.Spec.ActiveDeadlineSeconds
+		}
+		if tt.want != have {
+			t.Errorf("lifetime %v: want deadline of %d seconds, have %d", tt.lifetime, tt.want, have)
+		}
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	for value, want := range map[string]bool{"": false, "docker": false, "kube": true} {
+		t.Setenv(kube.BackendEnv, value)
+		if have := kube.Enabled(); want != have {
+			t.Errorf("%s=%q: want Enabled=%v, have %v", kube.BackendEnv, value, want, have)
+		}
+	}
+}
+
+func TestRun_Canceled(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	readyOnCreate(client, &corev1.PodStatus{Phase: corev1.PodPending})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := kube.Run(ctx, "alpine:3.19", kube.WithClient(client))
+	if err == nil {
+		t.Fatal("want error")
+	}
+	if pods, _ := client.CoreV1().Pods(metav1.NamespaceDefault).List(context.Background(), metav1.ListOptions{}); len(pods.Items) != 0 {
+		t.Errorf("want pod to be deleted, have %d pods", len(pods.Items))
+	}
+}
@@ -0,0 +1,22 @@
+package mssql_test
+
+import (
+	"testing"
+
+	"github.com/olivere/integrationtest/mssql"
+)
+
+func TestContainerCache_GetOrCreate(t *testing.T) {
+	cache := mssql.NewContainerCache()
+	defer cache.Close()
+
+	c1 := cache.GetOrCreate("one", func() *mssql.Container {
+		return mssql.Start(t)
+	})
+	c2 := cache.GetOrCreate("one", func() *mssql.Container {
+		return mssql.Start(t)
+	})
+	if c1 != c2 {
+		t.Fatal("want same container, have different")
+	}
+}
@@ -0,0 +1,131 @@
+package mssql_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/mssql"
+)
+
+func TestContainer_Start(t *testing.T) {
+	c := mssql.Start(t, mssql.WithTimeout(2*time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var database string
+	if err := c.DB().QueryRowContext(ctx, "SELECT DB_NAME()").Scan(&database); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := c.DatabaseName(), database; want != have {
+		t.Fatalf("want database %q, have %q", want, have)
+	}
+
+	// Clients of the endpoint see the same server
+	db, err := mssql.Connect(ctx, c.Endpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("could not stop container: %v", err)
+	}
+	if err := db.PingContext(ctx); err == nil {
+		t.Fatal("want error after Close, have nil")
+	}
+}
+
+func TestContainer_WithPassword(t *testing.T) {
+	c := mssql.Start(t, mssql.WithPassword("Sup3r-Secret"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.DB().PingContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(c.Endpoint(), "Sup3r-Secret") {
+		t.Fatalf("want password in endpoint, have %s", c.Endpoint())
+	}
+}
+
+func TestStart_InvalidPassword(t *testing.T) {
+	tests := []string{
+		"short1A",       // less than 8 characters
+		"alllowercase",  // one category
+		"lowerandUPPER", // two categories
+	}
+	for _, password := range tests {
+		_, err := mssql.Run(context.Background(), mssql.WithPassword(password))
+		if err == nil || !strings.Contains(err.Error(), "password") {
+			t.Errorf("password %q: want password error, have %v", password, err)
+		}
+	}
+}
+
+func TestStart_MemoryLimit(t *testing.T) {
+	_, err := mssql.Run(context.Background(), mssql.WithMemoryLimit(1<<30))
+	if err == nil || !strings.Contains(err.Error(), "2GB") {
+		t.Fatalf("want memory limit error, have %v", err)
+	}
+}
+
+func TestContainer_WithPostStart(t *testing.T) {
+	c := mssql.Start(t, mssql.WithPostStart(func(ctx context.Context, c *mssql.Container) error {
+		_, err := c.DB().ExecContext(ctx, "CREATE TABLE users (id INT PRIMARY KEY, email NVARCHAR(255) NOT NULL UNIQUE)")
+		return err
+	}))
+
+	_, err := c.DB().Exec("INSERT INTO users (id, email) VALUES (1, 'oliver@example.com')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.DB().Exec("INSERT INTO users (id, email) VALUES (2, 'oliver@example.com')")
+	if !mssql.IsDup(err) {
+		t.Fatalf("want duplicate key, have %v", err)
+	}
+}
+
+func TestContainer_CreateDatabase(t *testing.T) {
+	c := mssql.Start(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	db, err := c.CreateDatabase(ctx, "orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var database string
+	if err := db.QueryRowContext(ctx, "SELECT DB_NAME()").Scan(&database); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "orders", database; want != have {
+		t.Fatalf("want database %q, have %q", want, have)
+	}
+
+	// Dropping closes the open connections of db
+	if err := c.DropDatabase(ctx, "orders"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.CreateDatabase(ctx, c.DatabaseName()); !mssql.IsDupDB(err) {
+		t.Fatalf("want database to exist, have %v", err)
+	}
+}
+
+func TestImage(t *testing.T) {
+	tests := []struct {
+		want string
+		have string
+	}{
+		{want: "mcr.microsoft.com/mssql/server:2022-latest", have: mssql.Image()},
+		{want: "mcr.microsoft.com/mssql/server:2019-latest", have: mssql.Image(mssql.WithVersion("2019-latest"))},
+	}
+	for _, tt := range tests {
+		if tt.want != tt.have {
+			t.Errorf("want %q, have %q", tt.want, tt.have)
+		}
+	}
+}
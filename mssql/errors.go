@@ -0,0 +1,130 @@
+package mssql
+
+import (
+	"database/sql"
+	stderrors "errors"
+	"strings"
+
+	mssql "github.com/microsoft/go-mssqldb"
+)
+
+// IsNotFound returns true if the given error indicates that
+// a record could not be found.
+func IsNotFound(err error) bool {
+	return stderrors.Is(err, sql.ErrNoRows)
+}
+
+// IsMSSQLError returns true if the given error is from SQL Server and has
+// one of the given error numbers.
+//
+// See https://learn.microsoft.com/en-us/sql/relational-databases/errors-events/database-engine-events-and-errors
+// for a list of all SQL Server error numbers.
+func IsMSSQLError(err error, numbers ...int32) bool {
+	if err == nil {
+		return false
+	}
+	var mserr mssql.Error
+	if stderrors.As(err, &mserr) {
+		for _, number := range numbers {
+			if mserr.Number == number {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ErrorNumber returns the error number of the given error if it is from
+// SQL Server, e.g. 2627. It returns 0 otherwise.
+func ErrorNumber(err error) int32 {
+	var mserr mssql.Error
+	if stderrors.As(err, &mserr) {
+		return mserr.Number
+	}
+	return 0
+}
+
+// isConstraintConflict returns true if the given error indicates
+// a conflict with a constraint of the given kind, e.g. "FOREIGN KEY".
+// SQL Server reports foreign key and check violations with the same
+// number, and names the kind in the message only.
+func isConstraintConflict(err error, kind string) bool {
+	var mserr mssql.Error
+	if !stderrors.As(err, &mserr) || mserr.Number != 547 {
+		return false
+	}
+	return strings.Contains(mserr.Message, kind+" constraint")
+}
+
+// IsNotNullViolation returns true if the given error indicates that
+// NULL was inserted into a column that does not allow nulls (515).
+func IsNotNullViolation(err error) bool {
+	// 515 Cannot insert the value NULL into column
+	return IsMSSQLError(err, 515)
+}
+
+// IsForeignKeyViolation returns true if the given error indicates a
+// violation of a foreign key constraint, either by referencing a missing
+// row (547 The statement conflicted with the FOREIGN KEY constraint) or by
+// deleting or updating a referenced one (547 The statement conflicted with
+// the REFERENCE constraint).
+func IsForeignKeyViolation(err error) bool {
+	// 547 The statement conflicted with the FOREIGN KEY/REFERENCE constraint
+	return isConstraintConflict(err, "FOREIGN KEY") || isConstraintConflict(err, "REFERENCE")
+}
+
+// IsDup returns true if the given error indicates that a duplicate
+// record has been found, either by a unique or primary key constraint
+// (2627) or by a unique index (2601).
+func IsDup(err error) bool {
+	// 2627 Violation of UNIQUE KEY/PRIMARY KEY constraint
+	// 2601 Cannot insert duplicate key row with unique index
+	return IsMSSQLError(err, 2627, 2601)
+}
+
+// IsCheckViolation returns true if the given error indicates a
+// violation of a check constraint (547 The statement conflicted with the
+// CHECK constraint).
+func IsCheckViolation(err error) bool {
+	// 547 The statement conflicted with the CHECK constraint
+	return isConstraintConflict(err, "CHECK")
+}
+
+// IsDeadlockDetected returns true if the given error indicates that
+// the transaction was chosen as the victim of a deadlock and should be
+// retried (1205).
+func IsDeadlockDetected(err error) bool {
+	// 1205 Transaction was deadlocked and has been chosen as the deadlock victim
+	return IsMSSQLError(err, 1205)
+}
+
+// IsLockTimeout returns true if the given error indicates that
+// a statement gave up waiting for a lock after LOCK_TIMEOUT (1222).
+func IsLockTimeout(err error) bool {
+	// 1222 Lock request time out period exceeded
+	return IsMSSQLError(err, 1222)
+}
+
+// IsPerm returns true if the given error indicates a permission issue
+// (229 The permission was denied on the object).
+func IsPerm(err error) bool {
+	// 229 The permission was denied on the object
+	return IsMSSQLError(err, 229)
+}
+
+// IsDupDB returns true if the given error indicates the database already
+// exists. This is typically returned from the `CREATE DATABASE dbname` command
+// if `dbname` already exists (1801 Database already exists).
+func IsDupDB(err error) bool {
+	// 1801 Database already exists
+	return IsMSSQLError(err, 1801)
+}
+
+// IsDBNotExists returns true if the given error indicates that the
+// database does not exist, either when dropping it (3701) or using it
+// (911).
+func IsDBNotExists(err error) bool {
+	// 3701 Cannot drop the database because it does not exist
+	// 911 Database does not exist
+	return IsMSSQLError(err, 3701, 911)
+}
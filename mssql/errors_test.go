@@ -0,0 +1,103 @@
+package mssql_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	gomssql "github.com/microsoft/go-mssqldb"
+	"github.com/olivere/integrationtest/mssql"
+)
+
+func TestIsNotFound(t *testing.T) {
+	tests := []struct {
+		Error    error
+		Expected bool
+	}{
+		{Error: nil, Expected: false},
+		{Error: sql.ErrNoRows, Expected: true},
+		{Error: fmt.Errorf("kaboom: %w", sql.ErrNoRows), Expected: true},
+		{Error: errors.New("kaboom"), Expected: false},
+	}
+	for i, tc := range tests {
+		if want, have := tc.Expected, mssql.IsNotFound(tc.Error); want != have {
+			t.Errorf("#%d: mssql.IsNotFound(%v): want %v, have %v", i, tc.Error, want, have)
+		}
+	}
+}
+
+func TestIsDup(t *testing.T) {
+	tests := []struct {
+		Error    error
+		Expected bool
+	}{
+		{Error: nil, Expected: false},
+		{Error: gomssql.Error{Number: 2627}, Expected: true},
+		{Error: gomssql.Error{Number: 2601}, Expected: true},
+		{Error: fmt.Errorf("kaboom: %w", gomssql.Error{Number: 2627}), Expected: true},
+		{Error: gomssql.Error{Number: 547}, Expected: false},
+	}
+	for i, tc := range tests {
+		if want, have := tc.Expected, mssql.IsDup(tc.Error); want != have {
+			t.Errorf("#%d: mssql.IsDup(%v): want %v, have %v", i, tc.Error, want, have)
+		}
+	}
+}
+
+func TestIsForeignKeyViolation(t *testing.T) {
+	insert := gomssql.Error{Number: 547, Message: `The INSERT statement conflicted with the FOREIGN KEY constraint "FK_orders_users".`}
+	del := gomssql.Error{Number: 547, Message: `The DELETE statement conflicted with the REFERENCE constraint "FK_orders_users".`}
+	check := gomssql.Error{Number: 547, Message: `The INSERT statement conflicted with the CHECK constraint "CK_price".`}
+	tests := []struct {
+		Error    error
+		Expected bool
+	}{
+		{Error: nil, Expected: false},
+		{Error: insert, Expected: true},
+		{Error: del, Expected: true},
+		{Error: fmt.Errorf("kaboom: %w", insert), Expected: true},
+		{Error: check, Expected: false},
+	}
+	for i, tc := range tests {
+		if want, have := tc.Expected, mssql.IsForeignKeyViolation(tc.Error); want != have {
+			t.Errorf("#%d: mssql.IsForeignKeyViolation(%v): want %v, have %v", i, tc.Error, want, have)
+		}
+	}
+}
+
+func TestIsCheckViolation(t *testing.T) {
+	check := gomssql.Error{Number: 547, Message: `The INSERT statement conflicted with the CHECK constraint "CK_price".`}
+	fk := gomssql.Error{Number: 547, Message: `The INSERT statement conflicted with the FOREIGN KEY constraint "FK_orders_users".`}
+	tests := []struct {
+		Error    error
+		Expected bool
+	}{
+		{Error: nil, Expected: false},
+		{Error: check, Expected: true},
+		{Error: fmt.Errorf("kaboom: %w", check), Expected: true},
+		{Error: fk, Expected: false},
+	}
+	for i, tc := range tests {
+		if want, have := tc.Expected, mssql.IsCheckViolation(tc.Error); want != have {
+			t.Errorf("#%d: mssql.IsCheckViolation(%v): want %v, have %v", i, tc.Error, want, have)
+		}
+	}
+}
+
+func TestErrorNumber(t *testing.T) {
+	tests := []struct {
+		Error    error
+		Expected int32
+	}{
+		{Error: nil, Expected: 0},
+		{Error: errors.New("kaboom"), Expected: 0},
+		{Error: gomssql.Error{Number: 1205}, Expected: 1205},
+		{Error: fmt.Errorf("kaboom: %w", gomssql.Error{Number: 1222}), Expected: 1222},
+	}
+	for i, tc := range tests {
+		if want, have := tc.Expected, mssql.ErrorNumber(tc.Error); want != have {
+			t.Errorf("#%d: mssql.ErrorNumber(%v): want %v, have %v", i, tc.Error, want, have)
+		}
+	}
+}
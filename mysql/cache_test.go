@@ -0,0 +1,22 @@
+package mysql_test
+
+import (
+	"testing"
+
+	"github.com/olivere/integrationtest/mysql"
+)
+
+func TestContainerCache_GetOrCreate(t *testing.T) {
+	cache := mysql.NewContainerCache()
+	defer cache.Close()
+
+	c1 := cache.GetOrCreate("one", func() *mysql.Container {
+		return mysql.Start(t)
+	})
+	c2 := cache.GetOrCreate("one", func() *mysql.Container {
+		return mysql.Start(t)
+	})
+	if c1 != c2 {
+		t.Fatal("want same container, have different")
+	}
+}
@@ -0,0 +1,483 @@
+// Package mysql starts MySQL containers for integration tests, or
+// containers of the compatible MariaDB, see WithFlavor.
+package mysql
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/fixture"
+	"github.com/ory/dockertest/v3"
+)
+
+type Container struct {
+	flavor       Flavor
+	databaseName string
+	user         string
+	password     string
+	hostPort     string
+	dsn          string
+	db           *sql.DB
+	fixture      *fixture.Container
+
+	mu     sync.Mutex
+	closed bool
+}
+
+type startConfig struct {
+	fixture.Config
+	flavor       Flavor
+	databaseName string
+	user         string
+	password     string
+	postStart    []postStartFunc
+}
+
+type startConfigFunc func(*startConfig)
+
+type postStartFunc func(ctx context.Context, c *Container) error
+
+var _ core.Container = (*Container)(nil)
+
+// WithOptions applies the options that all container packages support,
+// see core.Config.
+func WithOptions(options ...core.Option) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.Apply(options...)
+	}
+}
+
+// Flavor is the server that the container runs.
+type Flavor string
+
+const (
+	// MySQL runs the mysql image. It is the default.
+	MySQL Flavor = "mysql"
+	// MariaDB runs the mariadb image, the fork of MySQL by its original
+	// developers. The driver, DSNs, and the API of Container are the
+	// same, but some error numbers differ, see IsCheckViolation.
+	MariaDB Flavor = "mariadb"
+)
+
+// defaultVersion returns the version of the flavor that Start starts
+// unless WithVersion says otherwise.
+func (f Flavor) defaultVersion() string {
+	if f == MariaDB {
+		return "11.4"
+	}
+	return "8.4"
+}
+
+// envPrefix returns the prefix of the environment variables that the
+// entrypoint of the image reads, e.g. "MYSQL_" for MYSQL_DATABASE.
+func (f Flavor) envPrefix() string {
+	if f == MariaDB {
+		return "MARIADB_"
+	}
+	return "MYSQL_"
+}
+
+// name returns the name of the server in messages, e.g. "MariaDB".
+func (f Flavor) name() string {
+	if f == MariaDB {
+		return "MariaDB"
+	}
+	return "MySQL"
+}
+
+// WithFlavor sets the server to start, e.g. MariaDB to validate
+// compatibility with both servers in one suite:
+//
+//	for _, flavor := range []mysql.Flavor{mysql.MySQL, mysql.MariaDB} {
+//		t.Run(string(flavor), func(t *testing.T) {
+//			c := mysql.Start(t, mysql.WithFlavor(flavor))
+//			...
+//		})
+//	}
+//
+// It defaults to MySQL.
+func WithFlavor(flavor Flavor) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.flavor = flavor
+	}
+}
+
+// WithDatabaseName sets the name of the database that the container
+// creates at startup. It defaults to "integrationtest".
+func WithDatabaseName(databaseName string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.databaseName = databaseName
+	}
+}
+
+// WithVersion sets the version of the flavor to start, e.g. "8.0" for
+// MySQL or "10.11" for MariaDB. It defaults to "8.4" for MySQL and "11.4"
+// for MariaDB.
+func WithVersion(version string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.Version = version
+	}
+}
+
+// WithCredentials sets the user and password that DB and Endpoint
+// connect with. It defaults to "root" with the password "mysql". Any
+// other user is created at startup with all privileges on the database,
+// but not on other databases, so DatabaseExists and friends need root.
+// The password of root is the same in either case.
+func WithCredentials(user, password string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.user = user
+		cfg.password = password
+	}
+}
+
+// WithNetwork connects the container to the given Docker network. Other
+// containers on the network can reach it by its Name.
+func WithNetwork(network *dockertest.Network) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.Networks = append(cfg.Networks, network)
+	}
+}
+
+// WithTimeout sets the time to wait for the container to become ready.
+// It is the same as WithStartupTimeout.
+func WithTimeout(timeout time.Duration) startConfigFunc {
+	return WithStartupTimeout(timeout)
+}
+
+// WithStartupTimeout sets the time to wait for the container to become
+// ready. It defaults to 60 seconds, as MySQL initializes its data
+// directory at startup.
+func WithStartupTimeout(timeout time.Duration) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.Timeout = timeout
+	}
+}
+
+// WithMaxLifetime sets the time after which Docker kills the container,
+// in case the test process dies before it can remove the container. It
+// defaults to core.DefaultMaxLifetime. Use a negative value to never kill
+// it.
+func WithMaxLifetime(lifetime time.Duration) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.MaxLifetime = lifetime
+	}
+}
+
+// WithCPULimit limits the number of CPUs the container may use, e.g. 1.5.
+func WithCPULimit(cpus float64) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.CPUs = cpus
+	}
+}
+
+// WithMemoryLimit sets the memory limit of the container in bytes.
+func WithMemoryLimit(bytes int64) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.Memory = bytes
+	}
+}
+
+// WithPullPolicy sets when to pull the image. It defaults to
+// core.PullIfNotPresent.
+func WithPullPolicy(policy core.PullPolicy) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.PullPolicy = policy
+	}
+}
+
+// WithSkipIfNoDocker skips the test, instead of failing it, if the Docker
+// daemon is unavailable. See core.SkipIfUnavailable.
+func WithSkipIfNoDocker() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.SkipNoDocker = true
+	}
+}
+
+// WithWaitFor sets the strategies that decide when the container is
+// ready, replacing the default of core.ForListeningPort("3306/tcp"). The
+// container is ready when all strategies succeed, in order, and the
+// database accepts connections.
+func WithWaitFor(strategies ...core.WaitStrategy) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.WaitFor = strategies
+	}
+}
+
+// WithRetryPolicy sets how readiness checks are retried while the
+// container starts, see core.WithRetryPolicy. By default, they are retried
+// with exponential backoff from 100ms to 5s until the timeout elapses.
+func WithRetryPolicy(maxAttempts int, initialBackoff, maxBackoff time.Duration) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.RetryPolicy = core.RetryPolicy{
+			MaxAttempts:    maxAttempts,
+			InitialBackoff: initialBackoff,
+			MaxBackoff:     maxBackoff,
+		}
+	}
+}
+
+// WithPortRetries sets how often to retry starting the container if a host
+// port is already in use, see core.WithPortRetries. By default, it is
+// retried core.DefaultPortRetries times.
+func WithPortRetries(retries int) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.PortRetries = retries
+	}
+}
+
+// WithBuiltImage builds the image from the Dockerfile in dir instead of
+// pulling it, e.g. a MySQL image with a custom my.cnf. See
+// core.WithBuiltImage.
+func WithBuiltImage(dir, dockerfile string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.Build = &core.Build{Dir: dir, Dockerfile: dockerfile}
+	}
+}
+
+// WithKeepContainer keeps the container after the test, e.g. to inspect
+// its state after a failing test. See core.WithKeepContainer.
+func WithKeepContainer() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.Keep = true
+	}
+}
+
+// WithPostStart adds post-startup operations to the container, e.g. to
+// create tables or seed data.
+func WithPostStart(funcs ...postStartFunc) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.postStart = append(cfg.postStart, funcs...)
+	}
+}
+
+// Start a MySQL container, or a MariaDB container with
+// WithFlavor(MariaDB). The container is removed when the test
+// finishes.
+func Start(tb testing.TB, options ...startConfigFunc) *Container {
+	tb.Helper()
+
+	if newStartConfig(options...).SkipNoDocker {
+		core.SkipIfUnavailable(tb)
+	}
+
+	c, err := start(context.Background(), append(slices.Clip(options), withTestName(tb.Name()))...)
+	if c != nil {
+		tb.Cleanup(func() {
+			c.Close()
+		})
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
+
+// Run starts a MySQL container outside of tests, e.g. for a local
+// development server that uses the same options as the tests. Unlike
+// Start, the container is not killed after core.DefaultMaxLifetime unless
+// WithMaxLifetime says so. The caller must close the container.
+func Run(ctx context.Context, options ...startConfigFunc) (*Container, error) {
+	c, err := start(ctx, append([]startConfigFunc{WithMaxLifetime(-1)}, options...)...)
+	if err != nil {
+		if c != nil {
+			c.Close()
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// withTestName includes the name of the test in the container name.
+func withTestName(name string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.TestName = name
+	}
+}
+
+// newStartConfig returns the defaults with options applied.
+func newStartConfig(options ...startConfigFunc) startConfig {
+	cfg := startConfig{
+		flavor:       MySQL,
+		databaseName: "integrationtest",
+		user:         "root",
+		password:     "mysql",
+	}
+	cfg.Keep = core.KeepContainers()
+	for _, o := range options {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// image returns the repository and tag of the image to start.
+func (cfg startConfig) image() (string, string) {
+	if cfg.Build != nil {
+		return cfg.Build.Image(string(cfg.flavor))
+	}
+	version := cmp.Or(cfg.Version, cfg.flavor.defaultVersion())
+	if cfg.flavor == MariaDB {
+		return core.Image("mariadb", "mariadb", version)
+	}
+	return core.Image("mysql", "mysql", version)
+}
+
+// Image returns the image that Start starts with the given options, e.g.
+// "mysql:8.4" or "mariadb:11.4", to pull it in advance with core.PullImages.
+func Image(options ...startConfigFunc) string {
+	repository, tag := newStartConfig(options...).image()
+	return repository + ":" + tag
+}
+
+// start a MySQL container. If it returns an error along with a non-nil
+// Container, the caller is responsible for closing it.
+func start(ctx context.Context, options ...startConfigFunc) (*Container, error) {
+	startCfg := newStartConfig(options...)
+
+	c := &Container{
+		flavor:       startCfg.flavor,
+		databaseName: startCfg.databaseName,
+		user:         startCfg.user,
+		password:     startCfg.password,
+	}
+
+	prefix := c.flavor.envPrefix()
+	env := []string{
+		prefix + "ROOT_PASSWORD=" + c.password,
+		prefix + "DATABASE=" + c.databaseName,
+	}
+	if c.user != "root" {
+		env = append(env, prefix+"USER="+c.user, prefix+"PASSWORD="+c.password)
+	}
+
+	repository, tag := startCfg.image()
+	var err error
+	c.fixture, err = fixture.Start(ctx, startCfg.Config, fixture.Spec{
+		Package:    "mysql",
+		Server:     c.flavor.name(),
+		Name:       c.databaseName,
+		Repository: repository,
+		Tag:        tag,
+		Env:        env,
+		Port:       "3306/tcp",
+		Timeout:    60 * time.Second,
+		// The entrypoint initializes the data directory with a server
+		// that does not listen on TCP, so the port is only open once it
+		// is done
+		Ready: func(hostPort string) core.WaitStrategy {
+			c.hostPort = hostPort
+			c.dsn = c.connectionString(c.databaseName)
+			return c.forConnection().WithAttemptTimeout(8 * time.Second)
+		},
+	})
+	if c.fixture == nil {
+		return nil, err
+	}
+	if err != nil {
+		return c, err
+	}
+	return c, fixture.RunPostStart(ctx, c.fixture, c, startCfg.postStart)
+}
+
+// connectionString returns the DSN of the given database on the server.
+func (c *Container) connectionString(databaseName string) string {
+	return formatDSN(c.hostPort, databaseName, c.user, c.password)
+}
+
+// Close stops and removes the container. It is safe to call Close more
+// than once.
+func (c *Container) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	if err := c.fixture.Close(c.dsn); err != nil {
+		return err
+	}
+
+	c.closed = true
+
+	if c.db == nil {
+		return nil
+	}
+	return c.db.Close()
+}
+
+// Name returns the name of the Docker container. Other containers on
+// a shared network (see WithNetwork) can use it as the hostname.
+func (c *Container) Name() string {
+	return c.fixture.Name()
+}
+
+// Endpoint returns the DSN of the database, e.g.
+// "root:mysql@tcp(localhost:32768)/integrationtest?parseTime=true", as
+// parsed by mysql.ParseDSN. It implements core.Container.
+func (c *Container) Endpoint() string {
+	return c.dsn
+}
+
+// Logs returns the output of the server so far. It implements core.Container.
+func (c *Container) Logs(ctx context.Context) (string, error) {
+	return c.fixture.Logs(ctx)
+}
+
+// WaitUntilReady waits until the strategies that decided when the
+// container was ready at start succeed again, see WithWaitFor. It
+// implements core.Container.
+func (c *Container) WaitUntilReady(ctx context.Context) error {
+	return c.fixture.WaitUntilReady(ctx)
+}
+
+// forConnection waits until the database accepts connections, and
+// connects c to it the first time it does.
+func (c *Container) forConnection() core.WaitStrategy {
+	return func(ctx context.Context, _ core.WaitTarget) error {
+		if c.db != nil {
+			return c.db.PingContext(ctx)
+		}
+		db, err := Connect(ctx, c.dsn)
+		if err != nil {
+			return err
+		}
+		c.db = db
+		return nil
+	}
+}
+
+// Flavor returns the server that the container runs, see WithFlavor.
+func (c *Container) Flavor() Flavor {
+	return c.flavor
+}
+
+// DatabaseName returns the name of the database in the container.
+func (c *Container) DatabaseName() string {
+	return c.databaseName
+}
+
+// HostPort returns the address of the server on the host, e.g.
+// "localhost:32768".
+func (c *Container) HostPort() string {
+	return c.hostPort
+}
+
+// DB returns the connection pool of the database.
+func (c *Container) DB() *sql.DB {
+	return c.db
+}
+
+// Config returns the configuration of the driver that DB connects with,
+// e.g. to connect with other options.
+func (c *Container) Config() *mysql.Config {
+	cfg, _ := mysql.ParseDSN(c.dsn)
+	return cfg
+}
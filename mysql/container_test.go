@@ -0,0 +1,131 @@
+package mysql_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/mysql"
+)
+
+func TestContainer_Start(t *testing.T) {
+	c := mysql.Start(t, mysql.WithTimeout(60*time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var database string
+	if err := c.DB().QueryRowContext(ctx, "SELECT DATABASE()").Scan(&database); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := c.DatabaseName(), database; want != have {
+		t.Fatalf("want database %q, have %q", want, have)
+	}
+
+	// Clients of the endpoint see the same server
+	db, err := mysql.Connect(ctx, c.Endpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("could not stop container: %v", err)
+	}
+	if err := db.PingContext(ctx); err == nil {
+		t.Fatal("want error after Close, have nil")
+	}
+}
+
+func TestContainer_WithVersion(t *testing.T) {
+	c := mysql.Start(t, mysql.WithVersion("8.0"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var version string
+	if err := c.DB().QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(version, "8.0.") {
+		t.Fatalf("want MySQL 8.0, have %s", version)
+	}
+}
+
+func TestContainer_WithCredentials(t *testing.T) {
+	c := mysql.Start(t,
+		mysql.WithDatabaseName("shop"),
+		mysql.WithCredentials("app", "s3cret"),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var user string
+	if err := c.DB().QueryRowContext(ctx, "SELECT CURRENT_USER()").Scan(&user); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(user, "app@") {
+		t.Fatalf("want user app, have %s", user)
+	}
+	if _, err := c.DB().ExecContext(ctx, "CREATE TABLE users (id INT PRIMARY KEY)"); err != nil {
+		t.Fatalf("want app to create tables in its database, have %v", err)
+	}
+}
+
+func TestContainer_WithPostStart(t *testing.T) {
+	c := mysql.Start(t, mysql.WithPostStart(func(ctx context.Context, c *mysql.Container) error {
+		_, err := c.DB().ExecContext(ctx, "CREATE TABLE users (id INT PRIMARY KEY, email VARCHAR(255) NOT NULL UNIQUE)")
+		return err
+	}))
+
+	_, err := c.DB().Exec("INSERT INTO users (id, email) VALUES (1, 'oliver@example.com')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.DB().Exec("INSERT INTO users (id, email) VALUES (2, 'oliver@example.com')")
+	if !mysql.IsDup(err) {
+		t.Fatalf("want duplicate entry, have %v", err)
+	}
+}
+
+func TestContainer_WithFlavor(t *testing.T) {
+	c := mysql.Start(t, mysql.WithFlavor(mysql.MariaDB), mysql.WithCredentials("app", "s3cret"))
+	if want, have := mysql.MariaDB, c.Flavor(); want != have {
+		t.Fatalf("want flavor %q, have %q", want, have)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var version string
+	if err := c.DB().QueryRowContext(ctx, "SELECT VERSION()").Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(version, "MariaDB") {
+		t.Fatalf("want MariaDB, have %s", version)
+	}
+
+	_, err := c.DB().ExecContext(ctx, "CREATE TABLE products (price INT CHECK (price > 0))")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.DB().ExecContext(ctx, "INSERT INTO products (price) VALUES (-1)")
+	if !mysql.IsCheckViolation(err) {
+		t.Fatalf("want check violation, have %v", err)
+	}
+}
+
+func TestImage(t *testing.T) {
+	tests := []struct {
+		want string
+		have string
+	}{
+		{want: "mysql:8.4", have: mysql.Image()},
+		{want: "mysql:8.0", have: mysql.Image(mysql.WithVersion("8.0"))},
+		{want: "mariadb:11.4", have: mysql.Image(mysql.WithFlavor(mysql.MariaDB))},
+		{want: "mariadb:10.11", have: mysql.Image(mysql.WithFlavor(mysql.MariaDB), mysql.WithVersion("10.11"))},
+	}
+	for _, tt := range tests {
+		if tt.want != tt.have {
+			t.Errorf("want %q, have %q", tt.want, tt.have)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ConnectionString builds the DSN of the go-sql-driver from the individual
+// components, e.g. "root:mysql@tcp(localhost:3306)/integrationtest?parseTime=true".
+func ConnectionString(host string, port uint16, name, user, pass string) string {
+	return formatDSN(net.JoinHostPort(host, fmt.Sprint(port)), name, user, pass)
+}
+
+// formatDSN returns the DSN of the database name on the server at addr.
+func formatDSN(addr, name, user, pass string) string {
+	cfg := mysql.NewConfig()
+	cfg.Net = "tcp"
+	cfg.Addr = addr
+	cfg.DBName = name
+	cfg.User = user
+	cfg.Passwd = pass
+	cfg.ParseTime = true
+	return cfg.FormatDSN()
+}
+
+// Connect to a MySQL server and connection check.
+func Connect(ctx context.Context, dsn string) (*sql.DB, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return connect(ctx, cfg)
+}
+
+// connect opens and pings a connection pool with cfg.
+func connect(ctx context.Context, cfg *mysql.Config) (*sql.DB, error) {
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	db := sql.OpenDB(connector)
+
+	// Ping
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// connectServer connects to the server of the database in dsn, without
+// selecting the database, and returns the name of the database.
+func connectServer(ctx context.Context, dsn string) (*sql.DB, string, error) {
+	cfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.DBName == "" {
+		return nil, "", errors.New("database name is empty")
+	}
+	name := cfg.DBName
+	cfg.DBName = ""
+	db, err := connect(ctx, cfg)
+	if err != nil {
+		return nil, "", err
+	}
+	return db, name, nil
+}
+
+// quoteIdentifier quotes name for use as an identifier in SQL.
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// DatabaseExists checks if the database of the given DSN on a MySQL
+// server does exist.
+func DatabaseExists(ctx context.Context, dsn string) (bool, error) {
+	db, name, err := connectServer(ctx, dsn)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	var n int64
+	err = db.QueryRowContext(
+		ctx,
+		"SELECT 1 FROM information_schema.schemata WHERE schema_name=?", name,
+	).Scan(&n)
+	if IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// CreateDatabaseIfNotExists creates the database of the given DSN on
+// a MySQL server if it doesn't already exist.
+func CreateDatabaseIfNotExists(ctx context.Context, dsn string) (bool, error) {
+	db, name, err := connectServer(ctx, dsn)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, "CREATE DATABASE "+quoteIdentifier(name))
+	if IsDupDB(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// DropDatabaseIfExists drops the database of the given DSN on a MySQL
+// server if it exists.
+func DropDatabaseIfExists(ctx context.Context, dsn string) (bool, error) {
+	db, name, err := connectServer(ctx, dsn)
+	if err != nil {
+		return false, err
+	}
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, "DROP DATABASE "+quoteIdentifier(name))
+	if IsDBNotExists(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,79 @@
+package mysql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/mysql"
+)
+
+func TestConnectionString(t *testing.T) {
+	dsn := mysql.ConnectionString("localhost", 3306, "shop", "root", "mysql")
+	if want, have := "root:mysql@tcp(localhost:3306)/shop?parseTime=true", dsn; want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+}
+
+func TestDatabaseManagement(t *testing.T) {
+	c := mysql.Start(t, mysql.WithTimeout(60*time.Second))
+	defer c.Close()
+
+	cfg := c.Config()
+	cfg.DBName = "new-database"
+	dsn := cfg.FormatDSN()
+
+	// Database should not exist here
+	exists, err := mysql.DatabaseExists(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := false, exists; want != have {
+		t.Fatalf("want Exists=%v, have %v", want, have)
+	}
+
+	// Database should be created here
+	created, err := mysql.CreateDatabaseIfNotExists(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !created {
+		t.Fatalf("want Created=%v, have %v", true, created)
+	}
+
+	// Database should exist now
+	exists, err = mysql.DatabaseExists(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := true, exists; want != have {
+		t.Fatalf("want Exists=%v, have %v", want, have)
+	}
+
+	// Recreating the database should be a no-op
+	created, err = mysql.CreateDatabaseIfNotExists(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created {
+		t.Fatalf("want Created=%v, have %v", false, created)
+	}
+
+	// Drop the database
+	dropped, err := mysql.DropDatabaseIfExists(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dropped {
+		t.Fatalf("want Dropped=%v, have %v", true, dropped)
+	}
+
+	// Dropping it again should be a no-op
+	dropped, err = mysql.DropDatabaseIfExists(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped {
+		t.Fatalf("want Dropped=%v, have %v", false, dropped)
+	}
+}
@@ -0,0 +1,120 @@
+package mysql
+
+import (
+	"database/sql"
+	stderrors "errors"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// IsNotFound returns true if the given error indicates that
+// a record could not be found.
+func IsNotFound(err error) bool {
+	return stderrors.Is(err, sql.ErrNoRows)
+}
+
+// IsMySQLError returns true if the given error is from MySQL or MariaDB
+// and has one of the given error numbers.
+//
+// See https://dev.mysql.com/doc/mysql-errors/8.4/en/server-error-reference.html
+// and https://mariadb.com/kb/en/mariadb-error-code-reference/ for a list
+// of all server error numbers. Both servers share the numbers below 1900,
+// but differ above it. The predicates of this package check the numbers of
+// both.
+func IsMySQLError(err error, numbers ...uint16) bool {
+	if err == nil {
+		return false
+	}
+	var myerr *mysql.MySQLError
+	if stderrors.As(err, &myerr) {
+		for _, number := range numbers {
+			if myerr.Number == number {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ErrorNumber returns the error number of the given error if it is from
+// MySQL or MariaDB, e.g. 1062. It returns 0 otherwise.
+func ErrorNumber(err error) uint16 {
+	var myerr *mysql.MySQLError
+	if stderrors.As(err, &myerr) {
+		return myerr.Number
+	}
+	return 0
+}
+
+// IsNotNullViolation returns true if the given error indicates that
+// NULL was written to a NOT NULL column (1048 ER_BAD_NULL_ERROR).
+func IsNotNullViolation(err error) bool {
+	// 1048 ER_BAD_NULL_ERROR
+	return IsMySQLError(err, 1048)
+}
+
+// IsForeignKeyViolation returns true if the given error indicates a
+// violation of a foreign key constraint, either by deleting or updating
+// a referenced row (1451 ER_ROW_IS_REFERENCED_2) or by referencing
+// a missing one (1452 ER_NO_REFERENCED_ROW_2).
+func IsForeignKeyViolation(err error) bool {
+	// 1451 ER_ROW_IS_REFERENCED_2, 1452 ER_NO_REFERENCED_ROW_2
+	return IsMySQLError(err, 1451, 1452)
+}
+
+// IsDup returns true if the given error indicates that a
+// duplicate record has been found (1062 ER_DUP_ENTRY).
+func IsDup(err error) bool {
+	// 1062 ER_DUP_ENTRY
+	return IsMySQLError(err, 1062)
+}
+
+// IsCheckViolation returns true if the given error indicates a
+// violation of a check constraint (3819 ER_CHECK_CONSTRAINT_VIOLATED of
+// MySQL, 4025 ER_CONSTRAINT_FAILED of MariaDB).
+func IsCheckViolation(err error) bool {
+	// 3819 ER_CHECK_CONSTRAINT_VIOLATED (MySQL)
+	// 4025 ER_CONSTRAINT_FAILED (MariaDB)
+	return IsMySQLError(err, 3819, 4025)
+}
+
+// IsDeadlockDetected returns true if the given error indicates that
+// a transaction was rolled back due to a deadlock and should be retried
+// (1213 ER_LOCK_DEADLOCK).
+func IsDeadlockDetected(err error) bool {
+	// 1213 ER_LOCK_DEADLOCK
+	return IsMySQLError(err, 1213)
+}
+
+// IsLockWaitTimeout returns true if the given error indicates that
+// a statement gave up waiting for a row lock (1205 ER_LOCK_WAIT_TIMEOUT).
+// With NOWAIT, MySQL fails with 3572 ER_LOCK_NOWAIT instead, while MariaDB
+// fails with 1205 as well.
+func IsLockWaitTimeout(err error) bool {
+	// 1205 ER_LOCK_WAIT_TIMEOUT
+	// 3572 ER_LOCK_NOWAIT (MySQL)
+	return IsMySQLError(err, 1205, 3572)
+}
+
+// IsPerm returns true if the given error indicates a permission issue
+// (1044 ER_DBACCESS_DENIED_ERROR, 1142 ER_TABLEACCESS_DENIED_ERROR).
+func IsPerm(err error) bool {
+	// 1044 ER_DBACCESS_DENIED_ERROR, 1142 ER_TABLEACCESS_DENIED_ERROR
+	return IsMySQLError(err, 1044, 1142)
+}
+
+// IsDupDB returns true if the given error indicates the database already
+// exists. This is typically returned from the `CREATE DATABASE dbname` command
+// if `dbname` already exists (1007 ER_DB_CREATE_EXISTS).
+func IsDupDB(err error) bool {
+	// 1007 ER_DB_CREATE_EXISTS
+	return IsMySQLError(err, 1007)
+}
+
+// IsDBNotExists returns true if the given error indicates that the
+// database does not exist, either when dropping it
+// (1008 ER_DB_DROP_EXISTS) or connecting to it (1049 ER_BAD_DB_ERROR).
+func IsDBNotExists(err error) bool {
+	// 1008 ER_DB_DROP_EXISTS, 1049 ER_BAD_DB_ERROR
+	return IsMySQLError(err, 1008, 1049)
+}
@@ -0,0 +1,98 @@
+package mysql_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/olivere/integrationtest/mysql"
+)
+
+func TestIsNotFound(t *testing.T) {
+	tests := []struct {
+		Error    error
+		Expected bool
+	}{
+		{Error: nil, Expected: false},
+		{Error: sql.ErrNoRows, Expected: true},
+		{Error: fmt.Errorf("kaboom: %w", sql.ErrNoRows), Expected: true},
+		{Error: errors.New("kaboom"), Expected: false},
+	}
+	for i, tc := range tests {
+		if want, have := tc.Expected, mysql.IsNotFound(tc.Error); want != have {
+			t.Errorf("#%d: mysql.IsNotFound(%v): want %v, have %v", i, tc.Error, want, have)
+		}
+	}
+}
+
+func TestIsDup(t *testing.T) {
+	tests := []struct {
+		Error    error
+		Expected bool
+	}{
+		{Error: nil, Expected: false},
+		{Error: &gomysql.MySQLError{Number: 1062}, Expected: true},
+		{Error: fmt.Errorf("kaboom: %w", &gomysql.MySQLError{Number: 1062}), Expected: true},
+		{Error: &gomysql.MySQLError{Number: 1452}, Expected: false},
+	}
+	for i, tc := range tests {
+		if want, have := tc.Expected, mysql.IsDup(tc.Error); want != have {
+			t.Errorf("#%d: mysql.IsDup(%v): want %v, have %v", i, tc.Error, want, have)
+		}
+	}
+}
+
+func TestIsForeignKeyViolation(t *testing.T) {
+	tests := []struct {
+		Error    error
+		Expected bool
+	}{
+		{Error: nil, Expected: false},
+		{Error: &gomysql.MySQLError{Number: 1451}, Expected: true},
+		{Error: &gomysql.MySQLError{Number: 1452}, Expected: true},
+		{Error: fmt.Errorf("kaboom: %w", &gomysql.MySQLError{Number: 1452}), Expected: true},
+		{Error: &gomysql.MySQLError{Number: 1062}, Expected: false},
+	}
+	for i, tc := range tests {
+		if want, have := tc.Expected, mysql.IsForeignKeyViolation(tc.Error); want != have {
+			t.Errorf("#%d: mysql.IsForeignKeyViolation(%v): want %v, have %v", i, tc.Error, want, have)
+		}
+	}
+}
+
+func TestIsCheckViolation(t *testing.T) {
+	tests := []struct {
+		Error    error
+		Expected bool
+	}{
+		{Error: nil, Expected: false},
+		{Error: &gomysql.MySQLError{Number: 3819}, Expected: true}, // MySQL
+		{Error: &gomysql.MySQLError{Number: 4025}, Expected: true}, // MariaDB
+		{Error: fmt.Errorf("kaboom: %w", &gomysql.MySQLError{Number: 4025}), Expected: true},
+		{Error: &gomysql.MySQLError{Number: 1062}, Expected: false},
+	}
+	for i, tc := range tests {
+		if want, have := tc.Expected, mysql.IsCheckViolation(tc.Error); want != have {
+			t.Errorf("#%d: mysql.IsCheckViolation(%v): want %v, have %v", i, tc.Error, want, have)
+		}
+	}
+}
+
+func TestErrorNumber(t *testing.T) {
+	tests := []struct {
+		Error    error
+		Expected uint16
+	}{
+		{Error: nil, Expected: 0},
+		{Error: errors.New("kaboom"), Expected: 0},
+		{Error: &gomysql.MySQLError{Number: 1213}, Expected: 1213},
+		{Error: fmt.Errorf("kaboom: %w", &gomysql.MySQLError{Number: 1205}), Expected: 1205},
+	}
+	for i, tc := range tests {
+		if want, have := tc.Expected, mysql.ErrorNumber(tc.Error); want != have {
+			t.Errorf("#%d: mysql.ErrorNumber(%v): want %v, have %v", i, tc.Error, want, have)
+		}
+	}
+}
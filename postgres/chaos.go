@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// HoldLock acquires an ACCESS EXCLUSIVE lock on table in a separate
+// transaction and holds it for the given duration, until ctx is canceled,
+// or until the returned release func is called, whichever comes first.
+// HoldLock returns once the lock is acquired, so statements touching
+// the table block (or run into lock_timeout) afterwards.
+//
+// The release func blocks until the lock is released and may be called
+// multiple times.
+func HoldLock(ctx context.Context, db *sql.DB, table string, d time.Duration) (release func(), err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	ident := pgx.Identifier(strings.Split(table, "."))
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE `+ident.Sanitize()+` IN ACCESS EXCLUSIVE MODE`); err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("could not lock %s: %w", ident.Sanitize(), err)
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+		case <-stop:
+		}
+		tx.Rollback()
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(stop) })
+		<-done
+	}, nil
+}
+
+// KillBackends terminates all backends except the calling one whose
+// current query contains matching or whose application_name equals
+// matching. Clients connected through these backends see their connection
+// drop. KillBackends returns the number of terminated backends.
+func KillBackends(ctx context.Context, db *sql.DB, matching string) (int, error) {
+	var n int
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM (
+			SELECT pg_terminate_backend(pid)
+			FROM pg_stat_activity
+			WHERE pid <> pg_backend_pid()
+			AND backend_type = 'client backend'
+			AND (strpos(query, $1) > 0 OR application_name = $1)
+		) AS t`,
+		matching,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("could not terminate backends: %w", err)
+	}
+	return n, nil
+}
+
+// SetStatementTimeout sets the statement_timeout of the current database,
+// so statements running longer than d are canceled. A zero duration
+// disables the timeout.
+//
+// The setting applies to new sessions only. Use KillBackends or
+// sql.DB.SetConnMaxLifetime to make pooled connections pick it up.
+func SetStatementTimeout(ctx context.Context, db *sql.DB, d time.Duration) error {
+	var database string
+	if err := db.QueryRowContext(ctx, `SELECT current_database()`).Scan(&database); err != nil {
+		return err
+	}
+	sql := fmt.Sprintf(`ALTER DATABASE %s SET statement_timeout = %d`,
+		pgx.Identifier([]string{database}).Sanitize(), d.Milliseconds())
+	if _, err := db.ExecContext(ctx, sql); err != nil {
+		return fmt.Errorf("could not set statement timeout: %w", err)
+	}
+	return nil
+}
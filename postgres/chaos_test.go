@@ -0,0 +1,102 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/postgres"
+)
+
+func TestHoldLock(t *testing.T) {
+	c := postgres.Start(t,
+		postgres.WithTimeout(30*time.Second),
+		postgres.WithPostStart(func(c *postgres.Container) error {
+			_, err := c.DB().Exec(`CREATE TABLE foo (id BIGINT PRIMARY KEY)`)
+			return err
+		}),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	release, err := postgres.HoldLock(ctx, c.DB(), "foo", 5*time.Second)
+	if err != nil {
+		t.Fatalf("could not hold lock: %v", err)
+	}
+	defer release()
+
+	// Inserting must run into the lock timeout
+	tx, err := c.DB().BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `SET LOCAL lock_timeout = '100ms'`); err != nil {
+		t.Fatal(err)
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO foo (id) VALUES (1)`)
+	if want, have := "55P03", postgres.PgErrorCode(err); want != have {
+		t.Fatalf("want error code %s, have %s (%v)", want, have, err)
+	}
+	tx.Rollback()
+
+	// Inserting works after releasing the lock
+	release()
+	if _, err := c.DB().ExecContext(ctx, `INSERT INTO foo (id) VALUES (1)`); err != nil {
+		t.Fatalf("could not insert after releasing lock: %v", err)
+	}
+}
+
+func TestKillBackends(t *testing.T) {
+	c := postgres.Start(t, postgres.WithTimeout(30*time.Second))
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	victim, err := c.DB().Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer victim.Close()
+	if _, err := victim.ExecContext(ctx, `SET application_name = 'victim'`); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := postgres.KillBackends(ctx, c.DB(), "victim")
+	if err != nil {
+		t.Fatalf("could not kill backends: %v", err)
+	}
+	if want, have := 1, n; want != have {
+		t.Fatalf("want n=%d, have %d", want, have)
+	}
+	if err := victim.PingContext(ctx); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
+
+func TestSetStatementTimeout(t *testing.T) {
+	c := postgres.Start(t, postgres.WithTimeout(30*time.Second))
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := postgres.SetStatementTimeout(ctx, c.DB(), 100*time.Millisecond); err != nil {
+		t.Fatalf("could not set statement timeout: %v", err)
+	}
+
+	// New sessions pick up the setting
+	db, err := postgres.Connect(ctx, c.ConnConfig().ConnString())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = db.ExecContext(ctx, `SELECT pg_sleep(1)`)
+	if want, have := "57014", postgres.PgErrorCode(err); want != have {
+		t.Fatalf("want error code %s, have %s (%v)", want, have, err)
+	}
+}
@@ -1,15 +1,25 @@
 package postgres
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	_ "embed"
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/olivere/integrationtest/internal/lifecycle"
+	"github.com/olivere/integrationtest/kube"
 	"github.com/ory/dockertest/v3"
 	"github.com/ory/dockertest/v3/docker"
 )
@@ -24,21 +34,139 @@ type Container struct {
 	ccfg         *pgx.ConnConfig
 	pool         *dockertest.Pool
 	resource     *dockertest.Resource
+	logWaiter    docker.CloseWaiter
+	timeout      time.Duration
+	logger       *slog.Logger
+	waitFor      core.WaitStrategy
+	retryPolicy  core.RetryPolicy
+	keep         bool
+	// reuse is true if a later run reuses the container, see WithReuse.
+	// networks are the IDs of the networks to disconnect it from on Close.
+	reuse    bool
+	networks []string
+	// external is true if the database is on an external server, see
+	// externalURLEnv, which admin is connected to.
+	external bool
+	admin    *sql.DB
+	// pod is the pod that PostgreSQL runs in instead of a container, see
+	// kube.Enabled.
+	pod *kube.Pod
+	// toxiproxy is the sidecar that the connections go through, see
+	// WithToxiproxy.
+	toxiproxy *core.Toxiproxy
+	chaos     *core.Chaos
 
 	mu     sync.Mutex
 	closed bool
 }
 
 type startConfig struct {
-	databaseName string
-	inMemory     bool
-	timeout      time.Duration
-	isTemplate   bool
-	postStart    []postStartFunc
+	databaseName     string
+	version          string
+	flavor           Flavor
+	inMemory         bool
+	icuLocale        string
+	icuRules         string
+	networks         []*dockertest.Network
+	attachments      []core.NetworkAttachment
+	pgCron           bool
+	timeout          time.Duration
+	maxLifetime      time.Duration
+	isTemplate       bool
+	env              []string
+	cpus             float64
+	memory           int64
+	shmSize          int64
+	logger           *slog.Logger
+	skipNoDocker     bool
+	testName         string
+	pullPolicy       core.PullPolicy
+	waitFor          []core.WaitStrategy
+	retryPolicy      core.RetryPolicy
+	portRetries      int
+	keep             bool
+	reuse            string
+	reuseChecksum    string
+	toxiproxy        bool
+	container        bool
+	snapshot         bool
+	snapshotChecksum string
+	build            *core.Build
+	postStart        []postStartFunc
 }
 
 type startConfigFunc func(*startConfig)
 
+var _ core.Container = (*Container)(nil)
+
+// WithOptions applies the options that all container packages support,
+// see core.Config.
+func WithOptions(options ...core.Option) startConfigFunc {
+	return func(cfg *startConfig) {
+		coreCfg := core.NewConfig(options...)
+		if coreCfg.Timeout != 0 {
+			cfg.timeout = coreCfg.Timeout
+		}
+		if coreCfg.MaxLifetime != 0 {
+			cfg.maxLifetime = coreCfg.MaxLifetime
+		}
+		if coreCfg.Version != "" {
+			cfg.version = coreCfg.Version
+		}
+		cfg.env = append(cfg.env, coreCfg.Env...)
+		if coreCfg.PullPolicy != "" {
+			cfg.pullPolicy = coreCfg.PullPolicy
+		}
+		cfg.attachments = append(cfg.attachments, coreCfg.Networks...)
+		if coreCfg.CPULimit != 0 {
+			cfg.cpus = coreCfg.CPULimit
+		}
+		if coreCfg.MemoryLimit != 0 {
+			cfg.memory = coreCfg.MemoryLimit
+		}
+		if coreCfg.ShmSize != 0 {
+			cfg.shmSize = coreCfg.ShmSize
+		}
+		if coreCfg.Logger != nil {
+			cfg.logger = coreCfg.Logger
+		}
+		if coreCfg.RetryPolicy != (core.RetryPolicy{}) {
+			cfg.retryPolicy = coreCfg.RetryPolicy
+		}
+		if coreCfg.PortRetries != 0 {
+			cfg.portRetries = coreCfg.PortRetries
+		}
+		if coreCfg.Keep {
+			cfg.keep = true
+		}
+		if coreCfg.Build != nil {
+			cfg.build = coreCfg.Build
+		}
+		cfg.waitFor = append(cfg.waitFor, coreCfg.WaitFor...)
+	}
+}
+
+// Flavor specifies the base image of the PostgreSQL container.
+type Flavor string
+
+const (
+	// Alpine uses the musl-based Alpine images, e.g. postgres:16-alpine.
+	Alpine Flavor = "alpine"
+	// Debian uses the glibc-based Debian images, e.g. postgres:16.
+	// Use it if you need glibc locales or packages from the PGDG
+	// repository.
+	Debian Flavor = "debian"
+)
+
+// imageTag returns the tag of the postgres image for the given version
+// and flavor.
+func imageTag(version string, flavor Flavor) string {
+	if flavor == Debian {
+		return version
+	}
+	return version + "-alpine"
+}
+
 type postStartFunc func(*Container) error
 
 func WithDatabaseName(databaseName string) startConfigFunc {
@@ -47,24 +175,179 @@ func WithDatabaseName(databaseName string) startConfigFunc {
 	}
 }
 
+// WithVersion sets the PostgreSQL version to start, e.g. "16" or "13.14".
+// It defaults to "16".
+func WithVersion(version string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.version = version
+	}
+}
+
+// WithFlavor sets the base image of the PostgreSQL container.
+// It defaults to Alpine.
+func WithFlavor(flavor Flavor) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.flavor = flavor
+	}
+}
+
 func WithInMemory(inMemory bool) startConfigFunc {
 	return func(cfg *startConfig) {
 		cfg.inMemory = inMemory
 	}
 }
 
+// WithICU initializes the database cluster with the ICU locale provider,
+// using locale as the default collation, e.g. "de-DE" or "und-x-icu".
+// The optional rules are passed as --icu-rules and require PostgreSQL 16
+// or later. ICU requires PostgreSQL 15 or later.
+func WithICU(locale, rules string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.icuLocale = locale
+		cfg.icuRules = rules
+	}
+}
+
+// WithPgCron installs and preloads the pg_cron extension and creates it
+// in the database, so scheduled jobs can be tested with TriggerJob.
+//
+// The extension is installed from the PGDG repository when the container
+// starts, so WithPgCron implies the Debian flavor and requires network
+// access from within the container, through the proxy of the host if
+// one is configured, see core.ProxyEnv. Consider raising the timeout.
+func WithPgCron() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.pgCron = true
+	}
+}
+
+// WithNetwork connects the container to the given Docker network. Other
+// containers on the network can reach it by its Name.
+func WithNetwork(network *dockertest.Network) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.networks = append(cfg.networks, network)
+	}
+}
+
+// WithTimeout sets the time to wait for the container to become ready.
+// It is the same as WithStartupTimeout.
 func WithTimeout(timeout time.Duration) startConfigFunc {
+	return WithStartupTimeout(timeout)
+}
+
+// WithStartupTimeout sets the time to wait for the container to become
+// ready. It defaults to 60 seconds.
+func WithStartupTimeout(timeout time.Duration) startConfigFunc {
 	return func(cfg *startConfig) {
 		cfg.timeout = timeout
 	}
 }
 
+// WithMaxLifetime sets the time after which Docker kills the container,
+// in case the test process dies before it can remove the container. It
+// defaults to core.DefaultMaxLifetime. Use a negative value to never kill
+// it.
+func WithMaxLifetime(lifetime time.Duration) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.maxLifetime = lifetime
+	}
+}
+
 func WithIsTemplate(isTemplate bool) startConfigFunc {
 	return func(cfg *startConfig) {
 		cfg.isTemplate = isTemplate
 	}
 }
 
+// WithCPULimit limits the number of CPUs the container may use, e.g. 1.5.
+func WithCPULimit(cpus float64) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.cpus = cpus
+	}
+}
+
+// WithMemoryLimit sets the memory limit of the container in bytes.
+func WithMemoryLimit(bytes int64) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.memory = bytes
+	}
+}
+
+// WithShmSize sets the size of /dev/shm of the container in bytes. It
+// defaults to the 64MB of Docker, which may not be enough for parallel
+// queries, as parallel workers exchange data via shared memory.
+func WithShmSize(bytes int64) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.shmSize = bytes
+	}
+}
+
+// WithPullPolicy sets when to pull the image. It defaults to
+// core.PullIfNotPresent.
+func WithPullPolicy(policy core.PullPolicy) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.pullPolicy = policy
+	}
+}
+
+// WithSkipIfNoDocker skips the test, instead of failing it, if the Docker
+// daemon is unavailable. See core.SkipIfUnavailable.
+func WithSkipIfNoDocker() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.skipNoDocker = true
+	}
+}
+
+// WithWaitFor sets the strategies that decide when the container is
+// ready, replacing the default of core.ForListeningPort("5432/tcp"), e.g.
+// core.ForExec("pg_isready", "-U", "postgres"). The container is ready
+// when all strategies succeed, in order, and the database accepts
+// connections.
+func WithWaitFor(strategies ...core.WaitStrategy) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.waitFor = strategies
+	}
+}
+
+// WithRetryPolicy sets how readiness checks are retried while the
+// container starts, see core.WithRetryPolicy. By default, they are retried
+// with exponential backoff from 100ms to 5s until the timeout elapses.
+func WithRetryPolicy(maxAttempts int, initialBackoff, maxBackoff time.Duration) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.retryPolicy = core.RetryPolicy{
+			MaxAttempts:    maxAttempts,
+			InitialBackoff: initialBackoff,
+			MaxBackoff:     maxBackoff,
+		}
+	}
+}
+
+// WithPortRetries sets how often to retry starting the container if a host
+// port is already in use, see core.WithPortRetries. By default, it is
+// retried core.DefaultPortRetries times.
+func WithPortRetries(retries int) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.portRetries = retries
+	}
+}
+
+// WithBuiltImage builds the image from the Dockerfile in dir instead of
+// pulling it, e.g. a PostgreSQL image with custom extensions. See
+// core.WithBuiltImage.
+func WithBuiltImage(dir, dockerfile string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.build = &core.Build{Dir: dir, Dockerfile: dockerfile}
+	}
+}
+
+// WithKeepContainer keeps the container after the test, e.g. to inspect
+// its state after a failing test. See core.WithKeepContainer.
+func WithKeepContainer() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.keep = true
+	}
+}
+
 // WithPostStart adds a post-startup operation to the container.
 // This can be used to install extensions, create tables, seed data etc.
 func WithPostStart(funcs ...postStartFunc) startConfigFunc {
@@ -74,15 +357,135 @@ func WithPostStart(funcs ...postStartFunc) startConfigFunc {
 }
 
 // Start a PostgreSQL container.
+//
+// If INTEGRATIONTEST_POSTGRES_URL is set, or the tests run in CI with
+// a postgres service container (see core.CIService), Start creates
+// a database of its own on that server instead of starting a container,
+// and Close drops it. Options that configure the container itself, e.g.
+// WithVersion or WithReuse, have no effect then.
+//
+// If INTEGRATIONTEST_BACKEND=kube, Start runs PostgreSQL as a pod in
+// Kubernetes instead of a Docker container, see kube.Enabled.
 func Start(tb testing.TB, options ...startConfigFunc) *Container {
 	tb.Helper()
 
-	startCfg := startConfig{
+	if cfg := newStartConfig(options...); cfg.skipNoDocker && cfg.useExternalURL() == "" && !cfg.useKube() {
+		core.SkipIfUnavailable(tb)
+	}
+
+	c, err := start(context.Background(), append(slices.Clip(options), withTestName(tb.Name()))...)
+	if c != nil {
+		tb.Cleanup(func() {
+			c.Close()
+		})
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
+
+// Run starts a PostgreSQL container outside of tests, e.g. for a local
+// development server that uses the same options as the tests. Unlike
+// Start, the container is not killed after core.DefaultMaxLifetime unless
+// WithMaxLifetime says so. The caller must close the container.
+//
+// If ctx is done before the container is ready, Run removes the container
+// and returns ctx.Err.
+func Run(ctx context.Context, options ...startConfigFunc) (*Container, error) {
+	c, err := start(ctx, append([]startConfigFunc{WithMaxLifetime(-1)}, options...)...)
+	if err != nil {
+		if c != nil {
+			c.Close()
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// withTestName includes the name of the test in the container name.
+func withTestName(name string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.testName = name
+	}
+}
+
+// newStartConfig returns the defaults with options applied.
+func newStartConfig(options ...startConfigFunc) startConfig {
+	cfg := startConfig{
 		databaseName: "integrationtest",
+		version:      "16",
+		flavor:       Alpine,
+		keep:         core.KeepContainers(),
 	}
 	for _, o := range options {
-		o(&startCfg)
+		o(&cfg)
 	}
+	if cfg.pgCron {
+		// pg_cron is not part of the image, but the Debian images come
+		// with the PGDG repository configured
+		cfg.flavor = Debian
+	}
+	return cfg
+}
+
+// command returns the environment and the entrypoint, if any, of the
+// container.
+func (cfg startConfig) command() (env, entrypoint []string) {
+	env = []string{
+		fmt.Sprintf("POSTGRES_DB=%s", cfg.databaseName),
+		"POSTGRES_USER=postgres",
+		"POSTGRES_PASSWORD=postgres",
+		"listen_addresses = '*'",
+	}
+	if cfg.inMemory {
+		env = append(env, "PGDATA=/data")
+	}
+	if cfg.snapshot {
+		env = append(env, "PGDATA="+snapshotDataDir)
+	}
+	if cfg.icuLocale != "" {
+		// The entrypoint evaluates POSTGRES_INITDB_ARGS in a shell
+		args := "--locale-provider=icu --icu-locale=" + shellQuote(cfg.icuLocale)
+		if cfg.icuRules != "" {
+			args += " --icu-rules=" + shellQuote(cfg.icuRules)
+		}
+		env = append(env, "POSTGRES_INITDB_ARGS="+args)
+	}
+	if cfg.pgCron {
+		// apt-get installs pg_cron through the proxy of the host, if any
+		env = append(env, core.ProxyEnv()...)
+	}
+	env = append(env, cfg.env...)
+
+	if cfg.pgCron {
+		entrypoint = []string{"bash", "-c", `set -e
+apt-get update -qq
+apt-get install -y -qq "postgresql-$PG_MAJOR-cron" >/dev/null
+exec docker-entrypoint.sh postgres -c shared_preload_libraries=pg_cron -c cron.database_name=` + shellQuote(cfg.databaseName)}
+	}
+	return env, entrypoint
+}
+
+// image returns the repository and tag of the image to start.
+func (cfg startConfig) image() (string, string) {
+	if cfg.build != nil {
+		return cfg.build.Image("postgres")
+	}
+	return core.Image("postgres", "postgres", imageTag(cfg.version, cfg.flavor))
+}
+
+// Image returns the image that Start starts with the given options, e.g.
+// "postgres:16-alpine", to pull it in advance with core.PullImages.
+func Image(options ...startConfigFunc) string {
+	repository, tag := newStartConfig(options...).image()
+	return repository + ":" + tag
+}
+
+// start a PostgreSQL container. If it returns an error along with
+// a non-nil Container, the caller is responsible for closing it.
+func start(ctx context.Context, options ...startConfigFunc) (*Container, error) {
+	startCfg := newStartConfig(options...)
 
 	timeout := startCfg.timeout
 	if timeout == 0 {
@@ -97,65 +500,195 @@ func Start(tb testing.TB, options ...startConfigFunc) *Container {
 		hostPort:     "",
 		db:           nil,
 		ccfg:         nil,
+		timeout:      timeout,
+		logger:       startCfg.logger,
+		retryPolicy:  startCfg.retryPolicy,
+		keep:         startCfg.keep,
+	}
+
+	if startCfg.snapshot && startCfg.inMemory {
+		return nil, errors.New("cannot snapshot an in-memory database: use either WithSnapshot or WithInMemory")
+	}
+
+	// Use an external server if configured, e.g. a service container in
+	// CI
+	if rawURL := startCfg.useExternalURL(); rawURL != "" {
+		if err := c.startExternal(ctx, rawURL); err != nil {
+			return c, err
+		}
+		if err := c.runPostStart(startCfg.postStart); err != nil {
+			return c, err
+		}
+		if c.isTemplate {
+			return c, c.setTemplate(true)
+		}
+		return c, nil
+	}
+
+	// Run as a pod if configured, e.g. in CI jobs without Docker
+	if startCfg.useKube() {
+		err := c.startKube(ctx, startCfg)
+		if err != nil && c.pod == nil {
+			// The pod did not start, so there is nothing to close
+			return nil, err
+		}
+		return c, err
 	}
 
 	var err error
-	c.pool, err = dockertest.NewPool("")
+	c.pool, err = dockerutil.Pool()
 	if err != nil {
-		tb.Fatalf("unable to connect to Docker: %v", err)
+		return nil, fmt.Errorf("unable to connect to Docker: %w", err)
 	}
-	if err = c.pool.Client.Ping(); err != nil {
-		tb.Fatalf(`could not connect to docker: %v`, err)
+	core.ReapOnce(c.pool.Client)
+
+	env, entrypoint := startCfg.command()
+
+	// Pull or build the image first, with its own timeout, so that a slow
+	// pull on a fresh machine does not count against the timeout of the
+	// container
+	repository, tag := startCfg.image()
+	pullPolicy := startCfg.pullPolicy
+
+	// Start from the snapshot of an earlier run, if any, which only
+	// exists locally
+	var fromSnapshot bool
+	snapshotRepo, snapshotTag := snapshotImage(startCfg.checksum(startCfg.snapshotChecksum))
+	if startCfg.snapshot && hasImage(c.pool.Client, snapshotRepo, snapshotTag) {
+		repository, tag = snapshotRepo, snapshotTag
+		pullPolicy = core.PullNever
+		fromSnapshot = true
 	}
 
-	env := []string{
-		fmt.Sprintf("POSTGRES_DB=%s", c.databaseName),
-		"POSTGRES_USER=postgres",
-		"POSTGRES_PASSWORD=postgres",
-		"listen_addresses = '*'",
+	pullCtx, cancel := context.WithTimeout(ctx, max(timeout, 5*time.Minute))
+	pullStart := time.Now()
+	if startCfg.build != nil && !fromSnapshot {
+		err = core.BuildImage(pullCtx, startCfg.build.Dir, startCfg.build.Dockerfile, repository+":"+tag)
+	} else {
+		err = core.Pull(pullCtx, c.pool.Client, repository, tag, pullPolicy)
 	}
-	if startCfg.inMemory {
-		env = append(env, "PGDATA=/data")
+	cancel()
+	lifecycle.Log(c.logger, "postgres", lifecycle.Pull, pullStart, err, "image", repository+":"+tag)
+	if err != nil {
+		return nil, err
 	}
 
-	c.resource, err = c.pool.RunWithOptions(&dockertest.RunOptions{
-		Name:       fmt.Sprintf("%s_%09d", c.databaseName, time.Now().UnixNano()),
-		Repository: "postgres",
-		Tag:        "16-alpine",
-		Env:        env,
-	}, func(config *docker.HostConfig) {
-		config.AutoRemove = true
-		config.RestartPolicy = docker.NeverRestart()
+	// Record the events of the container to explain why it failed to
+	// become ready, e.g. because it ran out of memory
+	events := dockerutil.RecordEvents(c.pool.Client)
+	defer events.Close()
 
-		if startCfg.inMemory {
-			config.Tmpfs = map[string]string{
-				"/data": "",
+	started := time.Now()
+	newName := func() string {
+		return dockerutil.Name(c.databaseName, startCfg.testName)
+	}
+	labels := core.Labels("postgres", startCfg.testName)
+	if c.keep {
+		labels[core.LabelKeep] = "true"
+	}
+	// Reused containers outlive the process, like kept ones
+	keep := c.keep
+	var checksum string
+	if startCfg.reuse != "" {
+		keep = true
+		checksum = startCfg.checksum(startCfg.reuseChecksum)
+		labels[core.LabelKeep] = "true"
+		labels[core.LabelChecksum] = checksum
+		newName = func() string {
+			return reuseName(startCfg.reuse)
+		}
+		c.resource, err = reusable(c.pool, newName(), checksum)
+		if err != nil {
+			return nil, err
+		}
+	}
+	reused := c.resource != nil
+	if !reused {
+		c.resource, err = dockerutil.RunUnique(c.pool, newName, cmp.Or(startCfg.portRetries, core.DefaultPortRetries), &dockertest.RunOptions{
+			Repository: repository,
+			Tag:        tag,
+			Env:        env,
+			Entrypoint: entrypoint,
+			Networks:   startCfg.networks,
+			Labels:     labels,
+		}, func(config *docker.HostConfig) {
+			config.AutoRemove = !keep
+			config.RestartPolicy = docker.NeverRestart()
+			dockerutil.SetLimits(config, startCfg.cpus, startCfg.memory, startCfg.shmSize)
+
+			if startCfg.inMemory {
+				config.Tmpfs = map[string]string{
+					"/data": "",
+				}
+			}
+		})
+		if errors.Is(err, docker.ErrContainerAlreadyExists) && startCfg.reuse != "" {
+			// Another test process started the container in the meantime
+			c.resource, err = reusable(c.pool, newName(), checksum)
+			if err == nil && c.resource == nil {
+				err = docker.ErrContainerAlreadyExists
 			}
+			reused = err == nil
+		}
+		if err != nil {
+			lifecycle.Log(c.logger, "postgres", lifecycle.Start, started, err)
+			return nil, fmt.Errorf("unable to start PostgreSQL container: %w", err)
+		}
+	}
+	lifecycle.Log(c.logger, "postgres", lifecycle.Start, started, nil, "container", c.resource.Container.ID, "reused", reused)
+	if reused {
+		for _, n := range startCfg.networks {
+			if err := c.pool.Client.ConnectNetwork(n.Network.ID, docker.NetworkConnectionOptions{Container: c.resource.Container.ID}); err != nil {
+				return c, fmt.Errorf("could not connect container to network %s: %w", n.Network.Name, err)
+			}
+		}
+	}
+	for _, a := range startCfg.attachments {
+		if err := a.Network.Connect(c.pool.Client, c.resource.Container.ID, a.Aliases...); err != nil {
+			return c, err
+		}
+	}
+	if startCfg.reuse != "" {
+		for _, n := range startCfg.networks {
+			c.networks = append(c.networks, n.Network.ID)
+		}
+		for _, a := range startCfg.attachments {
+			c.networks = append(c.networks, a.Network.ID())
 		}
-	})
-	if err != nil {
-		tb.Fatalf("unable to start PostgreSQL container: %v", err)
 	}
-	tb.Cleanup(func() {
-		c.Close()
-	})
 
-	// Tell docker to hard kill the container in "timeout" seconds
-	if err := c.resource.Expire(uint(timeout.Seconds())); err != nil {
-		tb.Fatal(err)
+	// Tell docker to hard kill the container after its lifetime, unless
+	// it is kept for debugging or reused
+	if lifetime := cmp.Or(startCfg.maxLifetime, core.DefaultMaxLifetime); lifetime > 0 && !keep {
+		if err := c.resource.Expire(uint(lifetime.Seconds())); err != nil {
+			return c, err
+		}
 	}
-	c.pool.MaxWait = timeout
 
-	c.hostPort = c.resource.GetHostPort("5432/tcp")
+	c.hostPort = dockerutil.HostPort(c.resource, "5432/tcp")
+	if startCfg.toxiproxy {
+		c.toxiproxy, err = core.StartToxiproxy(ctx, c.pool, startCfg.testName, c.resource.Container.ID, "5432/tcp",
+			core.WithTimeout(timeout),
+			core.WithPullPolicy(startCfg.pullPolicy),
+			core.WithRetryPolicy(startCfg.retryPolicy.MaxAttempts, startCfg.retryPolicy.InitialBackoff, startCfg.retryPolicy.MaxBackoff),
+			core.WithLogger(c.logger),
+			core.WithMaxLifetime(startCfg.maxLifetime),
+			core.WithPortRetries(startCfg.portRetries),
+		)
+		if err != nil {
+			return c, err
+		}
+		c.hostPort = c.toxiproxy.Addr()
+	}
 
 	c.dsn = fmt.Sprintf("postgres://postgres:postgres@%s/%s?sslmode=disable", c.hostPort, c.databaseName)
 	c.ccfg, err = pgx.ParseConfig(c.dsn)
 	if err != nil {
-		tb.Fatalf("could not parse connection string: %v", err)
+		return c, fmt.Errorf("could not parse connection string: %w", err)
 	}
 
 	// Configure logging from Docker container
-	logWaiter, err := c.pool.Client.AttachToContainerNonBlocking(docker.AttachToContainerOptions{
+	c.logWaiter, err = c.pool.Client.AttachToContainerNonBlocking(docker.AttachToContainerOptions{
 		Container: c.resource.Container.ID,
 		// OutputStream: os.Stdout,
 		// ErrorStream:  os.Stderr,
@@ -164,51 +697,127 @@ func Start(tb testing.TB, options ...startConfigFunc) *Container {
 		// Stream:       true,
 	})
 	if err != nil {
-		tb.Fatalf("could not connect to PostgreSQL container log output: %v", err)
+		return c, fmt.Errorf("could not connect to PostgreSQL container log output: %w", err)
 	}
 
-	// Register cleanup process
-	tb.Cleanup(func() {
-		err = logWaiter.Close()
-		if err != nil {
-			tb.Fatalf("could not close container logs: %v", err)
-		}
-		err = logWaiter.Wait()
+	// Connect to PostgreSQL container
+	waitFor := startCfg.waitFor
+	if len(waitFor) == 0 {
+		waitFor = []core.WaitStrategy{core.ForListeningPort("5432/tcp")}
+	}
+	c.waitFor = core.ForAll(
+		c.forRunning(),
+		core.ForAll(waitFor...),
+		c.forConnection().WithAttemptTimeout(8*time.Second),
+	)
+	err = core.WaitUntil(ctx, timeout, c.retryPolicy, c.waitTarget(), c.waitFor)
+	lifecycle.Log(c.logger, "postgres", lifecycle.Ready, started, err, "container", c.resource.Container.ID)
+	if err != nil {
+		diagnosis := dockerutil.Diagnose(context.Background(), c.pool.Client, c.resource.Container.ID, events)
+		return c, fmt.Errorf("could not connect to PostgreSQL container: %w\n%s", err, diagnosis)
+	}
+	if reused {
+		// Wait until the run that started the container has seeded it
+		err = core.WaitUntil(ctx, timeout, c.retryPolicy, c.waitTarget(), c.forChecksum(checksum))
 		if err != nil {
-			tb.Fatalf("could not wait for container logs to close: %v", err)
+			return c, fmt.Errorf("could not reuse PostgreSQL container %s: %w", c.Name(), err)
 		}
-	})
+	}
 
-	// Connect to PostgreSQL container
-	err = c.pool.Retry(func() (err error) {
-		ctx, cancel := context.WithTimeout(context.Background(), 8*time.Second)
-		defer cancel()
-		c.db, err = Connect(ctx, c.dsn)
-		return
-	})
-	if err != nil {
-		tb.Fatalf("could not connect to PostgreSQL container: %v", err)
+	if err := c.prepare(startCfg); err != nil {
+		return c, err
 	}
 
-	// Run all post-startup operations
-	for _, f := range startCfg.postStart {
-		err = f(c)
-		if err != nil {
-			tb.Fatalf("could not run post-startup operation: %v", err)
+	// Run all post-startup operations, unless an earlier run did
+	if !reused && !fromSnapshot {
+		if err := c.runPostStart(startCfg.postStart); err != nil {
+			return c, err
+		}
+		if startCfg.snapshot {
+			if err := c.commitSnapshot(ctx, snapshotRepo, snapshotTag); err != nil {
+				return c, err
+			}
 		}
 	}
 
 	// Make it a template database?
 	if c.isTemplate {
-		sql := fmt.Sprintf(`UPDATE pg_database SET datistemplate = TRUE WHERE datname = '%s'`,
-			pgx.Identifier([]string{c.databaseName}).Sanitize())
-		_, err := c.db.Exec(sql)
+		if err := c.setTemplate(true); err != nil {
+			return c, err
+		}
+	}
+
+	if startCfg.reuse != "" {
+		if !reused {
+			if err := c.setChecksum(ctx, checksum); err != nil {
+				return c, err
+			}
+		}
+		// Only keep the container for later runs once it is seeded
+		c.reuse = true
+	}
+
+	return c, nil
+}
+
+// prepare checks the ICU collation and creates the extensions that the
+// options ask for, once the database accepts connections.
+func (c *Container) prepare(startCfg startConfig) error {
+	// Make sure the database uses the ICU collation
+	if startCfg.icuLocale != "" {
+		var provider string
+		err := c.db.QueryRow(`SELECT datlocprovider FROM pg_database WHERE datname = current_database()`).Scan(&provider)
 		if err != nil {
-			tb.Fatalf("could not make database a template: %v", err)
+			return fmt.Errorf("could not check ICU collation: %w", err)
+		}
+		if provider != "i" {
+			return fmt.Errorf("database does not use the ICU locale provider (datlocprovider=%q)", provider)
 		}
 	}
 
-	return c
+	if startCfg.pgCron {
+		if _, err := c.db.Exec(`CREATE EXTENSION IF NOT EXISTS pg_cron`); err != nil {
+			return fmt.Errorf("could not create pg_cron extension: %w", err)
+		}
+	}
+	return nil
+}
+
+// runPostStart runs the post-startup operations funcs.
+func (c *Container) runPostStart(funcs []postStartFunc) error {
+	if len(funcs) == 0 {
+		return nil
+	}
+	seedStart := time.Now()
+	var err error
+	for _, f := range funcs {
+		if err = f(c); err != nil {
+			err = fmt.Errorf("could not run post-startup operation: %w", err)
+			break
+		}
+	}
+	var args []any
+	if c.resource != nil {
+		args = append(args, "container", c.resource.Container.ID)
+	}
+	lifecycle.Log(c.logger, "postgres", lifecycle.Seed, seedStart, err, args...)
+	return err
+}
+
+// setTemplate makes the database a template database, or a regular one.
+func (c *Container) setTemplate(isTemplate bool) error {
+	sql := fmt.Sprintf(`UPDATE pg_database SET datistemplate = %t WHERE datname = '%s'`,
+		isTemplate, pgx.Identifier([]string{c.databaseName}).Sanitize())
+	_, err := c.db.Exec(sql)
+	if err != nil {
+		return fmt.Errorf("could not make database a template: %w", err)
+	}
+	return nil
+}
+
+// shellQuote quotes s for use in a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
 func (c *Container) Close() error {
@@ -219,25 +828,152 @@ func (c *Container) Close() error {
 		return nil
 	}
 
-	if c.isTemplate {
-		sql := fmt.Sprintf(`UPDATE pg_database SET datistemplate = FALSE WHERE datname = '%s'`,
-			pgx.Identifier([]string{c.databaseName}).Sanitize())
-		_, err := c.db.Exec(sql)
-		if err != nil {
-			return fmt.Errorf("could not make database a template: %w", err)
+	if c.chaos != nil {
+		// Tests get the errors of the fault windows from Chaos.Wait
+		c.chaos.Close()
+	}
+	if c.toxiproxy != nil {
+		// Clean up through a healthy link
+		c.toxiproxy.Reset(context.Background())
+	}
+
+	if c.isTemplate && c.db != nil {
+		if err := c.setTemplate(false); err != nil {
+			return err
 		}
 	}
 
-	err := c.pool.Purge(c.resource)
-	if err != nil {
-		return fmt.Errorf("could not purge containers: %w", err)
+	if c.external {
+		return c.closeExternal()
+	}
+	if c.pod != nil {
+		return c.closeKube()
+	}
+
+	if c.toxiproxy != nil {
+		if err := c.toxiproxy.Close(); err != nil {
+			return err
+		}
+	}
+
+	if c.logWaiter != nil {
+		if err := c.logWaiter.Close(); err != nil {
+			return fmt.Errorf("could not close container logs: %w", err)
+		}
+		if err := c.logWaiter.Wait(); err != nil {
+			return fmt.Errorf("could not wait for container logs to close: %w", err)
+		}
+		c.logWaiter = nil
+	}
+
+	switch {
+	case c.reuse:
+		// Leave the container to later runs, but disconnect it from the
+		// networks of this one, which are removed after it
+		for _, id := range c.networks {
+			err := c.pool.Client.DisconnectNetwork(id, docker.NetworkConnectionOptions{
+				Container: c.resource.Container.ID,
+				Force:     true,
+			})
+			if err != nil {
+				return fmt.Errorf("could not disconnect container from network: %w", err)
+			}
+		}
+	case c.keep:
+		lifecycle.Kept(os.Stderr, "postgres", c.dsn, c.Name())
+	default:
+		closeStart := time.Now()
+		err := c.pool.Purge(c.resource)
+		lifecycle.Log(c.logger, "postgres", lifecycle.Close, closeStart, err, "container", c.resource.Container.ID)
+		if err != nil {
+			return fmt.Errorf("could not purge containers: %w", err)
+		}
 	}
 
 	c.closed = true
 
+	if c.db == nil {
+		return nil
+	}
 	return c.db.Close()
 }
 
+// Name returns the name of the Docker container. Other containers on
+// a shared network (see WithNetwork) can use it as the hostname. For an
+// external server, it returns its host and port, and for a pod the name
+// of its service.
+func (c *Container) Name() string {
+	if c.external {
+		return c.hostPort
+	}
+	if c.pod != nil {
+		return c.pod.Name()
+	}
+	return strings.TrimPrefix(c.resource.Container.Name, "/")
+}
+
+// Endpoint returns the DSN of the database. It implements core.Container.
+func (c *Container) Endpoint() string {
+	return c.dsn
+}
+
+// Logs returns the output of PostgreSQL so far. The output of an
+// external server is not available. It implements core.Container.
+func (c *Container) Logs(ctx context.Context) (string, error) {
+	if c.external {
+		return "", errors.New("postgres: no logs of an external server")
+	}
+	if c.pod != nil {
+		return c.pod.Logs(ctx)
+	}
+	return dockerutil.Logs(ctx, c.pool.Client, c.resource.Container.ID)
+}
+
+// WaitUntilReady waits until the strategies that decided when the
+// container was ready at start succeed again, see WithWaitFor. It
+// implements core.Container.
+func (c *Container) WaitUntilReady(ctx context.Context) error {
+	return core.WaitUntil(ctx, c.timeout, c.retryPolicy, c.waitTarget(), c.waitFor)
+}
+
+// waitTarget returns the target of the wait strategies, or nil for an
+// external server or a pod.
+func (c *Container) waitTarget() core.WaitTarget {
+	if c.external || c.pod != nil {
+		return nil
+	}
+	return core.DockerTarget(c.pool.Client, c.resource)
+}
+
+// forRunning fails permanently if the container is no longer running,
+// e.g. because it crashed, instead of waiting for it until the timeout.
+func (c *Container) forRunning() core.WaitStrategy {
+	return func(ctx context.Context, _ core.WaitTarget) error {
+		return dockerutil.Running(ctx, c.pool.Client, c.resource.Container.ID)
+	}
+}
+
+// forConnection waits until the database accepts connections, and
+// connects c to it the first time it does.
+func (c *Container) forConnection() core.WaitStrategy {
+	return func(ctx context.Context, _ core.WaitTarget) error {
+		if c.db != nil {
+			return c.db.PingContext(ctx)
+		}
+		db, err := Connect(ctx, c.dsn)
+		if err != nil {
+			return err
+		}
+		c.db = db
+		return nil
+	}
+}
+
+// DatabaseName returns the name of the database in the container.
+func (c *Container) DatabaseName() string {
+	return c.databaseName
+}
+
 func (c *Container) DB() *sql.DB {
 	return c.db
 }
@@ -251,7 +987,7 @@ func (c *Container) StartFromTemplate(tb testing.TB) (*sql.DB, *pgx.ConnConfig,
 		tb.Fatal("cannot clone a non-template database: use WithIsTemplate(true) to create a template database")
 	}
 
-	databaseName := fmt.Sprintf("%s_%09d", c.databaseName, time.Now().UnixNano())
+	databaseName := c.databaseName + "_" + dockerutil.Suffix()
 	sql := `CREATE DATABASE ` +
 		pgx.Identifier([]string{databaseName}).Sanitize() +
 		` TEMPLATE ` +
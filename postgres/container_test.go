@@ -1,14 +1,22 @@
 package postgres_test
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
+	"log/slog"
 	"math/rand"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
 	"github.com/olivere/integrationtest/postgres"
+	"github.com/ory/dockertest/v3"
+	"github.com/ory/dockertest/v3/docker"
 )
 
 func TestContainer_Start(t *testing.T) {
@@ -39,6 +47,48 @@ func TestContainer_Start(t *testing.T) {
 	}
 }
 
+func TestContainer_WithFlavor(t *testing.T) {
+	c := postgres.Start(t, postgres.WithTimeout(30*time.Second), postgres.WithFlavor(postgres.Debian))
+	defer c.Close()
+
+	// Debian images ship with glibc locales, Alpine images don't
+	var n int
+	err := c.DB().QueryRow("SELECT COUNT(*) FROM pg_collation WHERE collname = 'en_US.utf8'").Scan(&n)
+	if err != nil {
+		t.Fatalf("could not query collations: %v", err)
+	}
+	if want, have := 1, n; want != have {
+		t.Fatalf("want n=%d, have %d", want, have)
+	}
+}
+
+func TestContainer_WithICU(t *testing.T) {
+	c := postgres.Start(t, postgres.WithTimeout(30*time.Second), postgres.WithICU("de-DE", ""))
+	defer c.Close()
+
+	// ICU sorts case-insensitively first and puts umlauts next to their
+	// base letter, whereas the C collation sorts by code point
+	rows, err := c.DB().Query(`SELECT w FROM (VALUES ('b'), ('Ä'), ('a'), ('B')) AS t(w) ORDER BY w`)
+	if err != nil {
+		t.Fatalf("could not query: %v", err)
+	}
+	defer rows.Close()
+	var words []string
+	for rows.Next() {
+		var w string
+		if err := rows.Scan(&w); err != nil {
+			t.Fatalf("could not scan: %v", err)
+		}
+		words = append(words, w)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("could not iterate: %v", err)
+	}
+	if want, have := "a Ä b B", strings.Join(words, " "); want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+}
+
 func TestContainer_PostStart(t *testing.T) {
 	c := postgres.Start(t,
 		postgres.WithTimeout(10*time.Second),
@@ -466,3 +516,221 @@ func BenchmarkContainer_Template(b *testing.B) {
 		b.Fatalf("could not stop container: %v", err)
 	}
 }
+
+func TestContainer_Core(t *testing.T) {
+	var c core.Container = postgres.Start(t,
+		postgres.WithOptions(core.WithTimeout(60*time.Second), core.WithEnv("TZ=UTC")),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := c.WaitUntilReady(ctx); err != nil {
+		t.Fatalf("could not wait until ready: %v", err)
+	}
+	if !strings.HasPrefix(c.Endpoint(), "postgres://") {
+		t.Fatalf("want DSN as endpoint, have %q", c.Endpoint())
+	}
+	logs, err := c.Logs(ctx)
+	if err != nil {
+		t.Fatalf("could not get logs: %v", err)
+	}
+	if !strings.Contains(logs, "database system is ready to accept connections") {
+		t.Fatalf("want logs of PostgreSQL, have:\n%s", logs)
+	}
+}
+
+func TestContainer_WithSkipIfNoDocker(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://127.0.0.1:1")
+	t.Setenv(core.RequireDockerEnv, "")
+
+	var sub *testing.T
+	t.Run("start", func(t *testing.T) {
+		sub = t
+		postgres.Start(t, postgres.WithSkipIfNoDocker())
+		t.Fatal("want test to be skipped")
+	})
+	if !sub.Skipped() {
+		t.Fatal("want test to be skipped")
+	}
+}
+
+func TestImage(t *testing.T) {
+	t.Setenv(core.ImageEnv("postgres"), "")
+	t.Setenv(core.RegistryMirrorEnv, "")
+
+	if want, have := "postgres:16-alpine", postgres.Image(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "postgres:15", postgres.Image(postgres.WithVersion("15"), postgres.WithFlavor(postgres.Debian)); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "postgres:16", postgres.Image(postgres.WithPgCron()); want != have {
+		t.Errorf("want pg_cron to use the Debian image %q, have %q", want, have)
+	}
+
+	t.Setenv(core.RegistryMirrorEnv, "mirror.example.com")
+	if want, have := "mirror.example.com/library/postgres:16-alpine", postgres.Image(); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
+
+func TestContainer_CoreNetwork(t *testing.T) {
+	network := core.NewNetwork(t, "app")
+	postgres.Start(t, postgres.WithOptions(core.WithNetwork(network, "db")))
+
+	// Another container on the network reaches PostgreSQL by its alias
+	pool, err := dockerutil.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := pool.RunWithOptions(&dockertest.RunOptions{
+		Repository: "postgres",
+		Tag:        "16-alpine",
+		Cmd:        []string{"pg_isready", "-h", "db", "-p", "5432", "-t", "10"},
+		Networks:   []*dockertest.Network{network.Docker()},
+	})
+	if err != nil {
+		t.Fatalf("could not start client container: %v", err)
+	}
+	defer pool.Purge(client)
+
+	code, err := pool.Client.WaitContainer(client.Container.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 0 {
+		logs, _ := dockerutil.Logs(context.Background(), pool.Client, client.Container.ID)
+		t.Fatalf("want pg_isready to reach db, have exit code %d:\n%s", code, logs)
+	}
+}
+
+func TestContainer_WithLimits(t *testing.T) {
+	c := postgres.Start(t,
+		postgres.WithCPULimit(1),
+		postgres.WithMemoryLimit(512*1024*1024),
+		postgres.WithShmSize(256*1024*1024),
+	)
+
+	pool, err := dockerutil.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, err := pool.Client.InspectContainer(c.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := int64(100000), container.HostConfig.CPUQuota; want != have {
+		t.Errorf("want CPU quota %d, have %d", want, have)
+	}
+	if want, have := int64(512*1024*1024), container.HostConfig.Memory; want != have {
+		t.Errorf("want memory %d, have %d", want, have)
+	}
+	if want, have := int64(256*1024*1024), container.HostConfig.ShmSize; want != have {
+		t.Errorf("want shm size %d, have %d", want, have)
+	}
+}
+
+func TestContainer_WithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	c := postgres.Start(t, postgres.WithOptions(core.WithLogger(logger)))
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, event := range []string{"pull", "start", "ready", "close"} {
+		if !strings.Contains(buf.String(), "event="+event) {
+			t.Errorf("want %s event, have:\n%s", event, buf.String())
+		}
+	}
+}
+
+func TestContainer_WithWaitFor(t *testing.T) {
+	c := postgres.Start(t,
+		postgres.WithWaitFor(
+			core.ForLog(regexp.MustCompile(`database system is ready to accept connections`)),
+			core.ForExec("pg_isready", "-U", "postgres"),
+		),
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.WaitUntilReady(ctx); err != nil {
+		t.Fatalf("want container to be ready, have %v", err)
+	}
+	if err := c.DB().PingContext(ctx); err != nil {
+		t.Fatalf("could not ping: %v", err)
+	}
+}
+
+func TestContainer_WithRetryPolicy(t *testing.T) {
+	c := postgres.Start(t, postgres.WithRetryPolicy(0, 50*time.Millisecond, time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.DB().PingContext(ctx); err != nil {
+		t.Fatalf("could not ping: %v", err)
+	}
+}
+
+func TestContainer_WithKeepContainer(t *testing.T) {
+	c := postgres.Start(t, postgres.WithKeepContainer())
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := dockerutil.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, err := pool.Client.InspectContainer(c.Name())
+	if err != nil {
+		t.Fatalf("want container to be kept, have %v", err)
+	}
+	defer pool.Client.RemoveContainer(docker.RemoveContainerOptions{ID: container.ID, Force: true, RemoveVolumes: true})
+
+	if container.HostConfig.AutoRemove {
+		t.Error("want container not to be removed automatically")
+	}
+	if want, have := "true", container.Config.Labels[core.LabelKeep]; want != have {
+		t.Errorf("want keep label %q, have %q", want, have)
+	}
+	if !container.State.Running {
+		t.Errorf("want container to be running, have %s", container.State.Status)
+	}
+}
+
+func TestRun(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	c, err := postgres.Run(ctx, postgres.WithDatabaseName("dev"))
+	if err != nil {
+		t.Fatalf("could not run container: %v", err)
+	}
+	defer c.Close()
+
+	if want, have := "dev", c.DatabaseName(); want != have {
+		t.Errorf("want database %q, have %q", want, have)
+	}
+	if err := c.DB().PingContext(ctx); err != nil {
+		t.Fatalf("could not ping: %v", err)
+	}
+}
+
+func TestRun_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	c, err := postgres.Run(ctx)
+	if err == nil {
+		c.Close()
+		t.Fatal("want error, have nil")
+	}
+	if c != nil {
+		t.Fatalf("want no container, have %v", c.Name())
+	}
+}
@@ -0,0 +1,51 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// TriggerJob runs the command of the pg_cron job with the given name
+// immediately, instead of waiting for its schedule. The command runs in
+// the database the job is scheduled in. The container must be started with
+// WithPgCron.
+//
+// TriggerJob runs the command as the container's superuser and does not
+// record the run in cron.job_run_details.
+func (c *Container) TriggerJob(ctx context.Context, name string) error {
+	var command, database string
+	err := c.db.QueryRowContext(ctx,
+		`SELECT command, database FROM cron.job WHERE jobname = $1`, name,
+	).Scan(&command, &database)
+	if IsNotFound(err) {
+		return fmt.Errorf("pg_cron job %q not found", name)
+	}
+	if err != nil {
+		return fmt.Errorf("could not look up pg_cron job %q: %w", name, err)
+	}
+
+	db := c.db
+	if database != c.databaseName {
+		db, err = c.connectDatabase(ctx, database)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+	}
+
+	if _, err := db.ExecContext(ctx, command); err != nil {
+		return fmt.Errorf("could not run pg_cron job %q: %w", name, err)
+	}
+	return nil
+}
+
+// connectDatabase connects to another database on the container.
+func (c *Container) connectDatabase(ctx context.Context, database string) (*sql.DB, error) {
+	dsn := ConnectionString(c.ccfg.Host, c.ccfg.Port, database, "disable", c.ccfg.User, c.ccfg.Password)
+	db, err := Connect(ctx, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to database %q: %w", database, err)
+	}
+	return db, nil
+}
@@ -0,0 +1,44 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/postgres"
+)
+
+func TestContainer_TriggerJob(t *testing.T) {
+	c := postgres.Start(t,
+		postgres.WithTimeout(2*time.Minute),
+		postgres.WithPgCron(),
+		postgres.WithPostStart(func(c *postgres.Container) error {
+			_, err := c.DB().Exec(`
+				CREATE TABLE sessions (id BIGINT PRIMARY KEY, expires_at TIMESTAMPTZ NOT NULL);
+				INSERT INTO sessions (id, expires_at) VALUES (1, now() - interval '1 day'), (2, now() + interval '1 day');
+				SELECT cron.schedule('cleanup-sessions', '0 3 * * *', 'DELETE FROM sessions WHERE expires_at < now()');
+			`)
+			return err
+		}),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := c.TriggerJob(ctx, "cleanup-sessions"); err != nil {
+		t.Fatalf("could not trigger job: %v", err)
+	}
+
+	var n int
+	if err := c.DB().QueryRowContext(ctx, "SELECT COUNT(*) FROM sessions").Scan(&n); err != nil {
+		t.Fatalf("could not count sessions: %v", err)
+	}
+	if want, have := 1, n; want != have {
+		t.Fatalf("want n=%d, have %d", want, have)
+	}
+
+	if err := c.TriggerJob(ctx, "no-such-job"); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
@@ -0,0 +1,299 @@
+package postgres
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Masker returns the anonymized version of a column value. NULL values are
+// never passed to a Masker.
+type Masker func(value string) string
+
+// MaskSpec maps columns to the Masker that anonymizes their values.
+// Keys are of the form "table.column" or "schema.table.column", e.g.
+// "users.email" or "public.users.email".
+type MaskSpec map[string]Masker
+
+// MaskConstant replaces every value with s.
+func MaskConstant(s string) Masker {
+	return func(string) string {
+		return s
+	}
+}
+
+// MaskRegexp replaces all matches of re in a value with repl, which may
+// refer to submatches as in regexp.Regexp.ReplaceAllString.
+func MaskRegexp(re *regexp.Regexp, repl string) Masker {
+	return func(value string) string {
+		return re.ReplaceAllString(value, repl)
+	}
+}
+
+// MaskHash replaces every value with a hex-encoded SHA-256 hash of prefix
+// and the value, truncated to n characters (if n > 0). Equal values map
+// to equal hashes, so masked columns can still be joined on.
+func MaskHash(prefix string, n int) Masker {
+	return func(value string) string {
+		sum := sha256.Sum256([]byte(prefix + value))
+		h := hex.EncodeToString(sum[:])
+		if n > 0 && n < len(h) {
+			h = h[:n]
+		}
+		return prefix + h
+	}
+}
+
+// MaskFaker replaces every value with the result of fake, which is called
+// with a running number per column, starting at 0. Use it with a faker
+// library to produce realistic values.
+func MaskFaker(fake func(i int) string) Masker {
+	var i int
+	return func(string) string {
+		s := fake(i)
+		i++
+		return s
+	}
+}
+
+// LoadDump loads a plain-text dump as produced by `pg_dump --format=plain`
+// into the database, anonymizing the columns in spec on the way.
+//
+// Data must be in COPY format, i.e. the dump must not be created with
+// --inserts. Meta-commands of psql such as \connect are ignored. The db
+// must be opened with the pgx driver, e.g. with Connect.
+func LoadDump(ctx context.Context, db *sql.DB, r io.Reader, spec MaskSpec) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var loadErr error
+	err = conn.Raw(func(driverConn any) error {
+		pc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("postgres: LoadDump needs a database opened with pgx, have %T", driverConn)
+		}
+		if loadErr = loadDump(ctx, pc.Conn(), bufio.NewReader(r), spec); loadErr != nil {
+			// Discard the session, which may be in a failed transaction
+			return driver.ErrBadConn
+		}
+		// The preamble of pg_dump changes settings of the session, e.g.
+		// it empties the search_path, which must not leak into the pool
+		if _, loadErr = pc.Conn().Exec(ctx, "RESET ALL"); loadErr != nil {
+			return driver.ErrBadConn
+		}
+		return nil
+	})
+	if loadErr != nil {
+		return loadErr
+	}
+	return err
+}
+
+func loadDump(ctx context.Context, conn *pgx.Conn, r *bufio.Reader, spec MaskSpec) error {
+	var stmts strings.Builder
+	flush := func() error {
+		if strings.TrimSpace(stmts.String()) == "" {
+			return nil
+		}
+		_, err := conn.Exec(ctx, stmts.String())
+		stmts.Reset()
+		return err
+	}
+
+	for lineno := 1; ; lineno++ {
+		line, err := r.ReadString('\n')
+		if err == io.EOF && line == "" {
+			break
+		}
+		if err != nil && err != io.EOF {
+			return err
+		}
+
+		switch {
+		case strings.HasPrefix(line, `\`):
+			// psql meta-command
+		case strings.HasPrefix(line, "COPY ") && strings.HasSuffix(strings.TrimSpace(line), "FROM stdin;"):
+			if err := flush(); err != nil {
+				return fmt.Errorf("line %d: %w", lineno, err)
+			}
+			n, err := copyDump(ctx, conn, r, strings.TrimSuffix(strings.TrimSpace(line), ";"), spec)
+			if err != nil {
+				return fmt.Errorf("line %d: %w", lineno, err)
+			}
+			lineno += n
+		default:
+			stmts.WriteString(line)
+		}
+	}
+	return flush()
+}
+
+// copyDump reads the data of a COPY statement up to the terminating `\.`
+// line from r, masks the columns in spec, and copies the data into
+// the database. It returns the number of lines read.
+func copyDump(ctx context.Context, conn *pgx.Conn, r *bufio.Reader, stmt string, spec MaskSpec) (int, error) {
+	table, columns, err := parseCopyStmt(stmt)
+	if err != nil {
+		return 0, err
+	}
+
+	// Find the maskers per column
+	maskers := make([]Masker, len(columns))
+	var masked bool
+	for i, column := range columns {
+		if m, ok := spec[table+"."+column]; ok {
+			maskers[i], masked = m, true
+		} else if j := strings.LastIndexByte(table, '.'); j >= 0 {
+			if m, ok := spec[table[j+1:]+"."+column]; ok {
+				maskers[i], masked = m, true
+			}
+		}
+	}
+
+	pr, pw := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		_, err := conn.PgConn().CopyFrom(ctx, pr, stmt)
+		pr.CloseWithError(err)
+		done <- err
+	}()
+
+	var n int
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF && line == "" {
+			err = fmt.Errorf("unexpected end of dump in data for %s", table)
+		}
+		if err != nil && err != io.EOF {
+			pw.CloseWithError(err)
+			<-done
+			return n, err
+		}
+		n++
+		if strings.TrimRight(line, "\r\n") == `\.` {
+			break
+		}
+		if masked {
+			line = maskCopyLine(line, maskers)
+		}
+		if _, err := io.WriteString(pw, line); err != nil {
+			// The COPY failed, so we skip the rest of its data
+			continue
+		}
+	}
+	pw.Close()
+
+	if err := <-done; err != nil {
+		return n, fmt.Errorf("could not copy data into %s: %w", table, err)
+	}
+	return n, nil
+}
+
+var copyStmtRegexp = regexp.MustCompile(`^COPY (\S+) \((.*)\) FROM stdin$`)
+
+// parseCopyStmt returns the unquoted table and column names of a COPY
+// statement like `COPY public.users (id, "Name") FROM stdin`.
+func parseCopyStmt(stmt string) (string, []string, error) {
+	m := copyStmtRegexp.FindStringSubmatch(stmt)
+	if m == nil {
+		return "", nil, fmt.Errorf("unsupported COPY statement: %s", stmt)
+	}
+	table := strings.ReplaceAll(m[1], `"`, "")
+	var columns []string
+	for _, column := range strings.Split(m[2], ",") {
+		columns = append(columns, strings.Trim(strings.TrimSpace(column), `"`))
+	}
+	return table, columns, nil
+}
+
+// maskCopyLine applies maskers to the fields of a line in COPY text format.
+func maskCopyLine(line string, maskers []Masker) string {
+	line = strings.TrimSuffix(line, "\n")
+	fields := strings.Split(line, "\t")
+	for i, field := range fields {
+		if i >= len(maskers) || maskers[i] == nil || field == `\N` {
+			continue
+		}
+		fields[i] = escapeCopyField(maskers[i](unescapeCopyField(field)))
+	}
+	return strings.Join(fields, "\t") + "\n"
+}
+
+// unescapeCopyField decodes a field in COPY text format.
+func unescapeCopyField(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'v':
+			b.WriteByte('\v')
+		case 'x':
+			j := i + 1
+			for j < len(s) && j < i+3 && isHexDigit(s[j]) {
+				j++
+			}
+			if v, err := strconv.ParseUint(s[i+1:j], 16, 8); err == nil {
+				b.WriteByte(byte(v))
+				i = j - 1
+			} else {
+				b.WriteByte(c)
+			}
+		case '0', '1', '2', '3', '4', '5', '6', '7':
+			j := i
+			for j < len(s) && j < i+3 && s[j] >= '0' && s[j] <= '7' {
+				j++
+			}
+			v, _ := strconv.ParseUint(s[i:j], 8, 8)
+			b.WriteByte(byte(v))
+			i = j - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// escapeCopyField encodes a value in COPY text format.
+func escapeCopyField(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		"\n", `\n`,
+		"\r", `\r`,
+		"\t", `\t`,
+	).Replace(s)
+}
+
+func isHexDigit(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
@@ -0,0 +1,161 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/postgres"
+)
+
+const testDump = `--
+-- PostgreSQL database dump
+--
+
+\restrict abcdef
+
+SET statement_timeout = 0;
+SELECT pg_catalog.set_config('search_path', '', false);
+
+CREATE TABLE public.users (
+    id bigint NOT NULL,
+    email text NOT NULL,
+    "Notes" text
+);
+
+COPY public.users (id, email, "Notes") FROM stdin;
+1	alice@example.com	likes\ttabs
+2	bob@example.com	\N
+\.
+
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+
+\unrestrict abcdef
+`
+
+func TestLoadDump(t *testing.T) {
+	c := postgres.Start(t, postgres.WithTimeout(30*time.Second))
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := postgres.LoadDump(ctx, c.DB(), strings.NewReader(testDump), postgres.MaskSpec{
+		"users.email":        postgres.MaskRegexp(regexp.MustCompile(`^[^@]+`), "user"),
+		"public.users.Notes": postgres.MaskConstant("redacted\tvalue"),
+	})
+	if err != nil {
+		t.Fatalf("could not load dump: %v", err)
+	}
+
+	rows, err := c.DB().QueryContext(ctx, `SELECT email, COALESCE("Notes", 'NULL') FROM users ORDER BY id`)
+	if err != nil {
+		t.Fatalf("could not query users: %v", err)
+	}
+	defer rows.Close()
+	var have []string
+	for rows.Next() {
+		var email, notes string
+		if err := rows.Scan(&email, &notes); err != nil {
+			t.Fatalf("could not scan: %v", err)
+		}
+		have = append(have, email+"|"+notes)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("could not iterate: %v", err)
+	}
+	if want := "user@example.com|redacted\tvalue user@example.com|NULL"; want != strings.Join(have, " ") {
+		t.Fatalf("want %q, have %q", want, strings.Join(have, " "))
+	}
+}
+
+func TestLoadDump_ResetsSession(t *testing.T) {
+	c := postgres.Start(t, postgres.WithTimeout(30*time.Second))
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Make sure that later queries run on the connection of the dump
+	db, err := postgres.Connect(ctx, c.Endpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if err := postgres.LoadDump(ctx, db, strings.NewReader(testDump), nil); err != nil {
+		t.Fatalf("could not load dump: %v", err)
+	}
+	var searchPath string
+	if err := db.QueryRowContext(ctx, `SHOW search_path`).Scan(&searchPath); err != nil {
+		t.Fatal(err)
+	}
+	if searchPath == "" || searchPath == `""` {
+		t.Fatalf("want search_path of the pool after LoadDump, have %q", searchPath)
+	}
+	var n int
+	if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&n); err != nil {
+		t.Fatalf("could not query users after LoadDump: %v", err)
+	}
+}
+
+// otherDriver is a database/sql driver other than pgx.
+type otherDriver struct{}
+
+func (otherDriver) Open(string) (driver.Conn, error) { return otherConn{}, nil }
+
+type otherConn struct{}
+
+func (otherConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not implemented") }
+func (otherConn) Close() error                        { return nil }
+func (otherConn) Begin() (driver.Tx, error)           { return nil, errors.New("not implemented") }
+
+func init() {
+	sql.Register("integrationtest-other", otherDriver{})
+}
+
+func TestLoadDump_OtherDriver(t *testing.T) {
+	db, err := sql.Open("integrationtest-other", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = postgres.LoadDump(context.Background(), db, strings.NewReader(testDump), nil)
+	if err == nil || !strings.Contains(err.Error(), "pgx") {
+		t.Fatalf("want error about the driver, have %v", err)
+	}
+}
+
+func TestMaskers(t *testing.T) {
+	tests := []struct {
+		Masker   postgres.Masker
+		Input    string
+		Expected string
+	}{
+		{Masker: postgres.MaskConstant("x"), Input: "secret", Expected: "x"},
+		{Masker: postgres.MaskRegexp(regexp.MustCompile(`\d`), "#"), Input: "+49 123", Expected: "+## ###"},
+		{Masker: postgres.MaskHash("user-", 8), Input: "alice", Expected: "user-0e7b8c3e"},
+	}
+	for i, tc := range tests {
+		if want, have := tc.Expected, tc.Masker(tc.Input); want != have {
+			t.Errorf("#%d: want %q, have %q", i, want, have)
+		}
+	}
+
+	fake := postgres.MaskFaker(func(i int) string { return fmt.Sprintf("name%d", i) })
+	if want, have := "name0", fake("alice"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+	if want, have := "name1", fake("bob"); want != have {
+		t.Errorf("want %q, have %q", want, have)
+	}
+}
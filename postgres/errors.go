@@ -31,6 +31,40 @@ func IsPSQLError(err error, code string) bool {
 	return false
 }
 
+// PgErrorCode returns the SQLSTATE code of the given error if it is from
+// PostgreSQL, e.g. "23505". It returns an empty string otherwise.
+func PgErrorCode(err error) string {
+	var pgxerr *pgconn.PgError
+	if stderrors.As(err, &pgxerr) {
+		return pgxerr.Code
+	}
+	return ""
+}
+
+// ConstraintName returns the name of the constraint that the given error
+// from PostgreSQL refers to, e.g. for unique, foreign key, or check
+// violations. It returns an empty string otherwise.
+func ConstraintName(err error) string {
+	var pgxerr *pgconn.PgError
+	if stderrors.As(err, &pgxerr) {
+		return pgxerr.ConstraintName
+	}
+	return ""
+}
+
+// IsConstraintViolation returns true if the given error indicates a
+// violation of the given constraint, regardless of its kind.
+func IsConstraintViolation(err error, constraint string) bool {
+	return constraint != "" && ConstraintName(err) == constraint
+}
+
+// IsNotNullViolation returns true if the given error indicates a
+// violation of a not-null constraint (23502 not_null_violation).
+func IsNotNullViolation(err error) bool {
+	// 23502 not_null_violation
+	return IsPSQLError(err, "23502")
+}
+
 // IsForeignKeyViolation returns true if the given error indicates a
 // violation of a foreign key constraint (23503 foreign_key_violation).
 func IsForeignKeyViolation(err error) bool {
@@ -45,6 +79,36 @@ func IsDup(err error) bool {
 	return IsPSQLError(err, "23505")
 }
 
+// IsCheckViolation returns true if the given error indicates a
+// violation of a check constraint (23514 check_violation).
+func IsCheckViolation(err error) bool {
+	// 23514 check_violation
+	return IsPSQLError(err, "23514")
+}
+
+// IsSerializationFailure returns true if the given error indicates that
+// a transaction could not be serialized and should be retried
+// (40001 serialization_failure).
+func IsSerializationFailure(err error) bool {
+	// 40001 serialization_failure
+	return IsPSQLError(err, "40001")
+}
+
+// IsDeadlockDetected returns true if the given error indicates that
+// a transaction was aborted due to a deadlock (40P01 deadlock_detected).
+func IsDeadlockDetected(err error) bool {
+	// 40P01 deadlock_detected
+	return IsPSQLError(err, "40P01")
+}
+
+// IsInvalidTextRepresentation returns true if the given error indicates
+// that a value could not be parsed into the target type, e.g. an invalid
+// UUID (22P02 invalid_text_representation).
+func IsInvalidTextRepresentation(err error) bool {
+	// 22P02 invalid_text_representation
+	return IsPSQLError(err, "22P02")
+}
+
 // IsPerm returns true if the given error indicates a permission issue
 // (42501 insufficient_privilege).
 func IsPerm(err error) bool {
@@ -44,3 +44,72 @@ func TestIsDup(t *testing.T) {
 		}
 	}
 }
+
+func TestPgErrorCode(t *testing.T) {
+	tests := []struct {
+		Error    error
+		Expected string
+	}{
+		{Error: nil, Expected: ""},
+		{Error: sql.ErrNoRows, Expected: ""},
+		{Error: &pgconn.PgError{Code: "23514"}, Expected: "23514"},
+		{Error: fmt.Errorf("kaboom: %w", &pgconn.PgError{Code: "40001"}), Expected: "40001"},
+	}
+	for i, tc := range tests {
+		if want, have := tc.Expected, postgres.PgErrorCode(tc.Error); want != have {
+			t.Errorf("#%d: postgres.PgErrorCode(%v): want %q, have %q", i, tc.Error, want, have)
+		}
+	}
+}
+
+func TestConstraintName(t *testing.T) {
+	tests := []struct {
+		Error      error
+		Expected   string
+		Constraint string
+		Violation  bool
+	}{
+		{Error: nil, Expected: "", Constraint: "", Violation: false},
+		{Error: &pgconn.PgError{Code: "23505"}, Expected: "", Constraint: "", Violation: false},
+		{Error: &pgconn.PgError{Code: "23505", ConstraintName: "foo_pkey"}, Expected: "foo_pkey", Constraint: "foo_pkey", Violation: true},
+		{Error: fmt.Errorf("kaboom: %w", &pgconn.PgError{Code: "23514", ConstraintName: "foo_check"}), Expected: "foo_check", Constraint: "foo_check", Violation: true},
+		{Error: &pgconn.PgError{Code: "23514", ConstraintName: "foo_check"}, Expected: "foo_check", Constraint: "bar_check", Violation: false},
+	}
+	for i, tc := range tests {
+		if want, have := tc.Expected, postgres.ConstraintName(tc.Error); want != have {
+			t.Errorf("#%d: postgres.ConstraintName(%v): want %q, have %q", i, tc.Error, want, have)
+		}
+		if want, have := tc.Violation, postgres.IsConstraintViolation(tc.Error, tc.Constraint); want != have {
+			t.Errorf("#%d: postgres.IsConstraintViolation(%v, %q): want %v, have %v", i, tc.Error, tc.Constraint, want, have)
+		}
+	}
+}
+
+func TestErrorPredicates(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Predicate func(error) bool
+		Code      string
+	}{
+		{Name: "IsNotNullViolation", Predicate: postgres.IsNotNullViolation, Code: "23502"},
+		{Name: "IsForeignKeyViolation", Predicate: postgres.IsForeignKeyViolation, Code: "23503"},
+		{Name: "IsCheckViolation", Predicate: postgres.IsCheckViolation, Code: "23514"},
+		{Name: "IsSerializationFailure", Predicate: postgres.IsSerializationFailure, Code: "40001"},
+		{Name: "IsDeadlockDetected", Predicate: postgres.IsDeadlockDetected, Code: "40P01"},
+		{Name: "IsInvalidTextRepresentation", Predicate: postgres.IsInvalidTextRepresentation, Code: "22P02"},
+	}
+	for _, tc := range tests {
+		if tc.Predicate(nil) {
+			t.Errorf("postgres.%s(nil): want false, have true", tc.Name)
+		}
+		if tc.Predicate(&pgconn.PgError{Code: "00000"}) {
+			t.Errorf("postgres.%s(00000): want false, have true", tc.Name)
+		}
+		if !tc.Predicate(&pgconn.PgError{Code: tc.Code}) {
+			t.Errorf("postgres.%s(%s): want true, have false", tc.Name, tc.Code)
+		}
+		if !tc.Predicate(fmt.Errorf("kaboom: %w", &pgconn.PgError{Code: tc.Code})) {
+			t.Errorf("postgres.%s(wrapped %s): want true, have false", tc.Name, tc.Code)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package postgres_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/olivere/integrationtest/postgres"
+)
+
+func TestStart_External(t *testing.T) {
+	server := postgres.Start(t, postgres.WithDatabaseName("postgres"))
+	t.Setenv("INTEGRATIONTEST_POSTGRES_URL", server.Endpoint())
+
+	var seeded bool
+	c := postgres.Start(t,
+		postgres.WithDatabaseName("app"),
+		postgres.WithPostStart(func(c *postgres.Container) error {
+			seeded = true
+			_, err := c.DB().Exec(`CREATE TABLE users (name text)`)
+			return err
+		}),
+	)
+	if !seeded {
+		t.Fatal("want post-start operations to run on the external server")
+	}
+	if name := c.DatabaseName(); !strings.HasPrefix(name, "app_") {
+		t.Fatalf("want a database of its own, have %q", name)
+	}
+	if want, have := server.Name(), c.Name(); want == have {
+		t.Fatalf("want no container, have %s", have)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	exists, err := postgres.DatabaseExists(context.Background(), strings.Replace(server.Endpoint(), "/postgres?", "/"+c.DatabaseName()+"?", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exists {
+		t.Fatalf("want database %s to be dropped on Close", c.DatabaseName())
+	}
+}
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// FDW is a pair of PostgreSQL containers where Local has access to Remote
+// via the postgres_fdw extension.
+type FDW struct {
+	// Local is the container with the postgres_fdw extension installed.
+	Local *Container
+	// Remote is the container that Local accesses via the foreign server.
+	Remote *Container
+	// Server is the name of the foreign server in Local that points to
+	// Remote.
+	Server string
+}
+
+// StartFDW starts two PostgreSQL containers on a shared Docker network,
+// installs the postgres_fdw extension in the first one, and creates
+// a foreign server named "remote" plus a user mapping for the second one.
+//
+// The options apply to both containers. StartFDW always starts
+// containers, even if an external server is configured, as the foreign
+// server reaches Remote by its name on the network.
+func StartFDW(tb testing.TB, options ...startConfigFunc) *FDW {
+	tb.Helper()
+
+	pool, err := dockerutil.Pool()
+	if err != nil {
+		tb.Fatalf("unable to connect to Docker: %v", err)
+	}
+	network, err := pool.CreateNetwork(dockerutil.Name("integrationtest_fdw", tb.Name()), func(config *docker.CreateNetworkOptions) {
+		config.Labels = core.Labels("network", tb.Name())
+	})
+	if err != nil {
+		tb.Fatalf("could not create Docker network: %v", err)
+	}
+	// Registered first, so it runs after the containers are gone
+	tb.Cleanup(func() {
+		if err := network.Close(); err != nil {
+			tb.Logf("could not remove Docker network: %v", err)
+		}
+	})
+
+	options = append(options, WithNetwork(network), withContainer())
+	fdw := &FDW{
+		Remote: Start(tb, options...),
+		Local:  Start(tb, options...),
+		Server: "remote",
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	server := pgx.Identifier([]string{fdw.Server}).Sanitize()
+	stmts := []string{
+		`CREATE EXTENSION IF NOT EXISTS postgres_fdw`,
+		fmt.Sprintf(`CREATE SERVER %s FOREIGN DATA WRAPPER postgres_fdw OPTIONS (host %s, port '5432', dbname %s)`,
+			server, quoteLiteral(fdw.Remote.Name()), quoteLiteral(fdw.Remote.DatabaseName())),
+		fmt.Sprintf(`CREATE USER MAPPING FOR CURRENT_USER SERVER %s OPTIONS (user 'postgres', password 'postgres')`,
+			server),
+	}
+	for _, stmt := range stmts {
+		if _, err := fdw.Local.DB().ExecContext(ctx, stmt); err != nil {
+			tb.Fatalf("could not set up postgres_fdw: %v", err)
+		}
+	}
+
+	return fdw
+}
+
+// ImportForeignSchema imports all tables of remoteSchema in Remote as
+// foreign tables into localSchema in Local. The local schema is created
+// if it doesn't exist.
+func (fdw *FDW) ImportForeignSchema(ctx context.Context, remoteSchema, localSchema string) error {
+	local := pgx.Identifier([]string{localSchema}).Sanitize()
+	if _, err := fdw.Local.DB().ExecContext(ctx, `CREATE SCHEMA IF NOT EXISTS `+local); err != nil {
+		return err
+	}
+	_, err := fdw.Local.DB().ExecContext(ctx, fmt.Sprintf(`IMPORT FOREIGN SCHEMA %s FROM SERVER %s INTO %s`,
+		pgx.Identifier([]string{remoteSchema}).Sanitize(), pgx.Identifier([]string{fdw.Server}).Sanitize(), local))
+	return err
+}
+
+// quoteLiteral quotes s as a SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
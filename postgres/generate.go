@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+type generateConfig struct {
+	columns   []string
+	batchSize int
+	progress  func(done, total int)
+}
+
+type generateOption func(*generateConfig)
+
+// WithColumns sets the columns that GenerateRows fills. The values returned
+// by the generator must be in the same order. By default, all columns of the
+// table except generated columns are used, in their ordinal order.
+func WithColumns(columns ...string) generateOption {
+	return func(cfg *generateConfig) {
+		cfg.columns = columns
+	}
+}
+
+// WithBatchSize sets the number of rows that GenerateRows sends in a single
+// COPY operation. It defaults to 10000.
+func WithBatchSize(batchSize int) generateOption {
+	return func(cfg *generateConfig) {
+		cfg.batchSize = batchSize
+	}
+}
+
+// WithProgress registers a callback that GenerateRows calls after each batch
+// with the number of rows generated so far and the total number of rows.
+func WithProgress(progress func(done, total int)) generateOption {
+	return func(cfg *generateConfig) {
+		cfg.progress = progress
+	}
+}
+
+// GenerateRows inserts n rows into table, streaming them in batches through
+// the COPY protocol. The gen func is called for every row with its index
+// in [0, n) and must return the values for the configured columns.
+//
+// The table name may be schema-qualified, e.g. "public.foo". Every batch is
+// committed on its own, so the rows of completed batches remain in
+// the table if ctx is canceled or gen fails. GenerateRows returns the
+// number of rows inserted.
+//
+// COPY needs the pgx driver, so db must be opened with pgx, e.g. by
+// Container.DB or sql.Open("pgx", ...).
+func GenerateRows(ctx context.Context, db *sql.DB, table string, n int, gen func(i int) []any, options ...generateOption) (int, error) {
+	cfg := generateConfig{
+		batchSize: 10000,
+	}
+	for _, o := range options {
+		o(&cfg)
+	}
+	if cfg.batchSize <= 0 {
+		return 0, fmt.Errorf("invalid batch size %d", cfg.batchSize)
+	}
+
+	ident := pgx.Identifier(strings.Split(table, "."))
+
+	columns := cfg.columns
+	if len(columns) == 0 {
+		var err error
+		columns, err = tableColumns(ctx, db, ident)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var done int
+	err = conn.Raw(func(driverConn any) error {
+		pc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("postgres: GenerateRows needs a database opened with pgx, have %T", driverConn)
+		}
+		pgxConn := pc.Conn()
+
+		for done < n {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			offset := done
+			size := min(cfg.batchSize, n-done)
+			copied, err := pgxConn.CopyFrom(ctx, ident, columns, pgx.CopyFromSlice(size, func(i int) ([]any, error) {
+				values := gen(offset + i)
+				if len(values) != len(columns) {
+					return nil, fmt.Errorf("row %d: generator returned %d values for %d columns", offset+i, len(values), len(columns))
+				}
+				return values, nil
+			}))
+			done += int(copied)
+			if err != nil {
+				return fmt.Errorf("could not copy rows into %s: %w", ident.Sanitize(), err)
+			}
+
+			if cfg.progress != nil {
+				cfg.progress(done, n)
+			}
+		}
+		return nil
+	})
+	return done, err
+}
+
+// tableColumns returns the names of all non-generated columns of a table.
+func tableColumns(ctx context.Context, db *sql.DB, ident pgx.Identifier) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT attname
+		FROM pg_attribute
+		WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped AND attgenerated = ''
+		ORDER BY attnum`,
+		ident.Sanitize(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not look up columns of %s: %w", ident.Sanitize(), err)
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return nil, err
+		}
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("table %s has no columns", ident.Sanitize())
+	}
+	return columns, nil
+}
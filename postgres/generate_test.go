@@ -0,0 +1,74 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/postgres"
+)
+
+func TestGenerateRows(t *testing.T) {
+	c := postgres.Start(t,
+		postgres.WithTimeout(30*time.Second),
+		postgres.WithPostStart(func(c *postgres.Container) error {
+			_, err := c.DB().Exec(`CREATE TABLE foo (
+				id BIGINT PRIMARY KEY,
+				name TEXT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+			)`)
+			return err
+		}),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var batches int
+	n, err := postgres.GenerateRows(ctx, c.DB(), "public.foo", 25000, func(i int) []any {
+		return []any{int64(i), fmt.Sprintf("foo%d", i)}
+	},
+		postgres.WithColumns("id", "name"),
+		postgres.WithBatchSize(10000),
+		postgres.WithProgress(func(done, total int) {
+			batches++
+			t.Logf("generated %d of %d rows", done, total)
+		}),
+	)
+	if err != nil {
+		t.Fatalf("could not generate rows: %v", err)
+	}
+	if want, have := 25000, n; want != have {
+		t.Fatalf("want n=%d, have %d", want, have)
+	}
+	if want, have := 3, batches; want != have {
+		t.Fatalf("want batches=%d, have %d", want, have)
+	}
+
+	var count int
+	if err := c.DB().QueryRowContext(ctx, "SELECT COUNT(*) FROM foo").Scan(&count); err != nil {
+		t.Fatalf("could not count rows: %v", err)
+	}
+	if want, have := 25000, count; want != have {
+		t.Fatalf("want count=%d, have %d", want, have)
+	}
+}
+
+func TestGenerateRows_OtherDriver(t *testing.T) {
+	db, err := sql.Open("integrationtest-other", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	_, err = postgres.GenerateRows(context.Background(), db, "foo", 1, func(i int) []any {
+		return []any{i}
+	}, postgres.WithColumns("id"))
+	if err == nil || !strings.Contains(err.Error(), "pgx") {
+		t.Fatalf("want error about the driver, have %v", err)
+	}
+}
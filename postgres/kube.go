@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/lifecycle"
+	"github.com/olivere/integrationtest/kube"
+)
+
+// useKube returns true if the container runs as a pod in Kubernetes, see
+// kube.Enabled. Like with useExternalURL, containers that must run in
+// Docker never do.
+func (cfg startConfig) useKube() bool {
+	return kube.Enabled() && !cfg.toxiproxy && !cfg.container
+}
+
+// kubeUnsupported returns the options of cfg that need Docker, and hence
+// fail with a pod.
+func (cfg startConfig) kubeUnsupported() []string {
+	var options []string
+	if cfg.inMemory {
+		options = append(options, "WithInMemory")
+	}
+	if len(cfg.networks) > 0 || len(cfg.attachments) > 0 {
+		options = append(options, "WithNetwork")
+	}
+	if cfg.reuse != "" {
+		options = append(options, "WithReuse")
+	}
+	if cfg.snapshot {
+		options = append(options, "WithSnapshot")
+	}
+	if cfg.build != nil {
+		options = append(options, "WithBuiltImage")
+	}
+	return options
+}
+
+// startKube starts PostgreSQL as a pod instead of a container, and
+// connects to it via its service. Resource limits have no effect on pods.
+func (c *Container) startKube(ctx context.Context, startCfg startConfig) error {
+	if options := startCfg.kubeUnsupported(); len(options) > 0 {
+		return fmt.Errorf("postgres: %s not supported with %s=kube", strings.Join(options, ", "), kube.BackendEnv)
+	}
+
+	env, entrypoint := startCfg.command()
+	repository, tag := startCfg.image()
+	lifetime := startCfg.maxLifetime
+	if c.keep {
+		lifetime = -1
+	}
+
+	started := time.Now()
+	var err error
+	c.pod, err = kube.Run(ctx, repository+":"+tag,
+		kube.WithOptions(core.WithRetryPolicy(c.retryPolicy.MaxAttempts, c.retryPolicy.InitialBackoff, c.retryPolicy.MaxBackoff)),
+		kube.WithCommand(entrypoint...),
+		kube.WithEnv(env...),
+		kube.WithPorts(5432),
+		kube.WithTimeout(c.timeout),
+		kube.WithMaxLifetime(lifetime),
+	)
+	if err != nil {
+		lifecycle.Log(c.logger, "postgres", lifecycle.Start, started, err)
+		return fmt.Errorf("unable to start PostgreSQL pod: %w", err)
+	}
+	lifecycle.Log(c.logger, "postgres", lifecycle.Start, started, nil, "pod", c.pod.Name())
+
+	c.hostPort = c.pod.Address(5432)
+	c.dsn = fmt.Sprintf("postgres://postgres:postgres@%s/%s?sslmode=disable", c.hostPort, c.databaseName)
+	c.ccfg, err = pgx.ParseConfig(c.dsn)
+	if err != nil {
+		return fmt.Errorf("could not parse connection string: %w", err)
+	}
+
+	c.waitFor = c.forConnection().WithAttemptTimeout(8 * time.Second)
+	err = core.WaitUntil(ctx, c.timeout, c.retryPolicy, nil, c.waitFor)
+	lifecycle.Log(c.logger, "postgres", lifecycle.Ready, started, err, "pod", c.pod.Name())
+	if err != nil {
+		return fmt.Errorf("could not connect to PostgreSQL pod: %w", err)
+	}
+
+	if err := c.prepare(startCfg); err != nil {
+		return err
+	}
+	if err := c.runPostStart(startCfg.postStart); err != nil {
+		return err
+	}
+	if c.isTemplate {
+		return c.setTemplate(true)
+	}
+	return nil
+}
+
+// closeKube deletes the pod, unless it is kept.
+func (c *Container) closeKube() error {
+	var errs []error
+	if c.db != nil {
+		errs = append(errs, c.db.Close())
+	}
+	if c.keep {
+		lifecycle.Kept(os.Stderr, "postgres", c.dsn, c.pod.Name())
+	} else {
+		closeStart := time.Now()
+		err := c.pod.Close()
+		lifecycle.Log(c.logger, "postgres", lifecycle.Close, closeStart, err, "pod", c.pod.Name())
+		errs = append(errs, err)
+	}
+	c.closed = true
+	return errors.Join(errs...)
+}
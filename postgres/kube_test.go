@@ -0,0 +1,36 @@
+package postgres_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/olivere/integrationtest/kube"
+	"github.com/olivere/integrationtest/postgres"
+)
+
+// withoutKubeconfig makes the tests find no Kubernetes cluster.
+func withoutKubeconfig(t *testing.T) {
+	t.Setenv(kube.BackendEnv, "kube")
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("KUBECONFIG", "")
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+}
+
+func TestRun_Kube(t *testing.T) {
+	withoutKubeconfig(t)
+
+	_, err := postgres.Run(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "PostgreSQL pod") {
+		t.Fatalf("want to start a pod instead of a container, have %v", err)
+	}
+}
+
+func TestRun_KubeUnsupported(t *testing.T) {
+	withoutKubeconfig(t)
+
+	_, err := postgres.Run(context.Background(), postgres.WithReuse("kube", ""))
+	if err == nil || !strings.Contains(err.Error(), "WithReuse") {
+		t.Fatalf("want WithReuse to be unsupported, have %v", err)
+	}
+}
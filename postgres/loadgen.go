@@ -0,0 +1,147 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats summarizes a run of LoadGen.
+type Stats struct {
+	// Transactions is the number of committed transactions.
+	Transactions int64
+	// Errors is the number of transactions that failed, including those
+	// that failed after exhausting all retries.
+	Errors int64
+	// Retries is the number of retries due to serialization failures
+	// or deadlocks.
+	Retries int64
+	// Duration is the wall time of the run.
+	Duration time.Duration
+	// FirstError is the first error that made a transaction fail.
+	FirstError error
+}
+
+// Throughput returns the number of committed transactions per second.
+func (s Stats) Throughput() float64 {
+	if s.Duration <= 0 {
+		return 0
+	}
+	return float64(s.Transactions) / s.Duration.Seconds()
+}
+
+type loadGenConfig struct {
+	iterations int
+	maxRetries int
+	txOptions  *sql.TxOptions
+}
+
+type loadGenOption func(*loadGenConfig)
+
+// WithIterations sets the total number of transactions that LoadGen runs
+// across all workers. By default, LoadGen runs until its context is done.
+func WithIterations(iterations int) loadGenOption {
+	return func(cfg *loadGenConfig) {
+		cfg.iterations = iterations
+	}
+}
+
+// WithMaxRetries sets how often LoadGen retries a transaction that failed
+// due to a serialization failure or deadlock. It defaults to 10.
+func WithMaxRetries(maxRetries int) loadGenOption {
+	return func(cfg *loadGenConfig) {
+		cfg.maxRetries = maxRetries
+	}
+}
+
+// WithTxOptions sets the options of the transactions that LoadGen runs,
+// e.g. to use the serializable isolation level.
+func WithTxOptions(txOptions *sql.TxOptions) loadGenOption {
+	return func(cfg *loadGenConfig) {
+		cfg.txOptions = txOptions
+	}
+}
+
+// LoadGen runs fn concurrently in the given number of workers, each call
+// in its own transaction, until ctx is done or the number of iterations
+// (see WithIterations) is reached. A transaction is committed if fn returns
+// nil and rolled back otherwise. Transactions that fail with a serialization
+// failure or deadlock are retried.
+//
+// LoadGen is meant for testing the behavior of code under contention,
+// e.g. SERIALIZABLE retry loops. It returns the statistics of the run.
+func LoadGen(ctx context.Context, db *sql.DB, workers int, fn func(ctx context.Context, tx *sql.Tx) error, options ...loadGenOption) Stats {
+	cfg := loadGenConfig{
+		maxRetries: 10,
+	}
+	for _, o := range options {
+		o(&cfg)
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	var (
+		stats     Stats
+		started   atomic.Int64
+		committed atomic.Int64
+		failed    atomic.Int64
+		retries   atomic.Int64
+		errOnce   sync.Once
+		wg        sync.WaitGroup
+	)
+
+	run := func() error {
+		tx, err := db.BeginTx(ctx, cfg.txOptions)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := fn(ctx, tx); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	now := time.Now()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				if cfg.iterations > 0 && started.Add(1) > int64(cfg.iterations) {
+					return
+				}
+
+				var err error
+				for attempt := 0; ; attempt++ {
+					err = run()
+					if err == nil || attempt >= cfg.maxRetries || !(IsSerializationFailure(err) || IsDeadlockDetected(err)) {
+						break
+					}
+					retries.Add(1)
+				}
+
+				switch {
+				case err == nil:
+					committed.Add(1)
+				case ctx.Err() != nil && errors.Is(err, ctx.Err()):
+					// Canceled, so this doesn't count as an error
+				default:
+					failed.Add(1)
+					errOnce.Do(func() { stats.FirstError = err })
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	stats.Duration = time.Since(now)
+	stats.Transactions = committed.Load()
+	stats.Errors = failed.Load()
+	stats.Retries = retries.Load()
+	return stats
+}
@@ -0,0 +1,57 @@
+package postgres_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/postgres"
+)
+
+func TestLoadGen(t *testing.T) {
+	c := postgres.Start(t,
+		postgres.WithTimeout(30*time.Second),
+		postgres.WithPostStart(func(c *postgres.Container) error {
+			_, err := c.DB().Exec(`
+				CREATE TABLE counters (id INT PRIMARY KEY, n BIGINT NOT NULL);
+				INSERT INTO counters (id, n) VALUES (1, 0);
+			`)
+			return err
+		}),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	// Read-modify-write under SERIALIZABLE provokes serialization failures
+	stats := postgres.LoadGen(ctx, c.DB(), 8, func(ctx context.Context, tx *sql.Tx) error {
+		var n int64
+		if err := tx.QueryRowContext(ctx, `SELECT n FROM counters WHERE id = 1`).Scan(&n); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `UPDATE counters SET n = $1 WHERE id = 1`, n+1)
+		return err
+	},
+		postgres.WithIterations(200),
+		postgres.WithMaxRetries(100),
+		postgres.WithTxOptions(&sql.TxOptions{Isolation: sql.LevelSerializable}),
+	)
+	t.Logf("transactions=%d errors=%d retries=%d throughput=%.1f/s", stats.Transactions, stats.Errors, stats.Retries, stats.Throughput())
+
+	if stats.Errors != 0 {
+		t.Fatalf("want no errors, have %d: %v", stats.Errors, stats.FirstError)
+	}
+	if want, have := int64(200), stats.Transactions; want != have {
+		t.Fatalf("want transactions=%d, have %d", want, have)
+	}
+
+	var n int64
+	if err := c.DB().QueryRowContext(ctx, `SELECT n FROM counters WHERE id = 1`).Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := int64(200), n; want != have {
+		t.Fatalf("want n=%d, have %d", want, have)
+	}
+}
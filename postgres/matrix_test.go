@@ -0,0 +1,27 @@
+package postgres_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/postgres"
+)
+
+func TestStartMatrix(t *testing.T) {
+	postgres.StartMatrix(t, []string{"15", "16"}, func(tb testing.TB, c *postgres.Container) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		var version string
+		if err := c.DB().QueryRowContext(ctx, "SHOW server_version").Scan(&version); err != nil {
+			tb.Fatalf("could not query server version: %v", err)
+		}
+
+		name := tb.Name()
+		if want := name[strings.LastIndex(name, "/")+1:]; !strings.HasPrefix(version, want) {
+			tb.Fatalf("want server_version to start with %q, have %q", want, version)
+		}
+	}, postgres.WithTimeout(30*time.Second))
+}
@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateRangePartitions creates partitions for the time range [from, to)
+// of a table that is partitioned by range on the given column, one per
+// interval. The parent table must already exist, e.g.:
+//
+//	CREATE TABLE events (id BIGINT, created_at TIMESTAMPTZ NOT NULL)
+//	PARTITION BY RANGE (created_at)
+//
+// Partitions are named after the parent table and the start of their
+// range, e.g. events_20240101, and are created in the schema of the parent
+// table. Existing partitions with the same name are kept. Rows inserted
+// into the parent table, e.g. with GenerateRows, are routed into the
+// partitions; rows outside [from, to) are rejected by PostgreSQL.
+//
+// CreateRangePartitions returns the names of the partitions.
+func CreateRangePartitions(ctx context.Context, db *sql.DB, table, column string, from, to time.Time, interval time.Duration) ([]string, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid partition interval %v", interval)
+	}
+	if !from.Before(to) {
+		return nil, fmt.Errorf("invalid partition range: %v is not before %v", from, to)
+	}
+
+	parent := pgx.Identifier(strings.Split(table, "."))
+
+	// Make sure the table is partitioned by range on the column
+	var strategy, partColumn string
+	err := db.QueryRowContext(ctx, `
+		SELECT pt.partstrat, a.attname
+		FROM pg_partitioned_table pt
+		JOIN pg_attribute a ON a.attrelid = pt.partrelid AND a.attnum = pt.partattrs[0]
+		WHERE pt.partrelid = $1::regclass`,
+		parent.Sanitize(),
+	).Scan(&strategy, &partColumn)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("table %s is not partitioned", parent.Sanitize())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not look up partitioning of %s: %w", parent.Sanitize(), err)
+	}
+	if strategy != "r" || partColumn != column {
+		return nil, fmt.Errorf("table %s is not partitioned by range on column %q", parent.Sanitize(), column)
+	}
+
+	// Use the date only if all boundaries are at midnight
+	layout := "20060102"
+	if interval%(24*time.Hour) != 0 || !from.Equal(from.Truncate(24*time.Hour)) {
+		layout = "20060102150405"
+	}
+
+	var names []string
+	for lower := from; lower.Before(to); lower = lower.Add(interval) {
+		upper := lower.Add(interval)
+
+		partition := append(pgx.Identifier{}, parent...)
+		partition[len(partition)-1] += "_" + lower.UTC().Format(layout)
+
+		sql := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s PARTITION OF %s FOR VALUES FROM (%s) TO (%s)`,
+			partition.Sanitize(), parent.Sanitize(), quoteTime(lower), quoteTime(upper))
+		if _, err := db.ExecContext(ctx, sql); err != nil {
+			return names, fmt.Errorf("could not create partition %s: %w", partition.Sanitize(), err)
+		}
+		names = append(names, strings.Join(partition, "."))
+	}
+	return names, nil
+}
+
+// quoteTime formats t as a SQL timestamp literal.
+func quoteTime(t time.Time) string {
+	return quoteLiteral(t.Format("2006-01-02 15:04:05.999999Z07:00"))
+}
@@ -0,0 +1,66 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/postgres"
+)
+
+func TestCreateRangePartitions(t *testing.T) {
+	c := postgres.Start(t,
+		postgres.WithTimeout(30*time.Second),
+		postgres.WithPostStart(func(c *postgres.Container) error {
+			_, err := c.DB().Exec(`CREATE TABLE events (
+				id BIGINT NOT NULL,
+				created_at TIMESTAMPTZ NOT NULL
+			) PARTITION BY RANGE (created_at)`)
+			return err
+		}),
+	)
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	names, err := postgres.CreateRangePartitions(ctx, c.DB(), "public.events", "created_at", from, to, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("could not create partitions: %v", err)
+	}
+	if want, have := 7, len(names); want != have {
+		t.Fatalf("want %d partitions, have %d", want, have)
+	}
+	if want, have := "public.events_20240101", names[0]; want != have {
+		t.Fatalf("want partition %q, have %q", want, have)
+	}
+
+	// Seed data is routed into the partitions
+	n, err := postgres.GenerateRows(ctx, c.DB(), "events", 7*24, func(i int) []any {
+		return []any{int64(i), from.Add(time.Duration(i) * time.Hour)}
+	})
+	if err != nil {
+		t.Fatalf("could not generate rows: %v", err)
+	}
+	if want, have := 7*24, n; want != have {
+		t.Fatalf("want n=%d, have %d", want, have)
+	}
+
+	var count int
+	if err := c.DB().QueryRowContext(ctx, "SELECT COUNT(*) FROM events_20240103").Scan(&count); err != nil {
+		t.Fatalf("could not count rows: %v", err)
+	}
+	if want, have := 24, count; want != have {
+		t.Fatalf("want count=%d, have %d", want, have)
+	}
+
+	// Fails for non-partitioned tables
+	if _, err := c.DB().ExecContext(ctx, "CREATE TABLE plain (created_at TIMESTAMPTZ)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := postgres.CreateRangePartitions(ctx, c.DB(), "plain", "created_at", from, to, 24*time.Hour); err == nil {
+		t.Fatal("expected error, got nil")
+	}
+}
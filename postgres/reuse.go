@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/olivere/integrationtest/core"
+	"github.com/ory/dockertest/v3"
+)
+
+// WithReuse reuses the container that an earlier run of the tests started
+// with the same name, e.g. "app", instead of starting a new one, to skip
+// slow migrations and seeding across runs of go test. The name must be a
+// valid container name.
+//
+// The container is reused only if it runs and was started with the same
+// image, options, and checksum, e.g. of the migrations and fixtures that
+// the post-start operations apply (see core.ChecksumFS). Otherwise, it is
+// removed and a new one is started, so changing a migration rebuilds the
+// container automatically. The checksum is stored in the
+// core.LabelChecksum label of the container and as the comment of the
+// database once the post-start operations succeed.
+//
+// Post-start operations only run on new containers. Close does not remove
+// reused containers; remove them with docker rm -f.
+func WithReuse(name, checksum string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.reuse = name
+		cfg.reuseChecksum = checksum
+	}
+}
+
+// reuseName returns the name of the container reused as name.
+func reuseName(name string) string {
+	return "integrationtest_reuse_" + name
+}
+
+// checksum returns the checksum of the options that the data of a reused
+// container or a snapshot depends on, and of seed, the checksum passed to
+// WithReuse or WithSnapshot.
+func (cfg startConfig) checksum(seed string) string {
+	repository, tag := cfg.image()
+	return core.Checksum(
+		repository+":"+tag,
+		cfg.databaseName,
+		strconv.FormatBool(cfg.inMemory),
+		cfg.icuLocale,
+		cfg.icuRules,
+		strconv.FormatBool(cfg.pgCron),
+		strings.Join(cfg.env, "\n"),
+		seed,
+	)
+}
+
+// reusable returns the container with the given name if it runs and was
+// started with checksum, or nil if there is none. It removes stale
+// containers, i.e. stopped ones or ones with a different checksum.
+func reusable(pool *dockertest.Pool, name, checksum string) (*dockertest.Resource, error) {
+	resource, ok := pool.ContainerByName("^/" + name + "$")
+	if !ok {
+		return nil, nil
+	}
+	if resource.Container.State.Running && resource.Container.Config.Labels[core.LabelChecksum] == checksum {
+		return resource, nil
+	}
+	if err := pool.Purge(resource); err != nil {
+		return nil, fmt.Errorf("could not remove stale PostgreSQL container %s: %w", name, err)
+	}
+	return nil, nil
+}
+
+// forChecksum waits until the database has been seeded with checksum,
+// i.e. the run that started a reused container completed its post-start
+// operations.
+func (c *Container) forChecksum(checksum string) core.WaitStrategy {
+	return func(ctx context.Context, _ core.WaitTarget) error {
+		var comment sql.NullString
+		err := c.db.QueryRowContext(ctx, `SELECT shobj_description(oid, 'pg_database') FROM pg_database WHERE datname = current_database()`).Scan(&comment)
+		if err != nil {
+			return err
+		}
+		if comment.String != checksum {
+			return errors.New("database is not seeded yet")
+		}
+		return nil
+	}
+}
+
+// setChecksum stores checksum as the comment of the database, to tell
+// later runs that reuse the container that it has been seeded.
+func (c *Container) setChecksum(ctx context.Context, checksum string) error {
+	_, err := c.db.ExecContext(ctx, fmt.Sprintf(`COMMENT ON DATABASE %s IS '%s'`,
+		pgx.Identifier([]string{c.databaseName}).Sanitize(), checksum))
+	if err != nil {
+		return fmt.Errorf("could not store checksum: %w", err)
+	}
+	return nil
+}
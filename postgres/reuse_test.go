@@ -0,0 +1,80 @@
+package postgres_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/olivere/integrationtest/postgres"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+func TestContainer_WithReuse(t *testing.T) {
+	name := "test_" + dockerutil.Suffix()
+	var seeded int
+	start := func(checksum string) *postgres.Container {
+		return postgres.Start(t,
+			postgres.WithReuse(name, checksum),
+			postgres.WithPostStart(func(c *postgres.Container) error {
+				seeded++
+				_, err := c.DB().Exec(fmt.Sprintf(`CREATE TABLE seeds (checksum text); INSERT INTO seeds VALUES ('%s')`, checksum))
+				return err
+			}),
+		)
+	}
+
+	first := start("v1")
+	defer func() {
+		pool, err := dockerutil.NewPool()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pool.Client.RemoveContainer(docker.RemoveContainerOptions{ID: first.Name(), Force: true, RemoveVolumes: true})
+	}()
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	second := start("v1")
+	if want, have := first.Name(), second.Name(); want != have {
+		t.Fatalf("want container %s to be reused, have %s", want, have)
+	}
+	if want, have := 1, seeded; want != have {
+		t.Fatalf("want post-start operations to run %d time, have %d", want, have)
+	}
+	var checksum string
+	if err := second.DB().QueryRow(`SELECT checksum FROM seeds`).Scan(&checksum); err != nil {
+		t.Fatalf("want seeded data of the first run, have %v", err)
+	}
+	if err := second.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	third := start("v2")
+	defer third.Close()
+	if want, have := 2, seeded; want != have {
+		t.Fatalf("want a changed checksum to seed a new container, have %d seeds", have)
+	}
+	if err := third.DB().QueryRow(`SELECT checksum FROM seeds`).Scan(&checksum); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "v2", checksum; want != have {
+		t.Fatalf("want data seeded with %q, have %q", want, have)
+	}
+
+	pool, err := dockerutil.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	container, err := pool.Client.InspectContainer(third.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if container.HostConfig.AutoRemove {
+		t.Error("want reused container not to be removed automatically")
+	}
+	if container.Config.Labels[core.LabelChecksum] == "" {
+		t.Error("want checksum label")
+	}
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+)
+
+var shared struct {
+	mu sync.Mutex
+	c  *Container
+	// skip is the reason to skip tests using the shared container
+	skip string
+}
+
+// MainStart starts a PostgreSQL container that is shared by all tests of
+// a package, runs the tests, and closes the container afterwards. Use it
+// in TestMain and retrieve the container with SharedContainer:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(postgres.MainStart(m))
+//	}
+//
+// The container lives for 10 minutes unless overridden with WithTimeout.
+// MainStart returns the exit code of m.Run, or 1 if the container could
+// not be started. With WithSkipIfNoDocker, MainStart runs the tests
+// without a container if Docker is unavailable, and SharedContainer skips
+// them.
+func MainStart(m *testing.M, options ...startConfigFunc) int {
+	options = append([]startConfigFunc{WithTimeout(10 * time.Minute)}, options...)
+
+	if cfg := newStartConfig(options...); cfg.skipNoDocker && os.Getenv(core.RequireDockerEnv) == "" && cfg.useExternalURL() == "" && !cfg.useKube() {
+		if err := core.DockerAvailable(); err != nil {
+			shared.mu.Lock()
+			shared.skip = fmt.Sprintf("Docker is unavailable: %v", err)
+			shared.mu.Unlock()
+			return m.Run()
+		}
+	}
+
+	c, err := start(context.Background(), options...)
+	if c != nil {
+		defer func() {
+			if err := c.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "could not close shared PostgreSQL container: %v\n", err)
+			}
+		}()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "could not start shared PostgreSQL container: %v\n", err)
+		return 1
+	}
+
+	shared.mu.Lock()
+	shared.c = c
+	shared.mu.Unlock()
+
+	defer func() {
+		shared.mu.Lock()
+		shared.c = nil
+		shared.mu.Unlock()
+	}()
+
+	return m.Run()
+}
+
+// SharedContainer returns the container started by MainStart. It fails
+// the test if MainStart is not used in TestMain.
+//
+// Tests must not close the shared container. Use a template database
+// (see WithIsTemplate and StartFromTemplate) to isolate tests from
+// each other.
+func SharedContainer(tb testing.TB) *Container {
+	tb.Helper()
+
+	shared.mu.Lock()
+	defer shared.mu.Unlock()
+
+	if shared.skip != "" {
+		tb.Skip(shared.skip)
+	}
+	if shared.c == nil {
+		tb.Fatal("no shared PostgreSQL container: call postgres.MainStart in TestMain")
+	}
+	return shared.c
+}
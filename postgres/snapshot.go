@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ory/dockertest/v3/docker"
+)
+
+// snapshotRepository is the repository of the images that WithSnapshot
+// commits.
+const snapshotRepository = "integrationtest-snapshot/postgres"
+
+// snapshotDataDir is the data directory of containers with WithSnapshot.
+// Unlike the default data directory, it is not a volume of the image, so
+// docker commit includes it in the snapshot image.
+const snapshotDataDir = "/var/lib/postgresql/snapshot"
+
+// WithSnapshot starts the container from a snapshot of the data that the
+// post-start operations created in an earlier run, to turn slow seeding
+// into a fast startup across runs of go test. Unlike WithReuse, every
+// container starts from the same data, so tests cannot see the changes of
+// earlier runs.
+//
+// The first run with the image, options, and checksum, e.g. of the
+// migrations and fixtures that the post-start operations apply (see
+// core.ChecksumFS), commits the container after the post-start operations
+// to the local image integrationtest-snapshot/postgres:<checksum>. Later
+// runs start from that image and skip the post-start operations. Changing
+// the checksum takes a new snapshot; remove old ones with docker rmi.
+//
+// WithSnapshot cannot be combined with WithInMemory.
+func WithSnapshot(checksum string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.snapshot = true
+		cfg.snapshotChecksum = checksum
+	}
+}
+
+// snapshotImage returns the repository and tag of the snapshot image of
+// the container with the given checksum, see startConfig.checksum.
+func snapshotImage(checksum string) (string, string) {
+	return snapshotRepository, checksum
+}
+
+// hasImage returns true if the image exists locally.
+func hasImage(client *docker.Client, repository, tag string) bool {
+	_, err := client.InspectImage(repository + ":" + tag)
+	return err == nil
+}
+
+// commitSnapshot commits the container to the given image, after writing
+// all data to disk.
+func (c *Container) commitSnapshot(ctx context.Context, repository, tag string) error {
+	if _, err := c.db.ExecContext(ctx, `CHECKPOINT`); err != nil {
+		return fmt.Errorf("could not write data to disk for the snapshot: %w", err)
+	}
+	// Clear the labels of this run, e.g. core.LabelKeep, which containers
+	// started from the snapshot would inherit otherwise
+	var changes []string
+	for label := range c.resource.Container.Config.Labels {
+		if strings.HasPrefix(label, "org.olivere.integrationtest.") {
+			changes = append(changes, fmt.Sprintf(`LABEL %s=""`, label))
+		}
+	}
+	_, err := c.pool.Client.CommitContainer(docker.CommitContainerOptions{
+		Container:  c.resource.Container.ID,
+		Repository: repository,
+		Tag:        tag,
+		Message:    "integrationtest snapshot",
+		Changes:    changes,
+		Context:    ctx,
+	})
+	if err != nil {
+		return fmt.Errorf("could not commit snapshot %s:%s: %w", repository, tag, err)
+	}
+	return nil
+}
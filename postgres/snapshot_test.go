@@ -0,0 +1,67 @@
+package postgres_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/olivere/integrationtest/internal/dockerutil"
+	"github.com/olivere/integrationtest/postgres"
+	"github.com/ory/dockertest/v3/docker"
+)
+
+func TestContainer_WithSnapshot(t *testing.T) {
+	checksum := dockerutil.Suffix()
+	var seeded int
+	start := func() *postgres.Container {
+		return postgres.Start(t,
+			postgres.WithSnapshot(checksum),
+			postgres.WithPostStart(func(c *postgres.Container) error {
+				seeded++
+				_, err := c.DB().Exec(`CREATE TABLE users (name text); INSERT INTO users VALUES ('alice')`)
+				return err
+			}),
+		)
+	}
+
+	first := start()
+	pool, err := dockerutil.NewPool()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := first.DB().Exec(`INSERT INTO users VALUES ('bob')`); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	second := start()
+	defer second.Close()
+	container, err := pool.Client.InspectContainer(second.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Client.RemoveImageExtended(container.Image, docker.RemoveImageOptions{Force: true})
+	if !strings.HasPrefix(container.Config.Image, "integrationtest-snapshot/postgres:") {
+		t.Fatalf("want container to start from the snapshot, have image %s", container.Config.Image)
+	}
+
+	if want, have := 1, seeded; want != have {
+		t.Fatalf("want post-start operations to run %d time, have %d", want, have)
+	}
+	var n int
+	if err := second.DB().QueryRow(`SELECT COUNT(*) FROM users`).Scan(&n); err != nil {
+		t.Fatalf("want seeded data in the snapshot, have %v", err)
+	}
+	if want, have := 1, n; want != have {
+		t.Fatalf("want %d user of the snapshot, have %d", want, have)
+	}
+}
+
+func TestContainer_WithSnapshot_InMemory(t *testing.T) {
+	_, err := postgres.Run(context.Background(), postgres.WithSnapshot("v1"), postgres.WithInMemory(true))
+	if err == nil {
+		t.Fatal("want error, have nil")
+	}
+}
@@ -0,0 +1,44 @@
+package postgres
+
+import (
+	"github.com/olivere/integrationtest/core"
+)
+
+// WithToxiproxy routes the connections to PostgreSQL through a Toxiproxy
+// sidecar, so that resilience tests can degrade the link, e.g. add
+// latency or cut connections, without touching the database:
+//
+//	c := postgres.Start(t, postgres.WithToxiproxy())
+//	err := c.Toxiproxy().AddToxic(ctx, core.Toxic{
+//		Type:       "latency",
+//		Attributes: map[string]any{"latency": 500},
+//	})
+//
+// The DSN and DB of the container point at the proxy. Other
+// containers that reach PostgreSQL by its name (see WithNetwork) bypass
+// it. WithToxiproxy always starts a container, even if an external server
+// is configured.
+func WithToxiproxy() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.toxiproxy = true
+	}
+}
+
+// Toxiproxy returns the Toxiproxy sidecar that the connections go through,
+// or nil without WithToxiproxy.
+func (c *Container) Toxiproxy() *core.Toxiproxy {
+	return c.toxiproxy
+}
+
+// Chaos returns the Chaos API to inject faults into the link through the
+// Toxiproxy, e.g. to check how the code under test handles a slow
+// database. Its methods fail without WithToxiproxy.
+func (c *Container) Chaos() *core.Chaos {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.chaos == nil {
+		c.chaos = core.NewChaos(c.toxiproxy)
+	}
+	return c.chaos
+}
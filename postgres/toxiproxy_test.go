@@ -0,0 +1,111 @@
+package postgres_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/postgres"
+)
+
+func TestContainer_WithToxiproxy(t *testing.T) {
+	c := postgres.Start(t, postgres.WithToxiproxy())
+	if c.Toxiproxy() == nil {
+		t.Fatal("want Toxiproxy")
+	}
+	if addr := c.Toxiproxy().Addr(); !strings.Contains(c.Endpoint(), "@"+addr+"/") {
+		t.Fatalf("want DSN to point at the proxy %s, have %s", addr, c.Endpoint())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	err := c.Toxiproxy().AddToxic(ctx, core.Toxic{
+		Name:       "slow",
+		Type:       "latency",
+		Attributes: map[string]any{"latency": 300},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := c.DB().ExecContext(ctx, `SELECT 1`); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatalf("want latency of at least 300ms, have %v", elapsed)
+	}
+	if err := c.Toxiproxy().RemoveToxic(ctx, "slow"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Toxiproxy().Disable(ctx); err != nil {
+		t.Fatal(err)
+	}
+	pingCtx, pingCancel := context.WithTimeout(ctx, 2*time.Second)
+	defer pingCancel()
+	if err := c.DB().PingContext(pingCtx); err == nil {
+		t.Fatal("want ping to fail while the proxy is disabled")
+	}
+	if err := c.Toxiproxy().Reset(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DB().PingContext(ctx); err != nil {
+		t.Fatalf("want ping to succeed after Reset, have %v", err)
+	}
+}
+
+func TestContainer_Chaos(t *testing.T) {
+	c := postgres.Start(t, postgres.WithToxiproxy())
+	chaos := c.Chaos()
+
+	ping := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		return c.DB().PingContext(ctx)
+	}
+
+	if err := chaos.AddLatency(300*time.Millisecond, 0); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if err := ping(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatalf("want latency of at least 300ms, have %v", elapsed)
+	}
+	if err := chaos.Reset(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := chaos.Partition(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ping(); err == nil {
+		t.Fatal("want ping to fail during the partition")
+	}
+	if err := chaos.Heal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ping(); err != nil {
+		t.Fatalf("want ping to succeed after Heal, have %v", err)
+	}
+
+	chaos.Window(0, time.Second, core.PartitionFault())
+	if err := chaos.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ping(); err != nil {
+		t.Fatalf("want ping to succeed after the window, have %v", err)
+	}
+}
+
+func TestContainer_Chaos_NoToxiproxy(t *testing.T) {
+	c := postgres.Start(t)
+	if err := c.Chaos().Partition(); err == nil {
+		t.Fatal("want error without WithToxiproxy")
+	}
+}
@@ -0,0 +1,14 @@
+package redis
+
+import (
+	"github.com/olivere/integrationtest/core"
+)
+
+// ContainerCache is a thread-safe cache for Redis containers.
+type ContainerCache = core.Cache[*Container]
+
+// NewContainerCache returns a new ContainerCache, e.g. with
+// core.WithIdleTTL to close idle containers returned by Acquire.
+func NewContainerCache(options ...core.CacheOption) *ContainerCache {
+	return core.NewCache[*Container](options...)
+}
@@ -0,0 +1,22 @@
+package redis_test
+
+import (
+	"testing"
+
+	"github.com/olivere/integrationtest/redis"
+)
+
+func TestContainerCache_GetOrCreate(t *testing.T) {
+	cache := redis.NewContainerCache()
+	defer cache.Close()
+
+	c1 := cache.GetOrCreate("one", func() *redis.Container {
+		return redis.Start(t)
+	})
+	c2 := cache.GetOrCreate("one", func() *redis.Container {
+		return redis.Start(t)
+	})
+	if c1 != c2 {
+		t.Fatal("want same container, have different")
+	}
+}
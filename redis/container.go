@@ -0,0 +1,461 @@
+// Package redis starts Redis containers for integration tests, or
+// containers of the compatible Valkey, see WithEngine.
+package redis
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/core"
+	"github.com/olivere/integrationtest/internal/fixture"
+	"github.com/ory/dockertest/v3"
+	"github.com/redis/go-redis/v9"
+)
+
+// databases is the number of logical databases of the server, see
+// Container.StartDB.
+const databases = 64
+
+type Container struct {
+	engine   Engine
+	addr     string
+	password string
+	client   *redis.Client
+	fixture  *fixture.Container
+
+	mu     sync.Mutex
+	closed bool
+	// used are the logical databases in use, see StartDB
+	used [databases]bool
+}
+
+type startConfig struct {
+	fixture.Config
+	engine    Engine
+	password  string
+	postStart []postStartFunc
+}
+
+type startConfigFunc func(*startConfig)
+
+type postStartFunc func(ctx context.Context, c *Container) error
+
+var _ core.Container = (*Container)(nil)
+
+// WithOptions applies the options that all container packages support,
+// see core.Config.
+func WithOptions(options ...core.Option) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.Apply(options...)
+	}
+}
+
+// Engine is the server that the container runs.
+type Engine string
+
+const (
+	// Redis runs the redis image. It is the default.
+	Redis Engine = "redis"
+	// Valkey runs the valkey/valkey image, the fork of Redis 7.2 of the
+	// Linux Foundation. Clients, commands, and the API of Container are
+	// the same.
+	Valkey Engine = "valkey"
+)
+
+// defaultVersion returns the version of the engine that Start starts
+// unless WithVersion says otherwise.
+func (e Engine) defaultVersion() string {
+	if e == Valkey {
+		return "8"
+	}
+	return "7"
+}
+
+// name returns the name of the server in messages, e.g. "Valkey".
+func (e Engine) name() string {
+	if e == Valkey {
+		return "Valkey"
+	}
+	return "Redis"
+}
+
+// WithEngine sets the server to start, e.g. Valkey to test both engines
+// in one suite:
+//
+//	for _, engine := range []redis.Engine{redis.Redis, redis.Valkey} {
+//		t.Run(string(engine), func(t *testing.T) {
+//			c := redis.Start(t, redis.WithEngine(engine))
+//			...
+//		})
+//	}
+//
+// It defaults to Redis.
+func WithEngine(engine Engine) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.engine = engine
+	}
+}
+
+// WithVersion sets the version of the engine to start, e.g. "7.4" or "6".
+// It defaults to "7" for Redis and "8" for Valkey.
+func WithVersion(version string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.Version = version
+	}
+}
+
+// WithPassword requires clients to authenticate with password. Client
+// and Endpoint use it. By default, the server requires no password.
+func WithPassword(password string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.password = password
+	}
+}
+
+// WithNetwork connects the container to the given Docker network. Other
+// containers on the network can reach it by its Name.
+func WithNetwork(network *dockertest.Network) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.Networks = append(cfg.Networks, network)
+	}
+}
+
+// WithTimeout sets the time to wait for the container to become ready.
+// It is the same as WithStartupTimeout.
+func WithTimeout(timeout time.Duration) startConfigFunc {
+	return WithStartupTimeout(timeout)
+}
+
+// WithStartupTimeout sets the time to wait for the container to become
+// ready. It defaults to 30 seconds.
+func WithStartupTimeout(timeout time.Duration) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.Timeout = timeout
+	}
+}
+
+// WithMaxLifetime sets the time after which Docker kills the container,
+// in case the test process dies before it can remove the container. It
+// defaults to core.DefaultMaxLifetime. Use a negative value to never kill
+// it.
+func WithMaxLifetime(lifetime time.Duration) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.MaxLifetime = lifetime
+	}
+}
+
+// WithCPULimit limits the number of CPUs the container may use, e.g. 1.5.
+func WithCPULimit(cpus float64) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.CPUs = cpus
+	}
+}
+
+// WithMemoryLimit sets the memory limit of the container in bytes.
+func WithMemoryLimit(bytes int64) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.Memory = bytes
+	}
+}
+
+// WithPullPolicy sets when to pull the image. It defaults to
+// core.PullIfNotPresent.
+func WithPullPolicy(policy core.PullPolicy) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.PullPolicy = policy
+	}
+}
+
+// WithSkipIfNoDocker skips the test, instead of failing it, if the Docker
+// daemon is unavailable. See core.SkipIfUnavailable.
+func WithSkipIfNoDocker() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.SkipNoDocker = true
+	}
+}
+
+// WithWaitFor sets the strategies that decide when the container is
+// ready, replacing the default of core.ForListeningPort("6379/tcp"). The
+// container is ready when all strategies succeed, in order, and the
+// server answers PING.
+func WithWaitFor(strategies ...core.WaitStrategy) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.WaitFor = strategies
+	}
+}
+
+// WithRetryPolicy sets how readiness checks are retried while the
+// container starts, see core.WithRetryPolicy. By default, they are retried
+// with exponential backoff from 100ms to 5s until the timeout elapses.
+func WithRetryPolicy(maxAttempts int, initialBackoff, maxBackoff time.Duration) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.RetryPolicy = core.RetryPolicy{
+			MaxAttempts:    maxAttempts,
+			InitialBackoff: initialBackoff,
+			MaxBackoff:     maxBackoff,
+		}
+	}
+}
+
+// WithPortRetries sets how often to retry starting the container if a host
+// port is already in use, see core.WithPortRetries. By default, it is
+// retried core.DefaultPortRetries times.
+func WithPortRetries(retries int) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.PortRetries = retries
+	}
+}
+
+// WithBuiltImage builds the image from the Dockerfile in dir instead of
+// pulling it, e.g. a Redis image with modules. See core.WithBuiltImage.
+func WithBuiltImage(dir, dockerfile string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.Build = &core.Build{Dir: dir, Dockerfile: dockerfile}
+	}
+}
+
+// WithKeepContainer keeps the container after the test, e.g. to inspect
+// its state after a failing test. See core.WithKeepContainer.
+func WithKeepContainer() startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.Keep = true
+	}
+}
+
+// WithPostStart adds post-startup operations to the container, e.g. to
+// seed keys or load functions.
+func WithPostStart(funcs ...postStartFunc) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.postStart = append(cfg.postStart, funcs...)
+	}
+}
+
+// Start a Redis container, or a Valkey container with WithEngine(Valkey).
+// The container is removed when the test finishes.
+func Start(tb testing.TB, options ...startConfigFunc) *Container {
+	tb.Helper()
+
+	if newStartConfig(options...).SkipNoDocker {
+		core.SkipIfUnavailable(tb)
+	}
+
+	c, err := start(context.Background(), append(slices.Clip(options), withTestName(tb.Name()))...)
+	if c != nil {
+		tb.Cleanup(func() {
+			c.Close()
+		})
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return c
+}
+
+// Run starts a Redis container outside of tests, e.g. for a local
+// development server that uses the same options as the tests. Unlike
+// Start, the container is not killed after core.DefaultMaxLifetime unless
+// WithMaxLifetime says so. The caller must close the container.
+func Run(ctx context.Context, options ...startConfigFunc) (*Container, error) {
+	c, err := start(ctx, append([]startConfigFunc{WithMaxLifetime(-1)}, options...)...)
+	if err != nil {
+		if c != nil {
+			c.Close()
+		}
+		return nil, err
+	}
+	return c, nil
+}
+
+// withTestName includes the name of the test in the container name.
+func withTestName(name string) startConfigFunc {
+	return func(cfg *startConfig) {
+		cfg.TestName = name
+	}
+}
+
+// newStartConfig returns the defaults with options applied.
+func newStartConfig(options ...startConfigFunc) startConfig {
+	cfg := startConfig{
+		engine: Redis,
+	}
+	cfg.Keep = core.KeepContainers()
+	for _, o := range options {
+		o(&cfg)
+	}
+	return cfg
+}
+
+// image returns the repository and tag of the image to start.
+func (cfg startConfig) image() (string, string) {
+	if cfg.Build != nil {
+		return cfg.Build.Image(string(cfg.engine))
+	}
+	version := cmp.Or(cfg.Version, cfg.engine.defaultVersion())
+	if cfg.engine == Valkey {
+		return core.Image("valkey", "valkey/valkey", version)
+	}
+	return core.Image("redis", "redis", version)
+}
+
+// Image returns the image that Start starts with the given options, e.g.
+// "redis:7" or "valkey/valkey:8", to pull it in advance with core.PullImages.
+func Image(options ...startConfigFunc) string {
+	repository, tag := newStartConfig(options...).image()
+	return repository + ":" + tag
+}
+
+// start a Redis container. If it returns an error along with a non-nil
+// Container, the caller is responsible for closing it.
+func start(ctx context.Context, options ...startConfigFunc) (*Container, error) {
+	startCfg := newStartConfig(options...)
+
+	c := &Container{
+		engine:   startCfg.engine,
+		password: startCfg.password,
+	}
+
+	cmd := []string{string(c.engine) + "-server", "--databases", strconv.Itoa(databases), "--appendonly", "no", "--save", ""}
+	if c.password != "" {
+		cmd = append(cmd, "--requirepass", c.password)
+	}
+
+	repository, tag := startCfg.image()
+	var err error
+	c.fixture, err = fixture.Start(ctx, startCfg.Config, fixture.Spec{
+		Package:    "redis",
+		Server:     c.engine.name(),
+		Name:       string(c.engine),
+		Repository: repository,
+		Tag:        tag,
+		Cmd:        cmd,
+		Port:       "6379/tcp",
+		Timeout:    30 * time.Second,
+		Ready: func(addr string) core.WaitStrategy {
+			c.addr = addr
+			c.client = c.newClient(0)
+			return c.forPing().WithAttemptTimeout(5 * time.Second)
+		},
+	})
+	if c.fixture == nil {
+		return nil, err
+	}
+	if err != nil {
+		return c, err
+	}
+
+	return c, fixture.RunPostStart(ctx, c.fixture, c, startCfg.postStart)
+}
+
+// newClient returns a client of the logical database db.
+func (c *Container) newClient(db int) *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     c.addr,
+		Password: c.password,
+		DB:       db,
+	})
+}
+
+// Close stops and removes the container. It is safe to call Close more
+// than once.
+func (c *Container) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	if err := c.fixture.Close(c.endpoint(0)); err != nil {
+		return err
+	}
+
+	c.closed = true
+
+	if c.client == nil {
+		return nil
+	}
+	return c.client.Close()
+}
+
+// Name returns the name of the Docker container. Other containers on
+// a shared network (see WithNetwork) can use it as the hostname.
+func (c *Container) Name() string {
+	return c.fixture.Name()
+}
+
+// Endpoint returns the URL of the server, e.g.
+// "redis://:secret@localhost:32768/0", as parsed by redis.ParseURL. It
+// implements core.Container.
+func (c *Container) Endpoint() string {
+	return c.endpoint(0)
+}
+
+// endpoint returns the URL of the logical database db.
+func (c *Container) endpoint(db int) string {
+	u := url.URL{
+		Scheme: "redis",
+		Host:   c.addr,
+		Path:   "/" + strconv.Itoa(db),
+	}
+	if c.password != "" {
+		u.User = url.UserPassword("", c.password)
+	}
+	return u.String()
+}
+
+// Engine returns the server that the container runs, see WithEngine.
+func (c *Container) Engine() Engine {
+	return c.engine
+}
+
+// Addr returns the address of the server on the host, e.g.
+// "localhost:32768".
+func (c *Container) Addr() string {
+	return c.addr
+}
+
+// Password returns the password of the server, see WithPassword.
+func (c *Container) Password() string {
+	return c.password
+}
+
+// Client returns a client of the logical database 0.
+func (c *Container) Client() *redis.Client {
+	return c.client
+}
+
+// Logs returns the output of the server so far. It implements core.Container.
+func (c *Container) Logs(ctx context.Context) (string, error) {
+	return c.fixture.Logs(ctx)
+}
+
+// WaitUntilReady waits until the strategies that decided when the
+// container was ready at start succeed again, see WithWaitFor. It
+// implements core.Container.
+func (c *Container) WaitUntilReady(ctx context.Context) error {
+	return c.fixture.WaitUntilReady(ctx)
+}
+
+// forPing waits until the server answers PING, which it does only after
+// it loaded its data.
+func (c *Container) forPing() core.WaitStrategy {
+	return func(ctx context.Context, _ core.WaitTarget) error {
+		return c.client.Ping(ctx).Err()
+	}
+}
+
+// Reset removes all keys of all logical databases with FLUSHALL, e.g. to
+// isolate tests that share the container.
+func (c *Container) Reset(ctx context.Context) error {
+	if err := c.client.FlushAll(ctx).Err(); err != nil {
+		return fmt.Errorf("could not flush Redis: %w", err)
+	}
+	return nil
+}
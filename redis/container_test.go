@@ -0,0 +1,140 @@
+package redis_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestContainer_Start(t *testing.T) {
+	c := redis.Start(t, redis.WithTimeout(30*time.Second))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Client().Set(ctx, "greeting", "hello", 0).Err(); err != nil {
+		t.Fatalf("could not set key: %v", err)
+	}
+	if want, have := "hello", c.Client().Get(ctx, "greeting").Val(); want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+
+	// Clients of the endpoint see the same data
+	opts, err := goredis.ParseURL(c.Endpoint())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := goredis.NewClient(opts)
+	defer client.Close()
+	if want, have := "hello", client.Get(ctx, "greeting").Val(); want != have {
+		t.Fatalf("want %q via Endpoint, have %q", want, have)
+	}
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("could not stop container: %v", err)
+	}
+	if err := client.Ping(ctx).Err(); err == nil {
+		t.Fatal("want error after Close, have nil")
+	}
+}
+
+func TestContainer_WithVersion(t *testing.T) {
+	c := redis.Start(t, redis.WithVersion("6"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := c.Client().Info(ctx, "server").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(info, "redis_version:6.") {
+		t.Fatalf("want Redis 6, have %s", info)
+	}
+}
+
+func TestContainer_WithPassword(t *testing.T) {
+	c := redis.Start(t, redis.WithPassword("s3cret"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Client().Ping(ctx).Err(); err != nil {
+		t.Fatalf("want Client to authenticate, have %v", err)
+	}
+
+	client := goredis.NewClient(&goredis.Options{Addr: c.Addr()})
+	defer client.Close()
+	if err := client.Ping(ctx).Err(); err == nil || !strings.Contains(err.Error(), "NOAUTH") {
+		t.Fatalf("want NOAUTH without password, have %v", err)
+	}
+}
+
+func TestContainer_WithPostStart(t *testing.T) {
+	c := redis.Start(t, redis.WithPostStart(func(ctx context.Context, c *redis.Container) error {
+		return c.Client().Set(ctx, "seeded", "yes", 0).Err()
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if want, have := "yes", c.Client().Get(ctx, "seeded").Val(); want != have {
+		t.Fatalf("want seeded key, have %q", have)
+	}
+}
+
+func TestContainer_Reset(t *testing.T) {
+	c := redis.Start(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := c.Client().Set(ctx, "key", "value", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reset(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := c.Client().DBSize(ctx).Val(); n != 0 {
+		t.Fatalf("want no keys after Reset, have %d", n)
+	}
+}
+
+func TestContainer_WithEngine(t *testing.T) {
+	c := redis.Start(t, redis.WithEngine(redis.Valkey))
+	if want, have := redis.Valkey, c.Engine(); want != have {
+		t.Fatalf("want engine %q, have %q", want, have)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := c.Client().Info(ctx, "server").Result()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(info, "valkey_version:8.") {
+		t.Fatalf("want Valkey 8, have %s", info)
+	}
+	if err := c.Client().Set(ctx, "key", "value", 0).Err(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Reset(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestImage(t *testing.T) {
+	tests := []struct {
+		want string
+		have string
+	}{
+		{want: "redis:7", have: redis.Image()},
+		{want: "redis:7.4", have: redis.Image(redis.WithVersion("7.4"))},
+		{want: "valkey/valkey:8", have: redis.Image(redis.WithEngine(redis.Valkey))},
+		{want: "valkey/valkey:7.2", have: redis.Image(redis.WithEngine(redis.Valkey), redis.WithVersion("7.2"))},
+	}
+	for _, tt := range tests {
+		if tt.want != tt.have {
+			t.Errorf("want %q, have %q", tt.want, tt.have)
+		}
+	}
+}
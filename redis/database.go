@@ -0,0 +1,55 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// StartDB returns a client of a logical database of its own for the test,
+// so that tests that share the container, e.g. in a ContainerCache or
+// with MainStart, can run in parallel without seeing each other's keys:
+//
+//	func TestSessions(t *testing.T) {
+//		t.Parallel()
+//		rdb := redis.SharedContainer(t).StartDB(t)
+//		...
+//	}
+//
+// The database is flushed and released for other tests when the test
+// finishes. The server has 64 databases, of which Client uses the first,
+// so up to 63 tests can use StartDB at the same time.
+func (c *Container) StartDB(tb testing.TB) *redis.Client {
+	tb.Helper()
+
+	c.mu.Lock()
+	db := 0
+	for i := 1; i < len(c.used); i++ {
+		if !c.used[i] {
+			db = i
+			c.used[i] = true
+			break
+		}
+	}
+	c.mu.Unlock()
+	if db == 0 {
+		tb.Fatalf("redis: all %d databases are in use", databases-1)
+	}
+
+	client := c.newClient(db)
+	tb.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := client.FlushDB(ctx).Err(); err != nil {
+			tb.Errorf("redis: could not flush database %d: %v", db, err)
+		}
+		client.Close()
+
+		c.mu.Lock()
+		c.used[db] = false
+		c.mu.Unlock()
+	})
+	return client
+}
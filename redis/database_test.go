@@ -0,0 +1,40 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/redis"
+)
+
+func TestContainer_StartDB(t *testing.T) {
+	c := redis.Start(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var db int
+	t.Run("Isolated", func(t *testing.T) {
+		rdb := c.StartDB(t)
+		db = rdb.Options().DB
+		if db == 0 {
+			t.Fatal("want a database other than the one of Client")
+		}
+		if err := rdb.Set(ctx, "key", "value", 0).Err(); err != nil {
+			t.Fatal(err)
+		}
+		if n := c.Client().Exists(ctx, "key").Val(); n != 0 {
+			t.Fatal("want key to be invisible to Client")
+		}
+	})
+
+	// The database is flushed and released after the test
+	rdb := c.StartDB(t)
+	if want, have := db, rdb.Options().DB; want != have {
+		t.Fatalf("want database %d to be reused, have %d", want, have)
+	}
+	if n := rdb.DBSize(ctx).Val(); n != 0 {
+		t.Fatalf("want database to be flushed, have %d keys", n)
+	}
+}
@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olivere/integrationtest/internal/fixture"
+)
+
+var shared = fixture.Shared[*Container]{Package: "redis", Server: "Redis"}
+
+// MainStart starts a Redis container that is shared by all tests of
+// a package, runs the tests, and closes the container afterwards. Use it
+// in TestMain and retrieve the container with SharedContainer:
+//
+//	func TestMain(m *testing.M) {
+//		os.Exit(redis.MainStart(m))
+//	}
+//
+// The container lives for 10 minutes unless overridden with WithTimeout.
+// MainStart returns the exit code of m.Run, or 1 if the container could
+// not be started. With WithSkipIfNoDocker, MainStart runs the tests
+// without a container if Docker is unavailable, and SharedContainer skips
+// them.
+func MainStart(m *testing.M, options ...startConfigFunc) int {
+	options = append([]startConfigFunc{WithTimeout(10 * time.Minute)}, options...)
+	return shared.Main(m, newStartConfig(options...).SkipNoDocker, func() (*Container, error) {
+		return start(context.Background(), options...)
+	})
+}
+
+// SharedContainer returns the container started by MainStart. It fails
+// the test if MainStart is not used in TestMain.
+//
+// Tests must not close the shared container. Use StartDB to isolate tests
+// from each other.
+func SharedContainer(tb testing.TB) *Container {
+	tb.Helper()
+	return shared.Get(tb)
+}